@@ -0,0 +1,48 @@
+package credential
+
+import (
+	"time"
+
+	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
+)
+
+const (
+	TypeBasic  = "basic"
+	TypeBearer = "bearer"
+	TypeOAuth2 = "oauth2"
+)
+
+type Credential struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name             string `form:"name" json:"name" yaml:"name" binding:"required"`
+	Type             string `form:"type" json:"type" yaml:"type" binding:"required,oneof=basic bearer oauth2"`
+	Secret           `yaml:",inline"`
+}
+
+// Secret 凭据的敏感部分，basic 使用 username/password，bearer 使用 token，oauth2 使用 oauth2
+type Secret struct {
+	Username string  `form:"username" json:"username,omitempty" yaml:"username,omitempty"`
+	Password string  `form:"password" json:"password,omitempty" yaml:"password,omitempty"`
+	Token    string  `form:"token" json:"token,omitempty" yaml:"token,omitempty"`
+	OAuth2   *OAuth2 `json:"oauth2,omitempty" yaml:"oauth2,omitempty"`
+}
+
+// OAuth2 client credentials 授权，代理使用 client_id/client_secret 向 token_url 获取访问令牌，
+// 令牌在过期前自动刷新
+type OAuth2 struct {
+	TokenURL     string   `form:"token_url" json:"token_url" yaml:"token_url"`
+	ClientID     string   `form:"client_id" json:"client_id" yaml:"client_id"`
+	ClientSecret string   `form:"client_secret" json:"client_secret,omitempty" yaml:"client_secret,omitempty"`
+	Scopes       []string `form:"scopes" json:"scopes,omitempty" yaml:"scopes,omitempty"`
+}
+
+// CredentialInfo 凭据的对外展示信息，只有显式请求时才包含敏感内容
+type CredentialInfo struct {
+	UID       string    `json:"uid" yaml:"uid"`
+	Name      string    `json:"name" yaml:"name"`
+	Type      string    `json:"type" yaml:"type"`
+	Targets   int64     `json:"targets" yaml:"targets"`
+	Secret    *Secret   `json:"secret,omitempty" yaml:"secret,omitempty"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
@@ -0,0 +1,29 @@
+package metricrule
+
+import (
+	"time"
+
+	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
+)
+
+// MetricRule 代理返回抓取结果前应用的过滤规则，作用于单个 target 或某个 selector 下的所有 target
+type MetricRule struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name             string `form:"name" json:"name" yaml:"name" binding:"required"`
+	// TargetID 与 Selector 二选一，Selector 的格式为 key=value
+	TargetID uint   `form:"target_id" json:"target_id,omitempty" yaml:"target_id,omitempty"`
+	Selector string `form:"selector" json:"selector,omitempty" yaml:"selector,omitempty"`
+	// Keep 与 Drop 为指标族名称的正则，DropLabels 为标签名称的正则，均为完整匹配
+	Keep       string            `form:"keep" json:"keep,omitempty" yaml:"keep,omitempty"`
+	Drop       string            `form:"drop" json:"drop,omitempty" yaml:"drop,omitempty"`
+	DropLabels string            `form:"drop_labels" json:"drop_labels,omitempty" yaml:"drop_labels,omitempty"`
+	AddLabels  map[string]string `form:"add_labels" json:"add_labels,omitempty" yaml:"add_labels,omitempty"`
+}
+
+// MetricRuleInfo 规则的对外展示信息
+type MetricRuleInfo struct {
+	ID uint `json:"id" yaml:"id"`
+	MetricRule
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
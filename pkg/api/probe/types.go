@@ -0,0 +1,57 @@
+package probe
+
+import (
+	"time"
+
+	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
+)
+
+// Exporter 执行探测的 blackbox exporter，探测 target 通过名称引用
+type Exporter struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name             string `form:"name" json:"name" yaml:"name" binding:"required"`
+	// Address 为 host:port，可以带 http:// 或 https:// 前缀
+	Address    string `form:"address" json:"address" yaml:"address" binding:"required"`
+	MetricPath string `form:"metric_path" json:"metric_path,omitempty" yaml:"metric_path,omitempty"`
+}
+
+// ExporterInfo exporter 的对外展示信息
+type ExporterInfo struct {
+	ID         uint      `json:"id" yaml:"id"`
+	Name       string    `json:"name" yaml:"name"`
+	Address    string    `json:"address" yaml:"address"`
+	Schema     string    `json:"schema" yaml:"schema"`
+	MetricPath string    `json:"metric_path" yaml:"metric_path"`
+	Probes     int64     `json:"probes" yaml:"probes"`
+	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// Probe 批量创建使用同一个 exporter 与 module 的探测 target
+type Probe struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Exporter         string `json:"exporter" yaml:"exporter" binding:"required"`
+	Module           string `json:"module" yaml:"module" binding:"required"`
+	// Targets 探测的 URL 或主机，如 https://example.com 或 10.0.0.1:22
+	Targets          []string          `json:"targets" yaml:"targets" binding:"required,min=1"`
+	Labels           map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	ScrapeTime       int               `json:"scrape_time,omitempty" yaml:"scrape_time,omitempty"`
+	ScrapeTimeout    int               `json:"scrape_timeout,omitempty" yaml:"scrape_timeout,omitempty"`
+	InstanceSelector map[string]string `json:"selectors" yaml:"selectors" binding:"required"`
+}
+
+// ProbeInfo 探测 target 的对外展示信息，ID 即 target 的 ID
+type ProbeInfo struct {
+	ID            uint   `json:"id" yaml:"id"`
+	Exporter      string `json:"exporter" yaml:"exporter"`
+	Module        string `json:"module" yaml:"module"`
+	Target        string `json:"target" yaml:"target"`
+	ScrapeTime    int    `json:"scrape_time" yaml:"scrape_time"`
+	ScrapeTimeout int    `json:"scrape_timeout" yaml:"scrape_timeout"`
+}
+
+// QueryProbes 按 exporter 与 module 过滤探测 target
+type QueryProbes struct {
+	Exporter string `form:"exporter" json:"exporter"`
+	Module   string `form:"module" json:"module"`
+}
@@ -0,0 +1,42 @@
+package profile
+
+import (
+	"time"
+
+	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+// Profile 某类 exporter 的默认抓取配置，如 node、redis，target 通过名称引用
+// target 中未设置的字段使用 profile 的值，labels 与 params 合并，target 中的值优先
+type Profile struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name             string `json:"name" yaml:"name" binding:"required"`
+	// Port 地址中没有端口时追加的端口
+	Port          int               `json:"port,omitempty" yaml:"port,omitempty"`
+	MetricPath    string            `json:"metric_path,omitempty" yaml:"metric_path,omitempty"`
+	ScrapeTime    int               `json:"scrape_time,omitempty" yaml:"scrape_time,omitempty"`
+	ScrapeTimeout int               `json:"scrape_timeout,omitempty" yaml:"scrape_timeout,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Params        map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+	// Auth 保存到凭据表，展示时只包含凭据 UID
+	Auth *target.TargetAuth `json:"auth,omitempty" yaml:"auth,omitempty"`
+}
+
+// ProfileInfo profile 的对外展示信息
+type ProfileInfo struct {
+	ID uint `json:"id" yaml:"id"`
+	Profile
+	// Targets 引用该 profile 创建的 target 数量
+	Targets int64 `json:"targets" yaml:"targets"`
+	// Changed 更新时同步修改的 target 数量
+	Changed   int64     `json:"changed,omitempty" yaml:"changed,omitempty"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// UpdateOptions 更新 profile 时的选项
+type UpdateOptions struct {
+	// Propagate 为 true 时同步修改由该 profile 创建的 target，target 中单独设置过的字段保持不变
+	Propagate bool `form:"propagate" json:"propagate"`
+}
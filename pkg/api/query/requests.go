@@ -19,3 +19,25 @@ type QueryEditSelector struct {
 	NewKey   string `json:"new_key" binding:"required"`   // 新键
 	NewValue string `json:"new_value" binding:"required"` // 新值
 }
+
+// QueryWithShard 用于在多个 Prometheus 分片之间拆分 SD 输出
+// shards 为 0 时表示不分片
+type QueryWithShard struct {
+	Shard    int `form:"shard" json:"shard"`
+	Shards   int `form:"shards" json:"shards"`
+	Replicas int `form:"replicas,default=1" json:"replicas"`
+}
+
+type QueryWithUID struct {
+	UID string `uri:"uid" json:"uid" yaml:"uid" form:"uid" binding:"required"`
+}
+
+// QueryWithResourceVersion 删除时携带的版本号，不为 0 时只有与当前版本一致才删除
+type QueryWithResourceVersion struct {
+	ResourceVersion uint64 `form:"resource_version" json:"resource_version"`
+}
+
+// QueryWithSecrets 查询时是否返回凭据明文，默认只返回凭据引用
+type QueryWithSecrets struct {
+	ShowSecrets bool `form:"show_secrets" json:"show_secrets"`
+}
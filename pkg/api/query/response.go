@@ -1,6 +1,7 @@
 package query
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -53,8 +54,23 @@ func APIResponse(ctx *gin.Context, err error, data interface{}) {
 	})
 }
 
-// 400Response
+// ValidationError 校验失败的错误，Details 为结构化的违规列表
+type ValidationError interface {
+	error
+	Details() interface{}
+}
+
+// 400Response，校验失败时 data 中返回违规列表
 func API400Response(ctx *gin.Context, err error) {
+	var validationError ValidationError
+	if errors.As(err, &validationError) {
+		ctx.JSON(http.StatusBadRequest, Response{
+			Code: ErrValidation.Code,
+			Msg:  validationError.Error(),
+			Data: validationError.Details(),
+		})
+		return
+	}
 	returnCode, message := DecodeErr(err)
 	ctx.JSON(http.StatusBadRequest, Response{
 		Code: returnCode,
@@ -62,6 +78,15 @@ func API400Response(ctx *gin.Context, err error) {
 	})
 }
 
+// 403Response
+func API403Response(ctx *gin.Context, err error) {
+	returnCode, message := DecodeErr(err)
+	ctx.JSON(http.StatusForbidden, Response{
+		Code: returnCode,
+		Msg:  message,
+	})
+}
+
 // 404Response
 func API404Response(ctx *gin.Context, err error) {
 	returnCode, message := DecodeErr(err)
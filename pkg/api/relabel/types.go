@@ -0,0 +1,46 @@
+package relabel
+
+import (
+	"time"
+
+	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
+)
+
+// RelabelRule 作用于 selector 下所有 target 的 relabel 规则，SD 返回结果前按 position 与 id 的顺序应用
+// 字段与 Prometheus 的 relabel_configs 一致，标签中包含 __address__ 与 __ 开头的元标签
+type RelabelRule struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name             string `json:"name" yaml:"name"`
+	// Selector 格式为 key=value
+	Selector     string   `json:"selector" yaml:"selector" binding:"required"`
+	Position     int      `json:"position,omitempty" yaml:"position,omitempty"`
+	SourceLabels []string `json:"source_labels,omitempty" yaml:"source_labels,omitempty"`
+	Separator    string   `json:"separator,omitempty" yaml:"separator,omitempty"`
+	Regex        string   `json:"regex,omitempty" yaml:"regex,omitempty"`
+	TargetLabel  string   `json:"target_label,omitempty" yaml:"target_label,omitempty"`
+	// Replacement 未设置时为 $1，设置为空字符串时 replace 删除 target_label
+	Replacement *string `json:"replacement,omitempty" yaml:"replacement,omitempty"`
+	// Action 支持 replace (默认)、keep、drop、labelmap、labeldrop 与 labelkeep
+	Action string `json:"action,omitempty" yaml:"action,omitempty"`
+}
+
+// RelabelRuleInfo 规则的对外展示信息
+type RelabelRuleInfo struct {
+	ID uint `json:"id" yaml:"id"`
+	RelabelRule
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// SDTarget SD 返回的一组 target
+type SDTarget struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// TestResult 规则对当前一个 target 的效果，Before 为应用已保存的规则后的结果，Dropped 为 true 时 After 为空
+type TestResult struct {
+	Before  SDTarget  `json:"before"`
+	After   *SDTarget `json:"after,omitempty"`
+	Dropped bool      `json:"dropped"`
+}
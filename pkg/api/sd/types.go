@@ -0,0 +1,7 @@
+package sd
+
+type ScrapeConfigQuery struct {
+	Selector string `form:"selector" json:"selector" yaml:"selector" binding:"required"`
+	Format   string `form:"format,default=prometheus" json:"format" yaml:"format"`
+	JobName  string `form:"job_name" json:"job_name,omitempty" yaml:"job_name,omitempty"`
+}
@@ -1,6 +1,9 @@
 package target
 
 import (
+	"time"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
 	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
 )
 
@@ -19,11 +22,41 @@ type TargetItem struct {
 	Labels        map[string]string `json:"labels,omitempty" yaml:"labels,omitempty" form:"labels,omitempty"`
 	Params        map[string]string `json:"params,omitempty" yaml:"params,omitempty" form:"params,omitempty"`
 	Auth          *TargetAuth       `json:"auth,omitempty" yaml:"auth,omitempty"`
+	TLS           *TargetTLS        `json:"tls,omitempty" yaml:"tls,omitempty"`
+	// Headers 代理抓取时添加的请求头，如 X-Api-Key，加密保存
+	Headers map[string]string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Zone target 所在的网络区域，需要在配置文件的 [[zones]] 中定义，区域内的 target 经由代理抓取
+	Zone string `json:"zone,omitempty" yaml:"zone,omitempty"`
+	// Probe 不为空时为 blackbox 探测 target，Address 与 MetricPath 取自 exporter
+	Probe *TargetProbe `json:"probe,omitempty" yaml:"probe,omitempty"`
+	// Profile 引用的 profile 名称，未设置的字段使用 profile 中的默认值
+	Profile string `json:"profile,omitempty" yaml:"profile,omitempty"`
+}
+
+// TargetProbe 探测 target 引用的 blackbox exporter、module 与探测的目标
+type TargetProbe struct {
+	Exporter string `json:"exporter" yaml:"exporter"`
+	Module   string `json:"module" yaml:"module"`
+	Target   string `json:"target" yaml:"target"`
+}
+
+// TargetTLS 抓取 target 时使用的 TLS 配置，文件路径为 pantheon-server 所在主机上的路径
+// 设置后 target 经由代理抓取，由代理使用该配置连接 target
+type TargetTLS struct {
+	CAFile             string `form:"ca_file" json:"ca_file,omitempty" yaml:"ca_file,omitempty"`
+	CertFile           string `form:"cert_file" json:"cert_file,omitempty" yaml:"cert_file,omitempty"`
+	KeyFile            string `form:"key_file" json:"key_file,omitempty" yaml:"key_file,omitempty"`
+	ServerName         string `form:"server_name" json:"server_name,omitempty" yaml:"server_name,omitempty"`
+	InsecureSkipVerify bool   `form:"insecure_skip_verify" json:"insecure_skip_verify,omitempty" yaml:"insecure_skip_verify,omitempty"`
 }
 
 type TargetAuth struct {
 	Base        string `form:"base" json:"base,omitempty" yaml:"base,omitempty"`
 	BearerToken string `form:"bearer_token" json:"bearer_token,omitempty" yaml:"bearer_token,omitempty"`
+	// OAuth2 由代理使用 client credentials 获取访问令牌
+	OAuth2 *credential.OAuth2 `json:"oauth2,omitempty" yaml:"oauth2,omitempty"`
+	// Credential 引用已存在的凭据 UID，优先于 base 与 bearer_token
+	Credential string `form:"credential" json:"credential,omitempty" yaml:"credential,omitempty"`
 }
 
 type TargetList struct {
@@ -39,6 +72,10 @@ type TargetList struct {
 	ParamsString     string            `json:"params_string,omitempty"`
 	SelectorsString  string            `json:"selectors_string,omitempty"`
 	Auth             *TargetAuth       `json:"auth,omitempty"`
+	TLS              *TargetTLS        `json:"tls,omitempty"`
+	Headers          map[string]string `json:"headers,omitempty"`
+	Zone             string            `json:"zone,omitempty"`
+	ResourceVersion  uint64            `json:"resource_version,omitempty"`
 }
 
 type TargetChg struct {
@@ -47,4 +84,44 @@ type TargetChg struct {
 	ScrapeTime    int         `form:"scrap_time,default=30" json:"scrape_time,default=30,omitempty" yaml:"scrap_time" `
 	ScrapeTimeout int         `form:"scrape_timeout,default=10" json:"scrape_timeout,default=10,omitempty" yaml:"scrap_timeout"`
 	Auth          *TargetAuth `json:"auth,omitempty"`
+	TLS           *TargetTLS  `json:"tls,omitempty"`
+	// Headers 整体替换请求头，传入空对象可以清除
+	Headers map[string]string `json:"headers,omitempty"`
+	Zone    string            `json:"zone,omitempty"`
+	// ResourceVersion 不为 0 时只有与 target 当前版本一致才修改，否则返回 409
+	ResourceVersion uint64 `json:"resource_version,omitempty"`
+}
+
+// TargetRevision target 某个版本的完整状态，认证信息只返回凭据内容的摘要
+type TargetRevision struct {
+	Revision        uint64            `json:"revision" yaml:"revision"`
+	Address         string            `json:"address" yaml:"address"`
+	MetricPath      string            `json:"metric_path" yaml:"metric_path"`
+	ScrapeTime      int               `json:"scrape_time" yaml:"scrape_time"`
+	ScrapeTimeout   int               `json:"scrape_timeout" yaml:"scrape_timeout"`
+	Labels          map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+	Params          map[string]string `json:"params,omitempty" yaml:"params,omitempty"`
+	Selectors       map[string]string `json:"selectors,omitempty" yaml:"selectors,omitempty"`
+	AuthFingerprint string            `json:"auth_fingerprint,omitempty" yaml:"auth_fingerprint,omitempty"`
+	Deleted         bool              `json:"deleted,omitempty" yaml:"deleted,omitempty"`
+	CreatedAt       time.Time         `json:"created_at" yaml:"created_at"`
+}
+
+// TargetRollback 回滚 target 到指定版本
+type TargetRollback struct {
+	Revision uint64 `json:"revision" yaml:"revision" binding:"required"`
+}
+
+const (
+	UpsertCreated   = "created"
+	UpsertUpdated   = "updated"
+	UpsertUnchanged = "unchanged"
+)
+
+// UpsertResult PUT targets 中每个 target 的处理结果，Identity 由 schema、地址、路径、params 与 selector 计算
+type UpsertResult struct {
+	ID       uint   `json:"id" yaml:"id"`
+	Address  string `json:"address" yaml:"address"`
+	Identity string `json:"identity" yaml:"identity"`
+	Status   string `json:"status" yaml:"status"`
 }
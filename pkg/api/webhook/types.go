@@ -0,0 +1,113 @@
+package webhook
+
+import (
+	"time"
+
+	metav1 "github.com/cylonchau/pantheon/pkg/api/meta/v1"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+// 事件类型，target 事件在修改提交后发送，selector 事件在 selector 重命名后发送
+const (
+	EventTargetCreated   = "target.created"
+	EventTargetUpdated   = "target.updated"
+	EventTargetDeleted   = "target.deleted"
+	EventSelectorUpdated = "selector.updated"
+)
+
+// Events 支持订阅的事件类型
+var Events = []string{EventTargetCreated, EventTargetUpdated, EventTargetDeleted, EventSelectorUpdated}
+
+// 投递状态
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusDead      = "dead"
+)
+
+// 投递请求的 header，签名为使用 secret 计算的请求体 HMAC-SHA256，格式为 sha256=<hex>
+const (
+	HeaderEvent     = "X-Pantheon-Event"
+	HeaderDelivery  = "X-Pantheon-Delivery"
+	HeaderSignature = "X-Pantheon-Signature"
+)
+
+// Webhook 事件订阅，Selector 不为空时只接收该 selector 下 target 的事件，Events 为空时接收所有事件
+type Webhook struct {
+	*metav1.TypeMeta `form:"kind,omitempty" json:"kind,omitempty" yaml:"kind,omitempty"`
+	Name             string `json:"name" yaml:"name" binding:"required"`
+	URL              string `json:"url" yaml:"url" binding:"required"`
+	// Selector 格式为 key=value
+	Selector string   `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Events   []string `json:"events,omitempty" yaml:"events,omitempty"`
+	// Secret 用于签名请求体，加密保存，查询时不返回
+	Secret string `json:"secret,omitempty" yaml:"secret,omitempty"`
+}
+
+// WebhookInfo 订阅的对外展示信息
+type WebhookInfo struct {
+	ID        uint      `json:"id" yaml:"id"`
+	Name      string    `json:"name" yaml:"name"`
+	URL       string    `json:"url" yaml:"url"`
+	Selector  string    `json:"selector,omitempty" yaml:"selector,omitempty"`
+	Events    []string  `json:"events,omitempty" yaml:"events,omitempty"`
+	HasSecret bool      `json:"has_secret" yaml:"has_secret"`
+	CreatedAt time.Time `json:"created_at" yaml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" yaml:"updated_at"`
+}
+
+// Event 投递的请求体
+type Event struct {
+	ID        string         `json:"id"`
+	Type      string         `json:"type"`
+	Timestamp time.Time      `json:"timestamp"`
+	Target    *TargetEvent   `json:"target,omitempty"`
+	Selector  *SelectorEvent `json:"selector,omitempty"`
+}
+
+// TargetEvent target 事件的内容，为修改后的版本，删除事件为删除前的版本
+type TargetEvent struct {
+	ID uint `json:"id"`
+	target.TargetRevision
+}
+
+// SelectorEvent selector 重命名事件的内容
+type SelectorEvent struct {
+	Old     string `json:"old"`
+	New     string `json:"new"`
+	Targets []uint `json:"targets,omitempty"`
+}
+
+// Delivery 投递记录
+type Delivery struct {
+	ID             uint       `json:"id" yaml:"id"`
+	Webhook        string     `json:"webhook" yaml:"webhook"`
+	EventID        string     `json:"event_id" yaml:"event_id"`
+	Event          string     `json:"event" yaml:"event"`
+	Status         string     `json:"status" yaml:"status"`
+	Attempts       int        `json:"attempts" yaml:"attempts"`
+	LastStatusCode int        `json:"last_status_code,omitempty" yaml:"last_status_code,omitempty"`
+	LastError      string     `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" yaml:"next_attempt_at,omitempty"`
+	DeliveredAt    *time.Time `json:"delivered_at,omitempty" yaml:"delivered_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at" yaml:"created_at"`
+}
+
+// DeadLetter 重试次数用尽的投递，可以重新投递
+type DeadLetter struct {
+	ID         uint      `json:"id" yaml:"id"`
+	DeliveryID uint      `json:"delivery_id" yaml:"delivery_id"`
+	Webhook    string    `json:"webhook" yaml:"webhook"`
+	EventID    string    `json:"event_id" yaml:"event_id"`
+	Event      string    `json:"event" yaml:"event"`
+	Payload    string    `json:"payload" yaml:"payload"`
+	Attempts   int       `json:"attempts" yaml:"attempts"`
+	LastError  string    `json:"last_error,omitempty" yaml:"last_error,omitempty"`
+	CreatedAt  time.Time `json:"created_at" yaml:"created_at"`
+}
+
+// QueryDeliveries 投递记录的查询条件
+type QueryDeliveries struct {
+	Status string `form:"status" json:"status"`
+	Limit  int    `form:"limit" json:"limit"`
+}
@@ -9,9 +9,17 @@ import (
 	"k8s.io/kubectl/pkg/util/templates"
 
 	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/credential"
+	"github.com/cylonchau/pantheon/pkg/cmd/importer"
+	"github.com/cylonchau/pantheon/pkg/cmd/metricrule"
+	"github.com/cylonchau/pantheon/pkg/cmd/probe"
+	"github.com/cylonchau/pantheon/pkg/cmd/profile"
 	"github.com/cylonchau/pantheon/pkg/cmd/push"
+	"github.com/cylonchau/pantheon/pkg/cmd/relabel"
+	"github.com/cylonchau/pantheon/pkg/cmd/sd"
 	"github.com/cylonchau/pantheon/pkg/cmd/selector"
 	"github.com/cylonchau/pantheon/pkg/cmd/target"
+	"github.com/cylonchau/pantheon/pkg/cmd/webhook"
 )
 
 type PantheonctlOptions struct {
@@ -77,12 +85,28 @@ func NewPantheonctlCommand(o PantheonctlOptions) *cobra.Command {
 	selectorCmd := selector.NewCmdselector()
 	versionCmd := NewCmdVersion()
 	pushCmd := push.NewCmdPush()
+	sdCmd := sd.NewCmdSD()
+	importCmd := importer.NewCmdImport()
+	credentialCmd := credential.NewCmdCredential()
+	metricRuleCmd := metricrule.NewCmdMetricRule()
+	probeCmd := probe.NewCmdProbe()
+	profileCmd := profile.NewCmdProfile()
+	relabelCmd := relabel.NewCmdRelabelRule()
+	webhookCmd := webhook.NewCmdWebhook()
 	rootCmd.AddCommand(
 		targetCmd,
 		configCmd,
 		selectorCmd,
 		versionCmd,
 		pushCmd,
+		sdCmd,
+		importCmd,
+		credentialCmd,
+		metricRuleCmd,
+		probeCmd,
+		profileCmd,
+		relabelCmd,
+		webhookCmd,
 	)
 	return rootCmd
 }
@@ -0,0 +1,104 @@
+package credential
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+)
+
+var (
+	createExample = templates.Examples(i18n.T(`
+		# Create a basic auth credential
+		pantheonctl credential create --name mysql --type basic --username exporter --password xxx
+
+		# Create a bearer token credential
+		pantheonctl credential create --name node --type bearer --token xxx
+
+		# Create an oauth2 client credentials credential, the proxy fetches and refreshes the tokens
+		pantheonctl credential create --name saas --type oauth2 --token-url https://auth.example.com/oauth/token \
+		  --client-id pantheon --client-secret xxx --scopes metrics:read`))
+)
+
+// CredentialCreateOptions holds the options for the create command
+type CredentialCreateOptions struct {
+	credential.Credential
+	OAuth2 credential.OAuth2
+}
+
+// NewCredentialCreateOptions creates the options for the create command
+func NewCredentialCreateOptions() *CredentialCreateOptions {
+	return &CredentialCreateOptions{}
+}
+
+// newCmdCredentialCreate creates a new create command
+func newCmdCredentialCreate() *cobra.Command {
+	o := NewCredentialCreateOptions()
+
+	cmd := &cobra.Command{
+		Use:     "create --name node --type bearer --token xxx",
+		Short:   i18n.T("Create a credential"),
+		Example: createExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Validate(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Name, "name", "", "Name of the credential. This is required.")
+	cmd.Flags().StringVar(&o.Type, "type", "", "Type of the credential. One of: basic|bearer|oauth2")
+	cmd.Flags().StringVar(&o.Username, "username", "", "Username of a basic credential.")
+	cmd.Flags().StringVar(&o.Password, "password", "", "Password of a basic credential.")
+	cmd.Flags().StringVar(&o.Token, "token", "", "Token of a bearer credential.")
+	addOAuth2Flags(cmd, &o.OAuth2)
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("type")
+	return cmd
+}
+
+// Validate ensures the secret matches the credential type
+func (o *CredentialCreateOptions) Validate() error {
+	switch o.Type {
+	case credential.TypeBasic:
+		if o.Username == "" {
+			return fmt.Errorf("--username is required for basic credential")
+		}
+	case credential.TypeBearer:
+		if o.Token == "" {
+			return fmt.Errorf("--token is required for bearer credential")
+		}
+	case credential.TypeOAuth2:
+		if o.OAuth2.TokenURL == "" || o.OAuth2.ClientID == "" {
+			return fmt.Errorf("--token-url and --client-id are required for oauth2 credential")
+		}
+		o.Secret.OAuth2 = &o.OAuth2
+	default:
+		return fmt.Errorf("invalid type: %s. Valid values are '%s', '%s' or '%s'", o.Type, credential.TypeBasic, credential.TypeBearer, credential.TypeOAuth2)
+	}
+	return nil
+}
+
+// Run creates the credential and prints its uid
+func (o *CredentialCreateOptions) Run() error {
+	body, err := sonic.Marshal(o.Credential)
+	if err != nil {
+		return err
+	}
+	respBody, err := sendCredentialRequest("CreateCredential", "", body)
+	if err != nil {
+		return err
+	}
+
+	var info credential.CredentialInfo
+	if err := sonic.Unmarshal(respBody, &info); err != nil {
+		return fmt.Errorf("failed to decode response using sonic: %w", err)
+	}
+	fmt.Printf("credential %s created\n", info.UID)
+	return nil
+}
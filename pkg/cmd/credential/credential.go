@@ -0,0 +1,90 @@
+package credential
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	credentialExample = templates.Examples(i18n.T(`
+		# Create a bearer credential and reference it from a target
+		pantheonctl credential create --name node --type bearer --token xxx
+		pantheonctl target add --address 10.0.0.1:9100 --selector app=node --auth-credential <uid>
+
+		# Rotate the token, targets keep referencing the same uid
+		pantheonctl credential rotate <uid> --token yyy`))
+)
+
+// NewCmdCredential creates a new credential command.
+func NewCmdCredential() *cobra.Command {
+	credentialCmd := &cobra.Command{
+		Use:                   "credential",
+		Short:                 "Manage scrape credentials",
+		Aliases:               []string{"cred"},
+		DisableFlagsInUseLine: true,
+		Example:               credentialExample,
+	}
+	credentialCmd.AddCommand(
+		newCmdCredentialCreate(),
+		newCmdCredentialList(),
+		newCmdCredentialRotate(),
+		newCmdCredentialDelete(),
+	)
+	return credentialCmd
+}
+
+// addOAuth2Flags 添加 oauth2 凭据的参数
+func addOAuth2Flags(cmd *cobra.Command, conf *credential.OAuth2) {
+	cmd.Flags().StringVar(&conf.TokenURL, "token-url", "", "Token URL of an oauth2 credential.")
+	cmd.Flags().StringVar(&conf.ClientID, "client-id", "", "Client ID of an oauth2 credential.")
+	cmd.Flags().StringVar(&conf.ClientSecret, "client-secret", "", "Client secret of an oauth2 credential.")
+	cmd.Flags().StringSliceVar(&conf.Scopes, "scopes", nil, "Comma-separated scopes of an oauth2 credential.")
+}
+
+// sendCredentialRequest 调用凭据接口，非 200 时解析错误信息
+func sendCredentialRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
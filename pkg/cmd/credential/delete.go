@@ -0,0 +1,33 @@
+package credential
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	deleteExample = templates.Examples(i18n.T(`
+		# Delete a credential that is no longer referenced by any target
+		pantheonctl credential delete <uid>`))
+)
+
+// newCmdCredentialDelete creates a new delete command
+func newCmdCredentialDelete() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <uid>",
+		Short:   i18n.T("Delete a credential"),
+		Aliases: []string{"rm", "del"},
+		Example: deleteExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendCredentialRequest("DeleteCredential", "/"+args[0], nil); err != nil {
+				return err
+			}
+			fmt.Printf("credential %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package credential
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+)
+
+var (
+	listExample = templates.Examples(i18n.T(`
+		# List all credentials
+		pantheonctl credential list`))
+)
+
+// newCmdCredentialList creates a new list command
+func newCmdCredentialList() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List credentials"),
+		Aliases: []string{"ls"},
+		Example: listExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendCredentialRequest("ListCredentials", "", nil)
+			if err != nil {
+				return err
+			}
+
+			var credentials []credential.CredentialInfo
+			if err := sonic.Unmarshal(respBody, &credentials); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(credentials) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "UID\tNAME\tTYPE\tTARGETS\tUPDATED")
+			for _, info := range credentials {
+				fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\n", info.UID, info.Name, info.Type, info.Targets, info.UpdatedAt.Format("2006-01-02 15:04:05"))
+			}
+			return w.Flush()
+		},
+	}
+}
@@ -0,0 +1,67 @@
+package credential
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+)
+
+var (
+	rotateExample = templates.Examples(i18n.T(`
+		# Rotate a bearer token
+		pantheonctl credential rotate <uid> --token yyy
+
+		# Rotate a basic auth password
+		pantheonctl credential rotate <uid> --username exporter --password yyy
+
+		# Rotate the client secret of an oauth2 credential
+		pantheonctl credential rotate <uid> --token-url https://auth.example.com/oauth/token --client-id pantheon --client-secret yyy`))
+)
+
+// CredentialRotateOptions holds the options for the rotate command
+type CredentialRotateOptions struct {
+	credential.Secret
+	OAuth2 credential.OAuth2
+}
+
+// newCmdCredentialRotate creates a new rotate command
+func newCmdCredentialRotate() *cobra.Command {
+	o := &CredentialRotateOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "rotate <uid>",
+		Short:   i18n.T("Replace the secret of a credential"),
+		Example: rotateExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Run(args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Username, "username", "", "New username of a basic credential.")
+	cmd.Flags().StringVar(&o.Password, "password", "", "New password of a basic credential.")
+	cmd.Flags().StringVar(&o.Token, "token", "", "New token of a bearer credential.")
+	addOAuth2Flags(cmd, &o.OAuth2)
+	return cmd
+}
+
+// Run rotates the credential
+func (o *CredentialRotateOptions) Run(uid string) error {
+	if o.OAuth2.TokenURL != "" || o.OAuth2.ClientID != "" {
+		o.Secret.OAuth2 = &o.OAuth2
+	}
+	body, err := sonic.Marshal(o.Secret)
+	if err != nil {
+		return err
+	}
+	if _, err = sendCredentialRequest("RotateCredential", fmt.Sprintf("/%s/rotate", uid), body); err != nil {
+		return err
+	}
+	fmt.Printf("credential %s rotated\n", uid)
+	return nil
+}
@@ -0,0 +1,171 @@
+package importer
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	importExample = templates.Examples(i18n.T(`
+		# Import targets from prometheus.yml, one selector per job
+		pantheonctl import prometheus -f prometheus.yml --selector-from job`))
+
+	importPrometheusExample = templates.Examples(i18n.T(`
+		# Import targets from prometheus.yml, one selector per job
+		pantheonctl import prometheus -f prometheus.yml --selector-from job
+
+		# Use the value of the "env" label as selector
+		pantheonctl import prometheus -f prometheus.yml --selector-from label:env
+
+		# Print the converted targets without creating them
+		pantheonctl import prometheus -f prometheus.yml --dry-run`))
+)
+
+// NewCmdImport creates a new import command.
+func NewCmdImport() *cobra.Command {
+	importCmd := &cobra.Command{
+		Use:                   "import",
+		Short:                 "Import targets from other configurations",
+		DisableFlagsInUseLine: true,
+		Example:               importExample,
+	}
+	importCmd.AddCommand(newCmdImportPrometheus())
+	return importCmd
+}
+
+// ImportPrometheusOptions holds the options for the import prometheus command
+type ImportPrometheusOptions struct {
+	FilePath     string
+	SelectorFrom string
+	DryRun       bool
+}
+
+// NewImportPrometheusOptions creates the options for the import prometheus command
+func NewImportPrometheusOptions() *ImportPrometheusOptions {
+	return &ImportPrometheusOptions{
+		SelectorFrom: selectorFromJob,
+	}
+}
+
+// newCmdImportPrometheus creates a new import prometheus command
+func newCmdImportPrometheus() *cobra.Command {
+	o := NewImportPrometheusOptions()
+
+	cmd := &cobra.Command{
+		Use:     "prometheus -f prometheus.yml",
+		Short:   i18n.T("Import static_configs and file_sd_configs from a prometheus.yml"),
+		Example: importPrometheusExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().StringVarP(&o.FilePath, "file", "f", "", "Path to the prometheus.yml to import")
+	cmd.Flags().StringVar(&o.SelectorFrom, "selector-from", o.SelectorFrom, "Where the selector comes from. One of: job|label:<name>")
+	cmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "Print the converted targets without creating them")
+	cmd.MarkFlagRequired("file")
+	return cmd
+}
+
+// Run converts the prometheus config and creates the targets
+func (o *ImportPrometheusOptions) Run() error {
+	data, err := os.ReadFile(o.FilePath)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %s", err)
+	}
+
+	targets, report, err := ConvertPrometheusConfig(data, filepath.Dir(o.FilePath), o.SelectorFrom)
+	if err != nil {
+		return err
+	}
+
+	if o.DryRun {
+		output, err := yaml.Marshal(targets)
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(output))
+	} else {
+		for _, t := range targets {
+			if err := addTarget(t); err != nil {
+				return err
+			}
+			for key, value := range t.InstanceSelector {
+				fmt.Printf("%d targets imported for selector %s=%s\n", len(t.Targets), key, value)
+			}
+		}
+	}
+
+	printReport(os.Stderr, report)
+	return nil
+}
+
+func addTarget(t target.Target) error {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	body, err := sonic.Marshal(t)
+	if err != nil {
+		return err
+	}
+
+	api, exists := path_map.APIInterfaces["AddTarget"]
+	if !exists {
+		return fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s", cluster.Cluster.Server, api.Path)
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(body, &responseBody); err != nil {
+			return fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return fmt.Errorf("failed to import targets: %s", responseBody.Msg)
+	}
+	return nil
+}
+
+// printReport 输出无法导入的配置项，relabel 规则原样打印便于手工迁移
+func printReport(w io.Writer, report *ImportReport) {
+	if len(report.Skipped) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%d items were skipped:\n", len(report.Skipped))
+	for _, item := range report.Skipped {
+		fmt.Fprintf(w, "- job %q, %s: %s\n", item.Job, item.Item, item.Reason)
+		if item.Detail != "" {
+			for _, line := range strings.Split(item.Detail, "\n") {
+				fmt.Fprintf(w, "    %s\n", line)
+			}
+		}
+	}
+}
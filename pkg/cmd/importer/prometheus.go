@@ -0,0 +1,406 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+const (
+	defaultScrapeInterval = "1m"
+	defaultScrapeTimeout  = "10s"
+	selectorFromJob       = "job"
+	selectorFromLabel     = "label:"
+)
+
+type promConfig struct {
+	Global        promGlobal         `yaml:"global"`
+	ScrapeConfigs []promScrapeConfig `yaml:"scrape_configs"`
+}
+
+type promGlobal struct {
+	ScrapeInterval string `yaml:"scrape_interval"`
+	ScrapeTimeout  string `yaml:"scrape_timeout"`
+}
+
+type promScrapeConfig struct {
+	JobName              string                   `yaml:"job_name"`
+	ScrapeInterval       string                   `yaml:"scrape_interval"`
+	ScrapeTimeout        string                   `yaml:"scrape_timeout"`
+	MetricsPath          string                   `yaml:"metrics_path"`
+	Scheme               string                   `yaml:"scheme"`
+	Params               map[string][]string      `yaml:"params"`
+	BasicAuth            *promBasicAuth           `yaml:"basic_auth"`
+	BearerToken          string                   `yaml:"bearer_token"`
+	BearerTokenFile      string                   `yaml:"bearer_token_file"`
+	Authorization        *promAuthorization       `yaml:"authorization"`
+	OAuth2               *promOAuth2              `yaml:"oauth2"`
+	TLSConfig            *promTLSConfig           `yaml:"tls_config"`
+	StaticConfigs        []promTargetGroup        `yaml:"static_configs"`
+	FileSDConfigs        []promFileSDConfig       `yaml:"file_sd_configs"`
+	RelabelConfigs       []map[string]interface{} `yaml:"relabel_configs"`
+	MetricRelabelConfigs []map[string]interface{} `yaml:"metric_relabel_configs"`
+	Others               map[string]interface{}   `yaml:",inline"`
+}
+
+type promBasicAuth struct {
+	Username     string `yaml:"username"`
+	Password     string `yaml:"password"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+type promAuthorization struct {
+	Type            string `yaml:"type"`
+	Credentials     string `yaml:"credentials"`
+	CredentialsFile string `yaml:"credentials_file"`
+}
+
+type promOAuth2 struct {
+	ClientID         string   `yaml:"client_id"`
+	ClientSecret     string   `yaml:"client_secret"`
+	ClientSecretFile string   `yaml:"client_secret_file"`
+	Scopes           []string `yaml:"scopes"`
+	TokenURL         string   `yaml:"token_url"`
+}
+
+// promTLSConfig 文件路径按 pantheon-server 所在主机上的路径保存
+type promTLSConfig struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+type promTargetGroup struct {
+	Targets []string          `yaml:"targets" json:"targets"`
+	Labels  map[string]string `yaml:"labels" json:"labels"`
+}
+
+type promFileSDConfig struct {
+	Files []string `yaml:"files"`
+}
+
+// SkippedItem 记录无法转换为 pantheon target 的配置项
+type SkippedItem struct {
+	Job    string `json:"job" yaml:"job"`
+	Item   string `json:"item" yaml:"item"`
+	Reason string `json:"reason" yaml:"reason"`
+	Detail string `json:"detail,omitempty" yaml:"detail,omitempty"`
+}
+
+// ImportReport 导入结果报告
+type ImportReport struct {
+	Skipped []SkippedItem `json:"skipped" yaml:"skipped"`
+}
+
+func (r *ImportReport) skip(job, item, reason string, detail interface{}) {
+	entry := SkippedItem{Job: job, Item: item, Reason: reason}
+	if detail != nil {
+		if data, err := yaml.Marshal(detail); err == nil {
+			entry.Detail = strings.TrimSpace(string(data))
+		}
+	}
+	r.Skipped = append(r.Skipped, entry)
+}
+
+// ConvertPrometheusConfig 将 prometheus.yml 中的 static_configs 与 file_sd_configs 转换为 target 请求
+// baseDir 用于解析配置中的相对路径，selectorFrom 为 "job" 或 "label:<name>"
+func ConvertPrometheusConfig(data []byte, baseDir, selectorFrom string) ([]target.Target, *ImportReport, error) {
+	if selectorFrom != selectorFromJob && !(strings.HasPrefix(selectorFrom, selectorFromLabel) && len(selectorFrom) > len(selectorFromLabel)) {
+		return nil, nil, fmt.Errorf("invalid selector source: %s. Expected 'job' or 'label:<name>'", selectorFrom)
+	}
+
+	var config promConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse prometheus config: %w", err)
+	}
+
+	report := &ImportReport{Skipped: []SkippedItem{}}
+	requests := map[string]*target.Target{}
+
+	globalInterval := config.Global.ScrapeInterval
+	if globalInterval == "" {
+		globalInterval = defaultScrapeInterval
+	}
+	globalTimeout := config.Global.ScrapeTimeout
+	if globalTimeout == "" {
+		globalTimeout = defaultScrapeTimeout
+	}
+
+	for _, job := range config.ScrapeConfigs {
+		item, ok := convertJobSettings(job, baseDir, globalInterval, globalTimeout, report)
+		if !ok {
+			continue
+		}
+
+		for _, rule := range job.RelabelConfigs {
+			report.skip(job.JobName, "relabel_configs", "relabel rules are not supported", rule)
+		}
+		for _, rule := range job.MetricRelabelConfigs {
+			report.skip(job.JobName, "metric_relabel_configs", "metric relabel rules are not supported", rule)
+		}
+		for _, key := range sortedKeys(job.Others) {
+			report.skip(job.JobName, key, "unsupported scrape config option", job.Others[key])
+		}
+
+		groups := job.StaticConfigs
+		for _, fileSD := range job.FileSDConfigs {
+			fileGroups, err := readFileSDGroups(fileSD.Files, baseDir)
+			if err != nil {
+				report.skip(job.JobName, "file_sd_configs", err.Error(), fileSD.Files)
+				continue
+			}
+			groups = append(groups, fileGroups...)
+		}
+
+		for _, group := range groups {
+			selectorKey, selectorValue := selectorFromJob, job.JobName
+			if strings.HasPrefix(selectorFrom, selectorFromLabel) {
+				selectorKey = strings.TrimPrefix(selectorFrom, selectorFromLabel)
+				selectorValue = group.Labels[selectorKey]
+				if selectorValue == "" {
+					report.skip(job.JobName, "targets", fmt.Sprintf("target group has no label %q to use as selector", selectorKey), group.Targets)
+					continue
+				}
+			}
+
+			requestKey := selectorKey + "=" + selectorValue
+			request, exists := requests[requestKey]
+			if !exists {
+				request = &target.Target{
+					Targets:          []target.TargetItem{},
+					InstanceSelector: map[string]string{selectorKey: selectorValue},
+				}
+				requests[requestKey] = request
+			}
+
+			for _, address := range group.Targets {
+				targetItem := item
+				targetItem.Address = address
+				if item.Address != "" {
+					targetItem.Address = item.Address + address
+				}
+				targetItem.Labels = copyMap(group.Labels)
+				targetItem.Params = copyMap(item.Params)
+				request.Targets = append(request.Targets, targetItem)
+			}
+		}
+	}
+
+	results := make([]target.Target, 0, len(requests))
+	for _, key := range sortedKeys(requests) {
+		results = append(results, *requests[key])
+	}
+	return results, report, nil
+}
+
+// convertJobSettings 转换 job 级别的配置，返回的 TargetItem.Address 为 schema 前缀
+func convertJobSettings(job promScrapeConfig, baseDir, globalInterval, globalTimeout string, report *ImportReport) (target.TargetItem, bool) {
+	item := target.TargetItem{MetricPath: job.MetricsPath}
+	if item.MetricPath == "" {
+		item.MetricPath = "/metrics"
+	}
+
+	interval := job.ScrapeInterval
+	if interval == "" {
+		interval = globalInterval
+	}
+	scrapeTime, err := parseDurationSeconds(interval)
+	if err != nil {
+		report.skip(job.JobName, "scrape_interval", err.Error(), interval)
+		return item, false
+	}
+	item.ScrapeTime = scrapeTime
+
+	timeout := job.ScrapeTimeout
+	if timeout == "" {
+		timeout = globalTimeout
+	}
+	scrapeTimeout, err := parseDurationSeconds(timeout)
+	if err != nil {
+		report.skip(job.JobName, "scrape_timeout", err.Error(), timeout)
+		return item, false
+	}
+	item.ScrapeTimeout = scrapeTimeout
+
+	switch job.Scheme {
+	case "", "http":
+	case "https":
+		item.Address = "https://"
+	default:
+		report.skip(job.JobName, "scheme", "unsupported scheme", job.Scheme)
+		return item, false
+	}
+
+	if len(job.Params) > 0 {
+		item.Params = map[string]string{}
+		for _, key := range sortedKeys(job.Params) {
+			values := job.Params[key]
+			if len(values) == 0 {
+				continue
+			}
+			item.Params[key] = values[0]
+			if len(values) > 1 {
+				report.skip(job.JobName, "params."+key, "only the first value of a multi-valued param is kept", values[1:])
+			}
+		}
+	}
+
+	auth, err := convertAuth(job, baseDir)
+	if err != nil {
+		report.skip(job.JobName, "auth", err.Error(), nil)
+		return item, false
+	}
+	item.Auth = auth
+
+	if job.TLSConfig != nil {
+		item.TLS = &target.TargetTLS{
+			ServerName:         job.TLSConfig.ServerName,
+			InsecureSkipVerify: job.TLSConfig.InsecureSkipVerify,
+		}
+		if job.TLSConfig.CAFile != "" {
+			item.TLS.CAFile = resolvePath(baseDir, job.TLSConfig.CAFile)
+		}
+		if job.TLSConfig.CertFile != "" {
+			item.TLS.CertFile = resolvePath(baseDir, job.TLSConfig.CertFile)
+		}
+		if job.TLSConfig.KeyFile != "" {
+			item.TLS.KeyFile = resolvePath(baseDir, job.TLSConfig.KeyFile)
+		}
+	}
+	return item, true
+}
+
+func convertAuth(job promScrapeConfig, baseDir string) (*target.TargetAuth, error) {
+	if job.BasicAuth != nil {
+		password := job.BasicAuth.Password
+		if job.BasicAuth.PasswordFile != "" {
+			data, err := os.ReadFile(resolvePath(baseDir, job.BasicAuth.PasswordFile))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read basic_auth password_file: %w", err)
+			}
+			password = strings.TrimSpace(string(data))
+		}
+		return &target.TargetAuth{Base: job.BasicAuth.Username + ":" + password}, nil
+	}
+
+	if job.OAuth2 != nil {
+		clientSecret := job.OAuth2.ClientSecret
+		if job.OAuth2.ClientSecretFile != "" {
+			data, err := os.ReadFile(resolvePath(baseDir, job.OAuth2.ClientSecretFile))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read oauth2 client_secret_file: %w", err)
+			}
+			clientSecret = strings.TrimSpace(string(data))
+		}
+		return &target.TargetAuth{OAuth2: &credential.OAuth2{
+			TokenURL:     job.OAuth2.TokenURL,
+			ClientID:     job.OAuth2.ClientID,
+			ClientSecret: clientSecret,
+			Scopes:       job.OAuth2.Scopes,
+		}}, nil
+	}
+
+	token, tokenFile := job.BearerToken, job.BearerTokenFile
+	if job.Authorization != nil {
+		if job.Authorization.Type != "" && !strings.EqualFold(job.Authorization.Type, "Bearer") {
+			return nil, fmt.Errorf("unsupported authorization type %q", job.Authorization.Type)
+		}
+		token, tokenFile = job.Authorization.Credentials, job.Authorization.CredentialsFile
+	}
+	if tokenFile != "" {
+		data, err := os.ReadFile(resolvePath(baseDir, tokenFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read bearer token file: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		return &target.TargetAuth{BearerToken: token}, nil
+	}
+	return nil, nil
+}
+
+// readFileSDGroups 读取 file_sd_configs 引用的文件，支持 glob、JSON 与 YAML
+func readFileSDGroups(patterns []string, baseDir string) ([]promTargetGroup, error) {
+	var groups []promTargetGroup
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(resolvePath(baseDir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no file matches %s", pattern)
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, err
+			}
+			var fileGroups []promTargetGroup
+			// JSON 是 YAML 的子集，统一使用 YAML 解析
+			if err := yaml.Unmarshal(data, &fileGroups); err != nil {
+				return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+			}
+			groups = append(groups, fileGroups...)
+		}
+	}
+	return groups, nil
+}
+
+// parseDurationSeconds 解析 Prometheus 风格的时长 (支持 d/w/y)，返回秒数
+func parseDurationSeconds(raw string) (int, error) {
+	units := map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour, "y": 365 * 24 * time.Hour}
+	for suffix, unit := range units {
+		if strings.HasSuffix(raw, suffix) {
+			if value, err := strconv.Atoi(strings.TrimSuffix(raw, suffix)); err == nil {
+				return int((time.Duration(value) * unit).Seconds()), nil
+			}
+		}
+	}
+	duration, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", raw)
+	}
+	if duration < time.Second {
+		return 0, fmt.Errorf("duration %q is shorter than one second", raw)
+	}
+	return int(duration.Seconds()), nil
+}
+
+func resolvePath(baseDir, path string) string {
+	if filepath.IsAbs(path) || baseDir == "" {
+		return path
+	}
+	return filepath.Join(baseDir, path)
+}
+
+func copyMap(source map[string]string) map[string]string {
+	if len(source) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(source))
+	for key, value := range source {
+		result[key] = value
+	}
+	return result
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,130 @@
+package importer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testPrometheusConfig = `
+global:
+  scrape_interval: 15s
+  scrape_timeout: 5s
+scrape_configs:
+  - job_name: node
+    scheme: https
+    metrics_path: /probe
+    scrape_interval: 1m
+    params:
+      module: [http_2xx, tcp]
+    basic_auth:
+      username: admin
+      password_file: password
+    static_configs:
+      - targets: ["10.0.0.1:9100", "10.0.0.2:9100"]
+        labels:
+          env: prod
+    relabel_configs:
+      - source_labels: [__address__]
+        target_label: __param_target
+  - job_name: redis
+    authorization:
+      credentials: token
+    file_sd_configs:
+      - files: ["sd/*.json"]
+    consul_sd_configs:
+      - server: localhost:8500
+`
+
+func TestConvertPrometheusConfig(t *testing.T) {
+	// Arrange: 准备 password_file 与 file_sd 文件
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "password"), []byte("secret\n"), 0600))
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sd"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sd", "redis.json"),
+		[]byte(`[{"targets": ["10.0.0.3:9121"], "labels": {"env": "dev"}}]`), 0644))
+
+	// Act
+	targets, report, err := ConvertPrometheusConfig([]byte(testPrometheusConfig), dir, "job")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+
+	node := targets[0]
+	assert.Equal(t, map[string]string{"job": "node"}, node.InstanceSelector)
+	require.Len(t, node.Targets, 2)
+	assert.Equal(t, "https://10.0.0.1:9100", node.Targets[0].Address)
+	assert.Equal(t, "/probe", node.Targets[0].MetricPath)
+	assert.Equal(t, 60, node.Targets[0].ScrapeTime)
+	assert.Equal(t, 5, node.Targets[0].ScrapeTimeout)
+	assert.Equal(t, map[string]string{"env": "prod"}, node.Targets[0].Labels)
+	assert.Equal(t, map[string]string{"module": "http_2xx"}, node.Targets[0].Params)
+	require.NotNil(t, node.Targets[0].Auth)
+	assert.Equal(t, "admin:secret", node.Targets[0].Auth.Base)
+
+	redis := targets[1]
+	assert.Equal(t, map[string]string{"job": "redis"}, redis.InstanceSelector)
+	require.Len(t, redis.Targets, 1)
+	assert.Equal(t, "10.0.0.3:9121", redis.Targets[0].Address)
+	assert.Equal(t, "/metrics", redis.Targets[0].MetricPath)
+	assert.Equal(t, 15, redis.Targets[0].ScrapeTime)
+	assert.Equal(t, "token", redis.Targets[0].Auth.BearerToken)
+
+	items := map[string]SkippedItem{}
+	for _, item := range report.Skipped {
+		items[item.Job+"/"+item.Item] = item
+	}
+	assert.Len(t, report.Skipped, 3)
+	assert.Contains(t, items["node/relabel_configs"].Detail, "__param_target")
+	assert.Contains(t, items, "node/params.module")
+	assert.Contains(t, items, "redis/consul_sd_configs")
+}
+
+func TestConvertPrometheusConfig_SelectorFromLabel(t *testing.T) {
+	// Arrange
+	config := `
+scrape_configs:
+  - job_name: node
+    static_configs:
+      - targets: ["a:9100"]
+        labels: {env: prod}
+      - targets: ["b:9100"]
+        labels: {env: dev}
+      - targets: ["c:9100"]
+`
+
+	// Act
+	targets, report, err := ConvertPrometheusConfig([]byte(config), "", "label:env")
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, targets, 2)
+	assert.Equal(t, map[string]string{"env": "dev"}, targets[0].InstanceSelector)
+	assert.Equal(t, map[string]string{"env": "prod"}, targets[1].InstanceSelector)
+	require.Len(t, report.Skipped, 1)
+	assert.Contains(t, report.Skipped[0].Detail, "c:9100")
+}
+
+func TestConvertPrometheusConfig_InvalidSelectorSource(t *testing.T) {
+	// Act
+	_, _, err := ConvertPrometheusConfig([]byte("scrape_configs: []"), "", "label:")
+
+	// Assert
+	assert.Error(t, err)
+}
+
+func TestParseDurationSeconds(t *testing.T) {
+	tests := map[string]int{"30s": 30, "1m": 60, "1h30m": 5400, "1d": 86400}
+	for raw, want := range tests {
+		got, err := parseDurationSeconds(raw)
+		require.NoError(t, err, raw)
+		assert.Equal(t, want, got, raw)
+	}
+
+	_, err := parseDurationSeconds("500ms")
+	assert.Error(t, err)
+}
@@ -0,0 +1,97 @@
+package metricrule
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/metricrule"
+)
+
+var (
+	createExample = templates.Examples(i18n.T(`
+		# Drop metric families by regex for all targets selected by app=storage
+		pantheonctl metric-rule create --name storage --selector app=storage --drop 'vendor_debug_.*|go_gc_.*'
+
+		# Drop a high cardinality label and add a static label to a single target
+		pantheonctl metric-rule create --name node-1 --target-id 12 --drop-labels 'pod_uid' --add-labels dc=bj,env=prod`))
+)
+
+// MetricRuleCreateOptions holds the options for the create command
+type MetricRuleCreateOptions struct {
+	metricrule.MetricRule
+	AddLabelsString string
+}
+
+// NewMetricRuleCreateOptions creates the options for the create command
+func NewMetricRuleCreateOptions() *MetricRuleCreateOptions {
+	return &MetricRuleCreateOptions{}
+}
+
+// newCmdMetricRuleCreate creates a new create command
+func newCmdMetricRuleCreate() *cobra.Command {
+	o := NewMetricRuleCreateOptions()
+
+	cmd := &cobra.Command{
+		Use:     "create --name storage --selector app=storage --drop 'vendor_.*'",
+		Short:   i18n.T("Create a metric rule"),
+		Example: createExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Complete(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	cmd.Flags().StringVar(&o.Name, "name", "", "Name of the rule. This is required.")
+	cmd.Flags().UintVar(&o.TargetID, "target-id", 0, "ID of the target the rule applies to.")
+	cmd.Flags().StringVar(&o.Selector, "selector", "", "key=value selector, the rule applies to all targets of the selector.")
+	cmd.Flags().StringVar(&o.Keep, "keep", "", "Regex of metric family names to keep, all other families are dropped.")
+	cmd.Flags().StringVar(&o.Drop, "drop", "", "Regex of metric family names to drop.")
+	cmd.Flags().StringVar(&o.DropLabels, "drop-labels", "", "Regex of label names to drop.")
+	cmd.Flags().StringVar(&o.AddLabelsString, "add-labels", "", "Comma-separated key=value pairs of static labels to add.")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
+
+// Complete validates the scope of the rule and parses the static labels
+func (o *MetricRuleCreateOptions) Complete() error {
+	if (o.TargetID == 0) == (o.Selector == "") {
+		return fmt.Errorf("exactly one of --target-id or --selector is required")
+	}
+	if o.AddLabelsString != "" {
+		o.AddLabels = make(map[string]string)
+		for _, pair := range strings.Split(o.AddLabelsString, ",") {
+			kv := strings.SplitN(pair, "=", 2)
+			if len(kv) != 2 || kv[0] == "" {
+				return fmt.Errorf("invalid format for add-labels: expected 'key=value' or 'key1=value1,key2=value2'")
+			}
+			o.AddLabels[kv[0]] = kv[1]
+		}
+	}
+	return nil
+}
+
+// Run creates the rule and prints its id
+func (o *MetricRuleCreateOptions) Run() error {
+	body, err := sonic.Marshal(o.MetricRule)
+	if err != nil {
+		return err
+	}
+	respBody, err := sendMetricRuleRequest("CreateMetricRule", "", body)
+	if err != nil {
+		return err
+	}
+
+	var info metricrule.MetricRuleInfo
+	if err := sonic.Unmarshal(respBody, &info); err != nil {
+		return fmt.Errorf("failed to decode response using sonic: %w", err)
+	}
+	fmt.Printf("metric rule %d created\n", info.ID)
+	return nil
+}
@@ -0,0 +1,33 @@
+package metricrule
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	deleteExample = templates.Examples(i18n.T(`
+		# Delete a metric rule, targets are scraped unfiltered again
+		pantheonctl metric-rule delete <id>`))
+)
+
+// newCmdMetricRuleDelete creates a new delete command
+func newCmdMetricRuleDelete() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <id>",
+		Short:   i18n.T("Delete a metric rule"),
+		Aliases: []string{"rm", "del"},
+		Example: deleteExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendMetricRuleRequest("DeleteMetricRule", "/"+args[0], nil); err != nil {
+				return err
+			}
+			fmt.Printf("metric rule %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
@@ -0,0 +1,75 @@
+package metricrule
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/metricrule"
+)
+
+var (
+	listExample = templates.Examples(i18n.T(`
+		# List all metric rules
+		pantheonctl metric-rule list`))
+)
+
+// newCmdMetricRuleList creates a new list command
+func newCmdMetricRuleList() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List metric rules"),
+		Aliases: []string{"ls"},
+		Example: listExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendMetricRuleRequest("ListMetricRules", "", nil)
+			if err != nil {
+				return err
+			}
+
+			var rules []metricrule.MetricRuleInfo
+			if err := sonic.Unmarshal(respBody, &rules); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(rules) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tSCOPE\tKEEP\tDROP\tDROP LABELS\tADD LABELS")
+			for _, rule := range rules {
+				scope := rule.Selector
+				if rule.TargetID != 0 {
+					scope = fmt.Sprintf("target %d", rule.TargetID)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", rule.ID, rule.Name, scope,
+					orNone(rule.Keep), orNone(rule.Drop), orNone(rule.DropLabels), orNone(formatLabels(rule.AddLabels)))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func formatLabels(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for key, value := range labels {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
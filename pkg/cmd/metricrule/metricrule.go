@@ -0,0 +1,79 @@
+package metricrule
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	metricRuleExample = templates.Examples(i18n.T(`
+		# Drop noisy vendor metrics of all targets selected by app=storage
+		pantheonctl metric-rule create --name storage --selector app=storage --drop 'vendor_debug_.*'
+
+		# Keep only the needed families of a target and tag them with the datacenter
+		pantheonctl metric-rule create --name node-1 --target-id 12 --keep 'node_(cpu|memory)_.*' --add-labels dc=bj`))
+)
+
+// NewCmdMetricRule creates a new metric-rule command.
+func NewCmdMetricRule() *cobra.Command {
+	metricRuleCmd := &cobra.Command{
+		Use:                   "metric-rule",
+		Short:                 "Manage metric filtering rules applied by the scrape proxy",
+		Aliases:               []string{"rule", "mr"},
+		DisableFlagsInUseLine: true,
+		Example:               metricRuleExample,
+	}
+	metricRuleCmd.AddCommand(
+		newCmdMetricRuleCreate(),
+		newCmdMetricRuleList(),
+		newCmdMetricRuleDelete(),
+	)
+	return metricRuleCmd
+}
+
+// sendMetricRuleRequest 调用过滤规则接口，非 200 时解析错误信息
+func sendMetricRuleRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
@@ -43,8 +43,128 @@ var APIInterfaces = map[string]APIInterface{
 		Path:   "/ph/v1/targets",
 		Method: "POST",
 	},
+	"ListTargetRevisions": {
+		Path:   "/ph/v1/targets",
+		Method: "GET",
+	},
+	"RollbackTarget": {
+		Path:   "/ph/v1/targets",
+		Method: "POST",
+	},
 	"CleanDeletedTargets": {
 		Path:   "/ph/v1/targets/clean",
 		Method: "DELETE",
 	},
+	"ListCredentials": {
+		Path:   "/ph/v1/credentials",
+		Method: "GET",
+	},
+	"CreateCredential": {
+		Path:   "/ph/v1/credentials",
+		Method: "PUT",
+	},
+	"RotateCredential": {
+		Path:   "/ph/v1/credentials",
+		Method: "POST",
+	},
+	"DeleteCredential": {
+		Path:   "/ph/v1/credentials",
+		Method: "DELETE",
+	},
+	"ListMetricRules": {
+		Path:   "/ph/v1/metric_rules",
+		Method: "GET",
+	},
+	"CreateMetricRule": {
+		Path:   "/ph/v1/metric_rules",
+		Method: "PUT",
+	},
+	"DeleteMetricRule": {
+		Path:   "/ph/v1/metric_rules",
+		Method: "DELETE",
+	},
+	"ListProbes": {
+		Path:   "/ph/v1/probes",
+		Method: "GET",
+	},
+	"CreateProbes": {
+		Path:   "/ph/v1/probes",
+		Method: "PUT",
+	},
+	"ListProbeExporters": {
+		Path:   "/ph/v1/probe_exporters",
+		Method: "GET",
+	},
+	"CreateProbeExporter": {
+		Path:   "/ph/v1/probe_exporters",
+		Method: "PUT",
+	},
+	"DeleteProbeExporter": {
+		Path:   "/ph/v1/probe_exporters",
+		Method: "DELETE",
+	},
+	"ListProfiles": {
+		Path:   "/ph/v1/profiles",
+		Method: "GET",
+	},
+	"CreateProfile": {
+		Path:   "/ph/v1/profiles",
+		Method: "PUT",
+	},
+	"UpdateProfile": {
+		Path:   "/ph/v1/profiles",
+		Method: "POST",
+	},
+	"DeleteProfile": {
+		Path:   "/ph/v1/profiles",
+		Method: "DELETE",
+	},
+	"ListRelabelRules": {
+		Path:   "/ph/v1/relabel_rules",
+		Method: "GET",
+	},
+	"CreateRelabelRule": {
+		Path:   "/ph/v1/relabel_rules",
+		Method: "PUT",
+	},
+	"TestRelabelRule": {
+		Path:   "/ph/v1/relabel_rules/test",
+		Method: "POST",
+	},
+	"DeleteRelabelRule": {
+		Path:   "/ph/v1/relabel_rules",
+		Method: "DELETE",
+	},
+	"ListWebhooks": {
+		Path:   "/ph/v1/webhooks",
+		Method: "GET",
+	},
+	"CreateWebhook": {
+		Path:   "/ph/v1/webhooks",
+		Method: "PUT",
+	},
+	"UpdateWebhook": {
+		Path:   "/ph/v1/webhooks",
+		Method: "POST",
+	},
+	"DeleteWebhook": {
+		Path:   "/ph/v1/webhooks",
+		Method: "DELETE",
+	},
+	"ListWebhookDeliveries": {
+		Path:   "/ph/v1/webhooks",
+		Method: "GET",
+	},
+	"ListWebhookDeadLetters": {
+		Path:   "/ph/v1/webhook_dead_letters",
+		Method: "GET",
+	},
+	"RedeliverWebhookDeadLetter": {
+		Path:   "/ph/v1/webhook_dead_letters",
+		Method: "POST",
+	},
+	"GetScrapeConfig": {
+		Path:   "/ph/v1/sd/config",
+		Method: "GET",
+	},
 }
@@ -0,0 +1,134 @@
+package probe
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/probe"
+)
+
+var (
+	addExample = templates.Examples(i18n.T(`
+		# Probe a URL with the http_2xx module of the exporter bj
+		pantheonctl probe add --exporter bj --module http_2xx --url https://example.com --selector prom=fed
+
+		# Probe several hosts with labels
+		pantheonctl probe add --exporter bj --module tcp_connect --url 10.0.0.1:22,10.0.0.2:22 --labels team=infra --selector prom=fed`))
+
+	importExample = templates.Examples(i18n.T(`
+		# Probe every URL listed in a file, one per line, lines starting with # are ignored
+		pantheonctl probe import --exporter bj --module http_2xx -f urls.txt --selector prom=fed
+
+		# Read the list from stdin
+		cat urls.txt | pantheonctl probe import --exporter bj --module http_2xx -f - --selector prom=fed`))
+)
+
+// ProbeAddOptions holds the options for the add and import commands
+type ProbeAddOptions struct {
+	probe.Probe
+	filePath string
+}
+
+// NewProbeAddOptions creates the options for the add and import commands
+func NewProbeAddOptions() *ProbeAddOptions {
+	return &ProbeAddOptions{}
+}
+
+func (o *ProbeAddOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Exporter, "exporter", "", "Name of the blackbox exporter running the probes. This is required.")
+	cmd.Flags().StringVar(&o.Module, "module", "", "Blackbox exporter module, e.g. http_2xx. This is required.")
+	cmd.Flags().StringToStringVar(&o.InstanceSelector, "selector", nil, "Comma-separated key=value pairs for instance selectors. This is required.")
+	cmd.Flags().StringToStringVar(&o.Labels, "labels", nil, "Comma-separated key=value pairs for labels.")
+	cmd.Flags().IntVar(&o.ScrapeTime, "scrape-time", 30, "Specify the scrape time of the probes.")
+	cmd.Flags().IntVar(&o.ScrapeTimeout, "scrape-timeout", 10, "Specify the scrape timeout of the probes.")
+	cmd.MarkFlagRequired("exporter")
+	cmd.MarkFlagRequired("module")
+	cmd.MarkFlagRequired("selector")
+}
+
+// newCmdProbeAdd creates a new add command
+func newCmdProbeAdd() *cobra.Command {
+	o := NewProbeAddOptions()
+	cmd := &cobra.Command{
+		Use:     "add --exporter bj --module http_2xx --url https://example.com --selector prom=fed",
+		Short:   i18n.T("Add probe targets"),
+		Aliases: []string{"create"},
+		Example: addExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.Run()
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().StringSliceVar(&o.Targets, "url", nil, "URLs or hosts to probe, repeat the flag or separate them with commas. This is required.")
+	cmd.MarkFlagRequired("url")
+	return cmd
+}
+
+// newCmdProbeImport creates a new import command
+func newCmdProbeImport() *cobra.Command {
+	o := NewProbeAddOptions()
+	cmd := &cobra.Command{
+		Use:     "import --exporter bj --module http_2xx -f urls.txt --selector prom=fed",
+		Short:   i18n.T("Add probe targets from a list of URLs"),
+		Example: importExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.readTargets(); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().StringVarP(&o.filePath, "filename", "f", "", "File with one URL or host per line, - reads from stdin. This is required.")
+	cmd.MarkFlagRequired("filename")
+	return cmd
+}
+
+// readTargets 读取 URL 列表，忽略空行与 # 开头的注释
+func (o *ProbeAddOptions) readTargets() error {
+	var reader io.Reader = os.Stdin
+	if o.filePath != "-" {
+		file, err := os.Open(o.filePath)
+		if err != nil {
+			return fmt.Errorf("failed to open file: %w", err)
+		}
+		defer file.Close()
+		reader = file
+	}
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		o.Targets = append(o.Targets, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	if len(o.Targets) == 0 {
+		return fmt.Errorf("no URLs found in %s", o.filePath)
+	}
+	return nil
+}
+
+// Run creates the probes
+func (o *ProbeAddOptions) Run() error {
+	body, err := sonic.Marshal(o.Probe)
+	if err != nil {
+		return err
+	}
+	if _, err = sendProbeRequest("CreateProbes", "", body); err != nil {
+		return err
+	}
+	fmt.Printf("%d probes added\n", len(o.Targets))
+	return nil
+}
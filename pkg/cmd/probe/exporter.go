@@ -0,0 +1,116 @@
+package probe
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/probe"
+)
+
+var (
+	exporterAddExample = templates.Examples(i18n.T(`
+		# Register a blackbox exporter, the metric path defaults to /probe
+		pantheonctl probe exporter add --name bj --address 10.0.0.5:9115
+
+		# Register an exporter served over https
+		pantheonctl probe exporter add --name sh --address https://blackbox.sh.internal:9115`))
+)
+
+// newCmdExporter creates the exporter command with its subcommands
+func newCmdExporter() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:                   "exporter",
+		Short:                 i18n.T("Manage the blackbox exporters running the probes"),
+		Aliases:               []string{"exporters"},
+		DisableFlagsInUseLine: true,
+	}
+	cmd.AddCommand(
+		newCmdExporterAdd(),
+		newCmdExporterList(),
+		newCmdExporterDelete(),
+	)
+	return cmd
+}
+
+func newCmdExporterAdd() *cobra.Command {
+	o := &probe.Exporter{}
+	cmd := &cobra.Command{
+		Use:     "add --name bj --address 10.0.0.5:9115",
+		Short:   i18n.T("Register a blackbox exporter"),
+		Aliases: []string{"create"},
+		Example: exporterAddExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := sonic.Marshal(o)
+			if err != nil {
+				return err
+			}
+			respBody, err := sendProbeRequest("CreateProbeExporter", "", body)
+			if err != nil {
+				return err
+			}
+			var info probe.ExporterInfo
+			if err := sonic.Unmarshal(respBody, &info); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			fmt.Printf("blackbox exporter %s created\n", info.Name)
+			return nil
+		},
+	}
+	cmd.Flags().StringVar(&o.Name, "name", "", "Name of the exporter, probes reference it by name. This is required.")
+	cmd.Flags().StringVar(&o.Address, "address", "", "Address of the exporter, host:port with an optional http:// or https:// prefix. This is required.")
+	cmd.Flags().StringVar(&o.MetricPath, "metric-path", "/probe", "Probe path of the exporter.")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("address")
+	return cmd
+}
+
+func newCmdExporterList() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List blackbox exporters"),
+		Aliases: []string{"ls"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendProbeRequest("ListProbeExporters", "", nil)
+			if err != nil {
+				return err
+			}
+			var exporters []probe.ExporterInfo
+			if err := sonic.Unmarshal(respBody, &exporters); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(exporters) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tADDRESS\tPATH\tPROBES")
+			for _, e := range exporters {
+				fmt.Fprintf(w, "%s\t%s://%s\t%s\t%d\n", e.Name, e.Schema, e.Address, e.MetricPath, e.Probes)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func newCmdExporterDelete() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <name>",
+		Short:   i18n.T("Delete a blackbox exporter that is no longer referenced by probes"),
+		Aliases: []string{"rm", "del"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendProbeRequest("DeleteProbeExporter", "/"+args[0], nil); err != nil {
+				return err
+			}
+			fmt.Printf("blackbox exporter %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
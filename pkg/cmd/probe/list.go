@@ -0,0 +1,71 @@
+package probe
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/probe"
+)
+
+var (
+	listExample = templates.Examples(i18n.T(`
+		# List all probes
+		pantheonctl probe list
+
+		# List the probes of an exporter, delete one with pantheonctl target delete --id <id>
+		pantheonctl probe list --exporter bj --module http_2xx`))
+)
+
+// newCmdProbeList creates a new list command
+func newCmdProbeList() *cobra.Command {
+	o := &probe.QueryProbes{}
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List probe targets"),
+		Aliases: []string{"ls"},
+		Example: listExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values := url.Values{}
+			if o.Exporter != "" {
+				values.Set("exporter", o.Exporter)
+			}
+			if o.Module != "" {
+				values.Set("module", o.Module)
+			}
+			suffix := ""
+			if len(values) > 0 {
+				suffix = "?" + values.Encode()
+			}
+			respBody, err := sendProbeRequest("ListProbes", suffix, nil)
+			if err != nil {
+				return err
+			}
+
+			var probes []probe.ProbeInfo
+			if err := sonic.Unmarshal(respBody, &probes); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(probes) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "ID\tEXPORTER\tMODULE\tTARGET\tSCRAPE_TIME\tSCRAPE_TIMEOUT")
+			for _, p := range probes {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\n", p.ID, p.Exporter, p.Module, p.Target, p.ScrapeTime, p.ScrapeTimeout)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&o.Exporter, "exporter", "", "Only list the probes of this exporter.")
+	cmd.Flags().StringVar(&o.Module, "module", "", "Only list the probes using this module.")
+	return cmd
+}
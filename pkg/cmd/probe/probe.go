@@ -0,0 +1,83 @@
+package probe
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	probeExample = templates.Examples(i18n.T(`
+		# Register a blackbox exporter
+		pantheonctl probe exporter add --name bj --address 10.0.0.5:9115
+
+		# Probe a URL through the exporter
+		pantheonctl probe add --exporter bj --module http_2xx --url https://example.com --selector prom=fed
+
+		# Probe every URL listed in a file
+		pantheonctl probe import --exporter bj --module http_2xx -f urls.txt --selector prom=fed`))
+)
+
+// NewCmdProbe creates a new probe command.
+func NewCmdProbe() *cobra.Command {
+	probeCmd := &cobra.Command{
+		Use:                   "probe",
+		Short:                 "Manage blackbox probe targets and exporters",
+		Aliases:               []string{"probes"},
+		DisableFlagsInUseLine: true,
+		Example:               probeExample,
+	}
+	probeCmd.AddCommand(
+		newCmdProbeAdd(),
+		newCmdProbeImport(),
+		newCmdProbeList(),
+		newCmdExporter(),
+	)
+	return probeCmd
+}
+
+// sendProbeRequest 调用探测相关接口，非 200 时解析错误信息
+func sendProbeRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
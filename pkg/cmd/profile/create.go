@@ -0,0 +1,125 @@
+package profile
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/profile"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+var (
+	createExample = templates.Examples(i18n.T(`
+		# Create a profile for node-exporter
+		pantheonctl profile create --name node --port 9100 --labels exporter=node
+
+		# Create a profile for mysqld-exporter scraped with basic auth
+		pantheonctl profile create --name mysqld --port 9104 --scrape-time 60 --scrape-timeout 30 --auth-base user:pass`))
+
+	updateExample = templates.Examples(i18n.T(`
+		# Replace the profile, only targets created afterwards use it
+		pantheonctl profile update --name node --port 9100 --scrape-time 15
+
+		# Replace the profile and update the targets created from it, values changed on a target are kept
+		pantheonctl profile update --name node --port 9100 --scrape-time 15 --propagate`))
+)
+
+// ProfileOptions holds the options for the create and update commands
+type ProfileOptions struct {
+	profile.Profile
+	auth      target.TargetAuth
+	propagate bool
+}
+
+// NewProfileOptions creates the options for the create and update commands
+func NewProfileOptions() *ProfileOptions {
+	return &ProfileOptions{}
+}
+
+func (o *ProfileOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Name, "name", "", "Name of the profile, e.g. node or redis. This is required.")
+	cmd.Flags().IntVar(&o.Port, "port", 0, "Port appended to target addresses without a port.")
+	cmd.Flags().StringVar(&o.MetricPath, "metric-path", "", "Metric path of the targets, defaults to /metrics.")
+	cmd.Flags().IntVar(&o.ScrapeTime, "scrape-time", 0, "Scrape time of the targets, defaults to 30.")
+	cmd.Flags().IntVar(&o.ScrapeTimeout, "scrape-timeout", 0, "Scrape timeout of the targets, defaults to 10.")
+	cmd.Flags().StringToStringVar(&o.Labels, "labels", nil, "Comma-separated key=value pairs for labels.")
+	cmd.Flags().StringToStringVar(&o.Params, "params", nil, "Comma-separated key=value pairs for target parameters.")
+	cmd.Flags().StringVar(&o.auth.Base, "auth-base", "", "Basic auth of the targets, user:password.")
+	cmd.Flags().StringVar(&o.auth.BearerToken, "auth-bearer", "", "Bearer token of the targets.")
+	cmd.Flags().StringVar(&o.auth.Credential, "auth-credential", "", "Uid of an existing credential used by the targets.")
+	cmd.MarkFlagRequired("name")
+}
+
+// newCmdProfileCreate creates a new create command
+func newCmdProfileCreate() *cobra.Command {
+	o := NewProfileOptions()
+	cmd := &cobra.Command{
+		Use:     "create --name node --port 9100",
+		Short:   i18n.T("Create a profile"),
+		Aliases: []string{"add"},
+		Example: createExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			info, err := o.Run("CreateProfile", "")
+			if err != nil {
+				return err
+			}
+			fmt.Printf("profile %s created\n", info.Name)
+			return nil
+		},
+	}
+	o.addFlags(cmd)
+	return cmd
+}
+
+// newCmdProfileUpdate creates a new update command
+func newCmdProfileUpdate() *cobra.Command {
+	o := NewProfileOptions()
+	cmd := &cobra.Command{
+		Use:     "update --name node --port 9100 [--propagate]",
+		Short:   i18n.T("Replace a profile"),
+		Example: updateExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			suffix := "/" + url.PathEscape(o.Name)
+			if o.propagate {
+				suffix += "?propagate=true"
+			}
+			info, err := o.Run("UpdateProfile", suffix)
+			if err != nil {
+				return err
+			}
+			if o.propagate {
+				fmt.Printf("profile %s updated, %d targets changed\n", info.Name, info.Changed)
+			} else {
+				fmt.Printf("profile %s updated\n", info.Name)
+			}
+			return nil
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().BoolVar(&o.propagate, "propagate", false, "Also update the targets created from the profile.")
+	return cmd
+}
+
+// Run sends the profile to the server
+func (o *ProfileOptions) Run(apiName, suffix string) (info profile.ProfileInfo, err error) {
+	if o.auth != (target.TargetAuth{}) {
+		o.Auth = &o.auth
+	}
+	body, err := sonic.Marshal(o.Profile)
+	if err != nil {
+		return
+	}
+	respBody, err := sendProfileRequest(apiName, suffix, body)
+	if err != nil {
+		return
+	}
+	if err = sonic.Unmarshal(respBody, &info); err != nil {
+		err = fmt.Errorf("failed to decode response using sonic: %w", err)
+	}
+	return
+}
@@ -0,0 +1,72 @@
+package profile
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+
+	"github.com/cylonchau/pantheon/pkg/api/profile"
+)
+
+// newCmdProfileList creates a new list command
+func newCmdProfileList() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List profiles"),
+		Aliases: []string{"ls"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendProfileRequest("ListProfiles", "", nil)
+			if err != nil {
+				return err
+			}
+			var profiles []profile.ProfileInfo
+			if err := sonic.Unmarshal(respBody, &profiles); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(profiles) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "NAME\tPORT\tPATH\tSCRAPE_TIME\tSCRAPE_TIMEOUT\tLABELS\tTARGETS")
+			for _, p := range profiles {
+				fmt.Fprintf(w, "%s\t%d\t%s\t%d\t%d\t%s\t%d\n", p.Name, p.Port, p.MetricPath, p.ScrapeTime, p.ScrapeTimeout, formatPairs(p.Labels), p.Targets)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// newCmdProfileDelete creates a new delete command
+func newCmdProfileDelete() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <name>",
+		Short:   i18n.T("Delete a profile that is no longer referenced by targets"),
+		Aliases: []string{"rm", "del"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendProfileRequest("DeleteProfile", "/"+url.PathEscape(args[0]), nil); err != nil {
+				return err
+			}
+			fmt.Printf("profile %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+func formatPairs(pairs map[string]string) string {
+	items := make([]string, 0, len(pairs))
+	for key, value := range pairs {
+		items = append(items, key+"="+value)
+	}
+	sort.Strings(items)
+	return strings.Join(items, ",")
+}
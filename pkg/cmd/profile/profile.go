@@ -0,0 +1,83 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	profileExample = templates.Examples(i18n.T(`
+		# Create a profile for node-exporter
+		pantheonctl profile create --name node --port 9100 --labels exporter=node
+
+		# Add a target using the profile, the port, path and labels come from the profile
+		pantheonctl target add --profile node --address 10.0.0.1 --selector prom=fed
+
+		# Change the profile and the targets created from it
+		pantheonctl profile update --name node --port 9100 --scrape-time 15 --labels exporter=node --propagate`))
+)
+
+// NewCmdProfile creates a new profile command.
+func NewCmdProfile() *cobra.Command {
+	profileCmd := &cobra.Command{
+		Use:                   "profile",
+		Short:                 "Manage the target profiles of exporter types",
+		Aliases:               []string{"profiles"},
+		DisableFlagsInUseLine: true,
+		Example:               profileExample,
+	}
+	profileCmd.AddCommand(
+		newCmdProfileCreate(),
+		newCmdProfileUpdate(),
+		newCmdProfileList(),
+		newCmdProfileDelete(),
+	)
+	return profileCmd
+}
+
+// sendProfileRequest 调用 profile 接口，非 200 时解析错误信息
+func sendProfileRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
@@ -0,0 +1,85 @@
+package relabel
+
+import (
+	"fmt"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/relabel"
+)
+
+var (
+	createExample = templates.Examples(i18n.T(`
+		# Copy the host part of the address into a host label
+		pantheonctl relabel-rule create --name host --selector prom=fed --source-labels __address__ --regex '([^:]+):.*' --target-label host
+
+		# Drop the targets of a decommissioned team, rules with a lower position are applied first
+		pantheonctl relabel-rule create --name legacy --selector prom=fed --position 10 --source-labels team --regex legacy --action drop
+
+		# Remove temporary labels
+		pantheonctl relabel-rule create --name tmp --selector prom=fed --regex 'tmp_.*' --action labeldrop`))
+)
+
+// RelabelRuleOptions holds the options for the create and test commands
+type RelabelRuleOptions struct {
+	relabel.RelabelRule
+	replacement string
+}
+
+// NewRelabelRuleOptions creates the options for the create and test commands
+func NewRelabelRuleOptions() *RelabelRuleOptions {
+	return &RelabelRuleOptions{}
+}
+
+func (o *RelabelRuleOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Selector, "selector", "", "key=value selector, the rule applies to the SD response of the selector. This is required.")
+	cmd.Flags().StringSliceVar(&o.SourceLabels, "source-labels", nil, "Labels whose values are joined with the separator and matched against the regex.")
+	cmd.Flags().StringVar(&o.Separator, "separator", "", "Separator of the source label values, defaults to ;.")
+	cmd.Flags().StringVar(&o.Regex, "regex", "", "Regex matched against the whole value, defaults to (.*).")
+	cmd.Flags().StringVar(&o.TargetLabel, "target-label", "", "Label written by the replace action.")
+	cmd.Flags().StringVar(&o.replacement, "replacement", "$1", "Replacement of the replace and labelmap actions, an empty value removes the target label.")
+	cmd.Flags().StringVar(&o.Action, "action", "replace", "One of replace, keep, drop, labelmap, labeldrop or labelkeep.")
+	cmd.MarkFlagRequired("selector")
+}
+
+// Complete only sends the replacement when it is set on the command line
+func (o *RelabelRuleOptions) Complete(cmd *cobra.Command) {
+	if cmd.Flags().Changed("replacement") {
+		o.Replacement = &o.replacement
+	}
+}
+
+// newCmdRelabelRuleCreate creates a new create command
+func newCmdRelabelRuleCreate() *cobra.Command {
+	o := NewRelabelRuleOptions()
+	cmd := &cobra.Command{
+		Use:     "create --name env --selector prom=fed --source-labels env --target-label env",
+		Short:   i18n.T("Create a relabel rule"),
+		Example: createExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Complete(cmd)
+			body, err := sonic.Marshal(o.RelabelRule)
+			if err != nil {
+				return err
+			}
+			respBody, err := sendRelabelRuleRequest("CreateRelabelRule", "", body)
+			if err != nil {
+				return err
+			}
+			var info relabel.RelabelRuleInfo
+			if err := sonic.Unmarshal(respBody, &info); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			fmt.Printf("relabel rule %d created\n", info.ID)
+			return nil
+		},
+	}
+	o.addFlags(cmd)
+	cmd.Flags().StringVar(&o.Name, "name", "", "Name of the rule. This is required.")
+	cmd.Flags().IntVar(&o.Position, "position", 0, "Rules of a selector are applied by position, then by id.")
+	cmd.MarkFlagRequired("name")
+	return cmd
+}
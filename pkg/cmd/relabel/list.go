@@ -0,0 +1,93 @@
+package relabel
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/relabel"
+)
+
+var (
+	listExample = templates.Examples(i18n.T(`
+		# List all relabel rules
+		pantheonctl relabel-rule list
+
+		# List the rules of a selector in the order they are applied
+		pantheonctl relabel-rule list --selector prom=fed`))
+)
+
+// newCmdRelabelRuleList creates a new list command
+func newCmdRelabelRuleList() *cobra.Command {
+	var selector string
+	cmd := &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List relabel rules"),
+		Aliases: []string{"ls"},
+		Example: listExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			suffix := ""
+			if selector != "" {
+				suffix = "?selector=" + url.QueryEscape(selector)
+			}
+			respBody, err := sendRelabelRuleRequest("ListRelabelRules", suffix, nil)
+			if err != nil {
+				return err
+			}
+
+			var rules []relabel.RelabelRuleInfo
+			if err := sonic.Unmarshal(respBody, &rules); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(rules) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tSELECTOR\tPOSITION\tACTION\tSOURCE LABELS\tREGEX\tTARGET LABEL\tREPLACEMENT")
+			for _, rule := range rules {
+				replacement := "$1"
+				if rule.Replacement != nil {
+					replacement = fmt.Sprintf("%q", *rule.Replacement)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%s\t%s\t%s\t%s\t%s\n", rule.ID, rule.Name, rule.Selector, rule.Position, rule.Action,
+					orNone(strings.Join(rule.SourceLabels, ",")), orNone(rule.Regex), orNone(rule.TargetLabel), replacement)
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&selector, "selector", "", "Only list the rules of the key=value selector.")
+	return cmd
+}
+
+// newCmdRelabelRuleDelete creates a new delete command
+func newCmdRelabelRuleDelete() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <id>",
+		Short:   i18n.T("Delete a relabel rule"),
+		Aliases: []string{"rm", "del"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendRelabelRuleRequest("DeleteRelabelRule", "/"+url.PathEscape(args[0]), nil); err != nil {
+				return err
+			}
+			fmt.Printf("relabel rule %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
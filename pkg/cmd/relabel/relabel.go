@@ -0,0 +1,80 @@
+package relabel
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	relabelExample = templates.Examples(i18n.T(`
+		# Standardise the env label for every Prometheus consuming prom=fed
+		pantheonctl relabel-rule create --name env --selector prom=fed --source-labels env --regex 'prod.*' --target-label env --replacement prod
+
+		# Preview the effect of a rule on the current targets before creating it
+		pantheonctl relabel-rule test --selector prom=fed --source-labels team --regex legacy --action drop`))
+)
+
+// NewCmdRelabelRule creates a new relabel-rule command.
+func NewCmdRelabelRule() *cobra.Command {
+	relabelCmd := &cobra.Command{
+		Use:                   "relabel-rule",
+		Short:                 "Manage relabel rules applied to the SD response of a selector",
+		Aliases:               []string{"relabel", "rr"},
+		DisableFlagsInUseLine: true,
+		Example:               relabelExample,
+	}
+	relabelCmd.AddCommand(
+		newCmdRelabelRuleCreate(),
+		newCmdRelabelRuleTest(),
+		newCmdRelabelRuleList(),
+		newCmdRelabelRuleDelete(),
+	)
+	return relabelCmd
+}
+
+// sendRelabelRuleRequest 调用 relabel 规则接口，非 200 时解析错误信息
+func sendRelabelRuleRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
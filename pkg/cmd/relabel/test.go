@@ -0,0 +1,116 @@
+package relabel
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/relabel"
+)
+
+var (
+	testExample = templates.Examples(i18n.T(`
+		# Show which targets of prom=fed a drop rule would remove
+		pantheonctl relabel-rule test --selector prom=fed --source-labels team --regex legacy --action drop
+
+		# Show the labels a labelmap rule would add
+		pantheonctl relabel-rule test --selector prom=fed --regex '__meta_(.+)' --action labelmap`))
+)
+
+// newCmdRelabelRuleTest creates a new test command
+func newCmdRelabelRuleTest() *cobra.Command {
+	o := NewRelabelRuleOptions()
+	cmd := &cobra.Command{
+		Use:     "test --selector prom=fed --source-labels env --target-label env",
+		Short:   i18n.T("Show the effect of a relabel rule on the current targets without saving it"),
+		Example: testExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			o.Complete(cmd)
+			body, err := sonic.Marshal(o.RelabelRule)
+			if err != nil {
+				return err
+			}
+			respBody, err := sendRelabelRuleRequest("TestRelabelRule", "", body)
+			if err != nil {
+				return err
+			}
+			var results []relabel.TestResult
+			if err := sonic.Unmarshal(respBody, &results); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(results) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+			for _, result := range results {
+				printResult(result)
+			}
+			return nil
+		},
+	}
+	o.addFlags(cmd)
+	return cmd
+}
+
+// printResult 输出规则对一个 target 的改动，- 为删除的标签，+ 为新增或修改的标签
+func printResult(result relabel.TestResult) {
+	before := withAddress(result.Before)
+	name := before["instance"]
+	if name == "" {
+		name = before["__address__"]
+	}
+	if result.Dropped {
+		fmt.Printf("%s: dropped\n", name)
+		return
+	}
+	after := withAddress(*result.After)
+	var changes []string
+	for _, label := range unionNames(before, after) {
+		oldValue, inBefore := before[label]
+		newValue, inAfter := after[label]
+		if inBefore && (!inAfter || oldValue != newValue) {
+			changes = append(changes, fmt.Sprintf("  - %s=%s", label, oldValue))
+		}
+		if inAfter && (!inBefore || oldValue != newValue) {
+			changes = append(changes, fmt.Sprintf("  + %s=%s", label, newValue))
+		}
+	}
+	if len(changes) == 0 {
+		fmt.Printf("%s: unchanged\n", name)
+		return
+	}
+	fmt.Printf("%s:\n", name)
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+}
+
+func withAddress(target relabel.SDTarget) map[string]string {
+	labels := make(map[string]string, len(target.Labels)+1)
+	for name, value := range target.Labels {
+		labels[name] = value
+	}
+	if len(target.Targets) > 0 {
+		labels["__address__"] = target.Targets[0]
+	}
+	return labels
+}
+
+func unionNames(a, b map[string]string) []string {
+	seen := make(map[string]bool, len(a)+len(b))
+	names := make([]string, 0, len(a)+len(b))
+	for _, labels := range []map[string]string{a, b} {
+		for name := range labels {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,121 @@
+package sd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/sd"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	sdConfigExample = templates.Examples(i18n.T(`
+		# Generate a Prometheus scrape config
+		pantheonctl sd config --selector prom=fed
+
+		# Generate a vmagent scrape config with a custom job name
+		pantheonctl sd config --selector prom=fed --format vmagent --job-name node`))
+)
+
+// SDConfigOptions holds the options for the sd config command
+type SDConfigOptions struct {
+	Selector string
+	Format   string
+	JobName  string
+}
+
+// NewSDConfigOptions creates the options for the sd config command
+func NewSDConfigOptions() *SDConfigOptions {
+	return &SDConfigOptions{
+		Format: sd.FormatPrometheus,
+	}
+}
+
+// newCmdSDConfig creates a new sd config command
+func newCmdSDConfig() *cobra.Command {
+	o := NewSDConfigOptions()
+
+	configCmd := &cobra.Command{
+		Use:     "config --selector prom=fed",
+		Short:   i18n.T("Generate a scrape config for a selector"),
+		Example: sdConfigExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Validate(cmd, args); err != nil {
+				return err
+			}
+			return o.Run()
+		},
+	}
+
+	configCmd.Flags().StringVar(&o.Selector, "selector", "", "Selector in key=value format. This is required.")
+	configCmd.Flags().StringVar(&o.Format, "format", o.Format, "Output format. One of: prometheus|vmagent")
+	configCmd.Flags().StringVar(&o.JobName, "job-name", "", "Job name of the generated scrape config. Defaults to pantheon-<key>-<value>.")
+	configCmd.MarkFlagRequired("selector")
+	return configCmd
+}
+
+// Validate ensures the selector and format are valid
+func (o *SDConfigOptions) Validate(cmd *cobra.Command, args []string) error {
+	if _, _, err := sd.ParseSelector(o.Selector); err != nil {
+		return err
+	}
+	if o.Format != sd.FormatPrometheus && o.Format != sd.FormatVMAgent {
+		return fmt.Errorf("invalid format: %s. Valid values are '%s' or '%s'", o.Format, sd.FormatPrometheus, sd.FormatVMAgent)
+	}
+	return nil
+}
+
+// Run fetches the scrape config from the server and prints it
+func (o *SDConfigOptions) Run() error {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return err
+	}
+
+	api, exists := path_map.APIInterfaces["GetScrapeConfig"]
+	if !exists {
+		return fmt.Errorf("Unsupported API")
+	}
+
+	params := url.Values{}
+	params.Set("selector", o.Selector)
+	params.Set("format", o.Format)
+	if o.JobName != "" {
+		params.Set("job_name", o.JobName)
+	}
+	requestURL := fmt.Sprintf("%s%s?%s", cluster.Cluster.Server, api.Path, params.Encode())
+
+	resp, err := utils.SendRequest(api.Method, requestURL, nil, cluster.Cluster.Auth)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(body, &responseBody); err != nil {
+			return fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return fmt.Errorf("failed to generate scrape config: %s", responseBody.Msg)
+	}
+
+	fmt.Print(string(body))
+	return nil
+}
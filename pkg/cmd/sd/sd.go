@@ -0,0 +1,26 @@
+package sd
+
+import (
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+)
+
+var (
+	sdExample = templates.Examples(i18n.T(`
+		# Generate a Prometheus scrape config for a selector
+		pantheonctl sd config --selector prom=fed --format prometheus`))
+)
+
+// NewCmdSD creates a new sd command.
+func NewCmdSD() *cobra.Command {
+	sdCmd := &cobra.Command{
+		Use:                   "sd",
+		Short:                 "Service discovery helpers",
+		DisableFlagsInUseLine: true,
+		Example:               sdExample,
+	}
+	sdConfigCmd := newCmdSDConfig()
+	sdCmd.AddCommand(sdConfigCmd)
+	return sdCmd
+}
@@ -31,6 +31,18 @@ var (
 
 		# To attach multiple param to the target (for blackbox).
 		pantheonctl target add --address localhost:9115 --labels dc=prd-190,module=a04-ws --params target=google.com,module=http_2xx --selector prom=fed
+
+		# To scrape an HTTPS target with an internal CA and a client certificate (via the proxy).
+		pantheonctl target add --address 10.0.0.1:9100 --tls-ca-file /etc/pantheon/ca.pem --tls-cert-file /etc/pantheon/client.pem --tls-key-file /etc/pantheon/client-key.pem --selector prom=fed
+
+		# To send custom request headers to the target (via the proxy).
+		pantheonctl target add --address 10.0.0.1:9100 --headers X-Api-Key=xxx,X-Tenant=ops --selector prom=fed
+
+		# To add a target in an isolated network zone (via the proxy of the zone).
+		pantheonctl target add --address 172.16.0.1:9100 --zone dmz --selector prom=fed
+
+		# To add a target with the defaults of the node profile (port, path, intervals, labels and auth).
+		pantheonctl target add --profile node --address 10.0.0.1 --selector prom=fed
 	`))
 )
 
@@ -46,6 +58,10 @@ type TargetAddOptions struct {
 	SelectorsString string
 	ParamsString    string
 	Auth            TargetAuth
+	TLS             TargetTLS
+	Headers         map[string]string
+	Zone            string
+	Profile         string
 }
 
 // NewTargetOptions creates the options for target with default values
@@ -93,6 +109,11 @@ func newCmdTargetAdd() *cobra.Command {
 	addCmd.Flags().StringVar(&o.ParamsString, "params", "", "Comma-separated key=value pairs for target paramters. This is optional.")
 	addCmd.Flags().StringVar(&o.Auth.Base, "auth-base", "", "Specify the base auth of the target. This is optional.")
 	addCmd.Flags().StringVar(&o.Auth.BearerToken, "auth-bearer", "", "Specify the bearer token of the target. This is optional.")
+	addCmd.Flags().StringVar(&o.Auth.Credential, "auth-credential", "", "Specify the uid of an existing credential. This is optional.")
+	addTLSFlags(addCmd, &o.TLS)
+	addCmd.Flags().StringToStringVar(&o.Headers, "headers", nil, "Comma-separated name=value pairs of request headers sent when the proxy scrapes the target, e.g. X-Api-Key=xxx.")
+	addCmd.Flags().StringVar(&o.Profile, "profile", "", "Name of a profile, the metric path, scrape time and timeout, labels, params and auth that are not set use the profile.")
+	addCmd.Flags().StringVar(&o.Zone, "zone", "", "Network zone of the target defined in the server configuration, the target is scraped through the proxy of the zone.")
 	addCmd.MarkFlagRequired("address")
 	addCmd.MarkFlagRequired("selector")
 	return addCmd
@@ -100,6 +121,19 @@ func newCmdTargetAdd() *cobra.Command {
 
 // Complete processes the command line arguments and populates the options
 func (o *TargetAddOptions) Complete(cmd *cobra.Command) error {
+	// 使用 profile 时未指定的参数不发送默认值，由服务端使用 profile 中的值
+	if o.Profile != "" {
+		if !cmd.Flags().Changed("metric-path") {
+			o.MetricPath = ""
+		}
+		if !cmd.Flags().Changed("scrape-time") {
+			o.ScrapeTime = 0
+		}
+		if !cmd.Flags().Changed("scrape-timeout") {
+			o.ScrapeTimeout = 0
+		}
+	}
+
 	// Validate and process labels
 	if o.LabelsString != "" {
 		pairs := strings.Split(o.LabelsString, ",")
@@ -141,9 +175,11 @@ func (o *TargetAddOptions) Complete(cmd *cobra.Command) error {
 func (o *TargetAddOptions) Validate(cmd *cobra.Command, args []string) error {
 	// Define pattern for valid keys (letters, numbers, hyphen, starting with a letter)
 	keyPattern := "^[a-zA-Z][a-zA-Z0-9-]*$"
+	// Label names must be valid Prometheus label names, reserved __ names are checked by the server
+	labelPattern := "^[a-zA-Z_][a-zA-Z0-9_]*$"
 
 	// Validate labels and selectors for key=value format and proper key
-	if err := validateKeyValuePairs(o.Labels, keyPattern); err != nil {
+	if err := validateKeyValuePairs(o.Labels, labelPattern); err != nil {
 		return err
 	}
 	if err := validateKeyValuePairs(o.Selectors, keyPattern); err != nil {
@@ -171,9 +207,14 @@ func (o *TargetAddOptions) Run(args []string) error {
 				Auth: &target.TargetAuth{
 					Base:        o.Auth.Base,
 					BearerToken: o.Auth.BearerToken,
+					Credential:  o.Auth.Credential,
 				},
-				Labels: convertToRequestType(o.Labels),
-				Params: convertToRequestType(o.Params),
+				TLS:     o.TLS.toRequest(),
+				Headers: o.Headers,
+				Zone:    o.Zone,
+				Profile: o.Profile,
+				Labels:  convertToRequestType(o.Labels),
+				Params:  convertToRequestType(o.Params),
 			},
 		},
 		InstanceSelector: convertToRequestType(o.Selectors),
@@ -221,6 +262,20 @@ func (o *TargetAddOptions) Run(args []string) error {
 		return fmt.Errorf("failed to add target: %s", responseBody.Msg)
 	}
 
-	fmt.Printf("target <%s> created\n", o.Address)
+	// 服务端按唯一标识创建或更新，返回每个 target 的处理结果
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+	var responseBody struct {
+		Data []target.UpsertResult `json:"data"`
+	}
+	if err := sonic.Unmarshal(respBody, &responseBody); err != nil || len(responseBody.Data) == 0 {
+		fmt.Printf("target <%s> created\n", o.Address)
+		return nil
+	}
+	for _, result := range responseBody.Data {
+		fmt.Printf("target <%s> %s\n", result.Address, result.Status)
+	}
 	return nil
 }
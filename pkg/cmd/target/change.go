@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strings"
 
 	"github.com/bytedance/sonic"
 	"github.com/spf13/cobra"
@@ -27,6 +28,15 @@ var (
 
 		# Change a target with labels
 		pantheonctl target change --metric-path /prometheus --id 1
+
+		# Skip verification of the target certificate
+		pantheonctl target change --tls-insecure-skip-verify --id 1
+
+		# Replace the request headers sent to the target
+		pantheonctl target change --headers X-Api-Key=xxx,X-Tenant=ops --id 1
+
+		# Change the target only if nobody modified it since version 3
+		pantheonctl target change --scrape-time 60 --resource-version 3 --id 1
 	`))
 )
 
@@ -37,6 +47,11 @@ type TargetChangeOptions struct {
 	ScrapeTime    int
 	ScrapeTimeout int
 	Auth          TargetAuth
+	TLS           TargetTLS
+	Headers       map[string]string
+	Zone          string
+	// ResourceVersion 不为 0 时服务端检查版本，版本冲突时可以重新获取后重试
+	ResourceVersion uint64
 }
 
 // NewTargetChangeOptions creates the options for changing a target with default values
@@ -74,6 +89,11 @@ func newCmdTargetChange() *cobra.Command {
 	changeCmd.Flags().IntVar(&o.ScrapeTimeout, "scrape-timeout", 0, "Specify the scrape timeout of the target.")
 	changeCmd.Flags().StringVar(&o.Auth.Base, "auth-base", "", "Specify the base auth of the target. This is optional.")
 	changeCmd.Flags().StringVar(&o.Auth.BearerToken, "auth-bearer", "", "Specify the bearer token of the target. This is optional.")
+	changeCmd.Flags().StringVar(&o.Auth.Credential, "auth-credential", "", "Specify the uid of an existing credential. This is optional.")
+	addTLSFlags(changeCmd, &o.TLS)
+	changeCmd.Flags().StringToStringVar(&o.Headers, "headers", nil, "Comma-separated name=value pairs, replace the request headers sent when the proxy scrapes the target.")
+	changeCmd.Flags().StringVar(&o.Zone, "zone", "", "Move the target to a network zone defined in the server configuration.")
+	changeCmd.Flags().Uint64Var(&o.ResourceVersion, "resource-version", 0, "Only change the target if it is still at this version, as shown by target list -o json.")
 	changeCmd.MarkFlagRequired("id")
 	return changeCmd
 }
@@ -108,12 +128,12 @@ func (o *TargetChangeOptions) Run(args []string) error {
 		Auth: &target.TargetAuth{
 			Base:        o.Auth.Base,
 			BearerToken: o.Auth.BearerToken,
+			Credential:  o.Auth.Credential,
 		},
-	}
-
-	body, err := json.Marshal(targetQuery)
-	if err != nil {
-		return err
+		TLS:             o.TLS.toRequest(),
+		Headers:         o.Headers,
+		Zone:            o.Zone,
+		ResourceVersion: o.ResourceVersion,
 	}
 
 	api, exists := path_map.APIInterfaces["ChangeTarget"]
@@ -122,29 +142,91 @@ func (o *TargetChangeOptions) Run(args []string) error {
 	}
 	url := fmt.Sprintf("%s%s/%d", cluster.Cluster.Server, api.Path, o.ID)
 
-	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		body, err := ioutil.ReadAll(resp.Body)
+	for {
+		body, err := json.Marshal(targetQuery)
+		if err != nil {
+			return err
+		}
+		resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+		if err != nil {
+			return err
+		}
+		respBody, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
 		if err != nil {
 			return fmt.Errorf("failed to read response body: %w", err)
 		}
+		if resp.StatusCode == http.StatusOK {
+			break
+		}
+
 		var responseBody struct {
 			Code int    `json:"code"`
 			Msg  string `json:"msg"`
 		}
-
-		if err := sonic.Unmarshal(body, &responseBody); err != nil {
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
 			return fmt.Errorf("failed to decode response body: %w", err)
 		}
+		if resp.StatusCode != http.StatusConflict || targetQuery.ResourceVersion == 0 {
+			return fmt.Errorf("failed to change target: %s", responseBody.Msg)
+		}
 
-		return fmt.Errorf("failed to change target: %s", responseBody.Msg)
+		// 版本冲突时展示当前的 target，确认后使用当前版本重试
+		fmt.Printf("conflict: %s\n", responseBody.Msg)
+		current, err := o.fetchTarget()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("current target <%s://%s%s> scrape_time=%d scrape_timeout=%d zone=%s resource_version=%d\n",
+			current.Schema, current.Address, current.MetricPath, current.ScrapeTime, current.ScrapeTimeout, current.Zone, current.ResourceVersion)
+		fmt.Printf("Apply the change on top of version %d? [y/N]: ", current.ResourceVersion)
+		var input string
+		fmt.Scanln(&input)
+		if answer := strings.ToLower(strings.TrimSpace(input)); answer != "y" && answer != "yes" {
+			return fmt.Errorf("target <%d> not changed", o.ID)
+		}
+		targetQuery.ResourceVersion = current.ResourceVersion
 	}
 
 	fmt.Printf("target <%d> updated\n", o.ID)
 	return nil
 }
+
+type currentTarget struct {
+	Address         string `json:"address"`
+	Schema          string `json:"schema"`
+	MetricPath      string `json:"metric_path"`
+	ScrapeTime      int    `json:"scrape_time"`
+	ScrapeTimeout   int    `json:"scrape_timeout"`
+	Zone            string `json:"zone"`
+	ResourceVersion uint64 `json:"resource_version"`
+}
+
+// fetchTarget 重新获取 target 的当前配置与版本
+func (o *TargetChangeOptions) fetchTarget() (*currentTarget, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+	api, exists := path_map.APIInterfaces["GetTarget"]
+	if !exists {
+		return nil, fmt.Errorf("unsupported API")
+	}
+	resp, err := utils.SendRequest(api.Method, fmt.Sprintf("%s%s/%d", cluster.Cluster.Server, api.Path, o.ID), nil, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to re-fetch target <%d>, received status: %s", o.ID, resp.Status)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	current := &currentTarget{}
+	if err := sonic.Unmarshal(body, current); err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+	return current, nil
+}
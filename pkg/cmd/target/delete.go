@@ -26,9 +26,11 @@ var (
 
 // TargetDeleteOptions holds the options for the delete command
 type TargetDeleteOptions struct {
-	ID     uint
-	Yes    bool
-	Labels []TargetLabel `json:"labels,omitempty" yaml:"labels,omitempty" form:"labels,omitempty"`
+	ID  uint
+	Yes bool
+	// ResourceVersion 确认删除时看到的版本，期间被修改过的 target 不会被删除
+	ResourceVersion uint64
+	Labels          []TargetLabel `json:"labels,omitempty" yaml:"labels,omitempty" form:"labels,omitempty"`
 }
 
 // NewTargetDeleteOptions creates the options for the delete command
@@ -117,6 +119,9 @@ func (o *TargetDeleteOptions) Run() error {
 		if err != nil {
 			return err
 		}
+		if version, ok := t["resource_version"].(float64); ok {
+			o.ResourceVersion = uint64(version)
+		}
 		return confirmAndExecute(o.Yes, o.deleteTarget, fmt.Sprintf("Are you sure you want to delete target <%s://%s%s>?", t["schema"], t["address"], t["metric_path"]))
 	}
 	return fmt.Errorf("Invaild ID <%d>\n", o.ID)
@@ -136,6 +141,9 @@ func (o *TargetDeleteOptions) deleteTarget() error {
 			return fmt.Errorf("Unsupported API")
 		}
 		url := fmt.Sprintf("%s%s/%d", cluster.Cluster.Server, api.Path, o.ID)
+		if o.ResourceVersion != 0 {
+			url = fmt.Sprintf("%s?resource_version=%d", url, o.ResourceVersion)
+		}
 		// Send the HTTP request
 		resp, err := utils.SendRequest(api.Method, url, nil, cluster.Cluster.Auth)
 		if err != nil {
@@ -0,0 +1,145 @@
+package target
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	historyExample = templates.Examples(i18n.T(`
+		# Show every revision of target 1
+		pantheonctl target history 1`))
+
+	rollbackExample = templates.Examples(i18n.T(`
+		# Restore target 1 to revision 3, a deleted target is undeleted
+		pantheonctl target rollback 1 --revision 3`))
+)
+
+// newCmdTargetHistory creates a new history command
+func newCmdTargetHistory() *cobra.Command {
+	return &cobra.Command{
+		Use:     "history <id>",
+		Short:   i18n.T("Show the revisions of a target"),
+		Example: historyExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendTargetRequest("ListTargetRevisions", "/"+url.PathEscape(args[0])+"/revisions", nil)
+			if err != nil {
+				return err
+			}
+
+			var revisions []target.TargetRevision
+			if err := sonic.Unmarshal(respBody, &revisions); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(revisions) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "REVISION\tADDRESS\tMETRIC_PATH\tSCRAPE_TIME\tSCRAPE_TIMEOUT\tLABELS\tPARAMETERS\tSELECTORS\tAUTH\tDELETED\tCREATED")
+			for _, revision := range revisions {
+				auth := "None"
+				if revision.AuthFingerprint != "" {
+					auth = revision.AuthFingerprint[:12]
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%d\t%d\t%s\t%s\t%s\t%s\t%t\t%s\n", revision.Revision, revision.Address, revision.MetricPath,
+					revision.ScrapeTime, revision.ScrapeTimeout, joinPairs(revision.Labels), joinPairs(revision.Params), joinPairs(revision.Selectors),
+					auth, revision.Deleted, revision.CreatedAt.Format("2006-01-02 15:04:05"))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// newCmdTargetRollback creates a new rollback command
+func newCmdTargetRollback() *cobra.Command {
+	request := &target.TargetRollback{}
+	cmd := &cobra.Command{
+		Use:     "rollback <id> --revision N",
+		Short:   i18n.T("Restore a target to a previous revision"),
+		Example: rollbackExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := json.Marshal(request)
+			if err != nil {
+				return err
+			}
+			if _, err = sendTargetRequest("RollbackTarget", "/"+url.PathEscape(args[0])+"/rollback", body); err != nil {
+				return err
+			}
+			fmt.Printf("target <%s> rolled back to revision %d\n", args[0], request.Revision)
+			return nil
+		},
+	}
+	cmd.Flags().Uint64Var(&request.Revision, "revision", 0, "The revision to restore, as shown by target history.")
+	cmd.MarkFlagRequired("revision")
+	return cmd
+}
+
+func joinPairs(pairs map[string]string) string {
+	if len(pairs) == 0 {
+		return "<none>"
+	}
+	joined := make([]string, 0, len(pairs))
+	for key, value := range pairs {
+		joined = append(joined, key+"="+value)
+	}
+	sort.Strings(joined)
+	return strings.Join(joined, ",")
+}
+
+// sendTargetRequest 发送请求并返回响应体，非 200 时返回服务端的错误信息
+func sendTargetRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	requestURL := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, requestURL, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
@@ -37,6 +37,7 @@ type TargetListOptions struct {
 	Selector       []TargetLabel
 	IsShowLabels   bool
 	IsShowParams   bool
+	IsShowSecrets  bool
 	OutputFormat   string
 }
 
@@ -70,6 +71,7 @@ func newCmdTargetList() *cobra.Command {
 	listCmd.Flags().StringVar(&o.SelectorString, "selector", "", "Comma-separated key=value pairs for selectors (required)")
 	listCmd.Flags().BoolVar(&o.IsShowLabels, "show-labels", false, "When printing, show all labels as the last column (default hide labels column)")
 	listCmd.Flags().BoolVar(&o.IsShowParams, "show-params", false, "When printing, show all parameters as the last column (default hide parameters column)")
+	listCmd.Flags().BoolVar(&o.IsShowSecrets, "show-secrets", false, "Include credential secrets in json/yaml output (default only the credential reference)")
 	listCmd.Flags().StringVarP(&o.OutputFormat, "output", "o", "", "Output format. One of: json|yaml")
 	listCmd.MarkFlagRequired("selector")
 	return listCmd
@@ -134,6 +136,9 @@ func (o *TargetListOptions) listTargetsFromAPI() ([]target.TargetList, error) {
 
 	// 构建 URL，假设 selectors 至少包含一个值
 	url := fmt.Sprintf("%s%s/%s/%s", cluster.Cluster.Server, api.Path, o.Selector[0].Key, o.Selector[0].Value)
+	if o.IsShowSecrets {
+		url += "?show_secrets=true"
+	}
 
 	// 发送 HTTP 请求
 	resp, err := utils.SendRequest(api.Method, url, nil, cluster.Cluster.Auth)
@@ -203,6 +208,10 @@ func printTable(targets []target.TargetList, showLabels bool, showParams bool) e
 				authType = "Base Auth"
 			} else if target.Auth.BearerToken != "" {
 				authType = "Bearer Token"
+			} else if target.Auth.OAuth2 != nil {
+				authType = "OAuth2"
+			} else if target.Auth.Credential != "" {
+				authType = "Credential " + target.Auth.Credential
 			}
 		}
 		if len(authType) > maxAuthTypeWidth {
@@ -279,6 +288,10 @@ func printTable(targets []target.TargetList, showLabels bool, showParams bool) e
 				authType = "Base Auth"
 			} else if target.Auth.BearerToken != "" {
 				authType = "Bearer Token"
+			} else if target.Auth.OAuth2 != nil {
+				authType = "OAuth2"
+			} else if target.Auth.Credential != "" {
+				authType = "Credential " + target.Auth.Credential
 			}
 		}
 
@@ -8,6 +8,8 @@ import (
 	"github.com/spf13/cobra"
 	"k8s.io/kubectl/pkg/util/i18n"
 	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
 )
 
 var (
@@ -41,6 +43,38 @@ type TargetLabel struct {
 type TargetAuth struct {
 	Base        string `form:"base" json:"base" yaml:"base"`
 	BearerToken string `form:"bearer_token" json:"bearer_token" yaml:"bearer_token"`
+	Credential  string `form:"credential" json:"credential" yaml:"credential"`
+}
+
+type TargetTLS struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// addTLSFlags 注册 target TLS 相关的 flags，文件路径为 pantheon-server 所在主机上的路径
+func addTLSFlags(cmd *cobra.Command, tlsOptions *TargetTLS) {
+	cmd.Flags().StringVar(&tlsOptions.CAFile, "tls-ca-file", "", "CA bundle on the pantheon-server host used to verify the target. This is optional.")
+	cmd.Flags().StringVar(&tlsOptions.CertFile, "tls-cert-file", "", "Client certificate on the pantheon-server host presented to the target. This is optional.")
+	cmd.Flags().StringVar(&tlsOptions.KeyFile, "tls-key-file", "", "Client key on the pantheon-server host for --tls-cert-file. This is optional.")
+	cmd.Flags().StringVar(&tlsOptions.ServerName, "tls-server-name", "", "Server name used to verify the target certificate. This is optional.")
+	cmd.Flags().BoolVar(&tlsOptions.InsecureSkipVerify, "tls-insecure-skip-verify", false, "Skip verification of the target certificate. This is optional.")
+}
+
+// toRequest 未设置任何 TLS flag 时返回 nil
+func (t TargetTLS) toRequest() *target.TargetTLS {
+	if t == (TargetTLS{}) {
+		return nil
+	}
+	return &target.TargetTLS{
+		CAFile:             t.CAFile,
+		CertFile:           t.CertFile,
+		KeyFile:            t.KeyFile,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
 }
 
 // NewCmdTarget creates a new Target command.
@@ -57,6 +91,8 @@ func NewCmdTarget() *cobra.Command {
 	targetDeleteCmd := newCmdTargetDelete()
 	targetCleanCmd := newCmdTargetClean()
 	targetAddFromFileCmd := newCmdTargetAddFromFile()
+	targetHistoryCmd := newCmdTargetHistory()
+	targetRollbackCmd := newCmdTargetRollback()
 	targetCmd.AddCommand(
 		targetAddCmd,
 		targetListCmd,
@@ -64,6 +100,8 @@ func NewCmdTarget() *cobra.Command {
 		targetChangeCmd,
 		targetAddFromFileCmd,
 		targetCleanCmd,
+		targetHistoryCmd,
+		targetRollbackCmd,
 	)
 	return targetCmd
 }
@@ -79,7 +117,7 @@ func validateKeyValuePairs(pairs []TargetLabel, keyPattern string) error {
 
 		// Validate key format using regex pattern
 		if match, _ := regexp.MatchString(keyPattern, pair.Key); !match {
-			return fmt.Errorf("invalid key format: %s. Keys must start with a letter and can only contain letters, numbers, and hyphens (a-z,A-Z,0-9,_)", pair.Key)
+			return fmt.Errorf("invalid key format: %s. Keys must match %s", pair.Key, keyPattern)
 		}
 
 		// Ensure both key and value are provided (no standalone key allowed)
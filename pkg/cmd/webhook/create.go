@@ -0,0 +1,93 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+)
+
+var (
+	createExample = templates.Examples(i18n.T(`
+		# Receive every event
+		pantheonctl webhook create --name audit --url http://audit.example.com/events
+
+		# Only receive deletions of the targets of prom=fed, signed with a secret
+		pantheonctl webhook create --name cmdb --url https://cmdb.example.com/hooks --selector prom=fed --events target.deleted --secret xxx`))
+
+	updateExample = templates.Examples(i18n.T(`
+		# Point a webhook to a new URL, the secret is kept when --secret is not set
+		pantheonctl webhook update --name cmdb --url https://cmdb2.example.com/hooks --selector prom=fed`))
+)
+
+// WebhookOptions holds the options for the create and update commands
+type WebhookOptions struct {
+	webhook.Webhook
+}
+
+// NewWebhookOptions creates the options for the create and update commands
+func NewWebhookOptions() *WebhookOptions {
+	return &WebhookOptions{}
+}
+
+func (o *WebhookOptions) addFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&o.Name, "name", "", "Name of the webhook. This is required.")
+	cmd.Flags().StringVar(&o.URL, "url", "", "http(s) URL receiving the events as JSON POST requests. This is required.")
+	cmd.Flags().StringVar(&o.Selector, "selector", "", "Only send the events of the targets of the key=value selector.")
+	cmd.Flags().StringSliceVar(&o.Events, "events", nil, "Comma-separated event types, one of target.created, target.updated, target.deleted or selector.updated. Defaults to all.")
+	cmd.Flags().StringVar(&o.Secret, "secret", "", "Secret signing the request body, sent as sha256=<hmac> in X-Pantheon-Signature.")
+	cmd.MarkFlagRequired("name")
+	cmd.MarkFlagRequired("url")
+}
+
+func (o *WebhookOptions) send(apiName, suffix, action string) error {
+	body, err := sonic.Marshal(o.Webhook)
+	if err != nil {
+		return err
+	}
+	respBody, err := sendWebhookRequest(apiName, suffix, body)
+	if err != nil {
+		return err
+	}
+	var info webhook.WebhookInfo
+	if err := sonic.Unmarshal(respBody, &info); err != nil {
+		return fmt.Errorf("failed to decode response using sonic: %w", err)
+	}
+	fmt.Printf("webhook %s %s\n", info.Name, action)
+	return nil
+}
+
+// newCmdWebhookCreate creates a new create command
+func newCmdWebhookCreate() *cobra.Command {
+	o := NewWebhookOptions()
+	cmd := &cobra.Command{
+		Use:     "create --name cmdb --url https://cmdb.example.com/hooks",
+		Short:   i18n.T("Create a webhook"),
+		Example: createExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.send("CreateWebhook", "", "created")
+		},
+	}
+	o.addFlags(cmd)
+	return cmd
+}
+
+// newCmdWebhookUpdate creates a new update command
+func newCmdWebhookUpdate() *cobra.Command {
+	o := NewWebhookOptions()
+	cmd := &cobra.Command{
+		Use:     "update --name cmdb --url https://cmdb.example.com/hooks",
+		Short:   i18n.T("Replace a webhook"),
+		Example: updateExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.send("UpdateWebhook", "/"+url.PathEscape(o.Name), "updated")
+		},
+	}
+	o.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,196 @@
+package webhook
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+)
+
+var (
+	deliveriesExample = templates.Examples(i18n.T(`
+		# List the latest deliveries of a webhook
+		pantheonctl webhook deliveries cmdb
+
+		# List the deliveries still being retried
+		pantheonctl webhook deliveries cmdb --status pending --limit 20`))
+
+	deadLettersExample = templates.Examples(i18n.T(`
+		# List the deliveries that exhausted their retries, then send one again
+		pantheonctl webhook dead-letters
+		pantheonctl webhook redeliver 3`))
+)
+
+// newCmdWebhookList creates a new list command
+func newCmdWebhookList() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   i18n.T("List webhooks"),
+		Aliases: []string{"ls"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendWebhookRequest("ListWebhooks", "", nil)
+			if err != nil {
+				return err
+			}
+
+			var webhooks []webhook.WebhookInfo
+			if err := sonic.Unmarshal(respBody, &webhooks); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(webhooks) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "ID\tNAME\tURL\tSELECTOR\tEVENTS\tSECRET")
+			for _, item := range webhooks {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\n", item.ID, item.Name, item.URL,
+					orAll(item.Selector), orAll(strings.Join(item.Events, ",")), item.HasSecret)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// newCmdWebhookDelete creates a new delete command
+func newCmdWebhookDelete() *cobra.Command {
+	return &cobra.Command{
+		Use:     "delete <name>",
+		Short:   i18n.T("Delete a webhook"),
+		Aliases: []string{"rm", "del"},
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendWebhookRequest("DeleteWebhook", "/"+url.PathEscape(args[0]), nil); err != nil {
+				return err
+			}
+			fmt.Printf("webhook %s deleted\n", args[0])
+			return nil
+		},
+	}
+}
+
+// newCmdWebhookDeliveries creates a new deliveries command
+func newCmdWebhookDeliveries() *cobra.Command {
+	request := &webhook.QueryDeliveries{}
+	cmd := &cobra.Command{
+		Use:     "deliveries <name>",
+		Short:   i18n.T("List the deliveries of a webhook"),
+		Example: deliveriesExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			values := url.Values{}
+			if request.Status != "" {
+				values.Set("status", request.Status)
+			}
+			if request.Limit > 0 {
+				values.Set("limit", fmt.Sprint(request.Limit))
+			}
+			suffix := "/" + url.PathEscape(args[0]) + "/deliveries"
+			if len(values) > 0 {
+				suffix += "?" + values.Encode()
+			}
+			respBody, err := sendWebhookRequest("ListWebhookDeliveries", suffix, nil)
+			if err != nil {
+				return err
+			}
+
+			var deliveries []webhook.Delivery
+			if err := sonic.Unmarshal(respBody, &deliveries); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(deliveries) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "ID\tEVENT\tEVENT ID\tSTATUS\tATTEMPTS\tLAST CODE\tNEXT ATTEMPT\tLAST ERROR")
+			for _, delivery := range deliveries {
+				nextAttempt := "<none>"
+				if delivery.NextAttemptAt != nil {
+					nextAttempt = delivery.NextAttemptAt.Local().Format(time.RFC3339)
+				}
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n", delivery.ID, delivery.Event, delivery.EventID,
+					delivery.Status, delivery.Attempts, delivery.LastStatusCode, nextAttempt, orNone(delivery.LastError))
+			}
+			return w.Flush()
+		},
+	}
+	cmd.Flags().StringVar(&request.Status, "status", "", "Only list deliveries in this status, one of pending, succeeded or dead.")
+	cmd.Flags().IntVar(&request.Limit, "limit", 0, "Max number of deliveries, defaults to 100.")
+	return cmd
+}
+
+// newCmdWebhookDeadLetters creates a new dead-letters command
+func newCmdWebhookDeadLetters() *cobra.Command {
+	return &cobra.Command{
+		Use:     "dead-letters",
+		Short:   i18n.T("List the deliveries that exhausted their retries"),
+		Aliases: []string{"dlq"},
+		Example: deadLettersExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			respBody, err := sendWebhookRequest("ListWebhookDeadLetters", "", nil)
+			if err != nil {
+				return err
+			}
+
+			var letters []webhook.DeadLetter
+			if err := sonic.Unmarshal(respBody, &letters); err != nil {
+				return fmt.Errorf("failed to decode response using sonic: %w", err)
+			}
+			if len(letters) == 0 {
+				fmt.Println("No resources found.")
+				return nil
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+			fmt.Fprintln(w, "ID\tWEBHOOK\tEVENT\tEVENT ID\tATTEMPTS\tCREATED\tLAST ERROR")
+			for _, letter := range letters {
+				fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n", letter.ID, orNone(letter.Webhook), letter.Event, letter.EventID,
+					letter.Attempts, letter.CreatedAt.Local().Format(time.RFC3339), orNone(letter.LastError))
+			}
+			return w.Flush()
+		},
+	}
+}
+
+// newCmdWebhookRedeliver creates a new redeliver command
+func newCmdWebhookRedeliver() *cobra.Command {
+	return &cobra.Command{
+		Use:     "redeliver <dead-letter-id>",
+		Short:   i18n.T("Send a dead letter again"),
+		Example: deadLettersExample,
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, err := sendWebhookRequest("RedeliverWebhookDeadLetter", "/"+url.PathEscape(args[0])+"/redeliver", nil); err != nil {
+				return err
+			}
+			fmt.Printf("dead letter %s queued for delivery\n", args[0])
+			return nil
+		},
+	}
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
+
+func orAll(value string) string {
+	if value == "" {
+		return "<all>"
+	}
+	return value
+}
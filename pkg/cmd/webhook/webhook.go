@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/bytedance/sonic"
+	"github.com/spf13/cobra"
+	"k8s.io/kubectl/pkg/util/i18n"
+	"k8s.io/kubectl/pkg/util/templates"
+
+	"github.com/cylonchau/pantheon/pkg/cmd/config"
+	"github.com/cylonchau/pantheon/pkg/cmd/path_map"
+	"github.com/cylonchau/pantheon/pkg/utils"
+)
+
+var (
+	webhookExample = templates.Examples(i18n.T(`
+		# Notify the CMDB when a target of prom=fed changes
+		pantheonctl webhook create --name cmdb --url https://cmdb.example.com/hooks/pantheon --selector prom=fed --secret xxx
+
+		# Show why the deliveries of a webhook are failing
+		pantheonctl webhook deliveries cmdb --status pending`))
+)
+
+// NewCmdWebhook creates a new webhook command.
+func NewCmdWebhook() *cobra.Command {
+	webhookCmd := &cobra.Command{
+		Use:                   "webhook",
+		Short:                 "Manage webhooks notified of inventory changes",
+		Aliases:               []string{"wh"},
+		DisableFlagsInUseLine: true,
+		Example:               webhookExample,
+	}
+	webhookCmd.AddCommand(
+		newCmdWebhookCreate(),
+		newCmdWebhookUpdate(),
+		newCmdWebhookList(),
+		newCmdWebhookDelete(),
+		newCmdWebhookDeliveries(),
+		newCmdWebhookDeadLetters(),
+		newCmdWebhookRedeliver(),
+	)
+	return webhookCmd
+}
+
+// sendWebhookRequest 调用 webhook 接口，非 200 时解析错误信息
+func sendWebhookRequest(apiName, suffix string, body []byte) ([]byte, error) {
+	cluster, err := config.GetClusterConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	api, exists := path_map.APIInterfaces[apiName]
+	if !exists {
+		return nil, fmt.Errorf("Unsupported API")
+	}
+	url := fmt.Sprintf("%s%s%s", cluster.Cluster.Server, api.Path, suffix)
+
+	resp, err := utils.SendRequest(api.Method, url, body, cluster.Cluster.Auth)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var responseBody struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+		}
+		if err := sonic.Unmarshal(respBody, &responseBody); err != nil {
+			return nil, fmt.Errorf("failed to decode response body: %w", err)
+		}
+		return nil, fmt.Errorf("request failed: %s", responseBody.Msg)
+	}
+	return respBody, nil
+}
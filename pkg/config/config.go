@@ -25,21 +25,130 @@ type SQLiteConfig struct {
 	MaxOpenConnection int `mapstructure:"max_open_connection"`
 }
 
+// FileSDConfig file_sd 模式配置，用于只支持 file_sd_configs 的 Prometheus
+type FileSDConfig struct {
+	Enable          bool
+	Directory       string
+	Format          string   // json 或 yaml，默认 json
+	RefreshInterval int      `mapstructure:"refresh_interval"` // 单位秒
+	Selectors       []string // key=value 形式的 selector 列表
+}
+
+// ProxyConfig 抓取代理的连接池与限制配置，时间单位为秒，0 表示不限制
+type ProxyConfig struct {
+	DialTimeout           int   `mapstructure:"dial_timeout"`
+	TLSHandshakeTimeout   int   `mapstructure:"tls_handshake_timeout"`
+	ResponseHeaderTimeout int   `mapstructure:"response_header_timeout"`
+	IdleConnTimeout       int   `mapstructure:"idle_conn_timeout"`
+	MaxIdleConns          int   `mapstructure:"max_idle_conns"`
+	MaxConnsPerHost       int   `mapstructure:"max_conns_per_host"`
+	MaxResponseBytes      int64 `mapstructure:"max_response_bytes"`
+	// 代理连接的目标地址必须在 allow_cidrs 中 (为空时不限制)，且不能在 deny_cidrs 中
+	AllowCIDRs []string `mapstructure:"allow_cidrs"`
+	DenyCIDRs  []string `mapstructure:"deny_cidrs"`
+	// HA Prometheus 对同一 target 的抓取在 cache_window 内共享同一份响应，0 表示不缓存
+	// 窗口最长为 target 抓取间隔的一半
+	CacheWindow int `mapstructure:"cache_window"`
+	// 联邦接口同时抓取的 target 数量与整个请求的超时时间
+	FederateConcurrency int `mapstructure:"federate_concurrency"`
+	FederateTimeout     int `mapstructure:"federate_timeout"`
+}
+
+// WebhookConfig webhook 投递配置，时间单位为秒
+type WebhookConfig struct {
+	PollInterval   int `mapstructure:"poll_interval"`   // 检查待投递事件的间隔，默认 2
+	Timeout        int `mapstructure:"timeout"`         // 单次投递的超时时间，默认 10
+	MaxAttempts    int `mapstructure:"max_attempts"`    // 超过后进入 dead letter，默认 8
+	InitialBackoff int `mapstructure:"initial_backoff"` // 第一次重试的等待时间，之后每次翻倍，默认 1
+	MaxBackoff     int `mapstructure:"max_backoff"`     // 重试等待时间的上限，默认 300
+}
+
+// GCConfig 定期检查并修复 labels、params、selectors 与关联表的完整性，参考 pantheon-server db check
+type GCConfig struct {
+	Enable   bool
+	Interval int  `mapstructure:"interval"` // 单位秒，默认 3600
+	DryRun   bool `mapstructure:"dry_run"`  // 只报告问题，不修改数据
+	// OrphanSelector key=value 形式，修复时加入没有 selector 的 target，为空时只报告
+	OrphanSelector string `mapstructure:"orphan_selector"`
+}
+
+// ZoneConfig 网络区域，区域内的 target 经由区域的代理抓取
+type ZoneConfig struct {
+	Name string
+	// ProxyAddress SD 中区域内 target 使用的代理地址，为空时使用全局的 proxy_address
+	ProxyAddress string `mapstructure:"proxy_address"`
+	// Upstream 代理连接区域内 target 时使用的上游代理，支持 http/https (CONNECT) 与 socks5，为空时直接连接
+	Upstream string
+}
+
+// ValidationConfig target 的 labels、params 与 selectors 的校验策略
+type ValidationConfig struct {
+	// AllowReservedLabels 允许 __ 开头的 label，默认拒绝，避免覆盖 SD 输出中的 __metrics_path__ 等标签
+	AllowReservedLabels bool `mapstructure:"allow_reserved_labels"`
+	// AllowedValues 限制 label 的取值，键为 label 名称
+	AllowedValues map[string][]string `mapstructure:"allowed_values"`
+	// Required 指定 selector 下的 target 必须设置的 label
+	Required []RequiredLabelsConfig `mapstructure:"required"`
+}
+
+// RequiredLabelsConfig selector 为 key=value 形式，属于该 selector 的 target 必须设置 labels 中的所有 label
+type RequiredLabelsConfig struct {
+	Selector string
+	Labels   []string
+}
+
+// EncryptionConfig 凭据加密配置，主密钥从 key_file 或 key_env 指定的环境变量读取
+type EncryptionConfig struct {
+	KeyFile string `mapstructure:"key_file"`
+	KeyEnv  string `mapstructure:"key_env"`
+}
+
 // Config对象和config.toml文件保持一致
 type Config struct {
 	AppName        string
 	Address        string
 	Port           string
-	DatabaseDriver string       `mapstructure:"database_driver"`
-	ProxyAddress   string       `mapstructure:"proxy_address"`
-	ProxyTimeout   int          `mapstructure:"proxy_timeout"`
-	MySQL          MySQLConfig  //需要定义子类型对应的变量，如果不定义映射不成功
-	SQLite         SQLiteConfig //需要定义子类型对应的变量，如果不定义映射不成功
+	DatabaseDriver string           `mapstructure:"database_driver"`
+	ProxyAddress   string           `mapstructure:"proxy_address"`
+	ProxyTimeout   int              `mapstructure:"proxy_timeout"`
+	MySQL          MySQLConfig      //需要定义子类型对应的变量，如果不定义映射不成功
+	SQLite         SQLiteConfig     //需要定义子类型对应的变量，如果不定义映射不成功
+	FileSD         FileSDConfig     `mapstructure:"file_sd"`
+	Proxy          ProxyConfig      `mapstructure:"proxy"`
+	Encryption     EncryptionConfig `mapstructure:"encryption"`
+	Zones          []ZoneConfig     `mapstructure:"zones"`
+	Validation     ValidationConfig `mapstructure:"validation"`
+	Webhook        WebhookConfig    `mapstructure:"webhook"`
+	GC             GCConfig         `mapstructure:"gc"`
+}
+
+// Zone 根据名称查找网络区域
+func (c *Config) Zone(name string) (ZoneConfig, bool) {
+	if c == nil || name == "" {
+		return ZoneConfig{}, false
+	}
+	for _, zone := range c.Zones {
+		if zone.Name == name {
+			return zone, true
+		}
+	}
+	return ZoneConfig{}, false
 }
 
 func InitConfiguration(configFile string) error {
 	viper.SetDefault("Port", "2952")
 	viper.SetDefault("Address", "127.0.0.1")
+	viper.SetDefault("proxy.dial_timeout", 5)
+	viper.SetDefault("proxy.tls_handshake_timeout", 5)
+	viper.SetDefault("proxy.response_header_timeout", 10)
+	viper.SetDefault("proxy.idle_conn_timeout", 90)
+	viper.SetDefault("proxy.max_idle_conns", 10000)
+	viper.SetDefault("proxy.max_conns_per_host", 4)
+	viper.SetDefault("proxy.max_response_bytes", 64<<20)
+	viper.SetDefault("proxy.federate_concurrency", 16)
+	viper.SetDefault("proxy.federate_timeout", 30)
+	// 默认禁止访问 link-local 地址，避免通过代理访问云厂商的元数据服务
+	viper.SetDefault("proxy.deny_cidrs", []string{"169.254.0.0/16", "fe80::/10"})
 	viper.SetConfigType("toml")
 	viper.SetConfigFile(configFile)
 
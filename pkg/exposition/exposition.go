@@ -0,0 +1,328 @@
+// Package exposition 按规则过滤 Prometheus/OpenMetrics 文本格式的抓取结果，
+// 在返回给 Prometheus 之前删除无用的指标与标签，从源头降低基数
+package exposition
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// 同一个指标族中样本名称可能带有的后缀
+var familySuffixes = []string{"_bucket", "_sum", "_count", "_total", "_created", "_info", "_gsum", "_gcount"}
+
+// 直方图与摘要依赖的标签，删除后样本不再有意义，因此不允许删除
+var protectedLabels = map[string]bool{"le": true, "quantile": true}
+
+// Rule 一条过滤规则，正则均为完整匹配
+type Rule struct {
+	// KeepFamilies 不为空时只保留名称匹配的指标族
+	KeepFamilies *regexp.Regexp
+	// DropFamilies 删除名称匹配的指标族
+	DropFamilies *regexp.Regexp
+	// DropLabels 删除名称匹配的标签，le 与 quantile 除外
+	DropLabels *regexp.Regexp
+	// AddLabels 为所有样本添加静态标签，已存在的同名标签会被覆盖
+	AddLabels map[string]string
+}
+
+// Compile 编译规则，正则为空表示不启用对应的过滤
+func Compile(keep, drop, dropLabels string, addLabels map[string]string) (rule Rule, err error) {
+	if rule.KeepFamilies, err = compileAnchored(keep); err != nil {
+		return rule, fmt.Errorf("invalid keep regex: %w", err)
+	}
+	if rule.DropFamilies, err = compileAnchored(drop); err != nil {
+		return rule, fmt.Errorf("invalid drop regex: %w", err)
+	}
+	if rule.DropLabels, err = compileAnchored(dropLabels); err != nil {
+		return rule, fmt.Errorf("invalid drop labels regex: %w", err)
+	}
+	for name := range addLabels {
+		if !ValidLabelName(name) {
+			return rule, fmt.Errorf("invalid label name: %q", name)
+		}
+	}
+	rule.AddLabels = addLabels
+	return rule, nil
+}
+
+func compileAnchored(expr string) (*regexp.Regexp, error) {
+	if expr == "" {
+		return nil, nil
+	}
+	return regexp.Compile("^(?:" + expr + ")$")
+}
+
+// Filter 依次应用多条规则
+type Filter struct {
+	rules []Rule
+}
+
+// NewFilter 创建过滤器，没有规则时返回 nil
+func NewFilter(rules ...Rule) *Filter {
+	if len(rules) == 0 {
+		return nil
+	}
+	return &Filter{rules: rules}
+}
+
+// keepFamily 指标族需要通过所有规则的过滤
+func (f *Filter) keepFamily(family string) bool {
+	for _, rule := range f.rules {
+		if rule.KeepFamilies != nil && !rule.KeepFamilies.MatchString(family) {
+			return false
+		}
+		if rule.DropFamilies != nil && rule.DropFamilies.MatchString(family) {
+			return false
+		}
+	}
+	return true
+}
+
+// label 样本的标签，value 保留转义后的原始内容
+type label struct {
+	name  string
+	value string
+}
+
+func (f *Filter) relabel(labels []label) []label {
+	for _, rule := range f.rules {
+		if rule.DropLabels != nil {
+			kept := labels[:0]
+			for _, l := range labels {
+				if protectedLabels[l.name] || !rule.DropLabels.MatchString(l.name) {
+					kept = append(kept, l)
+				}
+			}
+			labels = kept
+		}
+		if len(rule.AddLabels) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(rule.AddLabels))
+		for name := range rule.AddLabels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			value := escapeLabelValue(rule.AddLabels[name])
+			replaced := false
+			for i := range labels {
+				if labels[i].name == name {
+					labels[i].value, replaced = value, true
+				}
+			}
+			if !replaced {
+				labels = append(labels, label{name: name, value: value})
+			}
+		}
+	}
+	return labels
+}
+
+// Reader 返回过滤后的抓取结果，按行处理，不需要读取完整的响应
+// 关闭返回的 Reader 时同时关闭 body
+func (f *Filter) Reader(body io.ReadCloser) io.ReadCloser {
+	return &filterReader{filter: f, source: bufio.NewReader(body), body: body}
+}
+
+// Apply 过滤完整的抓取结果
+func (f *Filter) Apply(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	reader := f.Reader(io.NopCloser(bytes.NewReader(data)))
+	if _, err := io.Copy(&out, reader); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+type filterReader struct {
+	filter *Filter
+	source *bufio.Reader
+	body   io.Closer
+	// family 最近一条 HELP/TYPE/UNIT 所描述的指标族
+	family string
+	// dropped family 是否被过滤
+	dropped bool
+	pending []byte
+	err     error
+}
+
+func (r *filterReader) Read(p []byte) (int, error) {
+	for len(r.pending) == 0 && r.err == nil {
+		line, err := r.source.ReadBytes('\n')
+		if len(line) > 0 {
+			r.pending = r.filterLine(line)
+		}
+		r.err = err
+	}
+	if len(r.pending) > 0 {
+		n := copy(p, r.pending)
+		r.pending = r.pending[n:]
+		return n, nil
+	}
+	return 0, r.err
+}
+
+func (r *filterReader) Close() error {
+	return r.body.Close()
+}
+
+// filterLine 处理一行内容，返回需要输出的内容，整行被过滤时返回 nil
+func (r *filterReader) filterLine(line []byte) []byte {
+	text := strings.TrimRight(string(line), "\r\n")
+	newline := line[len(text):]
+
+	if strings.HasPrefix(text, "#") {
+		fields := strings.Fields(text)
+		if len(fields) >= 3 && (fields[1] == "HELP" || fields[1] == "TYPE" || fields[1] == "UNIT") {
+			if fields[2] != r.family {
+				r.family = fields[2]
+				r.dropped = !r.filter.keepFamily(r.family)
+			}
+			if r.dropped {
+				return nil
+			}
+		}
+		return line
+	}
+	if strings.TrimSpace(text) == "" {
+		return line
+	}
+
+	name, labels, rest, err := parseSample(text)
+	if err != nil {
+		// 无法解析的行原样返回，由 Prometheus 报告格式错误
+		return line
+	}
+	if !r.filter.keepFamily(familyOf(r.family, name)) {
+		return nil
+	}
+	labels = r.filter.relabel(labels)
+
+	var out bytes.Buffer
+	out.WriteString(name)
+	if len(labels) > 0 {
+		out.WriteByte('{')
+		for i, l := range labels {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(l.name)
+			out.WriteString(`="`)
+			out.WriteString(l.value)
+			out.WriteByte('"')
+		}
+		out.WriteByte('}')
+	}
+	out.WriteString(rest)
+	out.Write(newline)
+	return out.Bytes()
+}
+
+// familyOf 返回样本所属的指标族，family 为最近一条 HELP/TYPE/UNIT 描述的指标族
+// 如 http_request_duration_seconds_bucket 属于 http_request_duration_seconds
+func familyOf(family, name string) string {
+	if family == "" || !strings.HasPrefix(name, family) {
+		return name
+	}
+	suffix := name[len(family):]
+	if suffix == "" {
+		return family
+	}
+	for _, known := range familySuffixes {
+		if suffix == known {
+			return family
+		}
+	}
+	return name
+}
+
+// parseSample 解析样本行，rest 为名称与标签之后的内容 (值、时间戳与 exemplar)
+func parseSample(text string) (name string, labels []label, rest string, err error) {
+	end := strings.IndexAny(text, "{ \t")
+	if end <= 0 {
+		return "", nil, "", fmt.Errorf("invalid sample: %q", text)
+	}
+	name = text[:end]
+	if text[end] != '{' {
+		return name, nil, text[end:], nil
+	}
+
+	i := end + 1
+	for {
+		for i < len(text) && (text[i] == ' ' || text[i] == ',') {
+			i++
+		}
+		if i >= len(text) {
+			return "", nil, "", fmt.Errorf("unterminated label set: %q", text)
+		}
+		if text[i] == '}' {
+			return name, labels, text[i+1:], nil
+		}
+		eq := strings.IndexByte(text[i:], '=')
+		if eq <= 0 {
+			return "", nil, "", fmt.Errorf("invalid label: %q", text)
+		}
+		labelName := strings.TrimSpace(text[i : i+eq])
+		i += eq + 1
+		if i >= len(text) || text[i] != '"' {
+			return "", nil, "", fmt.Errorf("invalid label value: %q", text)
+		}
+		start := i + 1
+		for i = start; i < len(text) && text[i] != '"'; i++ {
+			if text[i] == '\\' {
+				i++
+			}
+		}
+		if i >= len(text) {
+			return "", nil, "", fmt.Errorf("unterminated label value: %q", text)
+		}
+		labels = append(labels, label{name: labelName, value: text[start:i]})
+		i++
+	}
+}
+
+// ValidLabelName 判断是否为可以添加到样本上的标签名，__ 开头的名称由 Prometheus 保留
+func ValidLabelName(name string) bool {
+	return labelNameRe.MatchString(name) && !strings.HasPrefix(name, "__")
+}
+
+// FormatSample 生成一行文本格式的样本，标签按名称排序
+func FormatSample(name string, labels map[string]string, value float64) string {
+	names := make([]string, 0, len(labels))
+	for labelName := range labels {
+		names = append(names, labelName)
+	}
+	sort.Strings(names)
+	var out strings.Builder
+	out.WriteString(name)
+	if len(names) > 0 {
+		out.WriteByte('{')
+		for i, labelName := range names {
+			if i > 0 {
+				out.WriteByte(',')
+			}
+			out.WriteString(labelName)
+			out.WriteString(`="`)
+			out.WriteString(escapeLabelValue(labels[labelName]))
+			out.WriteByte('"')
+		}
+		out.WriteByte('}')
+	}
+	out.WriteByte(' ')
+	out.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	out.WriteByte('\n')
+	return out.String()
+}
+
+func escapeLabelValue(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
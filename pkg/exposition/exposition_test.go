@@ -0,0 +1,163 @@
+package exposition
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sample = `# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines 42
+# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{handler="/",pod="a-1",le="0.1"} 3
+http_request_duration_seconds_bucket{handler="/",pod="a-1",le="+Inf"} 5
+http_request_duration_seconds_sum{handler="/",pod="a-1"} 0.7
+http_request_duration_seconds_count{handler="/",pod="a-1"} 5
+# HELP vendor_debug_info Useless vendor series.
+# TYPE vendor_debug_info gauge
+vendor_debug_info{path="C:\\tmp\"x\"",pod="a-1"} 1
+untyped_metric{pod="a-1"} 1 1700000000000
+`
+
+func mustCompile(t *testing.T, keep, drop, dropLabels string, addLabels map[string]string) Rule {
+	t.Helper()
+	rule, err := Compile(keep, drop, dropLabels, addLabels)
+	require.NoError(t, err)
+	return rule
+}
+
+// TestFilter_Apply 测试按指标族过滤、删除标签与添加静态标签
+func TestFilter_Apply(t *testing.T) {
+	testCases := []struct {
+		name  string
+		rules []Rule
+		want  string
+	}{
+		{
+			name:  "drop families",
+			rules: []Rule{mustCompile(t, "", "vendor_.*|untyped_metric", "", nil)},
+			want: `# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines 42
+# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{handler="/",pod="a-1",le="0.1"} 3
+http_request_duration_seconds_bucket{handler="/",pod="a-1",le="+Inf"} 5
+http_request_duration_seconds_sum{handler="/",pod="a-1"} 0.7
+http_request_duration_seconds_count{handler="/",pod="a-1"} 5
+`,
+		},
+		{
+			name:  "keep families",
+			rules: []Rule{mustCompile(t, "http_.*", "", "", nil)},
+			want: `# HELP http_request_duration_seconds Request latency.
+# TYPE http_request_duration_seconds histogram
+http_request_duration_seconds_bucket{handler="/",pod="a-1",le="0.1"} 3
+http_request_duration_seconds_bucket{handler="/",pod="a-1",le="+Inf"} 5
+http_request_duration_seconds_sum{handler="/",pod="a-1"} 0.7
+http_request_duration_seconds_count{handler="/",pod="a-1"} 5
+`,
+		},
+		{
+			name: "drop and add labels",
+			rules: []Rule{
+				mustCompile(t, "vendor_.*|untyped_metric", "", "pod|le", nil),
+				mustCompile(t, "", "", "", map[string]string{"dc": "bj", "path": `D:\x`}),
+			},
+			want: `# HELP vendor_debug_info Useless vendor series.
+# TYPE vendor_debug_info gauge
+vendor_debug_info{path="D:\\x",dc="bj"} 1
+untyped_metric{dc="bj",path="D:\\x"} 1 1700000000000
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			got, err := NewFilter(tc.rules...).Apply([]byte(sample))
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, string(got))
+		})
+	}
+}
+
+// TestFilter_OpenMetrics 测试 OpenMetrics 格式中 _total 等后缀与 exemplar 的处理
+func TestFilter_OpenMetrics(t *testing.T) {
+	// Arrange
+	input := `# TYPE requests counter
+# HELP requests Requests.
+requests_total{code="200",instance_id="x"} 10 # {trace_id="abc"} 1 1700000000.000
+requests_created{code="200",instance_id="x"} 1700000000.000
+# TYPE noisy gauge
+noisy 1
+# EOF
+`
+	filter := NewFilter(mustCompile(t, "", "noisy", "instance_id", nil))
+
+	// Act
+	got, err := filter.Apply([]byte(input))
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `# TYPE requests counter
+# HELP requests Requests.
+requests_total{code="200"} 10 # {trace_id="abc"} 1 1700000000.000
+requests_created{code="200"} 1700000000.000
+# EOF
+`, string(got))
+}
+
+// TestCompile_Invalid 测试无效的正则与标签名
+func TestCompile_Invalid(t *testing.T) {
+	_, err := Compile("(", "", "", nil)
+	assert.Error(t, err)
+	_, err = Compile("", "", "", map[string]string{"__name__": "x"})
+	assert.Error(t, err)
+	_, err = Compile("", "", "", map[string]string{"bad-name": "x"})
+	assert.Error(t, err)
+}
+
+// TestMerger 测试合并多个抓取结果时同名指标族的样本连续输出，HELP/TYPE 只保留一次
+func TestMerger(t *testing.T) {
+	// Arrange
+	merger := NewMerger()
+	first := `# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines{instance="a"} 42
+# TYPE requests counter
+requests_total{instance="a"} 1
+`
+	second := `# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines{instance="b"} 7
+
+requests_total{instance="b"} 2
+# EOF
+`
+
+	// Act
+	merger.Add([]byte(first))
+	merger.Add([]byte(second))
+	merger.Add([]byte(FormatSample("up", map[string]string{"instance": "b", "dc": `x"y`}, 1)))
+	var out bytes.Buffer
+	_, err := merger.WriteTo(&out)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, `# HELP go_goroutines Number of goroutines.
+# TYPE go_goroutines gauge
+go_goroutines{instance="a"} 42
+go_goroutines{instance="b"} 7
+# TYPE requests counter
+requests_total{instance="a"} 1
+requests_total{instance="b"} 2
+up{dc="x\"y",instance="b"} 1
+`, out.String())
+}
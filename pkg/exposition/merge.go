@@ -0,0 +1,87 @@
+package exposition
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Merger 合并多个 target 的抓取结果，文本格式要求同一指标族的样本连续出现且 HELP/TYPE 只出现一次，
+// 因此按指标族重新分组，指标族按第一次出现的顺序输出，HELP/TYPE/UNIT 保留第一次出现的内容
+type Merger struct {
+	order    []string
+	families map[string]*mergedFamily
+}
+
+type mergedFamily struct {
+	// meta HELP/TYPE/UNIT 行，按类型去重
+	meta    [][]byte
+	kinds   map[string]bool
+	samples [][]byte
+}
+
+// NewMerger 创建合并器
+func NewMerger() *Merger {
+	return &Merger{families: make(map[string]*mergedFamily)}
+}
+
+func (m *Merger) family(name string) *mergedFamily {
+	found, exists := m.families[name]
+	if !exists {
+		found = &mergedFamily{kinds: make(map[string]bool)}
+		m.families[name] = found
+		m.order = append(m.order, name)
+	}
+	return found
+}
+
+// Add 加入一个文本格式的抓取结果，其他注释、空行、# EOF 与无法解析的行被丢弃
+func (m *Merger) Add(data []byte) {
+	var current string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), len(data)+1)
+	for scanner.Scan() {
+		text := strings.TrimRight(scanner.Text(), "\r")
+		if strings.TrimSpace(text) == "" {
+			continue
+		}
+		line := []byte(text + "\n")
+		if strings.HasPrefix(text, "#") {
+			fields := strings.Fields(text)
+			if len(fields) >= 3 && (fields[1] == "HELP" || fields[1] == "TYPE" || fields[1] == "UNIT") {
+				current = fields[2]
+				family := m.family(current)
+				if !family.kinds[fields[1]] {
+					family.kinds[fields[1]] = true
+					family.meta = append(family.meta, line)
+				}
+			}
+			continue
+		}
+		name, _, _, err := parseSample(text)
+		if err != nil {
+			continue
+		}
+		family := m.family(familyOf(current, name))
+		family.samples = append(family.samples, line)
+	}
+}
+
+// WriteTo 输出合并后的结果
+func (m *Merger) WriteTo(w io.Writer) (int64, error) {
+	var written int64
+	for _, name := range m.order {
+		family := m.families[name]
+		for _, lines := range [][][]byte{family.meta, family.samples} {
+			for _, line := range lines {
+				n, err := w.Write(line)
+				written += int64(n)
+				if err != nil {
+					return written, err
+				}
+			}
+		}
+	}
+	return written, nil
+}
@@ -0,0 +1,172 @@
+package filesd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+const defaultRefreshInterval = 30
+
+var fileNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_.-]`)
+
+// Writer 将 selector 对应的 SD 输出渲染为 file_sd_configs 可读取的文件
+type Writer struct {
+	directory string
+	format    string
+	interval  time.Duration
+	selectors []query.QueryWithLabel
+}
+
+// NewWriter 根据配置创建 file_sd writer
+func NewWriter(conf config.FileSDConfig) (*Writer, error) {
+	if conf.Directory == "" {
+		return nil, fmt.Errorf("file_sd directory is required")
+	}
+
+	format := strings.ToLower(conf.Format)
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "yaml" {
+		return nil, fmt.Errorf("invalid file_sd format: %s. Valid values are 'json' or 'yaml'", conf.Format)
+	}
+
+	interval := conf.RefreshInterval
+	if interval <= 0 {
+		interval = defaultRefreshInterval
+	}
+
+	selectors := make([]query.QueryWithLabel, 0, len(conf.Selectors))
+	for _, selector := range conf.Selectors {
+		kv := strings.SplitN(selector, "=", 2)
+		if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+			return nil, fmt.Errorf("invalid file_sd selector format: %s. Expected format: key=value", selector)
+		}
+		selectors = append(selectors, query.QueryWithLabel{Key: kv[0], Value: kv[1]})
+	}
+	if len(selectors) == 0 {
+		return nil, fmt.Errorf("at least one file_sd selector is required")
+	}
+
+	if err := os.MkdirAll(conf.Directory, 0755); err != nil {
+		return nil, err
+	}
+
+	return &Writer{
+		directory: conf.Directory,
+		format:    format,
+		interval:  time.Duration(interval) * time.Second,
+		selectors: selectors,
+	}, nil
+}
+
+// Run 按 refresh interval 周期性同步文件，直到 stopCh 关闭
+func (w *Writer) Run(stopCh <-chan struct{}) {
+	klog.V(0).Infof("Writing file_sd targets to %s every %s", w.directory, w.interval)
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.Sync(); err != nil {
+			klog.Errorf("Failed to sync file_sd targets: %v", err)
+		}
+		select {
+		case <-stopCh:
+			// 退出前再同步一次，保证最后的变更写入文件
+			if _, err := w.Sync(); err != nil {
+				klog.Errorf("Failed to sync file_sd targets: %v", err)
+			}
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sync 渲染所有 selector 并写入文件，返回实际发生写入的文件数
+// 单个 selector 失败时继续处理其余的 selector，返回所有的错误
+func (w *Writer) Sync() (written int, encounterError error) {
+	for i := range w.selectors {
+		selector := w.selectors[i]
+		targets, err := model.ListTargetWithSelector(&selector, nil)
+		if err != nil {
+			encounterError = errors.Join(encounterError, fmt.Errorf("list targets for selector %s=%s: %w", selector.Key, selector.Value, err))
+			continue
+		}
+
+		data, err := w.render(targets)
+		if err != nil {
+			encounterError = errors.Join(encounterError, err)
+			continue
+		}
+
+		changed, err := writeFileAtomic(filepath.Join(w.directory, w.fileName(selector)), data)
+		if err != nil {
+			encounterError = errors.Join(encounterError, err)
+			continue
+		}
+		if changed {
+			written++
+			klog.V(2).Infof("file_sd targets for selector %s=%s updated", selector.Key, selector.Value)
+		}
+	}
+	return written, encounterError
+}
+
+func (w *Writer) render(targets []model.TargetList) ([]byte, error) {
+	if w.format == "yaml" {
+		return yaml.Marshal(targets)
+	}
+	data, err := json.MarshalIndent(targets, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func (w *Writer) fileName(selector query.QueryWithLabel) string {
+	name := fileNameReplacer.ReplaceAllString(fmt.Sprintf("%s_%s", selector.Key, selector.Value), "_")
+	return name + "." + w.format
+}
+
+// writeFileAtomic 仅在内容发生变化时写入，先写临时文件再 rename，避免 Prometheus 读到半个文件
+func writeFileAtomic(path string, data []byte) (bool, error) {
+	if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+		return false, nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp")
+	if err != nil {
+		return false, err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err = tmp.Write(data); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return false, err
+	}
+	if err = tmp.Close(); err != nil {
+		return false, err
+	}
+	if err = os.Rename(tmp.Name(), path); err != nil {
+		return false, err
+	}
+	return true, nil
+}
@@ -0,0 +1,125 @@
+package filesd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// TestWriterSync_WriteOnlyOnChange 测试只有内容变化时才写入文件
+func TestWriterSync_WriteOnlyOnChange(t *testing.T) {
+	// Arrange
+	_ = model.SetupTestDB(t)
+	dir := t.TempDir()
+	writer, err := NewWriter(config.FileSDConfig{Directory: dir, Selectors: []string{"prom=fed"}})
+	require.NoError(t, err)
+
+	require.NoError(t, model.CreateTargets(&target.Target{
+		Targets:          []target.TargetItem{{Address: "127.0.0.1:9100"}},
+		InstanceSelector: map[string]string{"prom": "fed"},
+	}))
+
+	// Act
+	firstWritten, err1 := writer.Sync()
+	secondWritten, err2 := writer.Sync()
+
+	// Assert
+	require.NoError(t, err1)
+	require.NoError(t, err2)
+	assert.Equal(t, 1, firstWritten, "first sync should write the file")
+	assert.Equal(t, 0, secondWritten, "unchanged content should not be rewritten")
+
+	data, err := os.ReadFile(filepath.Join(dir, "prom_fed.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "127.0.0.1:9100")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "temporary files should be cleaned up")
+}
+
+// TestWriterSync_ReportsEveryError 测试多个 selector 写入失败时返回所有错误，其余 selector 照常写入
+func TestWriterSync_ReportsEveryError(t *testing.T) {
+	// Arrange
+	_ = model.SetupTestDB(t)
+	dir := t.TempDir()
+	writer, err := NewWriter(config.FileSDConfig{Directory: dir, Selectors: []string{"prom=a", "prom=b", "prom=c"}})
+	require.NoError(t, err)
+	// 与目标文件同名的目录使写入失败
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "prom_a.json"), 0755))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "prom_c.json"), 0755))
+
+	// Act
+	written, err := writer.Sync()
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "prom_a.json")
+	assert.Contains(t, err.Error(), "prom_c.json")
+	assert.Equal(t, 1, written)
+	assert.FileExists(t, filepath.Join(dir, "prom_b.json"))
+}
+
+// TestWriterRun_SyncsOnStop 测试 stopCh 关闭后退出前写入最后的变更
+func TestWriterRun_SyncsOnStop(t *testing.T) {
+	// Arrange
+	_ = model.SetupTestDB(t)
+	dir := t.TempDir()
+	writer, err := NewWriter(config.FileSDConfig{Directory: dir, RefreshInterval: 3600, Selectors: []string{"prom=fed"}})
+	require.NoError(t, err)
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		writer.Run(stopCh)
+		close(done)
+	}()
+	require.Eventually(t, func() bool {
+		_, err := os.Stat(filepath.Join(dir, "prom_fed.json"))
+		return err == nil
+	}, time.Second, 10*time.Millisecond)
+
+	// Act
+	require.NoError(t, model.CreateTargets(&target.Target{
+		Targets:          []target.TargetItem{{Address: "127.0.0.1:9100"}},
+		InstanceSelector: map[string]string{"prom": "fed"},
+	}))
+	close(stopCh)
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("writer did not stop")
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "prom_fed.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "127.0.0.1:9100")
+}
+
+// TestNewWriter_InvalidConfig 测试非法配置
+func TestNewWriter_InvalidConfig(t *testing.T) {
+	tests := []struct {
+		name string
+		conf config.FileSDConfig
+	}{
+		{name: "Missing directory", conf: config.FileSDConfig{Selectors: []string{"prom=fed"}}},
+		{name: "Invalid format", conf: config.FileSDConfig{Directory: t.TempDir(), Format: "xml", Selectors: []string{"prom=fed"}}},
+		{name: "Invalid selector", conf: config.FileSDConfig{Directory: t.TempDir(), Selectors: []string{"prom"}}},
+		{name: "No selector", conf: config.FileSDConfig{Directory: t.TempDir()}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewWriter(tt.conf)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package gc
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+const defaultInterval = 3600
+
+// Collector 定期检查并修复 labels、params、selectors 与关联表的完整性
+type Collector struct {
+	interval time.Duration
+	options  model.IntegrityOptions
+}
+
+// NewCollector 根据配置创建 collector
+func NewCollector(conf config.GCConfig) (*Collector, error) {
+	interval := conf.Interval
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	orphanSelector, err := ParseSelector(conf.OrphanSelector)
+	if err != nil {
+		return nil, err
+	}
+	return &Collector{
+		interval: time.Duration(interval) * time.Second,
+		options:  model.IntegrityOptions{DryRun: conf.DryRun, OrphanSelector: orphanSelector},
+	}, nil
+}
+
+// ParseSelector 解析 key=value 形式的 selector，为空时返回 nil
+func ParseSelector(selector string) (map[string]string, error) {
+	if selector == "" {
+		return nil, nil
+	}
+	key, value, found := strings.Cut(selector, "=")
+	if !found || key == "" || value == "" {
+		return nil, fmt.Errorf("invalid orphan selector format: %s. Expected format: key=value", selector)
+	}
+	return map[string]string{key: value}, nil
+}
+
+// Run 按 interval 周期性检查，直到 stopCh 关闭
+func (c *Collector) Run(stopCh <-chan struct{}) {
+	klog.V(0).Infof("Checking database integrity every %s, dry run %t", c.interval, c.options.DryRun)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := c.Collect(); err != nil {
+			klog.Errorf("Failed to check database integrity: %v", err)
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Collect 执行一次检查与修复，发现问题时记录日志
+func (c *Collector) Collect() (*model.IntegrityReport, error) {
+	report, err := model.RepairIntegrity(c.options)
+	if err != nil {
+		return nil, err
+	}
+	if report.Problems() == 0 {
+		klog.V(2).Info("database integrity check found no problems")
+		return report, nil
+	}
+	action := "repaired"
+	if report.DryRun {
+		action = "found (dry run)"
+	}
+	for _, table := range report.Tables {
+		if table.DanglingJoins+table.Duplicates+table.Orphaned > 0 {
+			klog.Warningf("%s: %d dangling join rows, %d duplicate rows, %d orphaned rows %s",
+				table.Table, table.DanglingJoins, table.Duplicates, table.Orphaned, action)
+		}
+	}
+	if len(report.TargetsWithoutSelector) > 0 {
+		klog.Warningf("%d targets without selector %v, %d attached to the orphan selector",
+			len(report.TargetsWithoutSelector), report.TargetsWithoutSelector, len(report.Reattached))
+	}
+	return report, nil
+}
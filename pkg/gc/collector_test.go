@@ -0,0 +1,44 @@
+package gc
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// TestNewCollector_InvalidOrphanSelector 测试 orphan selector 格式错误时返回错误
+func TestNewCollector_InvalidOrphanSelector(t *testing.T) {
+	// Act
+	_, err := NewCollector(config.GCConfig{OrphanSelector: "pantheon"})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+// TestCollect_RemovesOrphanedRows 测试定期任务删除孤立的 label，dry run 时只报告
+func TestCollect_RemovesOrphanedRows(t *testing.T) {
+	// Arrange
+	db := model.SetupTestDB(t)
+	require.NoError(t, db.Create(&model.Label{Key: "stale", Value: "x"}).Error)
+	dryRun, err := NewCollector(config.GCConfig{DryRun: true})
+	require.NoError(t, err)
+	collector, err := NewCollector(config.GCConfig{})
+	require.NoError(t, err)
+
+	// Act
+	reported, reportErr := dryRun.Collect()
+	repaired, repairErr := collector.Collect()
+
+	// Assert
+	require.NoError(t, reportErr)
+	require.NoError(t, repairErr)
+	assert.EqualValues(t, 1, reported.Problems())
+	assert.EqualValues(t, 1, repaired.Problems())
+	var labels int64
+	require.NoError(t, db.Model(&model.Label{}).Count(&labels).Error)
+	assert.Zero(t, labels)
+}
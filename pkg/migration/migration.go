@@ -8,9 +8,11 @@ import (
 	"gorm.io/driver/mysql"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
+	"k8s.io/klog/v2"
 
 	"github.com/cylonchau/pantheon/pkg/config"
 	"github.com/cylonchau/pantheon/pkg/model"
+	"github.com/cylonchau/pantheon/pkg/secret"
 )
 
 func Upgrade(driver string) (enconterError error) {
@@ -82,6 +84,14 @@ func createDatabaseIfNotExists(dbInterface *gorm.DB) error {
 }
 
 func upgradeMigrate(dbInterface *gorm.DB) (enconterError error) {
+	// 遗留的认证字段改为 text 保存密文，需要先删除原有索引
+	for _, index := range []string{"idx_targets_bearer_token", "idx_targets_base_auth"} {
+		if dbInterface.Migrator().HasIndex(&model.Target{}, index) {
+			if enconterError = dbInterface.Migrator().DropIndex(&model.Target{}, index); enconterError != nil {
+				return
+			}
+		}
+	}
 
 	if enconterError = dbInterface.AutoMigrate(&model.Target{}); enconterError != nil {
 		return
@@ -99,6 +109,80 @@ func upgradeMigrate(dbInterface *gorm.DB) (enconterError error) {
 		return
 	}
 
+	if enconterError = dbInterface.AutoMigrate(&model.Credential{}); enconterError != nil {
+		return
+	}
+
+	if enconterError = dbInterface.AutoMigrate(&model.MetricRule{}); enconterError != nil {
+		return
+	}
+
+	if enconterError = dbInterface.AutoMigrate(&model.BlackboxExporter{}); enconterError != nil {
+		return
+	}
+
+	if enconterError = dbInterface.AutoMigrate(&model.Profile{}); enconterError != nil {
+		return
+	}
+
+	if enconterError = dbInterface.AutoMigrate(&model.RelabelRule{}); enconterError != nil {
+		return
+	}
+
+	if enconterError = dbInterface.AutoMigrate(&model.TargetRevision{}); enconterError != nil {
+		return
+	}
+
+	if enconterError = dbInterface.AutoMigrate(&model.Webhook{}, &model.WebhookDelivery{}, &model.WebhookDeadLetter{}); enconterError != nil {
+		return
+	}
+
+	// 为升级前创建的 target 计算唯一标识
+	if enconterError = model.BackfillTargetIdentities(dbInterface); enconterError != nil {
+		return
+	}
+
+	// 将 targets 中遗留的明文认证信息迁移到凭据表
+	if enconterError = model.MigrateInlineCredentials(dbInterface); enconterError != nil {
+		return
+	}
+
+	// 配置主密钥后，加密凭据表中已有的明文
+	if enconterError = model.EncryptCredentials(dbInterface); enconterError != nil {
+		return
+	}
+
+	return nil
+}
+
+// RotateKey 使用 newKeyFile 中的主密钥重新加密所有凭据，完成后需要将配置中的主密钥替换为新密钥
+func RotateKey(driver, newKeyFile string) (enconterError error) {
+	var from, to *secret.Cipher
+	if from, enconterError = secret.LoadCipher(config.CONFIG.Encryption); enconterError != nil {
+		return fmt.Errorf("failed to load current master key: %w", enconterError)
+	}
+	if to, enconterError = secret.LoadCipher(config.EncryptionConfig{KeyFile: newKeyFile}); enconterError != nil {
+		return fmt.Errorf("failed to load new master key: %w", enconterError)
+	}
+
+	var dbInterface *gorm.DB
+	switch driver {
+	case "mysql":
+		dbInterface, enconterError = MySQL()
+	case "sqlite":
+		dbInterface, enconterError = SQLite()
+	default:
+		return errors.New("UnknownDriver")
+	}
+	if enconterError != nil {
+		return
+	}
+
+	var count int
+	if count, enconterError = model.RotateEncryptionKey(dbInterface, from, to); enconterError != nil {
+		return
+	}
+	klog.V(0).Infof("Re-encrypted %d credentials, target headers and webhook secrets with key %s, update [encryption] to use %s before restarting", count, to.KeyID(), newKeyFile)
 	return nil
 }
 
@@ -123,6 +207,43 @@ func autoMigrate(dbInterface *gorm.DB) (enconterError error) {
 			return
 		}
 	}
+	if !dbInterface.Migrator().HasTable(&model.Credential{}) {
+		if enconterError = dbInterface.AutoMigrate(&model.Credential{}); enconterError != nil {
+			return
+		}
+	}
+	if !dbInterface.Migrator().HasTable(&model.MetricRule{}) {
+		if enconterError = dbInterface.AutoMigrate(&model.MetricRule{}); enconterError != nil {
+			return
+		}
+	}
+	if !dbInterface.Migrator().HasTable(&model.BlackboxExporter{}) {
+		if enconterError = dbInterface.AutoMigrate(&model.BlackboxExporter{}); enconterError != nil {
+			return
+		}
+	}
+	if !dbInterface.Migrator().HasTable(&model.Profile{}) {
+		if enconterError = dbInterface.AutoMigrate(&model.Profile{}); enconterError != nil {
+			return
+		}
+	}
+	if !dbInterface.Migrator().HasTable(&model.RelabelRule{}) {
+		if enconterError = dbInterface.AutoMigrate(&model.RelabelRule{}); enconterError != nil {
+			return
+		}
+	}
+	if !dbInterface.Migrator().HasTable(&model.TargetRevision{}) {
+		if enconterError = dbInterface.AutoMigrate(&model.TargetRevision{}); enconterError != nil {
+			return
+		}
+	}
+	for _, table := range []interface{}{&model.Webhook{}, &model.WebhookDelivery{}, &model.WebhookDeadLetter{}} {
+		if !dbInterface.Migrator().HasTable(table) {
+			if enconterError = dbInterface.AutoMigrate(table); enconterError != nil {
+				return
+			}
+		}
+	}
 	return nil
 }
 
@@ -0,0 +1,478 @@
+package model
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+const (
+	credentialTableName  = "credentials"
+	inlineCredentialName = "inline"
+)
+
+var (
+	ErrCredentialNotFound = errors.New("credential not found")
+	ErrCredentialInUse    = errors.New("credential is still referenced by targets")
+)
+
+// Credential 服务端保存的抓取凭据，SD 输出中只出现不透明的 UID
+type Credential struct {
+	ID              uint                  `gorm:"primarykey"`
+	IsDel           soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	UID             string                `gorm:"uniqueIndex;type:varchar(64)"`
+	Name            string                `gorm:"index;type:varchar(255)"`
+	Type            string                `gorm:"type:varchar(16)"`
+	Secret          EncryptedString       `gorm:"type:text"`
+	Fingerprint     string                `gorm:"index;type:char(64)"` // 用于查找相同内容的凭据，避免解密
+	ResourceVersion uint64                // 每次替换敏感内容时递增，轮换主密钥不改变
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+}
+
+func (*Credential) TableName() string {
+	return credentialTableName
+}
+
+// AuthorizationHeader 返回代理转发时使用的 Authorization 头
+func (c *Credential) AuthorizationHeader() string {
+	switch c.Type {
+	case credential.TypeBasic:
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(c.Secret))
+	case credential.TypeBearer:
+		return "Bearer " + string(c.Secret)
+	}
+	return ""
+}
+
+// TargetAuth 返回 target 的认证信息，默认只包含凭据引用，showSecrets 为 true 时包含明文
+func (c *Credential) TargetAuth(showSecrets bool) *target.TargetAuth {
+	auth := &target.TargetAuth{Credential: c.UID}
+	if showSecrets {
+		switch c.Type {
+		case credential.TypeBasic:
+			auth.Base = string(c.Secret)
+		case credential.TypeBearer:
+			auth.BearerToken = string(c.Secret)
+		case credential.TypeOAuth2:
+			auth.OAuth2, _ = c.OAuth2()
+		}
+	}
+	return auth
+}
+
+func (c *Credential) info(targets int64, showSecrets bool) credential.CredentialInfo {
+	info := credential.CredentialInfo{
+		UID:       c.UID,
+		Name:      c.Name,
+		Type:      c.Type,
+		Targets:   targets,
+		CreatedAt: c.CreatedAt,
+		UpdatedAt: c.UpdatedAt,
+	}
+	if showSecrets {
+		info.Secret = &credential.Secret{}
+		switch c.Type {
+		case credential.TypeBasic:
+			username, password, _ := strings.Cut(string(c.Secret), ":")
+			info.Secret.Username, info.Secret.Password = username, password
+		case credential.TypeBearer:
+			info.Secret.Token = string(c.Secret)
+		case credential.TypeOAuth2:
+			info.Secret.OAuth2, _ = c.OAuth2()
+		}
+	}
+	return info
+}
+
+// OAuth2 返回 oauth2 凭据的 client credentials 配置
+func (c *Credential) OAuth2() (*credential.OAuth2, error) {
+	if c.Type != credential.TypeOAuth2 {
+		return nil, fmt.Errorf("credential %s is not an oauth2 credential", c.UID)
+	}
+	conf := &credential.OAuth2{}
+	if err := json.Unmarshal([]byte(c.Secret), conf); err != nil {
+		return nil, fmt.Errorf("invalid oauth2 credential %s: %w", c.UID, err)
+	}
+	return conf, nil
+}
+
+// credentialFingerprint 计算凭据内容的带密钥摘要
+func credentialFingerprint(credentialType, value string) string {
+	return secret.Fingerprint(credentialType + ":" + value)
+}
+
+// secretValue 将 API 中的 Secret 转换为存储格式，basic 为 username:password，oauth2 为 JSON
+func secretValue(credentialType string, secret credential.Secret) (string, error) {
+	switch credentialType {
+	case credential.TypeBasic:
+		if secret.Username == "" {
+			return "", fmt.Errorf("username is required for basic credential")
+		}
+		return secret.Username + ":" + secret.Password, nil
+	case credential.TypeBearer:
+		if secret.Token == "" {
+			return "", fmt.Errorf("token is required for bearer credential")
+		}
+		return secret.Token, nil
+	case credential.TypeOAuth2:
+		conf := secret.OAuth2
+		if conf == nil || conf.ClientID == "" {
+			return "", fmt.Errorf("oauth2.client_id is required for oauth2 credential")
+		}
+		if tokenURL, err := url.Parse(conf.TokenURL); err != nil || (tokenURL.Scheme != "http" && tokenURL.Scheme != "https") || tokenURL.Host == "" {
+			return "", fmt.Errorf("oauth2.token_url must be an http or https URL")
+		}
+		value, err := json.Marshal(conf)
+		if err != nil {
+			return "", err
+		}
+		return string(value), nil
+	}
+	return "", fmt.Errorf("invalid credential type: %s. Valid values are '%s', '%s' or '%s'", credentialType, credential.TypeBasic, credential.TypeBearer, credential.TypeOAuth2)
+}
+
+func newCredentialUID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+func createCredential(tx *gorm.DB, name, credentialType, value string) (*Credential, error) {
+	uid, err := newCredentialUID()
+	if err != nil {
+		return nil, err
+	}
+	newCredential := &Credential{
+		UID:         uid,
+		Name:        name,
+		Type:        credentialType,
+		Secret:      EncryptedString(value),
+		Fingerprint: credentialFingerprint(credentialType, value),
+	}
+	if err = tx.Create(newCredential).Error; err != nil {
+		return nil, err
+	}
+	return newCredential, nil
+}
+
+// CreateCredential 创建凭据
+func CreateCredential(request *credential.Credential) (info credential.CredentialInfo, encounterError error) {
+	// 内联凭据使用保留的名称，具名凭据不能与其混淆
+	if request.Name == inlineCredentialName {
+		return info, fmt.Errorf("credential name %q is reserved for inline credentials", inlineCredentialName)
+	}
+	var value string
+	if value, encounterError = secretValue(request.Type, request.Secret); encounterError != nil {
+		return
+	}
+	var newCredential *Credential
+	if newCredential, encounterError = createCredential(DB, request.Name, request.Type, value); encounterError != nil {
+		return
+	}
+	return newCredential.info(0, false), nil
+}
+
+// ListCredentials 查询所有凭据及其引用的 target 数量
+func ListCredentials() (results []credential.CredentialInfo, encounterError error) {
+	results = make([]credential.CredentialInfo, 0)
+	var credentials []Credential
+	if encounterError = DB.Omit("secret").Order("id").Find(&credentials).Error; encounterError != nil {
+		return
+	}
+	for i := range credentials {
+		var count int64
+		if encounterError = DB.Model(&Target{}).Where("credential_id = ?", credentials[i].ID).Count(&count).Error; encounterError != nil {
+			return
+		}
+		results = append(results, credentials[i].info(count, false))
+	}
+	return results, nil
+}
+
+// GetCredentialByUID 根据 UID 查询凭据，包含敏感内容，仅供服务端内部使用
+func GetCredentialByUID(uid string) (*Credential, error) {
+	found := &Credential{}
+	result := DB.Where("uid = ?", uid).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCredentialNotFound
+	}
+	return found, nil
+}
+
+// GetCredentialByID 根据 ID 查询凭据，包含敏感内容，仅供服务端内部使用
+func GetCredentialByID(id uint) (*Credential, error) {
+	found := &Credential{}
+	result := DB.Where("id = ?", id).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrCredentialNotFound
+	}
+	return found, nil
+}
+
+// GetCredentialInfo 根据 UID 查询凭据的展示信息，showSecrets 为 true 时包含明文
+func GetCredentialInfo(uid string, showSecrets bool) (info credential.CredentialInfo, encounterError error) {
+	var found *Credential
+	if found, encounterError = GetCredentialByUID(uid); encounterError != nil {
+		return
+	}
+	var count int64
+	if encounterError = DB.Model(&Target{}).Where("credential_id = ?", found.ID).Count(&count).Error; encounterError != nil {
+		return
+	}
+	return found.info(count, showSecrets), nil
+}
+
+// RotateCredential 原地替换凭据的敏感内容，UID 不变，因此 SD 输出与 Prometheus 配置都无需变化
+func RotateCredential(uid string, secret credential.Secret) (info credential.CredentialInfo, encounterError error) {
+	var found *Credential
+	if found, encounterError = GetCredentialByUID(uid); encounterError != nil {
+		return
+	}
+	var value string
+	if value, encounterError = secretValue(found.Type, secret); encounterError != nil {
+		return
+	}
+	if encounterError = DB.Model(found).Updates(map[string]interface{}{
+		"secret":           EncryptedString(value),
+		"fingerprint":      credentialFingerprint(found.Type, value),
+		"resource_version": gorm.Expr("resource_version + 1"),
+	}).Error; encounterError != nil {
+		return
+	}
+	klog.V(2).Infof("Credential %s rotated", uid)
+	return GetCredentialInfo(uid, false)
+}
+
+// DeleteCredential 删除未被任何 target 引用的凭据
+func DeleteCredential(uid string) (encounterError error) {
+	var found *Credential
+	if found, encounterError = GetCredentialByUID(uid); encounterError != nil {
+		return
+	}
+	var count int64
+	if encounterError = DB.Model(&Target{}).Where("credential_id = ?", found.ID).Count(&count).Error; encounterError != nil {
+		return
+	}
+	if count > 0 {
+		return ErrCredentialInUse
+	}
+	return DB.Delete(found).Error
+}
+
+// credentialIDForAuth 将请求中的认证信息解析为凭据 ID
+// 引用已有 UID 时直接使用；内联的 base/bearer_token/oauth2 只复用相同内容的内联凭据，不存在时自动创建，
+// 用户管理的具名凭据即使内容相同也不会被复用，轮换具名凭据不会影响内联认证的 target
+func credentialIDForAuth(tx *gorm.DB, auth *target.TargetAuth) (uint, error) {
+	if auth == nil {
+		return 0, nil
+	}
+	if auth.Credential != "" {
+		found := &Credential{}
+		result := tx.Where("uid = ?", auth.Credential).Limit(1).Find(found)
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 0 {
+			return 0, fmt.Errorf("%w: %s", ErrCredentialNotFound, auth.Credential)
+		}
+		return found.ID, nil
+	}
+
+	credentialType, value := "", ""
+	if auth.BearerToken != "" {
+		credentialType, value = credential.TypeBearer, auth.BearerToken
+	} else if auth.Base != "" {
+		credentialType, value = credential.TypeBasic, auth.Base
+	} else if auth.OAuth2 != nil {
+		var err error
+		credentialType = credential.TypeOAuth2
+		if value, err = secretValue(credentialType, credential.Secret{OAuth2: auth.OAuth2}); err != nil {
+			return 0, err
+		}
+	} else {
+		return 0, nil
+	}
+
+	existing := &Credential{}
+	result := tx.Select("id").Where("name = ? AND type = ? AND fingerprint = ?", inlineCredentialName, credentialType, credentialFingerprint(credentialType, value)).
+		Limit(1).Find(existing)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return existing.ID, nil
+	}
+	created, err := createCredential(tx, inlineCredentialName, credentialType, value)
+	if err != nil {
+		return 0, err
+	}
+	return created.ID, nil
+}
+
+// credentialsByID 批量查询凭据，withSecret 为 false 时不读取 (也不解密) 敏感内容
+func credentialsByID(ids []uint, withSecret bool) (map[uint]*Credential, error) {
+	credentials := make(map[uint]*Credential)
+	if len(ids) == 0 {
+		return credentials, nil
+	}
+	var found []Credential
+	query := DB.Where("id IN ?", ids)
+	if !withSecret {
+		query = query.Select("id", "uid", "type")
+	}
+	if err := query.Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for i := range found {
+		credentials[found[i].ID] = &found[i]
+	}
+	return credentials, nil
+}
+
+// credentialRow 凭据表的原始内容，敏感字段不解密
+type credentialRow struct {
+	ID          uint
+	Type        string
+	Secret      string
+	Fingerprint string
+}
+
+// EncryptCredentials 加密配置主密钥之前写入的明文凭据，并补全摘要
+func EncryptCredentials(db *gorm.DB) error {
+	var rows []credentialRow
+	if err := db.Table(credentialTableName).Select("id", "type", "secret", "fingerprint").Find(&rows).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		count := 0
+		for _, row := range rows {
+			// 未配置主密钥时只补全摘要
+			upToDate := secret.IsEncrypted(row.Secret) || secret.Default() == nil
+			if upToDate && row.Fingerprint != "" {
+				continue
+			}
+			plaintext, err := secret.Decrypt(row.Secret)
+			if err != nil {
+				return fmt.Errorf("credential %d: %w", row.ID, err)
+			}
+			if err = tx.Table(credentialTableName).Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"secret":      EncryptedString(plaintext),
+				"fingerprint": credentialFingerprint(row.Type, plaintext),
+			}).Error; err != nil {
+				return err
+			}
+			count++
+		}
+		if count > 0 {
+			klog.V(0).Infof("Encrypted %d credentials", count)
+		}
+		headers, err := encryptTargetHeaders(tx)
+		if err != nil {
+			return err
+		}
+		if headers > 0 {
+			klog.V(0).Infof("Encrypted headers of %d targets", headers)
+		}
+		webhooks, err := encryptWebhookSecrets(tx)
+		if err != nil {
+			return err
+		}
+		if webhooks > 0 {
+			klog.V(0).Infof("Encrypted secrets of %d webhooks", webhooks)
+		}
+		return nil
+	})
+}
+
+// RotateEncryptionKey 使用新的主密钥重新加密所有凭据、target 请求头与 webhook 签名密钥的数据密钥，并重新计算摘要
+// 返回重新加密的记录数
+func RotateEncryptionKey(db *gorm.DB, from, to *secret.Cipher) (int, error) {
+	var rows []credentialRow
+	if err := db.Table(credentialTableName).Select("id", "type", "secret", "fingerprint").Find(&rows).Error; err != nil {
+		return 0, err
+	}
+
+	headers, webhooks := 0, 0
+	err := db.Transaction(func(tx *gorm.DB) (err error) {
+		for _, row := range rows {
+			plaintext, err := from.Decrypt(row.Secret)
+			if err != nil {
+				return fmt.Errorf("credential %d: %w", row.ID, err)
+			}
+			rewrapped, err := from.Rewrap(row.Secret, to)
+			if err != nil {
+				return fmt.Errorf("credential %d: %w", row.ID, err)
+			}
+			if err = tx.Table(credentialTableName).Where("id = ?", row.ID).Updates(map[string]interface{}{
+				"secret":      rewrapped,
+				"fingerprint": to.Fingerprint(row.Type + ":" + plaintext),
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if headers, err = rewrapTargetHeaders(tx, from, to); err != nil {
+			return err
+		}
+		webhooks, err = rewrapWebhookSecrets(tx, from, to)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(rows) + headers + webhooks, nil
+}
+
+// MigrateInlineCredentials 将 targets 表中遗留的 bearer_token/base_auth 迁移到凭据表，并清空原字段
+func MigrateInlineCredentials(db *gorm.DB) error {
+	var targets []Target
+	if err := db.Unscoped().Select("id", "bearer_token", "base_auth").
+		Where("credential_id = 0 AND (bearer_token <> '' OR base_auth <> '')").
+		Find(&targets).Error; err != nil {
+		return err
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, legacy := range targets {
+			// bearer token 与 basic auth 同时存在时，代理以 bearer token 为准
+			credentialID, err := credentialIDForAuth(tx, &target.TargetAuth{Base: string(legacy.BaseAuth), BearerToken: string(legacy.BearerToken)})
+			if err != nil {
+				return err
+			}
+			if err = tx.Unscoped().Model(&Target{}).Where("id = ?", legacy.ID).Updates(map[string]interface{}{
+				"credential_id": credentialID,
+				"bearer_token":  "",
+				"base_auth":     "",
+			}).Error; err != nil {
+				return err
+			}
+		}
+		if len(targets) > 0 {
+			klog.V(0).Infof("Migrated inline credentials of %d targets", len(targets))
+		}
+		return nil
+	})
+}
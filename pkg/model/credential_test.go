@@ -0,0 +1,319 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+// TestCreateCredential_Success 测试创建凭据并生成 Authorization 头
+func TestCreateCredential_Success(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+
+	// Act
+	info, err := CreateCredential(&credential.Credential{
+		Name:   "mysql",
+		Type:   credential.TypeBasic,
+		Secret: credential.Secret{Username: "exporter", Password: "secret"},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Len(t, info.UID, 32)
+	found, err := GetCredentialByUID(info.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "Basic ZXhwb3J0ZXI6c2VjcmV0", found.AuthorizationHeader())
+}
+
+// TestCreateCredential_MissingSecret 测试缺少敏感内容时返回错误
+func TestCreateCredential_MissingSecret(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+
+	// Act
+	_, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+// TestCreateCredential_OAuth2 测试 oauth2 凭据的校验与展示，client_secret 只在显式请求时返回
+func TestCreateCredential_OAuth2(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	conf := &credential.OAuth2{TokenURL: "https://auth.example.com/token", ClientID: "pantheon", ClientSecret: "secret", Scopes: []string{"metrics"}}
+
+	// Act
+	info, err := CreateCredential(&credential.Credential{Name: "saas", Type: credential.TypeOAuth2, Secret: credential.Secret{OAuth2: conf}})
+	_, invalidErr := CreateCredential(&credential.Credential{Name: "saas", Type: credential.TypeOAuth2,
+		Secret: credential.Secret{OAuth2: &credential.OAuth2{TokenURL: "file:///etc/passwd", ClientID: "pantheon"}}})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Nil(t, info.Secret)
+	shown, err := GetCredentialInfo(info.UID, true)
+	require.NoError(t, err)
+	assert.Equal(t, conf, shown.Secret.OAuth2)
+	found, err := GetCredentialByUID(info.UID)
+	require.NoError(t, err)
+	assert.Empty(t, found.AuthorizationHeader(), "oauth2 tokens are fetched by the proxy")
+	assert.Error(t, invalidErr)
+}
+
+// TestRotateCredential_KeepsUID 测试轮换凭据后 UID 不变，内容被替换
+func TestRotateCredential_KeepsUID(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	info, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "old"}})
+	require.NoError(t, err)
+
+	// Act
+	rotated, err := RotateCredential(info.UID, credential.Secret{Token: "new"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, info.UID, rotated.UID)
+	found, err := GetCredentialByUID(info.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer new", found.AuthorizationHeader())
+}
+
+// TestCreateTargets_InlineAuthBecomesCredential 测试内联认证被保存为凭据，SD 输出只包含凭据引用
+func TestCreateTargets_InlineAuthBecomesCredential(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	config.CONFIG = &config.Config{ProxyAddress: "http://pantheon:8899/ph/v1/proxy"}
+	t.Cleanup(func() { config.CONFIG = nil })
+	request := &target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1:9100", Auth: &target.TargetAuth{BearerToken: "token"}},
+			{Address: "10.0.0.2:9100", Auth: &target.TargetAuth{BearerToken: "token"}},
+		},
+	}
+
+	// Act
+	require.NoError(t, CreateTargets(request))
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "app", Value: "node"}, nil)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	credentials, err := ListCredentials()
+	require.NoError(t, err)
+	require.Len(t, credentials, 1, "identical inline secrets should share one credential")
+	assert.Equal(t, int64(2), credentials[0].Targets)
+
+	for _, result := range results {
+		assert.Equal(t, []string{"pantheon:8899"}, result.Targets)
+		assert.Equal(t, credentials[0].UID, result.Labels["__param_cred"])
+		assert.NotContains(t, result.Labels, "__param_bearer")
+		assert.NotContains(t, result.Labels, "__param_base")
+		for _, value := range result.Labels {
+			assert.NotEqual(t, "token", value)
+		}
+	}
+}
+
+// TestCreateTargets_InlineAuthIgnoresNamedCredential 测试内联认证不复用内容相同的具名凭据，轮换具名凭据不影响内联认证的 target
+func TestCreateTargets_InlineAuthIgnoresNamedCredential(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	named, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "token"}})
+	require.NoError(t, err)
+	_, reservedErr := CreateCredential(&credential.Credential{Name: inlineCredentialName, Type: credential.TypeBearer, Secret: credential.Secret{Token: "other"}})
+
+	// Act
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1:9100", Auth: &target.TargetAuth{BearerToken: "token"}},
+			{Address: "10.0.0.2:9100", Auth: &target.TargetAuth{Credential: named.UID}},
+		},
+	}))
+	_, err = RotateCredential(named.UID, credential.Secret{Token: "rotated"})
+	require.NoError(t, err)
+
+	// Assert
+	assert.Error(t, reservedErr, "the inline credential name is reserved")
+	credentials, err := ListCredentials()
+	require.NoError(t, err)
+	require.Len(t, credentials, 2)
+	assert.Equal(t, named.UID, credentials[0].UID)
+	assert.Equal(t, int64(1), credentials[0].Targets)
+	assert.Equal(t, inlineCredentialName, credentials[1].Name)
+	inline, err := GetCredentialByUID(credentials[1].UID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token", inline.AuthorizationHeader())
+}
+
+// TestDeleteCredential_InUse 测试仍被 target 引用的凭据不能删除
+func TestDeleteCredential_InUse(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	info, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "token"}})
+	require.NoError(t, err)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Auth: &target.TargetAuth{Credential: info.UID}}},
+	}))
+
+	// Act
+	err = DeleteCredential(info.UID)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrCredentialInUse)
+}
+
+// TestMigrateInlineCredentials 测试遗留的明文认证被迁移到凭据表
+func TestMigrateInlineCredentials(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	require.NoError(t, db.Create(&Target{Address: "10.0.0.1:9100", Schema: "http", BearerToken: "token", BaseAuth: "user:pass"}).Error)
+	require.NoError(t, db.Create(&Target{Address: "10.0.0.2:9100", Schema: "http", BaseAuth: "user:pass"}).Error)
+
+	// Act
+	err := MigrateInlineCredentials(db)
+
+	// Assert
+	require.NoError(t, err)
+	var targets []Target
+	require.NoError(t, db.Order("id").Find(&targets).Error)
+	for _, migrated := range targets {
+		assert.NotZero(t, migrated.CredentialID)
+		assert.Empty(t, migrated.BearerToken)
+		assert.Empty(t, migrated.BaseAuth)
+	}
+	var bearer Credential
+	require.NoError(t, db.First(&bearer, targets[0].CredentialID).Error)
+	assert.Equal(t, "Bearer token", bearer.AuthorizationHeader())
+	var basic Credential
+	require.NoError(t, db.First(&basic, targets[1].CredentialID).Error)
+	assert.Equal(t, credential.TypeBasic, basic.Type)
+}
+
+func setupTestCipher(t *testing.T, fill byte) *secret.Cipher {
+	t.Helper()
+	c, err := secret.NewCipher(bytes.Repeat([]byte{fill}, 32))
+	require.NoError(t, err)
+	secret.SetDefault(c)
+	t.Cleanup(func() { secret.SetDefault(nil) })
+	return c
+}
+
+// TestCredential_EncryptedAtRest 测试配置主密钥后数据库中只保存密文，读取时自动解密
+func TestCredential_EncryptedAtRest(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	setupTestCipher(t, 1)
+
+	// Act
+	info, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "token"}})
+
+	// Assert
+	require.NoError(t, err)
+	var raw string
+	require.NoError(t, db.Table(credentialTableName).Where("uid = ?", info.UID).Pluck("secret", &raw).Error)
+	assert.True(t, secret.IsEncrypted(raw))
+	assert.NotContains(t, raw, "token")
+	found, err := GetCredentialByUID(info.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token", found.AuthorizationHeader())
+}
+
+// TestListTargetWithCtl_RedactsSecrets 测试默认只返回凭据引用，显式请求时返回明文
+func TestListTargetWithCtl_RedactsSecrets(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	setupTestCipher(t, 1)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Auth: &target.TargetAuth{BearerToken: "token"}}},
+	}))
+	selector := &query.QueryWithLabel{Key: "app", Value: "node"}
+
+	// Act
+	redacted, err := ListTargetWithCtl(selector, false)
+	require.NoError(t, err)
+	revealed, err := ListTargetWithCtl(selector, true)
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, redacted, 1)
+	require.NotNil(t, redacted[0].Auth)
+	assert.NotEmpty(t, redacted[0].Auth.Credential)
+	assert.Empty(t, redacted[0].Auth.BearerToken)
+	require.Len(t, revealed, 1)
+	assert.Equal(t, "token", revealed[0].Auth.BearerToken)
+
+	byID, err := GetTargetByID(redacted[0].ID, false)
+	require.NoError(t, err)
+	assert.Equal(t, redacted[0].Auth.Credential, byID.Auth.Credential)
+	assert.Empty(t, byID.Auth.BearerToken)
+}
+
+// TestEncryptCredentials 测试配置主密钥之前写入的明文凭据在升级时被加密
+func TestEncryptCredentials(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	inlineID, err := credentialIDForAuth(db, &target.TargetAuth{BearerToken: "token"})
+	require.NoError(t, err)
+	setupTestCipher(t, 1)
+
+	// Act
+	err = EncryptCredentials(db)
+
+	// Assert
+	require.NoError(t, err)
+	var raw string
+	require.NoError(t, db.Table(credentialTableName).Where("id = ?", inlineID).Pluck("secret", &raw).Error)
+	assert.True(t, secret.IsEncrypted(raw))
+	// 摘要使用主密钥重新计算，内联认证仍能复用该凭据
+	credentialID, err := credentialIDForAuth(db, &target.TargetAuth{BearerToken: "token"})
+	require.NoError(t, err)
+	assert.Equal(t, inlineID, credentialID)
+}
+
+// TestRotateEncryptionKey 测试轮换主密钥后凭据只能使用新密钥解密
+func TestRotateEncryptionKey(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	from := setupTestCipher(t, 1)
+	info, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "token"}})
+	require.NoError(t, err)
+	inlineID, err := credentialIDForAuth(db, &target.TargetAuth{BearerToken: "inline"})
+	require.NoError(t, err)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Headers: map[string]string{"X-Api-Key": "key"}}},
+	}))
+	to, err := secret.NewCipher(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+
+	// Act
+	count, err := RotateEncryptionKey(db, from, to)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	secret.SetDefault(to)
+	found, err := GetCredentialByUID(info.UID)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer token", found.AuthorizationHeader())
+	// 摘要使用新的主密钥重新计算，内联认证仍能复用已有的内联凭据
+	credentialID, err := credentialIDForAuth(db, &target.TargetAuth{BearerToken: "inline"})
+	require.NoError(t, err)
+	assert.Equal(t, inlineID, credentialID)
+	stored, err := FindProxyTarget("http", "10.0.0.1", "9100", "/metrics", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "key", stored.Headers["X-Api-Key"])
+}
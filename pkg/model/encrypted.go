@@ -0,0 +1,68 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+// EncryptedString 写入数据库时使用信封加密，读取时自动解密
+// 未配置主密钥时以明文保存，读取加密之前写入的明文数据时原样返回
+type EncryptedString string
+
+// Value 实现 driver.Valuer
+func (s EncryptedString) Value() (driver.Value, error) {
+	return secret.Encrypt(string(s))
+}
+
+// Scan 实现 sql.Scanner
+func (s *EncryptedString) Scan(value interface{}) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		*s = ""
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted string", value)
+	}
+	plaintext, err := secret.Decrypt(raw)
+	if err != nil {
+		return err
+	}
+	*s = EncryptedString(plaintext)
+	return nil
+}
+
+// EncryptedHeaders 以 JSON 格式加密保存的请求头，没有请求头时保存为空字符串
+type EncryptedHeaders map[string]string
+
+// Value 实现 driver.Valuer
+func (h EncryptedHeaders) Value() (driver.Value, error) {
+	if len(h) == 0 {
+		return "", nil
+	}
+	raw, err := json.Marshal(map[string]string(h))
+	if err != nil {
+		return nil, err
+	}
+	return secret.Encrypt(string(raw))
+}
+
+// Scan 实现 sql.Scanner
+func (h *EncryptedHeaders) Scan(value interface{}) error {
+	var plaintext EncryptedString
+	if err := plaintext.Scan(value); err != nil {
+		return err
+	}
+	if plaintext == "" {
+		*h = nil
+		return nil
+	}
+	return json.Unmarshal([]byte(plaintext), (*map[string]string)(h))
+}
@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+
+	"gorm.io/gorm"
+
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+// redactedHeaderValue 未显式请求时请求头的值以此代替
+const redactedHeaderValue = "<redacted>"
+
+var headerNameRe = regexp.MustCompile("^[!#$%&'*+.^_`|~0-9A-Za-z-]+$")
+
+// 这些请求头由代理自身设置，认证信息需要使用凭据
+var reservedHeaders = map[string]bool{
+	"Authorization":     true,
+	"Host":              true,
+	"Connection":        true,
+	"Content-Length":    true,
+	"Transfer-Encoding": true,
+	"Accept-Encoding":   true,
+}
+
+// newTargetHeaders 校验 target 的自定义请求头
+func newTargetHeaders(headers map[string]string) (EncryptedHeaders, error) {
+	if len(headers) == 0 {
+		return nil, nil
+	}
+	result := make(EncryptedHeaders, len(headers))
+	for name, value := range headers {
+		if !headerNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid header name: %q", name)
+		}
+		canonical := http.CanonicalHeaderKey(name)
+		if reservedHeaders[canonical] {
+			return nil, fmt.Errorf("header %s cannot be set on a target, use a credential for authentication", canonical)
+		}
+		result[canonical] = value
+	}
+	return result, nil
+}
+
+// redactHeaders 返回请求头，showSecrets 为 false 时只保留名称
+func redactHeaders(headers EncryptedHeaders, showSecrets bool) map[string]string {
+	if len(headers) == 0 {
+		return nil
+	}
+	result := make(map[string]string, len(headers))
+	for name, value := range headers {
+		if !showSecrets {
+			value = redactedHeaderValue
+		}
+		result[name] = value
+	}
+	return result
+}
+
+type targetHeadersRow struct {
+	ID      uint
+	Headers string
+}
+
+func targetHeadersRows(tx *gorm.DB) (rows []targetHeadersRow, err error) {
+	err = tx.Table(targetTableName).Select("id", "headers").Where("headers IS NOT NULL AND headers <> ''").Find(&rows).Error
+	return
+}
+
+// encryptTargetHeaders 加密配置主密钥之前写入的明文请求头
+func encryptTargetHeaders(tx *gorm.DB) (int, error) {
+	if secret.Default() == nil {
+		return 0, nil
+	}
+	rows, err := targetHeadersRows(tx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, row := range rows {
+		if secret.IsEncrypted(row.Headers) {
+			continue
+		}
+		encrypted, err := secret.Encrypt(row.Headers)
+		if err != nil {
+			return count, err
+		}
+		if err = tx.Table(targetTableName).Where("id = ?", row.ID).Update("headers", encrypted).Error; err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// rewrapTargetHeaders 使用新的主密钥重新加密请求头的数据密钥
+func rewrapTargetHeaders(tx *gorm.DB, from, to *secret.Cipher) (int, error) {
+	rows, err := targetHeadersRows(tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		rewrapped, err := from.Rewrap(row.Headers, to)
+		if err != nil {
+			return 0, fmt.Errorf("headers of target %d: %w", row.ID, err)
+		}
+		if err = tx.Table(targetTableName).Where("id = ?", row.ID).Update("headers", rewrapped).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
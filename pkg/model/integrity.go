@@ -0,0 +1,183 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+// integrityTable 以 key/value 保存、经由关联表被 target 引用的表
+type integrityTable struct {
+	name   string
+	join   string
+	column string
+}
+
+var integrityTables = []integrityTable{
+	{name: label_table_name, join: "target_labels", column: "label_id"},
+	{name: param_table_name, join: "target_params", column: "param_id"},
+	{name: selector_table_name, join: "target_selectors", column: "selector_id"},
+}
+
+// errIntegrityDryRun 回滚 dry run 中的修改
+var errIntegrityDryRun = errors.New("integrity dry run")
+
+// IntegrityTableReport 一张 key/value 表的检查结果
+type IntegrityTableReport struct {
+	Table         string
+	DanglingJoins int64 // 关联表中指向不存在 (或已删除) 的 target、或不存在的行的记录
+	Duplicates    int64 // key 与 value 相同的多余行，引用合并到 id 最小的行
+	Orphaned      int64 // 没有被任何 target 引用的行
+}
+
+// IntegrityReport 完整性检查的结果，DryRun 时为修复将会产生的变化
+type IntegrityReport struct {
+	DryRun                 bool
+	Tables                 []IntegrityTableReport
+	TargetsWithoutSelector []uint
+	Reattached             []uint // 加入了 orphan selector 的 target
+}
+
+// Problems 返回发现的问题数量
+func (r *IntegrityReport) Problems() int64 {
+	problems := int64(len(r.TargetsWithoutSelector))
+	for _, table := range r.Tables {
+		problems += table.DanglingJoins + table.Duplicates + table.Orphaned
+	}
+	return problems
+}
+
+// IntegrityOptions 修复选项
+type IntegrityOptions struct {
+	// DryRun 在事务中执行修复后回滚，报告与真正修复时完全一致
+	DryRun bool
+	// OrphanSelector 加入没有 selector 的 target，为空时只报告
+	OrphanSelector map[string]string
+}
+
+// RepairIntegrity 检查并修复关联数据：删除无效的关联记录，合并重复的行，删除没有被引用的行，
+// 最后为没有 selector 的 target 加入 orphan selector。所有修改在一个事务中完成
+func RepairIntegrity(options IntegrityOptions) (report *IntegrityReport, encounterError error) {
+	report = &IntegrityReport{DryRun: options.DryRun}
+	encounterError = DB.Transaction(func(tx *gorm.DB) error {
+		for _, table := range integrityTables {
+			tableReport := IntegrityTableReport{Table: table.name}
+			var err error
+			// 先删除无效的关联记录，由此产生的孤立行在之后一并删除
+			if tableReport.DanglingJoins, err = deleteDanglingJoins(tx, table); err != nil {
+				return err
+			}
+			if tableReport.Duplicates, err = mergeDuplicateRows(tx, table); err != nil {
+				return err
+			}
+			if tableReport.Orphaned, err = deleteOrphanedRows(tx, table); err != nil {
+				return err
+			}
+			report.Tables = append(report.Tables, tableReport)
+		}
+
+		if err := tx.Model(&Target{}).Where("id NOT IN (SELECT target_id FROM target_selectors)").
+			Order("id").Pluck("id", &report.TargetsWithoutSelector).Error; err != nil {
+			return err
+		}
+		if len(report.TargetsWithoutSelector) > 0 && len(options.OrphanSelector) > 0 {
+			if err := attachOrphanSelector(tx, report, options.OrphanSelector); err != nil {
+				return err
+			}
+		}
+
+		if options.DryRun {
+			return errIntegrityDryRun
+		}
+		return nil
+	})
+	if errors.Is(encounterError, errIntegrityDryRun) {
+		encounterError = nil
+	}
+	return report, encounterError
+}
+
+func deleteDanglingJoins(tx *gorm.DB, table integrityTable) (int64, error) {
+	result := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE target_id NOT IN (SELECT id FROM targets WHERE is_del = 0) OR %s NOT IN (SELECT id FROM %s)",
+		table.join, table.column, table.name))
+	return result.RowsAffected, result.Error
+}
+
+// mergeDuplicateRows 将重复行的引用合并到 id 最小的行后删除重复行
+func mergeDuplicateRows(tx *gorm.DB, table integrityTable) (merged int64, encounterError error) {
+	var groups []struct {
+		Key   string
+		Value string
+		Keep  uint
+	}
+	if encounterError = tx.Table(table.name).Select("`key`, `value`, MIN(id) AS keep").
+		Group("`key`, `value`").Having("COUNT(*) > 1").Scan(&groups).Error; encounterError != nil {
+		return
+	}
+	for _, group := range groups {
+		var duplicates []uint
+		if encounterError = tx.Table(table.name).Where("`key` = ? AND `value` = ? AND id != ?", group.Key, group.Value, group.Keep).
+			Pluck("id", &duplicates).Error; encounterError != nil {
+			return
+		}
+		for _, duplicate := range duplicates {
+			// 已经引用保留行的 target 不再重复关联
+			var linked []uint
+			if encounterError = tx.Table(table.join).Where(table.column+" = ?", group.Keep).Pluck("target_id", &linked).Error; encounterError != nil {
+				return
+			}
+			update := tx.Table(table.join).Where(table.column+" = ?", duplicate)
+			if len(linked) > 0 {
+				update = update.Where("target_id NOT IN ?", linked)
+			}
+			if encounterError = update.Update(table.column, group.Keep).Error; encounterError != nil {
+				return
+			}
+			if encounterError = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE %s = ?", table.join, table.column), duplicate).Error; encounterError != nil {
+				return
+			}
+			if encounterError = tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = ?", table.name), duplicate).Error; encounterError != nil {
+				return
+			}
+			merged++
+		}
+	}
+	return merged, nil
+}
+
+func deleteOrphanedRows(tx *gorm.DB, table integrityTable) (int64, error) {
+	result := tx.Exec(fmt.Sprintf("DELETE FROM %s WHERE id NOT IN (SELECT %s FROM %s)", table.name, table.column, table.join))
+	return result.RowsAffected, result.Error
+}
+
+// attachOrphanSelector 为没有 selector 的 target 加入 orphan selector，与已存在的 target 标识冲突时跳过
+func attachOrphanSelector(tx *gorm.DB, report *IntegrityReport, orphanSelector map[string]string) error {
+	selectors, err := createSelectors(tx, orphanSelector)
+	if err != nil {
+		return err
+	}
+	for _, id := range report.TargetsWithoutSelector {
+		err := tx.Transaction(func(tx *gorm.DB) error {
+			for _, selector := range selectors {
+				if err := tx.Table("target_selectors").Create(map[string]interface{}{"target_id": id, "selector_id": selector.ID}).Error; err != nil {
+					return err
+				}
+			}
+			if err := refreshTargetIdentities(tx, id); err != nil {
+				return err
+			}
+			return touchTargets(tx, id)
+		})
+		if errors.Is(err, ErrTargetConflict) {
+			klog.Warningf("target %d left without selector: %v", id, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		report.Reattached = append(report.Reattached, id)
+	}
+	return nil
+}
@@ -0,0 +1,135 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+// TestDeleteTarget_KeepsSharedRows 测试删除 target 时只删除不再被其他 target 引用的 label、param 与 selector
+func TestDeleteTarget_KeepsSharedRows(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1:9100", Labels: map[string]string{"env": "prod", "host": "a"}, Params: map[string]string{"module": "cpu"}},
+			{Address: "10.0.0.2:9100", Labels: map[string]string{"env": "prod"}, Params: map[string]string{"module": "cpu"}},
+		},
+	})
+	require.NoError(t, err)
+
+	// Act
+	err = DeleteTargetWithID(created[0].ID, 0)
+
+	// Assert
+	require.NoError(t, err)
+	var labels []string
+	require.NoError(t, db.Model(&Label{}).Order("`key`").Pluck("`key`", &labels).Error)
+	assert.Equal(t, []string{"env"}, labels, "label only used by the deleted target is removed")
+	var params, selectors int64
+	require.NoError(t, db.Model(&Param{}).Count(&params).Error)
+	require.NoError(t, db.Model(&Selector{}).Count(&selectors).Error)
+	assert.EqualValues(t, 1, params)
+	assert.EqualValues(t, 1, selectors)
+
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "prom", Value: "fed"}, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, map[string]string{"env": "prod"}, listed[0].Labels)
+}
+
+// corruptRelations 写入升级前的版本可能遗留的无效数据
+func corruptRelations(t *testing.T) (kept, orphan uint) {
+	db := DB
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1:9100", Labels: map[string]string{"env": "prod"}},
+			{Address: "10.0.0.2:9100"},
+		},
+	})
+	require.NoError(t, err)
+	kept, orphan = created[0].ID, created[1].ID
+
+	// 孤立的 label 与 param
+	require.NoError(t, db.Exec("INSERT INTO labels (`key`, `value`) VALUES ('stale', 'x')").Error)
+	require.NoError(t, db.Exec("INSERT INTO params (`key`, `value`) VALUES ('stale', 'x')").Error)
+	// 指向不存在的 target 与不存在的 label 的关联记录
+	require.NoError(t, db.Exec("INSERT INTO target_labels (target_id, label_id) VALUES (999, 1)").Error)
+	require.NoError(t, db.Exec("INSERT INTO target_labels (target_id, label_id) VALUES (?, 999)", kept).Error)
+	// 重复的 label，两个 target 分别引用两行
+	var duplicate Label
+	require.NoError(t, db.Exec("INSERT INTO labels (`key`, `value`) VALUES ('env', 'prod')").Error)
+	require.NoError(t, db.Where("`key` = 'env'").Order("id DESC").First(&duplicate).Error)
+	require.NoError(t, db.Exec("INSERT INTO target_labels (target_id, label_id) VALUES (?, ?)", orphan, duplicate.ID).Error)
+	require.NoError(t, db.Exec("INSERT INTO target_labels (target_id, label_id) VALUES (?, ?)", kept, duplicate.ID).Error)
+	// 没有 selector 的 target
+	require.NoError(t, db.Exec("DELETE FROM target_selectors WHERE target_id = ?", orphan).Error)
+	return kept, orphan
+}
+
+// TestRepairIntegrity_DryRunReportsWithoutChanges 测试 dry run 报告所有问题但不修改数据
+func TestRepairIntegrity_DryRunReportsWithoutChanges(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	_, orphan := corruptRelations(t)
+	var before int64
+	require.NoError(t, db.Table("target_labels").Count(&before).Error)
+
+	// Act
+	report, err := RepairIntegrity(IntegrityOptions{DryRun: true, OrphanSelector: map[string]string{"pantheon": "orphaned"}})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, report.Tables, 3)
+	assert.Equal(t, IntegrityTableReport{Table: "labels", DanglingJoins: 2, Duplicates: 1, Orphaned: 1}, report.Tables[0])
+	assert.Equal(t, IntegrityTableReport{Table: "params", Orphaned: 1}, report.Tables[1])
+	assert.Equal(t, IntegrityTableReport{Table: "selectors"}, report.Tables[2])
+	assert.Equal(t, []uint{orphan}, report.TargetsWithoutSelector)
+	assert.Equal(t, []uint{orphan}, report.Reattached)
+	assert.EqualValues(t, 6, report.Problems())
+
+	var after int64
+	require.NoError(t, db.Table("target_labels").Count(&after).Error)
+	assert.Equal(t, before, after, "dry run must not change the database")
+	var selectors int64
+	require.NoError(t, db.Model(&Selector{}).Where("`key` = 'pantheon'").Count(&selectors).Error)
+	assert.Zero(t, selectors)
+}
+
+// TestRepairIntegrity_Repair 测试修复后数据一致，再次检查不再发现问题
+func TestRepairIntegrity_Repair(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	kept, orphan := corruptRelations(t)
+
+	// Act
+	report, err := RepairIntegrity(IntegrityOptions{OrphanSelector: map[string]string{"pantheon": "orphaned"}})
+	again, againErr := RepairIntegrity(IntegrityOptions{DryRun: true})
+
+	// Assert
+	require.NoError(t, err)
+	assert.EqualValues(t, 6, report.Problems())
+	require.NoError(t, againErr)
+	assert.Zero(t, again.Problems())
+
+	var labels []Label
+	require.NoError(t, db.Preload("Targets").Find(&labels).Error)
+	require.Len(t, labels, 1, "duplicate and orphaned labels are removed")
+	assert.Equal(t, "env", labels[0].Key)
+	ids := []uint{}
+	for _, linked := range labels[0].Targets {
+		ids = append(ids, linked.ID)
+	}
+	assert.ElementsMatch(t, []uint{kept, orphan}, ids, "references of the duplicate are merged")
+
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "pantheon", Value: "orphaned"}, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, orphan, listed[0].ID)
+}
@@ -24,18 +24,24 @@ func (*LabelList) TableName() string {
 	return label_table_name
 }
 
-func CreateLabels(labels map[string]string) ([]Label, error) {
+func CreateLabels(labels map[string]string) (createdLabels []Label, encounterError error) {
+	encounterError = DB.Transaction(func(tx *gorm.DB) (err error) {
+		createdLabels, err = createLabels(tx, labels)
+		return err
+	})
+	return
+}
+
+// createLabels 在事务中查询或创建 label，键保存为小写
+func createLabels(tx *gorm.DB, labels map[string]string) ([]Label, error) {
 	var createdLabels []Label
-	tx := DB.Begin()
 	for key, value := range labels {
 		var label Label
 		if result := tx.Where(Label{Key: strings.ToLower(strings.TrimSpace(key)), Value: strings.TrimSpace(value)}).FirstOrCreate(&label); result.Error != nil {
-			tx.Rollback()
 			return nil, result.Error
 		}
 		createdLabels = append(createdLabels, label)
 	}
-	tx.Commit()
 	return createdLabels, nil
 }
 
@@ -0,0 +1,212 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+
+	"github.com/cylonchau/pantheon/pkg/api/metricrule"
+	"github.com/cylonchau/pantheon/pkg/exposition"
+)
+
+const metricRuleTableName = "metric_rules"
+
+var ErrMetricRuleNotFound = errors.New("metric rule not found")
+
+// MetricRule 代理返回抓取结果前应用的过滤规则，TargetID 为 0 时作用于 selector 下的所有 target
+type MetricRule struct {
+	ID            uint                  `gorm:"primarykey"`
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	Name          string                `gorm:"index;type:varchar(255)"`
+	TargetID      uint                  `gorm:"index"`
+	SelectorKey   string                `gorm:"index:idx_metric_rules_selector;type:varchar(255)"`
+	SelectorValue string                `gorm:"index:idx_metric_rules_selector;type:varchar(255)"`
+	Keep          string                `gorm:"type:text"`
+	Drop          string                `gorm:"type:text"`
+	DropLabels    string                `gorm:"type:text"`
+	AddLabels     map[string]string     `gorm:"serializer:json;type:text"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (*MetricRule) TableName() string {
+	return metricRuleTableName
+}
+
+// Compile 编译规则中的正则
+func (r *MetricRule) Compile() (exposition.Rule, error) {
+	return exposition.Compile(r.Keep, r.Drop, r.DropLabels, r.AddLabels)
+}
+
+func (r *MetricRule) info() metricrule.MetricRuleInfo {
+	info := metricrule.MetricRuleInfo{
+		ID: r.ID,
+		MetricRule: metricrule.MetricRule{
+			Name:       r.Name,
+			TargetID:   r.TargetID,
+			Keep:       r.Keep,
+			Drop:       r.Drop,
+			DropLabels: r.DropLabels,
+			AddLabels:  r.AddLabels,
+		},
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+	if r.SelectorKey != "" {
+		info.Selector = r.SelectorKey + "=" + r.SelectorValue
+	}
+	return info
+}
+
+// newMetricRule 校验请求并转换为规则，target 必须已存在，selector 的格式为 key=value
+func newMetricRule(request *metricrule.MetricRule) (*MetricRule, error) {
+	rule := &MetricRule{
+		Name:       request.Name,
+		TargetID:   request.TargetID,
+		Keep:       request.Keep,
+		Drop:       request.Drop,
+		DropLabels: request.DropLabels,
+		AddLabels:  request.AddLabels,
+	}
+	switch {
+	case request.TargetID != 0 && request.Selector != "":
+		return nil, fmt.Errorf("target_id and selector are mutually exclusive")
+	case request.TargetID != 0:
+		if _, err := GetProxyTargetByID(request.TargetID); err != nil {
+			return nil, err
+		}
+	case request.Selector != "":
+		key, value, found := strings.Cut(request.Selector, "=")
+		if !found || key == "" || value == "" {
+			return nil, fmt.Errorf("invalid selector %q, expected key=value", request.Selector)
+		}
+		rule.SelectorKey, rule.SelectorValue = key, value
+	default:
+		return nil, fmt.Errorf("one of target_id or selector is required")
+	}
+	if rule.Keep == "" && rule.Drop == "" && rule.DropLabels == "" && len(rule.AddLabels) == 0 {
+		return nil, fmt.Errorf("rule %s has nothing to do", rule.Name)
+	}
+	if _, err := rule.Compile(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// CreateMetricRule 创建过滤规则
+func CreateMetricRule(request *metricrule.MetricRule) (info metricrule.MetricRuleInfo, encounterError error) {
+	var rule *MetricRule
+	if rule, encounterError = newMetricRule(request); encounterError != nil {
+		return
+	}
+	if encounterError = DB.Create(rule).Error; encounterError != nil {
+		return
+	}
+	return rule.info(), nil
+}
+
+// ListMetricRules 查询所有过滤规则
+func ListMetricRules() (results []metricrule.MetricRuleInfo, encounterError error) {
+	results = make([]metricrule.MetricRuleInfo, 0)
+	var rules []MetricRule
+	if encounterError = DB.Order("id").Find(&rules).Error; encounterError != nil {
+		return
+	}
+	for i := range rules {
+		results = append(results, rules[i].info())
+	}
+	return results, nil
+}
+
+func getMetricRule(id uint) (*MetricRule, error) {
+	found := &MetricRule{}
+	result := DB.Where("id = ?", id).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrMetricRuleNotFound
+	}
+	return found, nil
+}
+
+// GetMetricRule 根据 ID 查询过滤规则
+func GetMetricRule(id uint) (info metricrule.MetricRuleInfo, encounterError error) {
+	var rule *MetricRule
+	if rule, encounterError = getMetricRule(id); encounterError != nil {
+		return
+	}
+	return rule.info(), nil
+}
+
+// UpdateMetricRule 使用请求的内容替换过滤规则
+func UpdateMetricRule(id uint, request *metricrule.MetricRule) (info metricrule.MetricRuleInfo, encounterError error) {
+	var existing, rule *MetricRule
+	if existing, encounterError = getMetricRule(id); encounterError != nil {
+		return
+	}
+	if rule, encounterError = newMetricRule(request); encounterError != nil {
+		return
+	}
+	rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+	if encounterError = DB.Save(rule).Error; encounterError != nil {
+		return
+	}
+	return rule.info(), nil
+}
+
+// DeleteMetricRule 删除过滤规则
+func DeleteMetricRule(id uint) error {
+	result := DB.Where("id = ?", id).Delete(&MetricRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrMetricRuleNotFound
+	}
+	return nil
+}
+
+// 规则通过 selector 作用于 target 的条件
+const metricRuleSelectorCondition = "EXISTS (SELECT 1 FROM target_selectors JOIN selectors ON selectors.id = target_selectors.selector_id " +
+	"WHERE target_selectors.target_id = ? AND selectors.`key` = metric_rules.selector_key AND selectors.`value` = metric_rules.selector_value)"
+
+// MetricRulesForTarget 查询作用于 target 的规则，先应用 selector 的规则，再应用 target 自身的规则
+func MetricRulesForTarget(targetID uint) (rules []MetricRule, encounterError error) {
+	encounterError = DB.Where("(target_id = ? OR (target_id = 0 AND "+metricRuleSelectorCondition+"))", targetID, targetID).
+		Order("target_id").Order("id").
+		Find(&rules).Error
+	return
+}
+
+// targetsWithMetricRules 返回配置了过滤规则的 target，这些 target 需要经由代理抓取
+func targetsWithMetricRules(targets []Target) (map[uint]bool, error) {
+	result := make(map[uint]bool)
+	if len(targets) == 0 {
+		return result, nil
+	}
+	targetIDs := make([]uint, 0, len(targets))
+	for _, t := range targets {
+		targetIDs = append(targetIDs, t.ID)
+	}
+	var ids []uint
+	if err := DB.Model(&MetricRule{}).Where("target_id IN ?", targetIDs).Pluck("target_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	var selected []uint
+	if err := DB.Table("target_selectors").
+		Joins("JOIN selectors ON selectors.id = target_selectors.selector_id").
+		Joins("JOIN metric_rules ON metric_rules.selector_key = selectors.`key` AND metric_rules.selector_value = selectors.`value`").
+		Where("metric_rules.target_id = 0 AND metric_rules.`is_del` = 0").
+		Where("target_selectors.target_id IN ?", targetIDs).
+		Distinct().Pluck("target_selectors.target_id", &selected).Error; err != nil {
+		return nil, err
+	}
+	for _, id := range append(ids, selected...) {
+		result[id] = true
+	}
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/metricrule"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+)
+
+// TestMetricRulesForTarget 测试 selector 与 target 的规则都作用于 target，且 selector 的规则先应用
+func TestMetricRulesForTarget(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "storage"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}, {Address: "10.0.0.2:9100"}},
+	}))
+	found, err := FindProxyTarget("http", "10.0.0.1", "9100", "/metrics", nil)
+	require.NoError(t, err)
+	targetRule, err := CreateMetricRule(&metricrule.MetricRule{Name: "one", TargetID: found.ID, DropLabels: "pod"})
+	require.NoError(t, err)
+	selectorRule, err := CreateMetricRule(&metricrule.MetricRule{Name: "all", Selector: "app=storage", Drop: "vendor_.*"})
+	require.NoError(t, err)
+	_, err = CreateMetricRule(&metricrule.MetricRule{Name: "other", Selector: "app=node", Drop: ".*"})
+	require.NoError(t, err)
+
+	// Act
+	rules, err := MetricRulesForTarget(found.ID)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, selectorRule.ID, rules[0].ID)
+	assert.Equal(t, targetRule.ID, rules[1].ID)
+}
+
+// TestMetricRule_TargetsAreProxied 测试配置了规则的 target 在 SD 输出中经由代理抓取
+func TestMetricRule_TargetsAreProxied(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	config.CONFIG = &config.Config{ProxyAddress: "http://pantheon:8899/ph/v1/proxy"}
+	t.Cleanup(func() { config.CONFIG = nil })
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "storage", "dc": "bj"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	}))
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"dc": "bj"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.2:9100"}},
+	}))
+	_, err := CreateMetricRule(&metricrule.MetricRule{Name: "storage", Selector: "app=storage", AddLabels: map[string]string{"team": "storage"}})
+	require.NoError(t, err)
+
+	// Act
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "dc", Value: "bj"}, nil)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	proxied := make(map[string]bool)
+	for _, result := range results {
+		proxied[result.Labels["instance"]] = result.Targets[0] == "pantheon:8899"
+	}
+	assert.Equal(t, map[string]bool{"10.0.0.1:9100": true, "10.0.0.2:9100": false}, proxied)
+}
+
+// TestCreateMetricRule_Invalid 测试无效的规则被拒绝
+func TestCreateMetricRule_Invalid(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	testCases := []struct {
+		name    string
+		request metricrule.MetricRule
+	}{
+		{name: "no scope", request: metricrule.MetricRule{Name: "x", Drop: "a"}},
+		{name: "both scopes", request: metricrule.MetricRule{Name: "x", TargetID: 1, Selector: "a=b", Drop: "a"}},
+		{name: "unknown target", request: metricrule.MetricRule{Name: "x", TargetID: 42, Drop: "a"}},
+		{name: "invalid selector", request: metricrule.MetricRule{Name: "x", Selector: "app", Drop: "a"}},
+		{name: "invalid regex", request: metricrule.MetricRule{Name: "x", Selector: "a=b", Drop: "("}},
+		{name: "empty rule", request: metricrule.MetricRule{Name: "x", Selector: "a=b"}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			_, err := CreateMetricRule(&tc.request)
+
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
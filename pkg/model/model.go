@@ -3,6 +3,7 @@ package model
 import (
 	"context"
 	"database/sql"
+	"fmt"
 	"net/url"
 	"strings"
 	"time"
@@ -141,3 +142,30 @@ func parseConfigURL(raw string) *url.URL {
 
 	return parsedURL
 }
+
+// proxyAddressFor 返回 SD 中 target 使用的代理地址，网络区域配置了 proxy_address 时使用区域的代理
+func proxyAddressFor(zone string) string {
+	if found, exists := config.CONFIG.Zone(zone); exists && found.ProxyAddress != "" {
+		return found.ProxyAddress
+	}
+	return config.CONFIG.ProxyAddress
+}
+
+// validateZone 检查网络区域是否在配置文件中定义
+func validateZone(zone string) error {
+	if zone == "" {
+		return nil
+	}
+	if _, exists := config.CONFIG.Zone(zone); !exists {
+		return fmt.Errorf("unknown zone %s, zones must be defined in [[zones]] of the configuration", zone)
+	}
+	return nil
+}
+
+// validationConfig 返回校验策略，未加载配置时使用默认策略
+func validationConfig() config.ValidationConfig {
+	if config.CONFIG == nil {
+		return config.ValidationConfig{}
+	}
+	return config.CONFIG.Validation
+}
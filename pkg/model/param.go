@@ -1,5 +1,7 @@
 package model
 
+import "gorm.io/gorm"
+
 var param_table_name = "params"
 
 type Param struct {
@@ -18,17 +20,23 @@ func (*ParamList) TableName() string {
 	return param_table_name
 }
 
-func CreateParams(params map[string]string) ([]Param, error) {
+func CreateParams(params map[string]string) (createdParams []Param, encounterError error) {
+	encounterError = DB.Transaction(func(tx *gorm.DB) (err error) {
+		createdParams, err = createParams(tx, params)
+		return err
+	})
+	return
+}
+
+// createParams 在事务中查询或创建 param
+func createParams(tx *gorm.DB, params map[string]string) ([]Param, error) {
 	var createdParams []Param
-	tx := DB.Begin()
 	for key, value := range params {
 		var param Param
 		if result := tx.Where(Param{Key: key, Value: value}).FirstOrCreate(&param); result.Error != nil {
-			tx.Rollback()
 			return nil, result.Error
 		}
 		createdParams = append(createdParams, param)
 	}
-	tx.Commit()
 	return createdParams, nil
 }
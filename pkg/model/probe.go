@@ -0,0 +1,251 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+
+	"github.com/cylonchau/pantheon/pkg/api/probe"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+const blackboxExporterTableName = "blackbox_exporters"
+
+var (
+	ErrExporterNotFound = errors.New("blackbox exporter not found")
+	ErrExporterExists   = errors.New("blackbox exporter already exists")
+	ErrExporterInUse    = errors.New("blackbox exporter is still referenced by probes")
+)
+
+// BlackboxExporter 执行探测的 blackbox exporter，探测 target 的地址与路径取自 exporter
+type BlackboxExporter struct {
+	ID         uint                  `gorm:"primarykey"`
+	IsDel      soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	Name       string                `gorm:"index;type:varchar(255)"`
+	Address    string                `gorm:"type:varchar(255)"`
+	Schema     string                `gorm:"type:char(5)"`
+	MetricPath string                `gorm:"type:varchar(255)"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (*BlackboxExporter) TableName() string {
+	return blackboxExporterTableName
+}
+
+func (e *BlackboxExporter) info(probes int64) probe.ExporterInfo {
+	return probe.ExporterInfo{
+		ID:         e.ID,
+		Name:       e.Name,
+		Address:    e.Address,
+		Schema:     e.Schema,
+		MetricPath: e.MetricPath,
+		Probes:     probes,
+		CreatedAt:  e.CreatedAt,
+		UpdatedAt:  e.UpdatedAt,
+	}
+}
+
+// apply 校验请求并更新 exporter 的地址，地址的 schema 默认为 http，路径默认为 /probe
+func (e *BlackboxExporter) apply(request *probe.Exporter) error {
+	address := strings.TrimSpace(request.Address)
+	schema := "http"
+	if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+		schema, address, _ = strings.Cut(address, "://")
+	}
+	if address == "" || strings.ContainsAny(address, "/ ") {
+		return fmt.Errorf("invalid exporter address %q, expected host:port", request.Address)
+	}
+	e.Name, e.Address, e.Schema, e.MetricPath = request.Name, address, schema, request.MetricPath
+	if e.MetricPath == "" {
+		e.MetricPath = "/probe"
+	}
+	return nil
+}
+
+func getExporterByName(tx *gorm.DB, name string) (*BlackboxExporter, error) {
+	found := &BlackboxExporter{}
+	result := tx.Where("name = ?", name).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrExporterNotFound
+	}
+	return found, nil
+}
+
+// CreateExporter 创建 blackbox exporter，名称不能重复
+func CreateExporter(request *probe.Exporter) (info probe.ExporterInfo, encounterError error) {
+	if _, encounterError = getExporterByName(DB, request.Name); encounterError == nil {
+		return info, ErrExporterExists
+	} else if !errors.Is(encounterError, ErrExporterNotFound) {
+		return
+	}
+	exporter := &BlackboxExporter{}
+	if encounterError = exporter.apply(request); encounterError != nil {
+		return
+	}
+	if encounterError = DB.Create(exporter).Error; encounterError != nil {
+		return
+	}
+	return exporter.info(0), nil
+}
+
+// ListExporters 查询所有 blackbox exporter 及引用它们的探测数量
+func ListExporters() (results []probe.ExporterInfo, encounterError error) {
+	results = make([]probe.ExporterInfo, 0)
+	var exporters []BlackboxExporter
+	if encounterError = DB.Order("name").Find(&exporters).Error; encounterError != nil {
+		return
+	}
+	var counts []struct {
+		ExporterID uint
+		Count      int64
+	}
+	if encounterError = DB.Model(&Target{}).Select("exporter_id, COUNT(*) AS count").
+		Where("exporter_id <> 0").Group("exporter_id").Scan(&counts).Error; encounterError != nil {
+		return
+	}
+	probes := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		probes[count.ExporterID] = count.Count
+	}
+	for i := range exporters {
+		results = append(results, exporters[i].info(probes[exporters[i].ID]))
+	}
+	return results, nil
+}
+
+// UpdateExporter 修改 exporter 的地址，引用它的探测 target 同步修改，SD 输出随之变化
+func UpdateExporter(name string, request *probe.Exporter) (info probe.ExporterInfo, encounterError error) {
+	encounterError = DB.Transaction(func(tx *gorm.DB) error {
+		exporter, err := getExporterByName(tx, name)
+		if err != nil {
+			return err
+		}
+		if request.Name != name {
+			return fmt.Errorf("exporter cannot be renamed")
+		}
+		if err = exporter.apply(request); err != nil {
+			return err
+		}
+		if err = tx.Save(exporter).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&Target{}).Where("exporter_id = ?", exporter.ID).Updates(map[string]interface{}{
+			"address":     exporter.Address,
+			"schema":      exporter.Schema,
+			"metric_path": exporter.MetricPath,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		var ids []uint
+		if err = tx.Model(&Target{}).Where("exporter_id = ?", exporter.ID).Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if err = refreshTargetIdentities(tx, ids...); err != nil {
+			return err
+		}
+		if err = touchTargets(tx, ids...); err != nil {
+			return err
+		}
+		info = exporter.info(result.RowsAffected)
+		return nil
+	})
+	return
+}
+
+// DeleteExporter 删除 exporter，仍被探测 target 引用时返回 ErrExporterInUse
+func DeleteExporter(name string) error {
+	exporter, err := getExporterByName(DB, name)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err = DB.Model(&Target{}).Where("exporter_id = ?", exporter.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrExporterInUse
+	}
+	return DB.Delete(exporter).Error
+}
+
+// CreateProbes 为每个探测目标创建一个 target，已存在的探测 target 不会重复创建
+func CreateProbes(request *probe.Probe) error {
+	request.Targets = normalizeProbeTargets(request.Targets)
+	if len(request.Targets) == 0 {
+		return fmt.Errorf("at least one probe target is required")
+	}
+	targets := &target.Target{InstanceSelector: request.InstanceSelector}
+	for _, probeTarget := range request.Targets {
+		targets.Targets = append(targets.Targets, target.TargetItem{
+			ScrapeTime:    request.ScrapeTime,
+			ScrapeTimeout: request.ScrapeTimeout,
+			Labels:        request.Labels,
+			Probe:         &target.TargetProbe{Exporter: request.Exporter, Module: request.Module, Target: probeTarget},
+		})
+	}
+	return CreateTargets(targets)
+}
+
+// normalizeProbeTargets 去除空白与重复的探测目标
+func normalizeProbeTargets(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[value] {
+			continue
+		}
+		seen[value] = true
+		result = append(result, value)
+	}
+	return result
+}
+
+// ListProbes 查询探测 target，exporter 与 module 为空时不过滤
+func ListProbes(query *probe.QueryProbes) (results []probe.ProbeInfo, encounterError error) {
+	results = make([]probe.ProbeInfo, 0)
+	tx := DB.Table(targetTableName).
+		Select("targets.id, blackbox_exporters.name AS exporter, targets.probe_module AS module, targets.probe_target AS target, targets.scrape_time, targets.scrape_timeout").
+		Joins("JOIN blackbox_exporters ON blackbox_exporters.id = targets.exporter_id").
+		Where("targets.`is_del` = 0")
+	if query.Exporter != "" {
+		tx = tx.Where("blackbox_exporters.name = ?", query.Exporter)
+	}
+	if query.Module != "" {
+		tx = tx.Where("targets.probe_module = ?", query.Module)
+	}
+	encounterError = tx.Order("targets.id").Scan(&results).Error
+	return
+}
+
+// resolveProbe 将探测 target 转换为访问 exporter 的 target，module 与探测目标作为参数传给 exporter
+func resolveProbe(tx *gorm.DB, item *target.TargetItem) (*BlackboxExporter, error) {
+	settings := item.Probe
+	settings.Target = strings.TrimSpace(settings.Target)
+	if settings.Exporter == "" || settings.Module == "" || settings.Target == "" {
+		return nil, fmt.Errorf("probe exporter, module and target are required")
+	}
+	exporter, err := getExporterByName(tx, settings.Exporter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, settings.Exporter)
+	}
+	item.Address = exporter.Schema + "://" + exporter.Address
+	item.MetricPath = exporter.MetricPath
+	params := make(map[string]string, len(item.Params)+2)
+	for key, value := range item.Params {
+		params[key] = value
+	}
+	params["module"] = settings.Module
+	params["target"] = settings.Target
+	item.Params = params
+	return exporter, nil
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/probe"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+)
+
+// TestCreateExporter_Duplicate 测试 exporter 名称不能重复，地址的 schema 与路径有默认值
+func TestCreateExporter_Duplicate(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	info, err := CreateExporter(&probe.Exporter{Name: "bj", Address: "10.0.0.5:9115"})
+	require.NoError(t, err)
+
+	// Act
+	_, err = CreateExporter(&probe.Exporter{Name: "bj", Address: "10.0.0.6:9115"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrExporterExists)
+	assert.Equal(t, "http", info.Schema)
+	assert.Equal(t, "/probe", info.MetricPath)
+}
+
+// TestCreateProbes_SD 测试探测 target 在 SD 中的地址为 exporter，instance 为探测目标
+func TestCreateProbes_SD(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	_, err := CreateExporter(&probe.Exporter{Name: "bj", Address: "https://10.0.0.5:9115"})
+	require.NoError(t, err)
+	request := &probe.Probe{
+		Exporter:         "bj",
+		Module:           "http_2xx",
+		Targets:          []string{"https://example.com", " https://example.com ", "10.0.0.1:22"},
+		Labels:           map[string]string{"team": "infra"},
+		InstanceSelector: map[string]string{"prom": "fed"},
+	}
+
+	// Act
+	require.NoError(t, CreateProbes(request))
+	require.NoError(t, CreateProbes(request))
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "prom", Value: "fed"}, nil)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	instances := make(map[string]bool)
+	for _, result := range results {
+		assert.Equal(t, []string{"10.0.0.5:9115"}, result.Targets)
+		assert.Equal(t, "/probe", result.Labels["__metrics_path__"])
+		assert.Equal(t, "https", result.Labels["__scheme__"])
+		assert.Equal(t, "http_2xx", result.Labels["__param_module"])
+		assert.Equal(t, result.Labels["instance"], result.Labels["__param_target"])
+		assert.Equal(t, "infra", result.Labels["team"])
+		instances[result.Labels["instance"]] = true
+	}
+	assert.Equal(t, map[string]bool{"https://example.com": true, "10.0.0.1:22": true}, instances)
+}
+
+// TestCreateProbes_UnknownExporter 测试引用不存在的 exporter 时创建失败
+func TestCreateProbes_UnknownExporter(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+
+	// Act
+	err := CreateProbes(&probe.Probe{
+		Exporter:         "missing",
+		Module:           "http_2xx",
+		Targets:          []string{"https://example.com"},
+		InstanceSelector: map[string]string{"prom": "fed"},
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrExporterNotFound)
+}
+
+// TestUpdateExporter_Cascades 测试修改 exporter 地址后探测 target 随之变化，仍被引用的 exporter 不能删除
+func TestUpdateExporter_Cascades(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	_, err := CreateExporter(&probe.Exporter{Name: "bj", Address: "10.0.0.5:9115"})
+	require.NoError(t, err)
+	_, err = CreateExporter(&probe.Exporter{Name: "sh", Address: "10.0.1.5:9115"})
+	require.NoError(t, err)
+	require.NoError(t, CreateProbes(&probe.Probe{
+		Exporter:         "bj",
+		Module:           "icmp",
+		Targets:          []string{"10.0.0.1", "10.0.0.2"},
+		InstanceSelector: map[string]string{"prom": "fed"},
+	}))
+	require.NoError(t, CreateProbes(&probe.Probe{
+		Exporter:         "sh",
+		Module:           "tcp_connect",
+		Targets:          []string{"10.0.1.1:22"},
+		InstanceSelector: map[string]string{"prom": "fed"},
+	}))
+
+	// Act
+	info, err := UpdateExporter("bj", &probe.Exporter{Name: "bj", Address: "10.0.0.9:9115", MetricPath: "/bb/probe"})
+	require.NoError(t, err)
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "prom", Value: "fed"}, nil)
+	require.NoError(t, err)
+	probes, err := ListProbes(&probe.QueryProbes{Exporter: "bj"})
+	require.NoError(t, err)
+	deleteErr := DeleteExporter("bj")
+
+	// Assert
+	assert.Equal(t, int64(2), info.Probes)
+	addresses := make(map[string]string)
+	for _, result := range results {
+		addresses[result.Labels["instance"]] = result.Targets[0] + result.Labels["__metrics_path__"]
+	}
+	assert.Equal(t, map[string]string{
+		"10.0.0.1":    "10.0.0.9:9115/bb/probe",
+		"10.0.0.2":    "10.0.0.9:9115/bb/probe",
+		"10.0.1.1:22": "10.0.1.5:9115/probe",
+	}, addresses)
+	require.Len(t, probes, 2)
+	assert.Equal(t, "icmp", probes[0].Module)
+	assert.ErrorIs(t, deleteErr, ErrExporterInUse)
+}
+
+// TestDeleteExporter 测试没有探测引用的 exporter 可以删除
+func TestDeleteExporter(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	_, err := CreateExporter(&probe.Exporter{Name: "bj", Address: "10.0.0.5:9115"})
+	require.NoError(t, err)
+
+	// Act
+	err = DeleteExporter("bj")
+
+	// Assert
+	require.NoError(t, err)
+	assert.ErrorIs(t, DeleteExporter("bj"), ErrExporterNotFound)
+}
@@ -0,0 +1,457 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+
+	"github.com/cylonchau/pantheon/pkg/api/profile"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/validation"
+)
+
+const profileTableName = "profiles"
+
+var (
+	ErrProfileNotFound = errors.New("profile not found")
+	ErrProfileExists   = errors.New("profile already exists")
+	ErrProfileInUse    = errors.New("profile is still referenced by targets")
+)
+
+// Profile 某类 exporter 的默认抓取配置，创建 target 时填充 target 中未设置的字段
+type Profile struct {
+	ID            uint                  `gorm:"primarykey"`
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	Name          string                `gorm:"index;type:varchar(255)"`
+	Port          int                   `gorm:"type:int"`
+	MetricPath    string                `gorm:"type:varchar(255)"`
+	ScrapeTime    int                   `gorm:"type:int"`
+	ScrapeTimeout int                   `gorm:"type:int"`
+	Labels        map[string]string     `gorm:"serializer:json;type:text"`
+	Params        map[string]string     `gorm:"serializer:json;type:text"`
+	CredentialID  uint                  `gorm:"index"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (*Profile) TableName() string {
+	return profileTableName
+}
+
+// apply 校验请求并更新 profile，认证信息保存到凭据表
+func (p *Profile) apply(tx *gorm.DB, request *profile.Profile) (err error) {
+	if request.Port < 0 || request.Port > 65535 {
+		return fmt.Errorf("invalid port %d", request.Port)
+	}
+	if request.ScrapeTime < 0 || request.ScrapeTimeout < 0 {
+		return fmt.Errorf("scrape_time and scrape_timeout must not be negative")
+	}
+	if request.MetricPath != "" && !strings.HasPrefix(request.MetricPath, "/") {
+		return fmt.Errorf("invalid metric_path %q, expected an absolute path", request.MetricPath)
+	}
+	v := validation.New(validationConfig())
+	v.Labels("labels", request.Labels)
+	v.Params("params", request.Params)
+	if err = v.Err(); err != nil {
+		return err
+	}
+	if p.CredentialID, err = credentialIDForAuth(tx, request.Auth); err != nil {
+		return err
+	}
+	p.Name, p.Port, p.MetricPath = request.Name, request.Port, request.MetricPath
+	p.ScrapeTime, p.ScrapeTimeout = request.ScrapeTime, request.ScrapeTimeout
+	p.Labels, p.Params = request.Labels, request.Params
+	return nil
+}
+
+func (p *Profile) info(targets int64) (profile.ProfileInfo, error) {
+	info := profile.ProfileInfo{
+		ID: p.ID,
+		Profile: profile.Profile{
+			Name:          p.Name,
+			Port:          p.Port,
+			MetricPath:    p.MetricPath,
+			ScrapeTime:    p.ScrapeTime,
+			ScrapeTimeout: p.ScrapeTimeout,
+			Labels:        p.Labels,
+			Params:        p.Params,
+		},
+		Targets:   targets,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+	if p.CredentialID != 0 {
+		credentials, err := credentialsByID([]uint{p.CredentialID}, false)
+		if err != nil {
+			return info, err
+		}
+		if found, exists := credentials[p.CredentialID]; exists {
+			info.Auth = found.TargetAuth(false)
+		}
+	}
+	return info, nil
+}
+
+// effective 返回 profile 应用到 target 后的路径与抓取间隔，未设置时为 target 的默认值
+func (p *Profile) effective() (metricPath string, scrapeTime, scrapeTimeout int) {
+	metricPath, scrapeTime, scrapeTimeout = p.MetricPath, p.ScrapeTime, p.ScrapeTimeout
+	if metricPath == "" {
+		metricPath = "/metrics"
+	}
+	if scrapeTime == 0 {
+		scrapeTime = 30
+	}
+	if scrapeTimeout == 0 {
+		scrapeTimeout = 10
+	}
+	return
+}
+
+func getProfileByName(tx *gorm.DB, name string) (*Profile, error) {
+	found := &Profile{}
+	result := tx.Where("name = ?", name).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrProfileNotFound
+	}
+	return found, nil
+}
+
+// CreateProfile 创建 profile，名称不能重复
+func CreateProfile(request *profile.Profile) (info profile.ProfileInfo, encounterError error) {
+	if _, encounterError = getProfileByName(DB, request.Name); encounterError == nil {
+		return info, ErrProfileExists
+	} else if !errors.Is(encounterError, ErrProfileNotFound) {
+		return
+	}
+	created := &Profile{}
+	if encounterError = created.apply(DB, request); encounterError != nil {
+		return
+	}
+	if encounterError = DB.Create(created).Error; encounterError != nil {
+		return
+	}
+	return created.info(0)
+}
+
+// ListProfiles 查询所有 profile 及引用它们的 target 数量
+func ListProfiles() (results []profile.ProfileInfo, encounterError error) {
+	results = make([]profile.ProfileInfo, 0)
+	var profiles []Profile
+	if encounterError = DB.Order("name").Find(&profiles).Error; encounterError != nil {
+		return
+	}
+	var counts []struct {
+		ProfileID uint
+		Count     int64
+	}
+	if encounterError = DB.Model(&Target{}).Select("profile_id, COUNT(*) AS count").
+		Where("profile_id <> 0").Group("profile_id").Scan(&counts).Error; encounterError != nil {
+		return
+	}
+	targets := make(map[uint]int64, len(counts))
+	for _, count := range counts {
+		targets[count.ProfileID] = count.Count
+	}
+	for i := range profiles {
+		info, err := profiles[i].info(targets[profiles[i].ID])
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, info)
+	}
+	return results, nil
+}
+
+// GetProfile 按名称查询 profile
+func GetProfile(name string) (info profile.ProfileInfo, encounterError error) {
+	found, encounterError := getProfileByName(DB, name)
+	if encounterError != nil {
+		return
+	}
+	var count int64
+	if encounterError = DB.Model(&Target{}).Where("profile_id = ?", found.ID).Count(&count).Error; encounterError != nil {
+		return
+	}
+	return found.info(count)
+}
+
+// UpdateProfile 修改 profile，只影响之后创建的 target；propagate 为 true 时同步修改由它创建的 target，
+// target 中与旧 profile 不一致 (单独设置过) 的字段保持不变
+func UpdateProfile(name string, request *profile.Profile, propagate bool) (info profile.ProfileInfo, encounterError error) {
+	var (
+		updated *Profile
+		changed int64
+	)
+	encounterError = DB.Transaction(func(tx *gorm.DB) error {
+		old, err := getProfileByName(tx, name)
+		if err != nil {
+			return err
+		}
+		if request.Name != name {
+			return fmt.Errorf("profile cannot be renamed")
+		}
+		updated = &Profile{}
+		*updated = *old
+		if err = updated.apply(tx, request); err != nil {
+			return err
+		}
+		if err = tx.Save(updated).Error; err != nil {
+			return err
+		}
+		if propagate {
+			changed, err = propagateProfile(tx, old, updated)
+		}
+		return err
+	})
+	if encounterError != nil {
+		return
+	}
+	var count int64
+	if encounterError = DB.Model(&Target{}).Where("profile_id = ?", updated.ID).Count(&count).Error; encounterError != nil {
+		return
+	}
+	if info, encounterError = updated.info(count); encounterError != nil {
+		return
+	}
+	info.Changed = changed
+	return info, nil
+}
+
+// DeleteProfile 删除 profile，仍被 target 引用时返回 ErrProfileInUse
+func DeleteProfile(name string) error {
+	found, err := getProfileByName(DB, name)
+	if err != nil {
+		return err
+	}
+	var count int64
+	if err = DB.Model(&Target{}).Where("profile_id = ?", found.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrProfileInUse
+	}
+	return DB.Delete(found).Error
+}
+
+// applyProfile 使用 profile 填充 target 中未设置的字段，labels 与 params 合并，target 中的值优先
+// 认证信息在 target 未设置时由调用方使用 profile 的凭据
+func applyProfile(tx *gorm.DB, item *target.TargetItem) (*Profile, error) {
+	found, err := getProfileByName(tx, item.Profile)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", err, item.Profile)
+	}
+	item.Address = withDefaultPort(item.Address, found.Port)
+	if item.MetricPath == "" {
+		item.MetricPath = found.MetricPath
+	}
+	if item.ScrapeTime == 0 {
+		item.ScrapeTime = found.ScrapeTime
+	}
+	if item.ScrapeTimeout == 0 {
+		item.ScrapeTimeout = found.ScrapeTimeout
+	}
+	item.Labels = mergeProfileMap(found.Labels, item.Labels)
+	item.Params = mergeProfileMap(found.Params, item.Params)
+	return found, nil
+}
+
+func mergeProfileMap(defaults, values map[string]string) map[string]string {
+	if len(defaults) == 0 {
+		return values
+	}
+	merged := make(map[string]string, len(defaults)+len(values))
+	for key, value := range defaults {
+		merged[key] = value
+	}
+	for key, value := range values {
+		merged[key] = value
+	}
+	return merged
+}
+
+// withDefaultPort 地址中没有端口时追加 port，保留地址中的 schema
+func withDefaultPort(address string, port int) string {
+	if port == 0 || address == "" {
+		return address
+	}
+	prefix, host := "", address
+	if schema, rest, found := strings.Cut(address, "://"); found {
+		prefix, host = schema+"://", rest
+	}
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return address
+	}
+	return prefix + net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(port))
+}
+
+// isEmptyAuth 命令行总是发送 auth，各字段为空时视为未设置
+func isEmptyAuth(auth *target.TargetAuth) bool {
+	return auth == nil || (auth.Base == "" && auth.BearerToken == "" && auth.Credential == "" && auth.OAuth2 == nil)
+}
+
+// propagateProfile 将 profile 的修改同步到由它创建的 target，返回修改过的 target 数量
+func propagateProfile(tx *gorm.DB, old, updated *Profile) (int64, error) {
+	var targets []Target
+	if err := tx.Preload("Labels").Preload("Params").Where("profile_id = ?", updated.ID).Find(&targets).Error; err != nil {
+		return 0, err
+	}
+	oldPath, oldTime, oldTimeout := old.effective()
+	newPath, newTime, newTimeout := updated.effective()
+
+	var changed int64
+	for i := range targets {
+		t := &targets[i]
+		updates := make(map[string]interface{})
+		if t.ExporterID == 0 && t.MetricPath == oldPath && newPath != oldPath {
+			updates["metric_path"] = newPath
+		}
+		if t.ScrapeTime == oldTime && newTime != oldTime {
+			updates["scrape_time"] = newTime
+		}
+		if t.ScrapeTimeout == oldTimeout && newTimeout != oldTimeout {
+			updates["scrape_timeout"] = newTimeout
+		}
+		if t.CredentialID == old.CredentialID && updated.CredentialID != old.CredentialID {
+			updates["credential_id"] = updated.CredentialID
+		}
+		if old.Port != 0 && updated.Port != 0 && updated.Port != old.Port && t.ExporterID == 0 {
+			if host, port, err := net.SplitHostPort(t.Address); err == nil && port == strconv.Itoa(old.Port) {
+				updates["address"] = net.JoinHostPort(host, strconv.Itoa(updated.Port))
+			}
+		}
+		if len(updates) > 0 {
+			if err := tx.Model(t).Updates(updates).Error; err != nil {
+				return changed, err
+			}
+		}
+
+		current := make(map[string]string, len(t.Labels))
+		for _, label := range t.Labels {
+			current[label.Key] = label.Value
+		}
+		remove, add := profileMapDiff(current, normalizeLabels(old.Labels), normalizeLabels(updated.Labels))
+		labelsChanged := len(remove) > 0 || len(add) > 0
+		if err := replaceTargetLabels(tx, t, remove, add); err != nil {
+			return changed, err
+		}
+
+		current = make(map[string]string, len(t.Params))
+		for _, param := range t.Params {
+			current[param.Key] = param.Value
+		}
+		remove, add = profileMapDiff(current, old.Params, updated.Params)
+		paramsChanged := len(remove) > 0 || len(add) > 0
+		if err := replaceTargetParams(tx, t, remove, add); err != nil {
+			return changed, err
+		}
+
+		_, addressChanged := updates["address"]
+		_, pathChanged := updates["metric_path"]
+		if addressChanged || pathChanged || paramsChanged {
+			if err := refreshTargetIdentities(tx, t.ID); err != nil {
+				return changed, err
+			}
+		}
+		if len(updates) > 0 || labelsChanged || paramsChanged {
+			if err := touchTargets(tx, t.ID); err != nil {
+				return changed, err
+			}
+			changed++
+		}
+	}
+	return changed, nil
+}
+
+// profileMapDiff 计算 target 需要移除与添加的键值，只修改 target 中仍与旧 profile 一致的键
+func profileMapDiff(current, old, updated map[string]string) (remove, add map[string]string) {
+	remove, add = make(map[string]string), make(map[string]string)
+	for key, oldValue := range old {
+		newValue, kept := updated[key]
+		if current[key] != oldValue || (kept && newValue == oldValue) {
+			continue
+		}
+		remove[key] = oldValue
+		if kept {
+			add[key] = newValue
+		}
+	}
+	for key, value := range updated {
+		if _, existed := old[key]; existed {
+			continue
+		}
+		if _, exists := current[key]; !exists {
+			add[key] = value
+		}
+	}
+	return remove, add
+}
+
+// normalizeLabels 与 CreateLabels 保持一致，label 的键保存为小写
+func normalizeLabels(labels map[string]string) map[string]string {
+	normalized := make(map[string]string, len(labels))
+	for key, value := range labels {
+		normalized[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+	}
+	return normalized
+}
+
+func replaceTargetLabels(tx *gorm.DB, t *Target, remove, add map[string]string) error {
+	var removed []Label
+	for _, label := range t.Labels {
+		if value, exists := remove[label.Key]; exists && value == label.Value {
+			removed = append(removed, label)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Model(t).Association("Labels").Delete(removed); err != nil {
+			return err
+		}
+	}
+	var added []Label
+	for key, value := range add {
+		var label Label
+		if err := tx.Where(Label{Key: key, Value: value}).FirstOrCreate(&label).Error; err != nil {
+			return err
+		}
+		added = append(added, label)
+	}
+	if len(added) > 0 {
+		return tx.Model(t).Association("Labels").Append(added)
+	}
+	return nil
+}
+
+func replaceTargetParams(tx *gorm.DB, t *Target, remove, add map[string]string) error {
+	var removed []Param
+	for _, param := range t.Params {
+		if value, exists := remove[param.Key]; exists && value == param.Value {
+			removed = append(removed, param)
+		}
+	}
+	if len(removed) > 0 {
+		if err := tx.Model(t).Association("Params").Delete(removed); err != nil {
+			return err
+		}
+	}
+	var added []Param
+	for key, value := range add {
+		var param Param
+		if err := tx.Where(Param{Key: key, Value: value}).FirstOrCreate(&param).Error; err != nil {
+			return err
+		}
+		added = append(added, param)
+	}
+	if len(added) > 0 {
+		return tx.Model(t).Association("Params").Append(added)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/profile"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+)
+
+func sdByInstance(t *testing.T) map[string]map[string]string {
+	t.Helper()
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "prom", Value: "fed"}, nil)
+	require.NoError(t, err)
+	byInstance := make(map[string]map[string]string, len(results))
+	for _, result := range results {
+		byInstance[result.Labels["instance"]] = result.Labels
+	}
+	return byInstance
+}
+
+// TestCreateTargets_WithProfile 测试 target 中未设置的字段使用 profile 的值，target 中的值优先
+func TestCreateTargets_WithProfile(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	// 配置了认证的 target 经由代理抓取
+	config.CONFIG = &config.Config{ProxyAddress: "http://pantheon:8899/ph/v1/proxy"}
+	t.Cleanup(func() { config.CONFIG = nil })
+	_, err := CreateProfile(&profile.Profile{
+		Name:          "node",
+		Port:          9100,
+		MetricPath:    "/node/metrics",
+		ScrapeTime:    60,
+		ScrapeTimeout: 20,
+		Labels:        map[string]string{"exporter": "node", "env": "prod"},
+		Auth:          &target.TargetAuth{BearerToken: "token"},
+	})
+	require.NoError(t, err)
+
+	// Act
+	err = CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1", Profile: "node"},
+			{Address: "10.0.0.2:9200", Profile: "node", ScrapeTime: 15, Labels: map[string]string{"env": "dev"}},
+		},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	labels := sdByInstance(t)
+	require.Len(t, labels, 2)
+	assert.Equal(t, "60s", labels["10.0.0.1:9100"]["__scrape_interval__"])
+	assert.Equal(t, "prod", labels["10.0.0.1:9100"]["env"])
+	assert.Equal(t, "15s", labels["10.0.0.2:9200"]["__scrape_interval__"])
+	assert.Equal(t, "15s", labels["10.0.0.2:9200"]["__scrape_timeout__"])
+	assert.Equal(t, "dev", labels["10.0.0.2:9200"]["env"])
+	assert.Equal(t, "node", labels["10.0.0.2:9200"]["exporter"])
+
+	var targets []Target
+	require.NoError(t, DB.Find(&targets).Error)
+	for _, found := range targets {
+		assert.Equal(t, "/node/metrics", found.MetricPath)
+		assert.NotZero(t, found.CredentialID)
+		assert.NotZero(t, found.ProfileID)
+	}
+}
+
+// TestCreateTargets_UnknownProfile 测试引用不存在的 profile 时创建失败
+func TestCreateTargets_UnknownProfile(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+
+	// Act
+	err := CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1", Profile: "missing"}},
+	})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrProfileNotFound)
+}
+
+// TestUpdateProfile_Propagate 测试同步修改由 profile 创建的 target，target 中单独设置过的字段保持不变
+func TestUpdateProfile_Propagate(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	request := &profile.Profile{Name: "node", Port: 9100, Labels: map[string]string{"exporter": "node", "tier": "base"}}
+	_, err := CreateProfile(request)
+	require.NoError(t, err)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1", Profile: "node"},
+			{Address: "10.0.0.2", Profile: "node", ScrapeTime: 15, Labels: map[string]string{"tier": "edge"}},
+			{Address: "10.0.0.3:9100"},
+		},
+	}))
+	request.Port = 9101
+	request.ScrapeTime = 60
+	request.Labels = map[string]string{"exporter": "node-exporter", "tier": "core", "team": "infra"}
+
+	// Act
+	info, err := UpdateProfile("node", request, true)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), info.Targets)
+	assert.Equal(t, int64(2), info.Changed)
+	labels := sdByInstance(t)
+	require.Len(t, labels, 3)
+	assert.Equal(t, "60s", labels["10.0.0.1:9101"]["__scrape_interval__"])
+	assert.Equal(t, "core", labels["10.0.0.1:9101"]["tier"])
+	assert.Equal(t, "node-exporter", labels["10.0.0.1:9101"]["exporter"])
+	assert.Equal(t, "infra", labels["10.0.0.1:9101"]["team"])
+	assert.Equal(t, "15s", labels["10.0.0.2:9101"]["__scrape_interval__"])
+	assert.Equal(t, "edge", labels["10.0.0.2:9101"]["tier"])
+	assert.Equal(t, "node-exporter", labels["10.0.0.2:9101"]["exporter"])
+	assert.Equal(t, "30s", labels["10.0.0.3:9100"]["__scrape_interval__"])
+}
+
+// TestUpdateProfile_WithoutPropagate 测试不同步时已创建的 target 保持不变，仍被引用的 profile 不能删除
+func TestUpdateProfile_WithoutPropagate(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	request := &profile.Profile{Name: "redis", Port: 9121}
+	_, err := CreateProfile(request)
+	require.NoError(t, err)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1", Profile: "redis"}},
+	}))
+	request.ScrapeTime = 60
+
+	// Act
+	info, err := UpdateProfile("redis", request, false)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), info.Targets, "targets still reference the profile")
+	assert.Equal(t, int64(0), info.Changed)
+	assert.Equal(t, "30s", sdByInstance(t)["10.0.0.1:9121"]["__scrape_interval__"])
+	assert.ErrorIs(t, DeleteProfile("redis"), ErrProfileInUse)
+	_, err = CreateProfile(&profile.Profile{Name: "redis"})
+	assert.ErrorIs(t, err, ErrProfileExists)
+}
@@ -0,0 +1,261 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/plugin/soft_delete"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	apirelabel "github.com/cylonchau/pantheon/pkg/api/relabel"
+	"github.com/cylonchau/pantheon/pkg/relabel"
+)
+
+const relabelRuleTableName = "relabel_rules"
+
+var ErrRelabelRuleNotFound = errors.New("relabel rule not found")
+
+// RelabelRule 保存在服务端的 relabel 规则，SD 返回 selector 下的 target 之前应用
+type RelabelRule struct {
+	ID            uint                  `gorm:"primarykey"`
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	Name          string                `gorm:"index;type:varchar(255)"`
+	SelectorKey   string                `gorm:"index:idx_relabel_rules_selector;type:varchar(255)"`
+	SelectorValue string                `gorm:"index:idx_relabel_rules_selector;type:varchar(255)"`
+	Position      int                   `gorm:"type:int"`
+	SourceLabels  []string              `gorm:"serializer:json;type:text"`
+	Separator     string                `gorm:"type:varchar(16)"`
+	Regex         string                `gorm:"type:text"`
+	TargetLabel   string                `gorm:"type:varchar(255)"`
+	Replacement   *string               `gorm:"type:text"` // 为空时使用默认值 $1
+	Action        string                `gorm:"type:varchar(16)"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (*RelabelRule) TableName() string {
+	return relabelRuleTableName
+}
+
+// Compile 转换为 relabel 规则并编译正则
+func (r *RelabelRule) Compile() (*relabel.Rule, error) {
+	replacement := relabel.DefaultReplacement
+	if r.Replacement != nil {
+		replacement = *r.Replacement
+	}
+	rule, err := relabel.Compile(relabel.Config{
+		SourceLabels: r.SourceLabels,
+		Separator:    r.Separator,
+		Regex:        r.Regex,
+		TargetLabel:  r.TargetLabel,
+		Replacement:  replacement,
+		Action:       relabel.Action(r.Action),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("relabel rule %s: %w", r.Name, err)
+	}
+	return rule, nil
+}
+
+func (r *RelabelRule) info() apirelabel.RelabelRuleInfo {
+	return apirelabel.RelabelRuleInfo{
+		ID: r.ID,
+		RelabelRule: apirelabel.RelabelRule{
+			Name:         r.Name,
+			Selector:     r.SelectorKey + "=" + r.SelectorValue,
+			Position:     r.Position,
+			SourceLabels: r.SourceLabels,
+			Separator:    r.Separator,
+			Regex:        r.Regex,
+			TargetLabel:  r.TargetLabel,
+			Replacement:  r.Replacement,
+			Action:       r.Action,
+		},
+		CreatedAt: r.CreatedAt,
+		UpdatedAt: r.UpdatedAt,
+	}
+}
+
+// newRelabelRule 校验请求并转换为规则，selector 的格式为 key=value
+func newRelabelRule(request *apirelabel.RelabelRule) (*RelabelRule, error) {
+	key, value, found := strings.Cut(request.Selector, "=")
+	if !found || key == "" || value == "" {
+		return nil, fmt.Errorf("invalid selector %q, expected key=value", request.Selector)
+	}
+	rule := &RelabelRule{
+		Name:          request.Name,
+		SelectorKey:   key,
+		SelectorValue: value,
+		Position:      request.Position,
+		SourceLabels:  request.SourceLabels,
+		Separator:     request.Separator,
+		Regex:         request.Regex,
+		TargetLabel:   request.TargetLabel,
+		Replacement:   request.Replacement,
+		Action:        request.Action,
+	}
+	if rule.Action == "" {
+		rule.Action = string(relabel.Replace)
+	}
+	if _, err := rule.Compile(); err != nil {
+		return nil, err
+	}
+	return rule, nil
+}
+
+// CreateRelabelRule 创建 relabel 规则
+func CreateRelabelRule(request *apirelabel.RelabelRule) (info apirelabel.RelabelRuleInfo, encounterError error) {
+	if request.Name == "" {
+		return info, fmt.Errorf("relabel rule name is required")
+	}
+	var rule *RelabelRule
+	if rule, encounterError = newRelabelRule(request); encounterError != nil {
+		return
+	}
+	if encounterError = DB.Create(rule).Error; encounterError != nil {
+		return
+	}
+	return rule.info(), nil
+}
+
+// ListRelabelRules 查询 relabel 规则，selector 不为空时只返回该 selector 的规则，按应用顺序排列
+func ListRelabelRules(selector string) (results []apirelabel.RelabelRuleInfo, encounterError error) {
+	results = make([]apirelabel.RelabelRuleInfo, 0)
+	tx := DB.Order("selector_key").Order("selector_value").Order("position").Order("id")
+	if selector != "" {
+		key, value, _ := strings.Cut(selector, "=")
+		tx = tx.Where("selector_key = ? AND selector_value = ?", key, value)
+	}
+	var rules []RelabelRule
+	if encounterError = tx.Find(&rules).Error; encounterError != nil {
+		return
+	}
+	for i := range rules {
+		results = append(results, rules[i].info())
+	}
+	return results, nil
+}
+
+func getRelabelRule(id uint) (*RelabelRule, error) {
+	found := &RelabelRule{}
+	result := DB.Where("id = ?", id).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrRelabelRuleNotFound
+	}
+	return found, nil
+}
+
+// GetRelabelRule 根据 ID 查询 relabel 规则
+func GetRelabelRule(id uint) (info apirelabel.RelabelRuleInfo, encounterError error) {
+	var rule *RelabelRule
+	if rule, encounterError = getRelabelRule(id); encounterError != nil {
+		return
+	}
+	return rule.info(), nil
+}
+
+// UpdateRelabelRule 使用请求的内容替换 relabel 规则
+func UpdateRelabelRule(id uint, request *apirelabel.RelabelRule) (info apirelabel.RelabelRuleInfo, encounterError error) {
+	var existing, rule *RelabelRule
+	if existing, encounterError = getRelabelRule(id); encounterError != nil {
+		return
+	}
+	if request.Name == "" {
+		request.Name = existing.Name
+	}
+	if rule, encounterError = newRelabelRule(request); encounterError != nil {
+		return
+	}
+	rule.ID, rule.CreatedAt = existing.ID, existing.CreatedAt
+	if encounterError = DB.Save(rule).Error; encounterError != nil {
+		return
+	}
+	return rule.info(), nil
+}
+
+// DeleteRelabelRule 删除 relabel 规则
+func DeleteRelabelRule(id uint) error {
+	result := DB.Where("id = ?", id).Delete(&RelabelRule{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrRelabelRuleNotFound
+	}
+	return nil
+}
+
+// relabelRulesFor 查询并编译 selector 的 relabel 规则
+func relabelRulesFor(key, value string) ([]*relabel.Rule, error) {
+	var rules []RelabelRule
+	if err := DB.Where("selector_key = ? AND selector_value = ?", key, value).
+		Order("position").Order("id").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	compiled := make([]*relabel.Rule, 0, len(rules))
+	for i := range rules {
+		rule, err := rules[i].Compile()
+		if err != nil {
+			return nil, err
+		}
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// relabelTargets 对 SD 结果应用规则，__address__ 为 target 的地址，被丢弃或地址为空的 target 不返回
+func relabelTargets(results []TargetList, rules ...*relabel.Rule) []TargetList {
+	if len(rules) == 0 {
+		return results
+	}
+	relabeled := make([]TargetList, 0, len(results))
+	for _, result := range results {
+		labels := make(map[string]string, len(result.Labels)+1)
+		for name, value := range result.Labels {
+			labels[name] = value
+		}
+		if len(result.Targets) > 0 {
+			labels["__address__"] = result.Targets[0]
+		}
+		processed, kept := relabel.Process(labels, rules...)
+		if !kept || processed["__address__"] == "" {
+			continue
+		}
+		address := processed["__address__"]
+		delete(processed, "__address__")
+		relabeled = append(relabeled, TargetList{Targets: []string{address}, Labels: processed})
+	}
+	return relabeled
+}
+
+// TestRelabelRule 展示规则对 selector 当前 target 的效果，Before 为应用已保存的规则后的 SD 输出
+func TestRelabelRule(request *apirelabel.RelabelRule) (results []apirelabel.TestResult, encounterError error) {
+	rule, encounterError := newRelabelRule(request)
+	if encounterError != nil {
+		return
+	}
+	compiled, encounterError := rule.Compile()
+	if encounterError != nil {
+		return
+	}
+	current, encounterError := ListTargetWithSelector(&query.QueryWithLabel{Key: rule.SelectorKey, Value: rule.SelectorValue}, nil)
+	if encounterError != nil {
+		return
+	}
+	results = make([]apirelabel.TestResult, 0, len(current))
+	for _, before := range current {
+		result := apirelabel.TestResult{Before: apirelabel.SDTarget{Targets: before.Targets, Labels: before.Labels}}
+		if after := relabelTargets([]TargetList{before}, compiled); len(after) > 0 {
+			result.After = &apirelabel.SDTarget{Targets: after[0].Targets, Labels: after[0].Labels}
+		} else {
+			result.Dropped = true
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
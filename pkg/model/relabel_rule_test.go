@@ -0,0 +1,105 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/relabel"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+func createRelabelTestTargets(t *testing.T) {
+	t.Helper()
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1:9100", Labels: map[string]string{"env": "production", "team": "infra"}},
+			{Address: "10.0.0.2:9100", Labels: map[string]string{"env": "prod", "team": "legacy"}},
+		},
+	}))
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "edge"},
+		Targets:          []target.TargetItem{{Address: "10.0.1.1:9100", Labels: map[string]string{"env": "production"}}},
+	}))
+}
+
+// TestListTargetWithSelector_RelabelRules 测试 selector 的规则按 position 顺序作用于 SD 输出，不影响其它 selector
+func TestListTargetWithSelector_RelabelRules(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	createRelabelTestTargets(t)
+	prod := "prod"
+	_, err := CreateRelabelRule(&relabel.RelabelRule{Name: "env", Selector: "prom=fed", Position: 1,
+		SourceLabels: []string{"env"}, Regex: "prod.*", TargetLabel: "env", Replacement: &prod})
+	require.NoError(t, err)
+	_, err = CreateRelabelRule(&relabel.RelabelRule{Name: "legacy", Selector: "prom=fed", Position: 2,
+		SourceLabels: []string{"team"}, Regex: "legacy", Action: "drop"})
+	require.NoError(t, err)
+	_, err = CreateRelabelRule(&relabel.RelabelRule{Name: "host", Selector: "prom=fed",
+		SourceLabels: []string{"__address__"}, Regex: `([^:]+):\d+`, TargetLabel: "host"})
+	require.NoError(t, err)
+
+	// Act
+	fed, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "prom", Value: "fed"}, nil)
+	require.NoError(t, err)
+	edge, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "prom", Value: "edge"}, nil)
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, fed, 1)
+	assert.Equal(t, []string{"10.0.0.1:9100"}, fed[0].Targets)
+	assert.Equal(t, "prod", fed[0].Labels["env"])
+	assert.Equal(t, "10.0.0.1", fed[0].Labels["host"])
+	assert.NotContains(t, fed[0].Labels, "__address__")
+	require.Len(t, edge, 1)
+	assert.Equal(t, "production", edge[0].Labels["env"])
+}
+
+// TestTestRelabelRule 测试预览规则的效果，规则不会被保存
+func TestTestRelabelRule(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	createRelabelTestTargets(t)
+
+	// Act
+	results, err := TestRelabelRule(&relabel.RelabelRule{Selector: "prom=fed", SourceLabels: []string{"team"}, Regex: "legacy", Action: "drop"})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	dropped := make(map[string]bool)
+	for _, result := range results {
+		dropped[result.Before.Labels["instance"]] = result.Dropped
+		if !result.Dropped {
+			assert.Equal(t, result.Before, *result.After)
+		}
+	}
+	assert.Equal(t, map[string]bool{"10.0.0.1:9100": false, "10.0.0.2:9100": true}, dropped)
+	rules, err := ListRelabelRules("")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+}
+
+// TestCreateRelabelRule_Invalid 测试无效的规则被拒绝
+func TestCreateRelabelRule_Invalid(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+
+	for name, request := range map[string]*relabel.RelabelRule{
+		"selector": {Name: "a", Selector: "prom", TargetLabel: "a"},
+		"regex":    {Name: "a", Selector: "prom=fed", TargetLabel: "a", Regex: "("},
+		"action":   {Name: "a", Selector: "prom=fed", Action: "hashmod"},
+		"name":     {Selector: "prom=fed", TargetLabel: "a"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			_, err := CreateRelabelRule(request)
+
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package model
 
+import (
+	"gorm.io/gorm"
+
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+	"github.com/cylonchau/pantheon/pkg/validation"
+)
+
 var selector_table_name = "selectors"
 
 type Selector struct {
@@ -20,10 +27,14 @@ func (*SelectorList) TableName() string {
 
 // CreateSelectors 创建 Selector
 func CreateSelectors(selectors map[string]string) ([]Selector, error) {
+	return createSelectors(DB, selectors)
+}
+
+func createSelectors(tx *gorm.DB, selectors map[string]string) ([]Selector, error) {
 	var createdSelectors []Selector
 	for key, value := range selectors {
 		var selector Selector
-		result := DB.Where(Label{Key: key, Value: value}).FirstOrCreate(&selector)
+		result := tx.Where(Label{Key: key, Value: value}).FirstOrCreate(&selector)
 		if result.Error != nil {
 			return nil, result.Error
 		}
@@ -42,6 +53,11 @@ func ListSelector() (selectors []SelectorList, encounterError error) {
 
 // UpdateSelectorByKeyValue 更新指定 Key 和 Value 的 Selector
 func UpdateSelectorByKeyValue(oldKey, oldValue, newKey, newValue string) (encounterError error) {
+	v := validation.New(validationConfig())
+	v.Selectors("selector", map[string]string{newKey: newValue})
+	if encounterError = v.Err(); encounterError != nil {
+		return
+	}
 	var selector Selector
 
 	// 查找现有的 Selector
@@ -49,8 +65,31 @@ func UpdateSelectorByKeyValue(oldKey, oldValue, newKey, newValue string) (encoun
 		// 更新 Key 和 Value
 		selector.Key = newKey
 		selector.Value = newValue
-		// 保存更新
-		encounterError = DB.Save(&selector).Error
+		// 保存更新，selector 属于 target 的唯一标识，同时更新关联的 target
+		encounterError = DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Save(&selector).Error; err != nil {
+				return err
+			}
+			var ids []uint
+			if err := tx.Table("target_selectors").Where("selector_id = ?", selector.ID).Pluck("target_id", &ids).Error; err != nil {
+				return err
+			}
+			if err := refreshTargetIdentities(tx, ids...); err != nil {
+				return err
+			}
+			if err := touchTargets(tx, ids...); err != nil {
+				return err
+			}
+			// 订阅了旧 selector 或新 selector 的 webhook 都会收到通知
+			return enqueueWebhookEvent(tx, webhook.Event{
+				Type: webhook.EventSelectorUpdated,
+				Selector: &webhook.SelectorEvent{
+					Old:     oldKey + "=" + oldValue,
+					New:     newKey + "=" + newValue,
+					Targets: ids,
+				},
+			}, map[string]string{oldKey: oldValue}, map[string]string{newKey: newValue})
+		})
 	}
 	return encounterError
 }
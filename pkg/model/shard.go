@@ -0,0 +1,68 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+)
+
+// targetIdentity 返回 target 的身份标识，由 schema、address、path 与 params 组成
+func targetIdentity(schema, address, metricPath string, params map[string]string) string {
+	return fmt.Sprintf("%s://%s%s?%s", schema, address, metricPath, mapToURLParams(params))
+}
+
+// ValidateShard 校验分片参数
+func ValidateShard(shard *query.QueryWithShard) error {
+	if shard == nil || shard.Shards == 0 {
+		return nil
+	}
+	if shard.Shards < 0 {
+		return fmt.Errorf("invalid shards <%d>; must be greater than 0", shard.Shards)
+	}
+	if shard.Shard < 0 || shard.Shard >= shard.Shards {
+		return fmt.Errorf("invalid shard <%d>; must be between 0 and %d", shard.Shard, shard.Shards-1)
+	}
+	if shard.Replicas < 1 || shard.Replicas > shard.Shards {
+		return fmt.Errorf("invalid replicas <%d>; must be between 1 and %d", shard.Replicas, shard.Shards)
+	}
+	return nil
+}
+
+// inShard 判断 target 是否属于指定分片
+// 主分片使用 jump consistent hash 计算，分片数变化时只有最少的 target 会迁移；
+// 副本依次落在主分片之后的分片上，用于 HA 场景下的 Prometheus 对
+func inShard(identity string, shard *query.QueryWithShard) bool {
+	if shard == nil || shard.Shards <= 1 {
+		return true
+	}
+	replicas := shard.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+	primary := jumpHash(shardKey(identity), shard.Shards)
+	for i := 0; i < replicas; i++ {
+		if (primary+i)%shard.Shards == shard.Shard {
+			return true
+		}
+	}
+	return false
+}
+
+// shardKey 将 target 身份标识转换为稳定的 64 位哈希值
+func shardKey(identity string) uint64 {
+	sum := sha256.Sum256([]byte(identity))
+	return binary.BigEndian.Uint64(sum[:8])
+}
+
+// jumpHash 实现 Lamping & Veach 的 jump consistent hash
+func jumpHash(key uint64, buckets int) int {
+	var b, j int64 = -1, 0
+	for j < int64(buckets) {
+		b = j
+		key = key*2862933555777941757 + 1
+		j = int64(float64(b+1) * (float64(int64(1)<<31) / float64((key>>33)+1)))
+	}
+	return int(b)
+}
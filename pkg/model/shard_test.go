@@ -0,0 +1,147 @@
+package model
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+// TestInShard_Disjoint 测试不带副本时每个 target 只属于一个分片
+func TestInShard_Disjoint(t *testing.T) {
+	// Arrange
+	shards := 4
+	identities := make([]string, 0, 1000)
+	for i := 0; i < 1000; i++ {
+		identities = append(identities, targetIdentity("http", fmt.Sprintf("10.0.%d.%d:9100", i/256, i%256), "/metrics", nil))
+	}
+
+	// Act & Assert
+	counts := make([]int, shards)
+	for _, identity := range identities {
+		owners := 0
+		for shard := 0; shard < shards; shard++ {
+			if inShard(identity, &query.QueryWithShard{Shard: shard, Shards: shards, Replicas: 1}) {
+				owners++
+				counts[shard]++
+			}
+		}
+		assert.Equal(t, 1, owners, "target %s should belong to exactly one shard", identity)
+	}
+	for shard, count := range counts {
+		assert.NotZero(t, count, "shard %d should not be empty", shard)
+	}
+}
+
+// TestInShard_Replicas 测试副本因子使每个 target 落在多个分片上
+func TestInShard_Replicas(t *testing.T) {
+	// Arrange
+	shards := 3
+	identity := targetIdentity("https", "exporter.example.com:443", "/metrics", map[string]string{"module": "http_2xx"})
+
+	// Act
+	owners := 0
+	for shard := 0; shard < shards; shard++ {
+		if inShard(identity, &query.QueryWithShard{Shard: shard, Shards: shards, Replicas: 2}) {
+			owners++
+		}
+	}
+
+	// Assert
+	assert.Equal(t, 2, owners, "target should be scraped by exactly 2 shards")
+}
+
+// shardMembership 查询每个分片的 SD 输出，返回每个 target 所在的分片
+func shardMembership(t *testing.T, shards, replicas int) map[string][]int {
+	t.Helper()
+	membership := make(map[string][]int)
+	for shard := 0; shard < shards; shard++ {
+		results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "prom", Value: "fed"}, &query.QueryWithShard{Shard: shard, Shards: shards, Replicas: replicas})
+		require.NoError(t, err)
+		for _, result := range results {
+			instance := result.Labels["instance"]
+			membership[instance] = append(membership[instance], shard)
+		}
+	}
+	return membership
+}
+
+// createShardTargets 在 prom=fed 下创建 count 个 target
+func createShardTargets(t *testing.T, from, count int) {
+	t.Helper()
+	items := make([]target.TargetItem, 0, count)
+	for i := from; i < from+count; i++ {
+		items = append(items, target.TargetItem{Address: fmt.Sprintf("10.0.%d.%d:9100", i/256, i%256)})
+	}
+	require.NoError(t, CreateTargets(&target.Target{InstanceSelector: map[string]string{"prom": "fed"}, Targets: items}))
+}
+
+// TestListTargetWithSelector_ShardAssignmentStable 测试新增 target 不改变已有 target 的分片，
+// 副本落在主分片之后的连续分片上，分片数增加时 target 只会迁移到新增的分片
+func TestListTargetWithSelector_ShardAssignmentStable(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	createShardTargets(t, 0, 200)
+	before := shardMembership(t, 4, 2)
+	primaries := shardMembership(t, 4, 1)
+
+	// Act
+	createShardTargets(t, 200, 100)
+	after := shardMembership(t, 4, 2)
+	grown := shardMembership(t, 5, 1)
+
+	// Assert
+	require.Len(t, before, 200)
+	require.Len(t, after, 300)
+	for instance, shards := range before {
+		assert.Equal(t, shards, after[instance], "target %s changed shards after other targets were added", instance)
+	}
+	for instance, shards := range after {
+		require.Len(t, shards, 2, "target %s should be scraped by exactly 2 shards", instance)
+		primary := primaries[instance]
+		if primary == nil {
+			continue
+		}
+		require.Len(t, primary, 1)
+		assert.ElementsMatch(t, []int{primary[0], (primary[0] + 1) % 4}, shards, "replica of %s should follow its primary shard", instance)
+	}
+	moved := 0
+	for instance, primary := range primaries {
+		if grown[instance][0] != primary[0] {
+			assert.Equal(t, 4, grown[instance][0], "target %s may only move to the new shard", instance)
+			moved++
+		}
+	}
+	// jump hash 期望迁移 1/5 的 target
+	assert.InDelta(t, 40, moved, 25)
+}
+
+// TestValidateShard 测试分片参数校验
+func TestValidateShard(t *testing.T) {
+	tests := []struct {
+		name    string
+		shard   *query.QueryWithShard
+		wantErr bool
+	}{
+		{name: "Disabled", shard: &query.QueryWithShard{}, wantErr: false},
+		{name: "Valid", shard: &query.QueryWithShard{Shard: 2, Shards: 3, Replicas: 1}, wantErr: false},
+		{name: "Shard out of range", shard: &query.QueryWithShard{Shard: 3, Shards: 3, Replicas: 1}, wantErr: true},
+		{name: "Negative shards", shard: &query.QueryWithShard{Shard: 0, Shards: -1, Replicas: 1}, wantErr: true},
+		{name: "Too many replicas", shard: &query.QueryWithShard{Shard: 0, Shards: 2, Replicas: 3}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateShard(tt.shard)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
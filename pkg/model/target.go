@@ -1,48 +1,132 @@
 package model
 
 import (
-	"crypto/md5"
-	"encoding/base64"
-	"encoding/hex"
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/plugin/soft_delete"
 	"k8s.io/klog/v2"
 
+	"github.com/cylonchau/pantheon/pkg/api/credential"
 	"github.com/cylonchau/pantheon/pkg/api/query"
 	"github.com/cylonchau/pantheon/pkg/api/target"
-	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/validation"
 )
 
 const targetTableName = "targets"
 
+var ErrTargetNotFound = errors.New("target not found")
+
+// ErrTargetAmbiguous 多个 target 匹配代理请求的地址，无法确定使用哪一个 target 的凭据与 TLS 设置
+var ErrTargetAmbiguous = errors.New("target is ambiguous")
+
+// ErrTargetVersionConflict 请求中的 resource_version 与 target 当前的版本不一致
+var ErrTargetVersionConflict = errors.New("target has been modified by someone else, re-fetch it and retry")
+
 type Target struct {
-	ID            uint                  `gorm:"primarykey"`
-	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`
-	Address       string                `gorm:"index;type:varchar(255)"`
-	Schema        string                `gorm:"type:char(5)"`
-	MetricPath    string                `gorm:"index;type:varchar(255)"`
-	ScrapeTime    int                   `gorm:"index;type:int"`
-	ScrapeTimeout int                   `gorm:"index;type:int"`
-	BearerToken   string                `gorm:"index;type:varchar(255)"`
-	BaseAuth      string                `gorm:"index;type:varchar(255)"`
-	Labels        []Label               `gorm:"many2many:target_labels;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	Params        []Param               `gorm:"many2many:target_params;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
-	Selectors     []Selector            `gorm:"many2many:target_selectors;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	ID              uint                  `gorm:"primarykey"`
+	IsDel           soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	Address         string                `gorm:"index;type:varchar(255)"`
+	Schema          string                `gorm:"type:char(5)"`
+	MetricPath      string                `gorm:"index;type:varchar(255)"`
+	ScrapeTime      int                   `gorm:"index;type:int"`
+	ScrapeTimeout   int                   `gorm:"index;type:int"`
+	BearerToken     EncryptedString       `gorm:"type:text"` // 遗留字段，升级时迁移到 credentials 表
+	BaseAuth        EncryptedString       `gorm:"type:text"` // 遗留字段，升级时迁移到 credentials 表
+	CredentialID    uint                  `gorm:"index"`
+	TLS             TargetTLS             `gorm:"embedded;embeddedPrefix:tls_"`
+	Headers         EncryptedHeaders      `gorm:"type:text"`               // 代理抓取时添加的请求头
+	HasHeaders      bool                  `gorm:"->;-:migration"`          // SD 查询时计算，避免解密请求头
+	Zone            string                `gorm:"index;type:varchar(255)"` // 所在的网络区域，区域内的 target 经由区域的代理抓取
+	ExporterID      uint                  `gorm:"index"`                   // 探测 target 引用的 blackbox exporter
+	ProbeModule     string                `gorm:"type:varchar(255)"`
+	ProbeTarget     string                `gorm:"type:varchar(2048)"`
+	ProfileID       uint                  `gorm:"index"`                     // 创建时引用的 profile，更新 profile 时可同步修改
+	Identity        *string               `gorm:"uniqueIndex;type:char(64)"` // schema、地址、路径、params 与 selector 的摘要，删除后置空
+	ResourceVersion uint64                `gorm:"not null;default:1"`        // 每次修改递增，修改与删除时用于检测并发冲突
+	Labels          []Label               `gorm:"many2many:target_labels;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Params          []Param               `gorm:"many2many:target_params;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+	Selectors       []Selector            `gorm:"many2many:target_selectors;constraint:OnUpdate:CASCADE,OnDelete:CASCADE;"`
+}
+
+// TargetTLS 抓取 target 时使用的 TLS 配置，以 tls_ 前缀保存在 targets 表中
+type TargetTLS struct {
+	CAFile             string `gorm:"type:varchar(255)" json:"ca_file,omitempty"`
+	CertFile           string `gorm:"type:varchar(255)" json:"cert_file,omitempty"`
+	KeyFile            string `gorm:"type:varchar(255)" json:"key_file,omitempty"`
+	ServerName         string `gorm:"type:varchar(255)" json:"server_name,omitempty"`
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+}
+
+func newTargetTLS(tlsConfig *target.TargetTLS) (TargetTLS, error) {
+	if tlsConfig == nil {
+		return TargetTLS{}, nil
+	}
+	if (tlsConfig.CertFile == "") != (tlsConfig.KeyFile == "") {
+		return TargetTLS{}, fmt.Errorf("tls cert_file and key_file must be set together")
+	}
+	return TargetTLS{
+		CAFile:             tlsConfig.CAFile,
+		CertFile:           tlsConfig.CertFile,
+		KeyFile:            tlsConfig.KeyFile,
+		ServerName:         tlsConfig.ServerName,
+		InsecureSkipVerify: tlsConfig.InsecureSkipVerify,
+	}, nil
+}
+
+// IsEmpty 未配置 TLS 时 target 由 Prometheus 直接抓取
+func (t TargetTLS) IsEmpty() bool {
+	return t == TargetTLS{}
+}
+
+// API 转换为 API 类型，未配置时返回 nil
+func (t TargetTLS) API() *target.TargetTLS {
+	if t.IsEmpty() {
+		return nil
+	}
+	return &target.TargetTLS{
+		CAFile:             t.CAFile,
+		CertFile:           t.CertFile,
+		KeyFile:            t.KeyFile,
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
 }
 
+// columns 用于整体替换 TLS 配置，包括清空
+func (t TargetTLS) columns() map[string]interface{} {
+	return map[string]interface{}{
+		"tls_ca_file":              t.CAFile,
+		"tls_cert_file":            t.CertFile,
+		"tls_key_file":             t.KeyFile,
+		"tls_server_name":          t.ServerName,
+		"tls_insecure_skip_verify": t.InsecureSkipVerify,
+	}
+}
+
+const targetTLSColumns = "targets.tls_ca_file, targets.tls_cert_file, targets.tls_key_file, targets.tls_server_name, targets.tls_insecure_skip_verify"
+
 type TargetRaw struct {
-	Address       string `gorm:"index;type:varchar(255)" json:"address"`
-	Schema        string `gorm:"type:char(5)" json:"schema"`
-	MetricPath    string `gorm:"index;type:varchar(255)" json:"metric_path"`
-	ScrapeTime    int    `gorm:"index;type:int" json:"scrape_time"`
-	ScrapeTimeout int    `gorm:"index;type:int" json:"scrape_timeout"`
-	BearerToken   string `gorm:"index;type:varchar(255)" json:"bearer_token"`
-	BaseAuth      string `gorm:"index;type:varchar(255)" json:"base_auth"`
+	Address         string `gorm:"index;type:varchar(255)" json:"address"`
+	Schema          string `gorm:"type:char(5)" json:"schema"`
+	MetricPath      string `gorm:"index;type:varchar(255)" json:"metric_path"`
+	ScrapeTime      int    `gorm:"index;type:int" json:"scrape_time"`
+	ScrapeTimeout   int    `gorm:"index;type:int" json:"scrape_timeout"`
+	CredentialID    uint   `gorm:"index" json:"credential_id,omitempty"`
+	ResourceVersion uint64 `json:"resource_version"`
+
+	TLS     TargetTLS          `gorm:"embedded;embeddedPrefix:tls_" json:"tls"`
+	Headers EncryptedHeaders   `gorm:"type:text" json:"headers,omitempty"`
+	Zone    string             `gorm:"type:varchar(255)" json:"zone,omitempty"`
+	Auth    *target.TargetAuth `gorm:"-" json:"auth,omitempty"`
 }
 
 type TargetList struct {
@@ -50,15 +134,6 @@ type TargetList struct {
 	Labels  map[string]string `json:"labels"`
 }
 
-type tempTargetList struct {
-	ID            uint   `gorm:"primarykey"`
-	Address       string `gorm:"index;type:varchar(255)"`
-	Schema        string `gorm:"type:char(5)"`
-	MetricPath    string `gorm:"index;type:varchar(255)"`
-	SelectorKey   string
-	SelectorValue string
-}
-
 type SwapResult struct {
 	TargetID      uint
 	Address       string
@@ -87,99 +162,129 @@ func (*TargetRaw) TableName() string {
 }
 
 func (t *Target) BeforeDelete(tx *gorm.DB) (err error) {
-
-	// 找到与此 Target 相关的所有 Params
-	var params []Param
-	if err := tx.Model(t).Association("Params").Find(&params); err != nil {
-		klog.V(4).Infof("Error fetching params: %v", err)
-		return err
-	}
-
-	for _, param := range params {
-		paramsCount := tx.Model(&Target{}). // 假设 Target 是关联的主模型
-							Joins("JOIN target_params ON target_params.param_id = ?").
-							Where("target_params.target_id != ?", param.ID, t.ID).
-							Find(nil)
-		if paramsCount.RowsAffected == 0 {
-			if err := tx.Delete(&param).Error; err != nil {
-				klog.V(4).Infof("Error deleting param: %v", err)
-				return err
-			}
+	// 删除前保存当前状态，之后可以通过回滚恢复；软删除后释放唯一标识，相同的 target 可以重新创建
+	if t.ID != 0 {
+		// DeleteTargetWithID 通过 context 传入期望的版本，版本号只在这里递增一次
+		expected, _ := tx.Statement.Context.Value(expectedVersionKey{}).(uint64)
+		if err := bumpResourceVersion(tx, t.ID, expected); err != nil {
+			return err
+		}
+		if err := recordTargetRevision(tx, t.ID, true); err != nil {
+			klog.V(4).Infof("Error recording revision: %v", err)
+			return err
+		}
+		if err := tx.Model(&Target{}).Where("id = ?", t.ID).UpdateColumn("identity", nil).Error; err != nil {
+			klog.V(4).Infof("Error releasing identity: %v", err)
+			return err
 		}
 	}
 
-	if err := tx.Model(t).Association("Params").Clear(); err != nil {
-		klog.V(4).Infof("Error cleaning params relation: %v", err)
-		return err
+	// 解除关联后删除其他 target 不再引用的 labels、params 与 selectors
+	for _, association := range targetAssociations {
+		var ids []uint
+		if err := tx.Table(association.joinTable).Where("target_id = ?", t.ID).Pluck(association.column, &ids).Error; err != nil {
+			klog.V(4).Infof("Error fetching %s: %v", association.name, err)
+			return err
+		}
+		if err := tx.Model(t).Association(association.name).Clear(); err != nil {
+			klog.V(4).Infof("Error cleaning %s relation: %v", association.name, err)
+			return err
+		}
+		if err := deleteUnreferenced(tx, association.joinTable, association.column, association.model, ids); err != nil {
+			klog.V(4).Infof("Error deleting %s: %v", association.name, err)
+			return err
+		}
 	}
+	return nil
+}
 
-	// 找到与此 Target 相关的所有 Labels
-	var labels []Label
-	if err := tx.Model(t).Association("Labels").Find(&labels); err != nil {
-		klog.V(4).Infof("Error fetching labels: %v", err)
-		return err
-	}
+// targetAssociations target 与 labels、params、selectors 的多对多关联
+var targetAssociations = []struct {
+	name      string
+	joinTable string
+	column    string
+	model     interface{}
+}{
+	{name: "Labels", joinTable: "target_labels", column: "label_id", model: &Label{}},
+	{name: "Params", joinTable: "target_params", column: "param_id", model: &Param{}},
+	{name: "Selectors", joinTable: "target_selectors", column: "selector_id", model: &Selector{}},
+}
 
-	for _, label := range labels {
-		labelsCount := tx.Model(&Target{}). // 假设 Target 是关联的主模型
-							Joins("JOIN target_labels ON target_labels.label_id = ?").
-							Where("target_labels.target_id != ?", label.ID, t.ID).
-							Find(nil)
-		if labelsCount.RowsAffected == 0 {
-			if err := tx.Delete(&label).Error; err != nil {
-				klog.V(4).Infof("Error deleting label: %v", err)
-				return err
-			}
-		}
+// deleteUnreferenced 删除 ids 中不再被任何 target 引用的记录
+func deleteUnreferenced(tx *gorm.DB, joinTable, column string, model interface{}, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
 	}
-
-	if err := tx.Model(t).Association("Labels").Clear(); err != nil {
-		klog.V(4).Infof("Error cleaning labels relation: %v", err)
+	var referenced []uint
+	if err := tx.Table(joinTable).Where(column+" IN ?", ids).Distinct(column).Pluck(column, &referenced).Error; err != nil {
 		return err
 	}
-
-	var selectors []Selector
-	if err := tx.Model(t).Association("Selectors").Find(&selectors); err != nil {
-		klog.V(4).Infof("Error fetching selectors: %v", err)
-		return err
+	inUse := make(map[uint]bool, len(referenced))
+	for _, id := range referenced {
+		inUse[id] = true
 	}
-	for _, selector := range selectors {
-		selectorCount := tx.Model(&Target{}). // 假设 Target 是关联的主模型
-							Joins("JOIN target_selectors ON target_selectors.selector_id = ?").
-							Where("target_selectors.target_id != ?", selector.ID, t.ID).
-							Find(nil)
-		if selectorCount.RowsAffected == 0 {
-			if err := tx.Delete(&selector).Error; err != nil {
-				klog.V(4).Infof("Error deleting selector: %v", err)
-				return err
-			}
+	var unreferenced []uint
+	for _, id := range ids {
+		if !inUse[id] {
+			unreferenced = append(unreferenced, id)
 		}
 	}
-	if err := tx.Model(t).Association("Selectors").Clear(); err != nil {
-		klog.V(4).Infof("Error cleaning selectors relation: %v", err)
-		return err
+	if len(unreferenced) == 0 {
+		return nil
 	}
+	return tx.Where("id IN ?", unreferenced).Delete(model).Error
+}
 
-	return nil
+// CreateTargets 创建或更新 target，参考 UpsertTargets
+func CreateTargets(target *target.Target) error {
+	_, encounterError := UpsertTargets(target)
+	return encounterError
 }
 
-func CreateTargets(target *target.Target) (encounterError error) {
+// UpsertTargets 按唯一标识创建 target，标识已存在时更新 labels 与抓取间隔，返回每个 target 的处理结果
+func UpsertTargets(request *target.Target) (results []target.UpsertResult, encounterError error) {
 	tx := DB.Begin()
 	defer func() {
 		if encounterError != nil {
 			tx.Rollback()
 		} else {
-			tx.Commit()
+			encounterError = tx.Commit().Error
 		}
 	}()
 
+	// 未设置的字段使用 profile 中的默认值，合并 profile 后再校验 labels、params 与 selectors
+	items := append(request.Targets[:0:0], request.Targets...)
+	profiles := make([]*Profile, len(items))
+	for i := range items {
+		if items[i].Profile != "" {
+			if profiles[i], encounterError = applyProfile(tx, &items[i]); encounterError != nil {
+				return nil, encounterError
+			}
+		}
+	}
+	if encounterError = validation.Target(validationConfig(), request.InstanceSelector, items); encounterError != nil {
+		return nil, encounterError
+	}
+
 	// 创建选择器
-	instanceSelectors, encounterError := CreateSelectors(target.InstanceSelector)
+	instanceSelectors, encounterError := createSelectors(tx, request.InstanceSelector)
 	if encounterError != nil {
-		return encounterError
+		return nil, encounterError
 	}
+	selectorString := mapToURLParams(request.InstanceSelector)
+
+	results = make([]target.UpsertResult, 0, len(items))
+	for i, targetItem := range items {
+		profile := profiles[i]
+
+		// 探测 target 的地址与路径取自 blackbox exporter
+		var exporter *BlackboxExporter
+		if targetItem.Probe != nil {
+			if exporter, encounterError = resolveProbe(tx, &targetItem); encounterError != nil {
+				return nil, encounterError
+			}
+		}
 
-	for _, targetItem := range target.Targets {
 		// 默认值处理
 		if targetItem.MetricPath == "" {
 			targetItem.MetricPath = "/metrics"
@@ -194,15 +299,46 @@ func CreateTargets(target *target.Target) (encounterError error) {
 		if targetItem.ScrapeTimeout > targetItem.ScrapeTime {
 			targetItem.ScrapeTimeout = targetItem.ScrapeTime
 		}
+		tlsConfig, err := newTargetTLS(targetItem.TLS)
+		if err != nil {
+			return nil, err
+		}
+		headers, err := newTargetHeaders(targetItem.Headers)
+		if err != nil {
+			return nil, err
+		}
+		if err = validateZone(targetItem.Zone); err != nil {
+			return nil, err
+		}
 		// 处理 Address 字段，分离 Schema 和 Address
 		var schema string
 		if strings.HasPrefix(targetItem.Address, "http://") || strings.HasPrefix(targetItem.Address, "https://") {
 			schema = strings.Split(targetItem.Address, "://")[0]
 			targetItem.Address = strings.Split(targetItem.Address, "://")[1]
+		} else if !tlsConfig.IsEmpty() {
+			schema = "https" // 配置了 TLS 时默认使用 https
 		} else {
 			schema = "http" // 默认值
 		}
 
+		identity := targetUID(schema, targetItem.Address, targetItem.MetricPath, mapToURLParams(targetItem.Params), selectorString)
+		result := target.UpsertResult{Address: schema + "://" + targetItem.Address + targetItem.MetricPath, Identity: identity}
+
+		// 标识已存在时只更新 labels 与抓取间隔
+		existing := &Target{}
+		found := tx.Preload("Labels").Where("identity = ?", identity).Limit(1).Find(existing)
+		if found.Error != nil {
+			return nil, found.Error
+		}
+		if found.RowsAffected > 0 {
+			result.ID = existing.ID
+			if result.Status, encounterError = updateExistingTarget(tx, existing, &targetItem); encounterError != nil {
+				return nil, encounterError
+			}
+			results = append(results, result)
+			continue
+		}
+
 		// 创建新的 Target 实例
 		newTarget := &Target{
 			Address:       targetItem.Address,
@@ -210,198 +346,146 @@ func CreateTargets(target *target.Target) (encounterError error) {
 			MetricPath:    targetItem.MetricPath,
 			ScrapeTime:    targetItem.ScrapeTime,
 			ScrapeTimeout: targetItem.ScrapeTimeout,
+			TLS:           tlsConfig,
+			Headers:       headers,
+			Zone:          targetItem.Zone,
+			Identity:      &identity,
+		}
+		if profile != nil {
+			newTarget.ProfileID = profile.ID
+		}
+		if exporter != nil {
+			newTarget.ExporterID = exporter.ID
+			newTarget.ProbeModule = targetItem.Probe.Module
+			newTarget.ProbeTarget = targetItem.Probe.Target
 		}
 
-		if targetItem.Auth != nil {
-			if targetItem.Auth.BearerToken != "" {
-				newTarget.BearerToken = targetItem.Auth.BearerToken
-			} else if targetItem.Auth.Base != "" {
-				newTarget.BaseAuth = targetItem.Auth.Base
-			}
+		// 认证信息统一保存到凭据表，target 只记录引用
+		if newTarget.CredentialID, encounterError = credentialIDForAuth(tx, targetItem.Auth); encounterError != nil {
+			return nil, encounterError
+		}
+		if profile != nil && isEmptyAuth(targetItem.Auth) {
+			newTarget.CredentialID = profile.CredentialID
 		}
 
-		// 动态构建 Selectors 查询条件
-		// selector 为全局条件，所以放置最上部
-		selectorConditions := DB
-		if len(target.InstanceSelector) > 0 {
-			for key, value := range target.InstanceSelector {
-				selectorConditions = selectorConditions.Or("selectors.key = ? AND selectors.value = ?", key, value)
+		if encounterError = tx.Create(newTarget).Error; encounterError != nil {
+			return nil, encounterError
+		}
+		if len(targetItem.Labels) > 0 {
+			var createdLabels []Label
+			if createdLabels, encounterError = createLabels(tx, targetItem.Labels); encounterError != nil {
+				return nil, encounterError
+			}
+			if encounterError = tx.Model(newTarget).Association("Labels").Append(createdLabels); encounterError != nil {
+				return nil, encounterError
 			}
 		}
-		var existTargets []tempTargetList
-		preQuery := DB.Table(targetTableName).
-			Select("targets.id as id, selectors.key as `selector_key`, selectors.value as `selector_value`, targets.address, targets.metric_path, targets.schema").
-			Joins("JOIN target_selectors ON target_selectors.target_id = targets.id").
-			Joins("JOIN selectors ON selectors.id = target_selectors.selector_id").
-			Where("targets.`is_del` = 0").
-			Where("targets.address = ? AND targets.metric_path = ? AND targets.scrape_time = ? AND targets.scrape_timeout = ?",
-				newTarget.Address, newTarget.MetricPath, newTarget.ScrapeTime, newTarget.ScrapeTimeout).
-			Where(selectorConditions)
-
-		encounterError = preQuery.Find(&existTargets).Error
-		if encounterError != nil {
-			if errors.Is(encounterError, gorm.ErrRecordNotFound) && len(existTargets) == 0 {
-				encounterError = DB.Model(&Target{}).Create(&newTarget).Error
-			} else {
-				return encounterError
+		if len(targetItem.Params) > 0 {
+			var createdParams []Param
+			if createdParams, encounterError = createParams(tx, targetItem.Params); encounterError != nil {
+				return nil, encounterError
 			}
-		} else {
-			isCreateTarget := true
-			for _, existTargetItem := range existTargets {
-				// 先获取相关的 params
-				// 用于查找已经存在的params
-				var judgeTargetsParamsRelation []swapMap
-				judgeParamQuery := DB.Table(targetTableName).
-					Select("targets.id as id, params.key as `key`, params.value as `value`").
-					Joins("JOIN target_selectors ON target_selectors.target_id = targets.id").
-					Joins("JOIN selectors ON selectors.id = target_selectors.selector_id").
-					Joins("JOIN target_params ON target_params.target_id = targets.id").
-					Joins("JOIN params ON params.id = target_params.param_id")
-
-				judgeParamQuery.Where("targets.id = ?", existTargetItem.ID)
-
-				// 拼接selector
-				if len(instanceSelectors) > 0 {
-					judgeParamQuery = judgeParamQuery.Where(selectorConditions)
-				}
-
-				// 获取这个target的所有params
-				if encounterError = judgeParamQuery.Scan(&judgeTargetsParamsRelation).Error; encounterError != nil {
-					return
-				}
-
-				// 拼接param
-				paramsMap := make(map[int]map[string]string)
-				for _, param := range judgeTargetsParamsRelation {
-					if paramsMap[param.ID] == nil {
-						paramsMap[param.ID] = make(map[string]string)
-					}
-					paramsMap[param.ID][param.Key] = param.Value
-				}
-
-				existJudgeParamsString := mapToURLParams(paramsMap[int(existTargetItem.ID)])
-				existTargetUniqueKey := hex.EncodeToString(md5.New().Sum([]byte(fmt.Sprintf("%s://%s%s?%s", existTargetItem.Schema, existTargetItem.Address, existTargetItem.MetricPath, existJudgeParamsString))))
-
-				newJudgeParamsString := mapToURLParams(targetItem.Params)
-				newTargetUniqueKey := hex.EncodeToString(md5.New().Sum([]byte(fmt.Sprintf("%s://%s%s?%s", schema, targetItem.Address, targetItem.MetricPath, newJudgeParamsString))))
-
-				if existTargetUniqueKey == newTargetUniqueKey {
-					isCreateTarget = false
-				}
+			if encounterError = tx.Model(newTarget).Association("Params").Append(createdParams); encounterError != nil {
+				return nil, encounterError
 			}
-			if isCreateTarget {
-				if encounterError = DB.Model(&Target{}).Create(&newTarget).Error; encounterError != nil {
-					return encounterError
-				}
+		}
+		// 关联 Selectors
+		if len(instanceSelectors) > 0 {
+			if encounterError = tx.Model(newTarget).Association("Selectors").Append(instanceSelectors); encounterError != nil {
+				return nil, encounterError
 			}
 		}
+		if encounterError = recordTargetRevision(tx, newTarget.ID, false); encounterError != nil {
+			return nil, encounterError
+		}
+		result.ID, result.Status = newTarget.ID, target.UpsertCreated
+		results = append(results, result)
+	}
+	return results, nil
+}
 
-		if newTarget.ID != 0 {
-
-			if len(targetItem.Labels) > 0 {
-				// 动态构建查询条件
-				queryLabels := DB.Table("targets").Select("*").
-					Joins("LEFT JOIN target_labels ON target_labels.target_id = targets.id").
-					Joins("LEFT JOIN labels ON labels.id = target_labels.label_id").
-					Joins("LEFT JOIN target_selectors ON target_selectors.target_id = targets.id").
-					Joins("LEFT JOIN selectors ON selectors.id = target_selectors.selector_id").
-					Where("targets.address = ? AND targets.metric_path = ? AND targets.scrape_time = ? AND targets.scrape_timeout = ?",
-						newTarget.Address, newTarget.MetricPath, newTarget.ScrapeTime, newTarget.ScrapeTimeout).
-					Where("targets.id = ?", newTarget.ID)
-
-				// 检查 Labels
-				if len(targetItem.Labels) > 0 {
-					labelConditions := DB
-					for key, value := range targetItem.Labels {
-						labelConditions = labelConditions.Or("labels.key = ? AND labels.value = ?", key, value)
-					}
-					queryLabels = queryLabels.Where(labelConditions)
-				}
-				// 动态关联selector条件
-				if len(target.InstanceSelector) > 0 {
-					queryLabels = queryLabels.Where(selectorConditions)
-				}
-
-				// 执行查询
-				// 执行查询并检查结果
-				var existingTargets []Target
-				// 如果找到目标，说明已存在，跳过插入
-				if encounterError = queryLabels.Find(&existingTargets).Error; encounterError == nil && len(existingTargets) != len(targetItem.Labels) {
-					// 关联 Labels
-					// 在这里调用 CreateLabels
-					var createdLabels []Label
-					if createdLabels, encounterError = CreateLabels(targetItem.Labels); encounterError == nil {
-						if encounterError = DB.Model(&newTarget).Association("Labels").Append(createdLabels); encounterError != nil {
-							return encounterError
-						}
-					}
-				}
-			}
+// updateExistingTarget 使用请求中的抓取间隔与 labels 替换已存在的 target，其余字段保持不变
+func updateExistingTarget(tx *gorm.DB, existing *Target, item *target.TargetItem) (string, error) {
+	updates := make(map[string]interface{})
+	if existing.ScrapeTime != item.ScrapeTime {
+		updates["scrape_time"] = item.ScrapeTime
+	}
+	if existing.ScrapeTimeout != item.ScrapeTimeout {
+		updates["scrape_timeout"] = item.ScrapeTimeout
+	}
+	if len(updates) > 0 {
+		if err := tx.Model(existing).Updates(updates).Error; err != nil {
+			return "", err
+		}
+	}
 
-			if len(targetItem.Params) > 0 {
-				// 动态构建查询条件
-				queryParams := DB.Table(targetTableName).Select("*").
-					Joins("LEFT JOIN target_params ON target_params.target_id = targets.id").
-					Joins("LEFT JOIN params ON params.id = target_params.param_id").
-					Joins("LEFT JOIN target_selectors ON target_selectors.target_id = targets.id").
-					Joins("LEFT JOIN selectors ON selectors.id = target_selectors.selector_id").
-					Where("targets.address = ? AND targets.metric_path = ? AND targets.scrape_time = ? AND targets.scrape_timeout = ?",
-						newTarget.Address, newTarget.MetricPath, newTarget.ScrapeTime, newTarget.ScrapeTimeout).
-					Where("targets.id = ?", newTarget.ID)
-
-				// 动态构建 Params 查询条件
-				paramConditions := DB
-				for key, value := range targetItem.Params {
-					paramConditions = paramConditions.Or("params.key = ? AND params.value = ?", key, value)
-				}
-				queryParams = queryParams.Where(paramConditions)
-				// 动态构建 Selectors 查询条件
-				if len(target.InstanceSelector) > 0 {
-					queryParams = queryParams.Where(selectorConditions)
-				}
-				// 执行查询
-				// 执行查询并检查结果
-				var existingTargets []Target
-				// 如果找到目标，说明已存在，跳过插入
-				if encounterError = queryParams.Find(&existingTargets).Error; encounterError == nil && len(existingTargets) != len(targetItem.Params) {
-					// 关联 Params
-					// 在这里调用 CreateLabels
-					var createdParams []Param
-					if createdParams, encounterError = CreateParams(targetItem.Params); encounterError == nil {
-						if encounterError = DB.Model(&newTarget).Association("Params").Append(createdParams); encounterError != nil {
-							return encounterError
-						}
-					}
-				}
-			}
-			// 关联 Selectors
-			if encounterError = DB.Model(&newTarget).Association("Selectors").Append(instanceSelectors); encounterError != nil {
-				return encounterError
-			}
+	desired := normalizeLabels(item.Labels)
+	remove, add := make(map[string]string), make(map[string]string)
+	current := make(map[string]string, len(existing.Labels))
+	for _, label := range existing.Labels {
+		current[label.Key] = label.Value
+		if value, exists := desired[label.Key]; !exists || value != label.Value {
+			remove[label.Key] = label.Value
+		}
+	}
+	for key, value := range desired {
+		if currentValue, exists := current[key]; !exists || currentValue != value {
+			add[key] = value
 		}
+	}
+	if err := replaceTargetLabels(tx, existing, remove, add); err != nil {
+		return "", err
+	}
 
+	if len(updates) > 0 || len(remove) > 0 || len(add) > 0 {
+		if err := touchTargets(tx, existing.ID); err != nil {
+			return "", err
+		}
+		return target.UpsertUpdated, nil
 	}
-	return
+	return target.UpsertUnchanged, nil
 }
 
-func DeleteTargetWithID(id uint) (encounterError error) {
+// expectedVersionKey 删除 target 时经由 context 传给 BeforeDelete 的期望版本
+type expectedVersionKey struct{}
+
+// DeleteTargetWithID 删除 target，resourceVersion 不为 0 时只有版本一致才删除
+func DeleteTargetWithID(id uint, resourceVersion uint64) (encounterError error) {
 	existingTarget := &Target{}
 	targetResult := DB.Model(&Target{}).Where("id = ? ", id).Find(existingTarget)
 	if encounterError = targetResult.Error; encounterError == nil {
 		if targetResult.RowsAffected > 0 {
-			tx := DB.Begin()
-			if encounterError = tx.Delete(existingTarget).Error; encounterError == nil {
-				encounterError = tx.Commit().Error
-			} else {
-				encounterError = tx.Rollback().Error
-			}
+			ctx := context.WithValue(context.Background(), expectedVersionKey{}, resourceVersion)
+			encounterError = DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				return tx.Delete(existingTarget).Error
+			})
 		} else {
-			encounterError = fmt.Errorf("No target found with the provided id: %d", id)
+			encounterError = fmt.Errorf("%w: %d", ErrTargetNotFound, id)
 		}
 	}
 	return encounterError
 }
 
+// bumpResourceVersion 递增 target 的版本号，expected 不为 0 时只有当前版本与 expected 一致才更新
+func bumpResourceVersion(tx *gorm.DB, id uint, expected uint64) error {
+	update := tx.Model(&Target{}).Where("id = ?", id)
+	if expected != 0 {
+		update = update.Where("resource_version = ?", expected)
+	}
+	result := update.UpdateColumn("resource_version", gorm.Expr("resource_version + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 && expected != 0 {
+		var current Target
+		if err := tx.Select("id", "resource_version").Where("id = ?", id).Limit(1).Find(&current).Error; err != nil {
+			return err
+		}
+		return fmt.Errorf("%w: expected version %d, current version %d", ErrTargetVersionConflict, expected, current.ResourceVersion)
+	}
+	return nil
+}
+
 func CleanMarkAsDeleted() (encounterError error) {
 	existingTargets := &[]Target{}
 	// 查询所有标记为已删除的目标
@@ -409,8 +493,15 @@ func CleanMarkAsDeleted() (encounterError error) {
 	if encounterError = targetResult.Error; encounterError == nil {
 		if targetResult.RowsAffected > 0 {
 			tx := DB.Begin()
-			// 删除所有已标记为已删除的目标
+			// 删除所有已标记为已删除的目标及其历史版本
+			ids := make([]uint, 0, len(*existingTargets))
+			for _, existingTarget := range *existingTargets {
+				ids = append(ids, existingTarget.ID)
+			}
 			if encounterError = tx.Unscoped().Delete(existingTargets).Error; encounterError == nil {
+				encounterError = purgeTargetRevisions(tx, ids...)
+			}
+			if encounterError == nil {
 				encounterError = tx.Commit().Error
 			} else {
 				_ = tx.Rollback().Error
@@ -501,11 +592,14 @@ func DeleteTargets(target *target.Target) error {
 			query = query.Where("targets.address = ?", targetItem.Address)
 		}
 		if targetItem.Auth != nil {
+			if targetItem.Auth.Credential != "" {
+				query = query.Where("targets.credential_id IN (?)", DB.Model(&Credential{}).Select("id").Where("uid = ?", targetItem.Auth.Credential))
+			}
 			if targetItem.Auth.Base != "" {
-				query = query.Where("targets.base_auth = ?", targetItem.Auth.Base)
+				query = query.Where("targets.credential_id IN (?)", DB.Model(&Credential{}).Select("id").Where("type = ? AND fingerprint = ?", credential.TypeBasic, credentialFingerprint(credential.TypeBasic, targetItem.Auth.Base)))
 			}
 			if targetItem.Auth.BearerToken != "" {
-				query = query.Where("targets.bearer_token = ?", targetItem.Auth.BearerToken)
+				query = query.Where("targets.credential_id IN (?)", DB.Model(&Credential{}).Select("id").Where("type = ? AND fingerprint = ?", credential.TypeBearer, credentialFingerprint(credential.TypeBearer, targetItem.Auth.BearerToken)))
 			}
 		}
 
@@ -530,7 +624,7 @@ func DeleteTargets(target *target.Target) error {
 		tx := DB.Begin()
 
 		// 删除目标
-		if err := DB.Delete(&Target{}, id).Error; err != nil {
+		if err := DB.Delete(&Target{ID: id}).Error; err != nil {
 			tx.Rollback()
 			return err
 		}
@@ -545,7 +639,8 @@ func DeleteTargets(target *target.Target) error {
 	return nil
 }
 
-func ListTargetWithCtl(query *query.QueryWithLabel) (results []target.TargetList, encounterError error) {
+// ListTargetWithCtl 查询 selector 下的 target，认证信息默认只返回凭据引用，showSecrets 为 true 时返回明文
+func ListTargetWithCtl(query *query.QueryWithLabel, showSecrets bool) (results []target.TargetList, encounterError error) {
 	results = make([]target.TargetList, 0)
 	// 先获取相关的 params
 	var targetsParamsRelation []swapMap
@@ -592,7 +687,7 @@ func ListTargetWithCtl(query *query.QueryWithLabel) (results []target.TargetList
 	}
 	targets := []Target{}
 	if encounterError = DB.Table(targetTableName).
-		Select("targets.id as id, targets.address, targets.schema, targets.metric_path, targets.scrape_time, targets.scrape_timeout, targets.bearer_token, targets.base_auth").
+		Select("targets.id as id, targets.address, targets.schema, targets.metric_path, targets.scrape_time, targets.scrape_timeout, targets.credential_id, targets.headers, targets.zone, targets.resource_version, "+targetTLSColumns).
 		Joins("JOIN target_selectors ON target_selectors.target_id = targets.id").
 		Joins("JOIN selectors ON selectors.id = target_selectors.selector_id").
 		Where("targets.`is_del` = 0").
@@ -602,26 +697,28 @@ func ListTargetWithCtl(query *query.QueryWithLabel) (results []target.TargetList
 		return
 	}
 
+	var credentialMap map[uint]*Credential
+	if credentialMap, encounterError = credentialsByID(targetCredentialIDs(targets), showSecrets); encounterError != nil {
+		return
+	}
+
 	targetResults := map[string]target.TargetList{}
 	for _, rawTarget := range targets {
-		paramsString := mapToURLParams(paramsMap[int(rawTarget.ID)])
-		uniqueKey := hex.EncodeToString(md5.New().Sum([]byte(fmt.Sprintf("%s://%s%s?%s", rawTarget.Schema, rawTarget.Address, rawTarget.MetricPath, paramsString))))
+		uniqueKey := targetIdentity(rawTarget.Schema, rawTarget.Address, rawTarget.MetricPath, paramsMap[int(rawTarget.ID)])
 
 		targetResult := target.TargetList{
-			ID:            rawTarget.ID,
-			Address:       rawTarget.Schema + "://" + rawTarget.Address,
-			MetricPath:    rawTarget.MetricPath,
-			ScrapeTimeout: rawTarget.ScrapeTimeout,
-			ScrapeTime:    rawTarget.ScrapeTime,
-		}
-		if rawTarget.BaseAuth != "" || rawTarget.BearerToken != "" {
-			targetResult.Auth = &target.TargetAuth{}
-			if rawTarget.BaseAuth != "" {
-				targetResult.Auth.Base = rawTarget.BaseAuth
-			}
-			if rawTarget.BearerToken != "" {
-				targetResult.Auth.BearerToken = rawTarget.BearerToken
-			}
+			ID:              rawTarget.ID,
+			Address:         rawTarget.Schema + "://" + rawTarget.Address,
+			MetricPath:      rawTarget.MetricPath,
+			ScrapeTimeout:   rawTarget.ScrapeTimeout,
+			ScrapeTime:      rawTarget.ScrapeTime,
+			TLS:             rawTarget.TLS.API(),
+			Headers:         redactHeaders(rawTarget.Headers, showSecrets),
+			Zone:            rawTarget.Zone,
+			ResourceVersion: rawTarget.ResourceVersion,
+		}
+		if found, exists := credentialMap[rawTarget.CredentialID]; exists {
+			targetResult.Auth = found.TargetAuth(showSecrets)
 		}
 
 		// 加入 labels
@@ -641,11 +738,44 @@ func ListTargetWithCtl(query *query.QueryWithLabel) (results []target.TargetList
 		results = append(results, result)
 	}
 	return results, encounterError
+}
 
-	return nil, encounterError
+// sdWatermarkSources 影响 SD 输出的表与其聚合值，任一值变化时 SD 输出可能变化。
+// target 的任何修改 (包括 labels、params 与 selector) 都会递增其 resource_version，删除的 target 保留在表中
+var sdWatermarkSources = []struct {
+	table      string
+	aggregates []string
+}{
+	{table: targetTableName, aggregates: []string{"COUNT(*)", "COALESCE(SUM(resource_version), 0)"}},
+	{table: selector_table_name, aggregates: []string{"COUNT(*)", "COALESCE(MAX(id), 0)"}},
+	{table: relabelRuleTableName, aggregates: []string{"COUNT(*)", "COALESCE(SUM(is_del), 0)", "MAX(updated_at)"}},
+	{table: metricRuleTableName, aggregates: []string{"COUNT(*)", "COALESCE(SUM(is_del), 0)", "MAX(updated_at)"}},
 }
 
-func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList, encounterError error) {
+// SDWatermark 返回 SD 输出的水位，只需一次查询，水位不变时 SD 输出不变
+func SDWatermark() (string, error) {
+	var columns []string
+	for _, source := range sdWatermarkSources {
+		for _, aggregate := range source.aggregates {
+			columns = append(columns, fmt.Sprintf("(SELECT %s FROM %s)", aggregate, source.table))
+		}
+	}
+	values := make([]sql.NullString, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := DB.Raw("SELECT " + strings.Join(columns, ", ")).Row().Scan(dest...); err != nil {
+		return "", err
+	}
+	watermark := make([]string, len(values))
+	for i, value := range values {
+		watermark[i] = value.String
+	}
+	return strings.Join(watermark, ","), nil
+}
+
+func ListTargetWithSelector(query *query.QueryWithLabel, shard *query.QueryWithShard) (results []TargetList, encounterError error) {
 	results = make([]TargetList, 0)
 	// 先获取相关的 params
 	var targetsParamsRelation []swapMap
@@ -666,7 +796,7 @@ func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList,
 		if paramsMap[param.ID] == nil {
 			paramsMap[param.ID] = make(map[string]string)
 		}
-		paramsMap[param.ID][fmt.Sprintf("__param_%s", param.Key)] = param.Value
+		paramsMap[param.ID][param.Key] = param.Value
 	}
 
 	// 获取 labels 数据
@@ -693,7 +823,9 @@ func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList,
 
 	targets := []Target{}
 	if encounterError = DB.Table(targetTableName).
-		Select("targets.id as id, targets.address, targets.schema, targets.metric_path, targets.scrape_time, targets.scrape_timeout, targets.bearer_token, targets.base_auth").
+		Select("targets.id as id, targets.address, targets.schema, targets.metric_path, targets.scrape_time, targets.scrape_timeout, targets.credential_id, targets.zone, "+
+			"targets.exporter_id, targets.probe_module, targets.probe_target, "+
+			"(targets.headers IS NOT NULL AND targets.headers <> '') AS has_headers, "+targetTLSColumns).
 		Joins("JOIN target_selectors ON target_selectors.target_id = targets.id").
 		Joins("JOIN selectors ON selectors.id = target_selectors.selector_id").
 		Where("selectors.key = ? AND selectors.value = ?", query.Key, query.Value).
@@ -701,14 +833,28 @@ func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList,
 		Scan(&targets).Error; encounterError != nil {
 		return
 	}
+
+	var credentialMap map[uint]*Credential
+	if credentialMap, encounterError = credentialsByID(targetCredentialIDs(targets), false); encounterError != nil {
+		return
+	}
+	var ruleTargets map[uint]bool
+	if ruleTargets, encounterError = targetsWithMetricRules(targets); encounterError != nil {
+		return
+	}
 	targetResults := make(map[string]TargetList)
 	for _, target := range targets {
-		paramsString := mapToURLParams(paramsMap[int(target.ID)])
-		uniqueKey := hex.EncodeToString(md5.New().Sum([]byte(fmt.Sprintf("%s://%s%s?%s", target.Schema, target.Address, target.MetricPath, paramsString))))
+		identity := targetIdentity(target.Schema, target.Address, target.MetricPath, paramsMap[int(target.ID)])
+		// 分片模式下只返回属于当前分片的 target
+		if !inShard(identity, shard) {
+			continue
+		}
 
+		// 需要认证、自定义 TLS、请求头、配置了过滤规则或位于网络区域中的 target 由代理抓取
+		proxied := target.CredentialID != 0 || !target.TLS.IsEmpty() || target.HasHeaders || ruleTargets[target.ID] || target.Zone != ""
 		var targetResult TargetList
-		if target.BearerToken != "" || target.BaseAuth != "" {
-			proxyParsedURL := parseConfigURL(config.CONFIG.ProxyAddress)
+		if proxied {
+			proxyParsedURL := parseConfigURL(proxyAddressFor(target.Zone))
 
 			targetResult = TargetList{
 				Targets: []string{proxyParsedURL.Host},
@@ -744,7 +890,7 @@ func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList,
 		// 加入 params
 		if params, exists := paramsMap[int(target.ID)]; exists {
 			for k, v := range params {
-				targetResult.Labels[k] = v
+				targetResult.Labels[fmt.Sprintf("__param_%s", k)] = v
 			}
 		}
 
@@ -752,17 +898,18 @@ func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList,
 			targetResult.Labels["instance"] = value
 		}
 
-		// 处理 BearerToken 和 BaseAuth
-		if target.BearerToken != "" || target.BaseAuth != "" {
-			if target.BearerToken != "" {
-				targetResult.Labels["__param_bearer"] = target.BearerToken
-			}
-			if target.BaseAuth != "" {
-				baseAuthEncoded := base64.StdEncoding.EncodeToString([]byte(target.BaseAuth))
-				targetResult.Labels["__param_base"] = baseAuthEncoded
-			}
-			if target.BearerToken != "" && target.BaseAuth != "" {
-				delete(targetResult.Labels, "__param_base")
+		// 探测 target：__address__ 为 blackbox exporter，instance 为探测的目标
+		if target.ExporterID != 0 {
+			targetResult.Labels["__param_module"] = target.ProbeModule
+			targetResult.Labels["__param_target"] = target.ProbeTarget
+			targetResult.Labels["instance"] = target.ProbeTarget
+		}
+
+		// SD 中只携带凭据与 target 的引用，由代理在服务端解析
+		if proxied {
+			targetResult.Labels["__param_tid"] = strconv.FormatUint(uint64(target.ID), 10)
+			if found, exists := credentialMap[target.CredentialID]; exists {
+				targetResult.Labels["__param_cred"] = found.UID
 			}
 
 			// 处理 schema 和 host 和 path
@@ -788,72 +935,215 @@ func ListTargetWithSelector(query *query.QueryWithLabel) (results []TargetList,
 		}
 
 		// 添加到 targetMap
-		targetResults[uniqueKey] = targetResult
+		targetResults[identity] = targetResult
 	}
 
-	// 将聚合后的 targetMap 转换为 results 切片
-	for _, result := range targetResults {
-		results = append(results, result)
+	// 将聚合后的 targetMap 按 key 排序后转换为 results 切片，保证输出稳定
+	uniqueKeys := make([]string, 0, len(targetResults))
+	for uniqueKey := range targetResults {
+		uniqueKeys = append(uniqueKeys, uniqueKey)
+	}
+	sort.Strings(uniqueKeys)
+	for _, uniqueKey := range uniqueKeys {
+		results = append(results, targetResults[uniqueKey])
 	}
 
-	return results, nil
+	// 应用 selector 的 relabel 规则，所有使用该 selector 的 Prometheus 得到相同的标签
+	rules, encounterError := relabelRulesFor(query.Key, query.Value)
+	if encounterError != nil {
+		return nil, encounterError
+	}
+	return relabelTargets(results, rules...), nil
 }
 
-func GetTargetByID(targetID uint) (target TargetRaw, encounterError error) {
-	encounterError = DB.Table(targetTableName).Where("id = ?", targetID).Where("`is_del` = 0").First(&target).Error
+// GetTargetByID 根据 ID 查询 target，认证信息默认只返回凭据引用，showSecrets 为 true 时返回明文
+func GetTargetByID(targetID uint, showSecrets bool) (target TargetRaw, encounterError error) {
+	if encounterError = DB.Table(targetTableName).Where("id = ?", targetID).Where("`is_del` = 0").First(&target).Error; encounterError != nil {
+		return
+	}
+	if target.CredentialID != 0 {
+		var credentialMap map[uint]*Credential
+		if credentialMap, encounterError = credentialsByID([]uint{target.CredentialID}, showSecrets); encounterError != nil {
+			return
+		}
+		if found, exists := credentialMap[target.CredentialID]; exists {
+			target.Auth = found.TargetAuth(showSecrets)
+		}
+	}
+	target.Headers = redactHeaders(target.Headers, showSecrets)
 	return
 }
 
 func ChangeTargetWithID(id uint, updates *target.TargetChg) (encounterError error) {
 	existingTarget := &Target{}
-	targetResult := DB.Model(&Target{}).Where("id = ?", id).First(existingTarget)
+	targetResult := DB.Model(&Target{}).Where("id = ?", id).Limit(1).Find(existingTarget)
 	if encounterError = targetResult.Error; encounterError == nil {
 		if targetResult.RowsAffected > 0 {
-			// 创建一个事务
-			tx := DB.Begin()
-
-			// 创建一个更新映射
-			updateData := Target{}
-
-			if updates.Address != "" {
-				updateData.Address = updates.Address
-				var schema string
-				if strings.HasPrefix(updates.Address, "http://") || strings.HasPrefix(updates.Address, "https://") {
-					schema = strings.Split(updates.Address, "://")[0]
-					updates.Address = strings.Split(updates.Address, "://")[1]
-				} else {
-					schema = "http" // 默认值
-				}
-				updateData.Schema = schema
-			}
-			if updates.MetricPath != "" {
-				updateData.MetricPath = updates.MetricPath
-			}
-			if updates.ScrapeTime > 0 {
-				updateData.ScrapeTime = updates.ScrapeTime
-			}
-			if updates.ScrapeTimeout > 0 {
-				updateData.ScrapeTimeout = updates.ScrapeTimeout
-			}
-
-			if updates.Auth != nil {
-				if updates.Auth.Base != "" {
-					updateData.BaseAuth = updates.Auth.Base
-				}
-				if updates.Auth.BearerToken != "" {
-					updateData.BearerToken = updates.Auth.BearerToken
+			encounterError = DB.Transaction(func(tx *gorm.DB) error {
+				if err := changeTarget(tx, existingTarget, updates); err != nil {
+					return err
 				}
-			}
-
-			// 执行更新
-			if encounterError = tx.Model(existingTarget).Updates(updateData).Error; encounterError == nil {
-				encounterError = tx.Commit().Error
-			} else {
-				tx.Rollback()
-			}
+				return recordTargetRevision(tx, existingTarget.ID, false)
+			})
 		} else {
-			encounterError = fmt.Errorf("No target found with the provided id: %d", id)
+			encounterError = fmt.Errorf("%w: %d", ErrTargetNotFound, id)
 		}
 	}
 	return encounterError
 }
+
+// changeTarget 在事务中修改 target，请求携带 resource_version 时先检查版本，修改后重新计算唯一标识
+func changeTarget(tx *gorm.DB, existingTarget *Target, updates *target.TargetChg) (encounterError error) {
+	if encounterError = bumpResourceVersion(tx, existingTarget.ID, updates.ResourceVersion); encounterError != nil {
+		return encounterError
+	}
+
+	// 创建一个更新映射
+	updateData := Target{}
+
+	if updates.Address != "" {
+		address, schema := updates.Address, "http" // 默认值
+		if strings.HasPrefix(address, "http://") || strings.HasPrefix(address, "https://") {
+			schema, address, _ = strings.Cut(address, "://")
+		}
+		updateData.Address = address
+		updateData.Schema = schema
+	}
+	if updates.MetricPath != "" {
+		updateData.MetricPath = updates.MetricPath
+	}
+	if updates.ScrapeTime > 0 {
+		updateData.ScrapeTime = updates.ScrapeTime
+	}
+	if updates.ScrapeTimeout > 0 {
+		updateData.ScrapeTimeout = updates.ScrapeTimeout
+	}
+
+	if updates.Auth != nil {
+		if updateData.CredentialID, encounterError = credentialIDForAuth(tx, updates.Auth); encounterError != nil {
+			return encounterError
+		}
+	}
+
+	// TLS 配置整体替换，传入空对象可以清除
+	if updates.TLS != nil {
+		var tlsConfig TargetTLS
+		if tlsConfig, encounterError = newTargetTLS(updates.TLS); encounterError != nil {
+			return encounterError
+		}
+		if encounterError = tx.Model(existingTarget).Updates(tlsConfig.columns()).Error; encounterError != nil {
+			return encounterError
+		}
+	}
+
+	if updates.Zone != "" {
+		if encounterError = validateZone(updates.Zone); encounterError != nil {
+			return encounterError
+		}
+		updateData.Zone = updates.Zone
+	}
+
+	// 请求头整体替换，传入空对象可以清除
+	if updates.Headers != nil {
+		var headers EncryptedHeaders
+		if headers, encounterError = newTargetHeaders(updates.Headers); encounterError != nil {
+			return encounterError
+		}
+		if encounterError = tx.Model(existingTarget).Update("headers", headers).Error; encounterError != nil {
+			return encounterError
+		}
+	}
+
+	// 执行更新，地址或路径修改后重新计算唯一标识
+	if encounterError = tx.Model(existingTarget).Updates(updateData).Error; encounterError != nil {
+		return encounterError
+	}
+	return refreshTargetIdentities(tx, existingTarget.ID)
+}
+
+var proxyTargetColumns = []string{"id", "address", "schema", "metric_path", "scrape_time", "scrape_timeout", "credential_id", "headers", "zone",
+	"exporter_id", "probe_module", "probe_target",
+	"tls_ca_file", "tls_cert_file", "tls_key_file", "tls_server_name", "tls_insecure_skip_verify"}
+
+// ScrapeParams 返回抓取 target 时携带的 URL 参数，只来自 target 保存的 params 与探测设置，需要预加载 Params
+func (t *Target) ScrapeParams() url.Values {
+	params := url.Values{}
+	for _, param := range t.Params {
+		params.Set(param.Key, param.Value)
+	}
+	if t.ExporterID != 0 {
+		params.Set("module", t.ProbeModule)
+		params.Set("target", t.ProbeTarget)
+	}
+	return params
+}
+
+// GetProxyTargetByID 查询代理抓取 target 所需的字段与 params，已删除的 target 返回 ErrTargetNotFound
+func GetProxyTargetByID(targetID uint) (*Target, error) {
+	found := &Target{}
+	result := DB.Select(proxyTargetColumns).Preload("Params").Where("id = ?", targetID).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrTargetNotFound
+	}
+	return found, nil
+}
+
+// FindProxyTarget 根据 schema、host、port 与 path 查询已注册的 target，代理只允许抓取已注册的 target
+// 省略默认端口的 address 也可以匹配。多个 target 的地址相同时按 params 区分，仍无法区分时返回 ErrTargetAmbiguous
+func FindProxyTarget(schema, host, port, path string, params url.Values) (*Target, error) {
+	addresses := []string{host + ":" + port}
+	if (schema == "http" && port == "80") || (schema == "https" && port == "443") {
+		addresses = append(addresses, host)
+	}
+	var candidates []Target
+	if err := DB.Select(proxyTargetColumns).Preload("Params").
+		Where("targets.schema = ? AND targets.address IN ? AND targets.metric_path = ?", schema, addresses, path).
+		Order("id").Find(&candidates).Error; err != nil {
+		return nil, err
+	}
+	if len(candidates) == 0 {
+		return nil, ErrTargetNotFound
+	}
+	if len(candidates) == 1 {
+		return &candidates[0], nil
+	}
+	var matched []*Target
+	for i := range candidates {
+		if candidates[i].ScrapeParams().Encode() == params.Encode() {
+			matched = append(matched, &candidates[i])
+		}
+	}
+	if len(matched) != 1 {
+		return nil, fmt.Errorf("%w: %d targets match %s://%s:%s%s, use tid instead", ErrTargetAmbiguous, len(candidates), schema, host, port, path)
+	}
+	return matched[0], nil
+}
+
+// ListFederateTargets 查询 selector 下联邦接口需要抓取的 target，包括抓取所需的字段、labels 与 params
+func ListFederateTargets(key, value string) (targets []Target, encounterError error) {
+	columns := make([]string, 0, len(proxyTargetColumns))
+	for _, column := range proxyTargetColumns {
+		columns = append(columns, "targets."+column)
+	}
+	encounterError = DB.Select(columns).
+		Joins("JOIN target_selectors ON target_selectors.target_id = targets.id").
+		Joins("JOIN selectors ON selectors.id = target_selectors.selector_id").
+		Where("selectors.key = ? AND selectors.value = ?", key, value).
+		Preload("Labels").Preload("Params").
+		Order("targets.id").
+		Find(&targets).Error
+	return
+}
+
+func targetCredentialIDs(targets []Target) []uint {
+	ids := make([]uint, 0)
+	for _, t := range targets {
+		if t.CredentialID != 0 {
+			ids = append(ids, t.CredentialID)
+		}
+	}
+	return ids
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"gorm.io/gorm"
+	"k8s.io/klog/v2"
+)
+
+// ErrTargetConflict 修改后的 target 与已存在的 target 标识相同
+var ErrTargetConflict = errors.New("a target with the same identity already exists")
+
+// targetUID 计算 target 的唯一标识，由 schema、地址、路径、params 与 selector 组成，params 与 selector 按键排序
+func targetUID(schema, address, metricPath, params, selectors string) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s://%s%s?%s#%s", schema, address, metricPath, params, selectors)))
+	return hex.EncodeToString(sum[:])
+}
+
+// uid 根据已加载的 Params 与 Selectors 计算唯一标识
+func (t *Target) uid() string {
+	params, selectors := url.Values{}, url.Values{}
+	for _, param := range t.Params {
+		params.Add(param.Key, param.Value)
+	}
+	for _, selector := range t.Selectors {
+		selectors.Add(selector.Key, selector.Value)
+	}
+	return targetUID(t.Schema, t.Address, t.MetricPath, params.Encode(), selectors.Encode())
+}
+
+// refreshTargetIdentities 地址、路径、params 或 selector 修改后重新计算 target 的唯一标识
+// 与其他 target 冲突时返回 ErrTargetConflict
+func refreshTargetIdentities(tx *gorm.DB, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	var targets []Target
+	if err := tx.Preload("Params").Preload("Selectors").Where("id IN ?", ids).Find(&targets).Error; err != nil {
+		return err
+	}
+	for i := range targets {
+		identity := targets[i].uid()
+		if targets[i].Identity != nil && *targets[i].Identity == identity {
+			continue
+		}
+		var count int64
+		if err := tx.Model(&Target{}).Where("identity = ? AND id <> ?", identity, targets[i].ID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("%w: %s://%s%s", ErrTargetConflict, targets[i].Schema, targets[i].Address, targets[i].MetricPath)
+		}
+		if err := tx.Model(&targets[i]).UpdateColumn("identity", identity).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// BackfillTargetIdentities 为升级前创建的 target 计算唯一标识
+// 标识重复的 target 只保留最早创建的一个，其余的保持为空并输出日志，需要手动清理
+func BackfillTargetIdentities(tx *gorm.DB) error {
+	var targets []Target
+	if err := tx.Preload("Params").Preload("Selectors").Where("identity IS NULL").Order("id").Find(&targets).Error; err != nil {
+		return err
+	}
+	for i := range targets {
+		identity := targets[i].uid()
+		var existing Target
+		result := tx.Select("id").Where("identity = ?", identity).Limit(1).Find(&existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected > 0 {
+			klog.Warningf("target %d duplicates target %d (%s://%s%s), identity left empty",
+				targets[i].ID, existing.ID, targets[i].Schema, targets[i].Address, targets[i].MetricPath)
+			continue
+		}
+		if err := tx.Model(&targets[i]).UpdateColumn("identity", identity).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+)
+
+// TestUpsertTargets_Statuses 测试重复提交相同标识的 target 时更新 labels 与抓取间隔，不会创建重复的 target
+func TestUpsertTargets_Statuses(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	request := func(scrapeTime int, labels map[string]string) *target.Target {
+		return &target.Target{
+			InstanceSelector: map[string]string{"app": "node"},
+			Targets: []target.TargetItem{{
+				Address: "10.0.0.1:9100", ScrapeTime: scrapeTime, ScrapeTimeout: 5,
+				Params: map[string]string{"collect": "cpu"}, Labels: labels,
+			}},
+		}
+	}
+
+	// Act
+	created, err := UpsertTargets(request(30, map[string]string{"dc": "a", "env": "prd"}))
+	require.NoError(t, err)
+	updated, err := UpsertTargets(request(60, map[string]string{"dc": "b"}))
+	require.NoError(t, err)
+	unchanged, err := UpsertTargets(request(60, map[string]string{"dc": "b"}))
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, created, 1)
+	assert.Equal(t, target.UpsertCreated, created[0].Status)
+	assert.Len(t, created[0].Identity, 64)
+	require.Len(t, updated, 1)
+	assert.Equal(t, target.UpsertUpdated, updated[0].Status)
+	assert.Equal(t, created[0].ID, updated[0].ID)
+	assert.Equal(t, created[0].Identity, updated[0].Identity)
+	require.Len(t, unchanged, 1)
+	assert.Equal(t, target.UpsertUnchanged, unchanged[0].Status)
+
+	var stored []Target
+	require.NoError(t, db.Preload("Labels").Find(&stored).Error)
+	require.Len(t, stored, 1)
+	assert.Equal(t, 60, stored[0].ScrapeTime)
+	require.Len(t, stored[0].Labels, 1)
+	assert.Equal(t, "dc", stored[0].Labels[0].Key)
+	assert.Equal(t, "b", stored[0].Labels[0].Value)
+}
+
+// TestUpsertTargets_IdentityIncludesParamsAndSelector 测试 params 或 selector 不同的 target 为不同的 target
+func TestUpsertTargets_IdentityIncludesParamsAndSelector(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	item := target.TargetItem{Address: "10.0.0.1:9100"}
+	withParams := target.TargetItem{Address: "10.0.0.1:9100", Params: map[string]string{"collect": "cpu"}}
+
+	// Act
+	first, err := UpsertTargets(&target.Target{InstanceSelector: map[string]string{"app": "node"}, Targets: []target.TargetItem{item, withParams}})
+	require.NoError(t, err)
+	second, err := UpsertTargets(&target.Target{InstanceSelector: map[string]string{"app": "other"}, Targets: []target.TargetItem{item}})
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, first, 2)
+	assert.Equal(t, target.UpsertCreated, first[0].Status)
+	assert.Equal(t, target.UpsertCreated, first[1].Status)
+	assert.NotEqual(t, first[0].Identity, first[1].Identity)
+	require.Len(t, second, 1)
+	assert.Equal(t, target.UpsertCreated, second[0].Status)
+	var count int64
+	require.NoError(t, db.Model(&Target{}).Count(&count).Error)
+	assert.EqualValues(t, 3, count)
+}
+
+// TestUpsertTargets_RecreateAfterDelete 测试删除 target 后释放唯一标识，可以重新创建
+func TestUpsertTargets_RecreateAfterDelete(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	request := &target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	}
+	created, err := UpsertTargets(request)
+	require.NoError(t, err)
+	require.NoError(t, DeleteTargetWithID(created[0].ID, 0))
+
+	// Act
+	recreated, err := UpsertTargets(request)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, recreated, 1)
+	assert.Equal(t, target.UpsertCreated, recreated[0].Status)
+	assert.NotEqual(t, created[0].ID, recreated[0].ID)
+	assert.Equal(t, created[0].Identity, recreated[0].Identity)
+}
+
+// TestChangeTargetWithID_IdentityConflict 测试修改地址后与已存在的 target 冲突时返回 ErrTargetConflict
+func TestChangeTargetWithID_IdentityConflict(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}, {Address: "10.0.0.2:9100"}},
+	})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+
+	// Act
+	err = ChangeTargetWithID(created[1].ID, &target.TargetChg{Address: "10.0.0.1:9100"})
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTargetConflict)
+}
+
+// TestBackfillTargetIdentities 测试升级时为已有 target 计算标识，重复的 target 保持为空
+func TestBackfillTargetIdentities(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+	selectors, err := CreateSelectors(map[string]string{"app": "node"})
+	require.NoError(t, err)
+	duplicate := &Target{Address: "10.0.0.1:9100", Schema: "http", MetricPath: "/metrics", ScrapeTime: 30, ScrapeTimeout: 10, Selectors: selectors}
+	require.NoError(t, db.Create(duplicate).Error)
+	require.NoError(t, db.Model(&Target{}).Where("id = ?", created[0].ID).UpdateColumn("identity", nil).Error)
+
+	// Act
+	err = BackfillTargetIdentities(db)
+
+	// Assert
+	require.NoError(t, err)
+	var stored []Target
+	require.NoError(t, db.Order("id").Find(&stored).Error)
+	require.Len(t, stored, 2)
+	require.NotNil(t, stored[0].Identity)
+	assert.Equal(t, created[0].Identity, *stored[0].Identity)
+	assert.Nil(t, stored[1].Identity)
+}
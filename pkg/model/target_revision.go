@@ -0,0 +1,243 @@
+package model
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+)
+
+const targetRevisionTableName = "target_revisions"
+
+var ErrTargetRevisionNotFound = errors.New("target revision not found")
+
+// TargetRevision target 每个版本的完整状态，Revision 与 target 的 resource_version 一致
+// 删除 target 前同样保存一个版本，回滚到任意版本时恢复已删除的 target
+type TargetRevision struct {
+	ID              uint              `gorm:"primarykey"`
+	TargetID        uint              `gorm:"index:idx_target_revisions_target"`
+	Revision        uint64            `gorm:"index:idx_target_revisions_target"`
+	Address         string            `gorm:"type:varchar(255)"`
+	Schema          string            `gorm:"type:char(5)"`
+	MetricPath      string            `gorm:"type:varchar(255)"`
+	ScrapeTime      int               `gorm:"type:int"`
+	ScrapeTimeout   int               `gorm:"type:int"`
+	Labels          map[string]string `gorm:"serializer:json;type:text"`
+	Params          map[string]string `gorm:"serializer:json;type:text"`
+	Selectors       map[string]string `gorm:"serializer:json;type:text"`
+	CredentialID    uint
+	AuthFingerprint string `gorm:"type:char(64)"` // 凭据 ID 与版本的摘要，用于比较版本间认证是否变化，与主密钥无关
+	Deleted         bool   // 该版本由删除 target 产生
+	CreatedAt       time.Time
+}
+
+func (*TargetRevision) TableName() string {
+	return targetRevisionTableName
+}
+
+func (r *TargetRevision) info() target.TargetRevision {
+	return target.TargetRevision{
+		Revision:        r.Revision,
+		Address:         r.Schema + "://" + r.Address,
+		MetricPath:      r.MetricPath,
+		ScrapeTime:      r.ScrapeTime,
+		ScrapeTimeout:   r.ScrapeTimeout,
+		Labels:          r.Labels,
+		Params:          r.Params,
+		Selectors:       r.Selectors,
+		AuthFingerprint: r.AuthFingerprint,
+		Deleted:         r.Deleted,
+		CreatedAt:       r.CreatedAt,
+	}
+}
+
+// recordTargetRevision 保存 target 当前的状态并通知订阅了该 target 的 webhook，已删除的 target 不再保存
+func recordTargetRevision(tx *gorm.DB, id uint, deleted bool) error {
+	current := &Target{}
+	result := tx.Preload("Labels").Preload("Params").Preload("Selectors").Where("id = ?", id).Limit(1).Find(current)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	revision := &TargetRevision{
+		TargetID:      current.ID,
+		Revision:      current.ResourceVersion,
+		Address:       current.Address,
+		Schema:        current.Schema,
+		MetricPath:    current.MetricPath,
+		ScrapeTime:    current.ScrapeTime,
+		ScrapeTimeout: current.ScrapeTimeout,
+		Labels:        make(map[string]string, len(current.Labels)),
+		Params:        make(map[string]string, len(current.Params)),
+		Selectors:     make(map[string]string, len(current.Selectors)),
+		CredentialID:  current.CredentialID,
+		Deleted:       deleted,
+	}
+	for _, label := range current.Labels {
+		revision.Labels[label.Key] = label.Value
+	}
+	for _, param := range current.Params {
+		revision.Params[param.Key] = param.Value
+	}
+	for _, selector := range current.Selectors {
+		revision.Selectors[selector.Key] = selector.Value
+	}
+	if current.CredentialID != 0 {
+		found := &Credential{}
+		if err := tx.Unscoped().Select("id", "resource_version").Where("id = ?", current.CredentialID).Limit(1).Find(found).Error; err != nil {
+			return err
+		}
+		revision.AuthFingerprint = authFingerprint(current.CredentialID, found.ResourceVersion)
+	}
+	if err := tx.Create(revision).Error; err != nil {
+		return err
+	}
+	// 新建的 target 版本号为 1，之后的每次修改都会递增版本号
+	event := webhook.EventTargetUpdated
+	if deleted {
+		event = webhook.EventTargetDeleted
+	} else if revision.Revision == 1 {
+		event = webhook.EventTargetCreated
+	}
+	return enqueueTargetEvent(tx, event, revision)
+}
+
+// authFingerprint 由凭据 ID 与版本计算认证摘要，凭据被替换或 target 改用其他凭据时摘要改变
+func authFingerprint(credentialID uint, version uint64) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", credentialID, version)))
+	return hex.EncodeToString(sum[:])
+}
+
+// touchTargets 递增 target 的版本号并保存新的版本
+func touchTargets(tx *gorm.DB, ids ...uint) error {
+	for _, id := range ids {
+		if err := bumpResourceVersion(tx, id, 0); err != nil {
+			return err
+		}
+		if err := recordTargetRevision(tx, id, false); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ListTargetRevisions 按版本顺序返回 target 的历史版本，已删除的 target 同样可以查询
+func ListTargetRevisions(id uint) (results []target.TargetRevision, encounterError error) {
+	var count int64
+	if encounterError = DB.Unscoped().Model(&Target{}).Where("id = ?", id).Count(&count).Error; encounterError != nil {
+		return
+	}
+	if count == 0 {
+		return nil, fmt.Errorf("%w: %d", ErrTargetNotFound, id)
+	}
+	var revisions []TargetRevision
+	if encounterError = DB.Where("target_id = ?", id).Order("revision").Order("id").Find(&revisions).Error; encounterError != nil {
+		return
+	}
+	results = make([]target.TargetRevision, 0, len(revisions))
+	for i := range revisions {
+		results = append(results, revisions[i].info())
+	}
+	return results, nil
+}
+
+// RollbackTarget 将 target 恢复到指定版本的状态并保存为新的版本，已删除的 target 同时被恢复
+func RollbackTarget(id uint, revision uint64) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		existing := &Target{}
+		result := tx.Unscoped().Where("id = ?", id).Limit(1).Find(existing)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: %d", ErrTargetNotFound, id)
+		}
+		snapshot := &TargetRevision{}
+		result = tx.Where("target_id = ? AND revision = ?", id, revision).Order("id DESC").Limit(1).Find(snapshot)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return fmt.Errorf("%w: target %d revision %d", ErrTargetRevisionNotFound, id, revision)
+		}
+		if snapshot.CredentialID != 0 {
+			var count int64
+			if err := tx.Model(&Credential{}).Where("id = ?", snapshot.CredentialID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return fmt.Errorf("%w: credential of revision %d no longer exists", ErrCredentialNotFound, revision)
+			}
+		}
+
+		// 撤销软删除，唯一标识在 changeTarget 中重新计算
+		if existing.IsDel != 0 {
+			if err := tx.Unscoped().Model(existing).UpdateColumn("is_del", 0).Error; err != nil {
+				return err
+			}
+			existing.IsDel = 0
+		}
+
+		// 替换关联前记录原有的 labels、params 与 selectors，替换后删除不再被引用的记录
+		previous := make([][]uint, len(targetAssociations))
+		for i, association := range targetAssociations {
+			if err := tx.Table(association.joinTable).Where("target_id = ?", id).Pluck(association.column, &previous[i]).Error; err != nil {
+				return err
+			}
+		}
+
+		labels, err := createLabels(tx, snapshot.Labels)
+		if err != nil {
+			return err
+		}
+		if err = tx.Model(existing).Association("Labels").Replace(labels); err != nil {
+			return err
+		}
+		params, err := createParams(tx, snapshot.Params)
+		if err != nil {
+			return err
+		}
+		if err = tx.Model(existing).Association("Params").Replace(params); err != nil {
+			return err
+		}
+		selectors, err := createSelectors(tx, snapshot.Selectors)
+		if err != nil {
+			return err
+		}
+		if err = tx.Model(existing).Association("Selectors").Replace(selectors); err != nil {
+			return err
+		}
+		for i, association := range targetAssociations {
+			if err = deleteUnreferenced(tx, association.joinTable, association.column, association.model, previous[i]); err != nil {
+				return err
+			}
+		}
+
+		// 地址、路径与抓取间隔经由正常的修改流程恢复
+		if err = tx.Model(existing).UpdateColumn("credential_id", snapshot.CredentialID).Error; err != nil {
+			return err
+		}
+		if err = changeTarget(tx, existing, &target.TargetChg{
+			Address:       snapshot.Schema + "://" + snapshot.Address,
+			MetricPath:    snapshot.MetricPath,
+			ScrapeTime:    snapshot.ScrapeTime,
+			ScrapeTimeout: snapshot.ScrapeTimeout,
+		}); err != nil {
+			return err
+		}
+		return recordTargetRevision(tx, id, false)
+	})
+}
+
+// purgeTargetRevisions 彻底删除 target 时清理其历史版本
+func purgeTargetRevisions(tx *gorm.DB, ids ...uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return tx.Where("target_id IN ?", ids).Delete(&TargetRevision{}).Error
+}
@@ -0,0 +1,172 @@
+package model
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+// TestRollbackTarget_UndeleteRestoresRevision 测试删除后回滚到早期版本时恢复 target 及其 labels、params 与 selector
+func TestRollbackTarget_UndeleteRestoresRevision(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets: []target.TargetItem{{
+			Address: "10.0.0.1:9100", ScrapeTime: 30,
+			Labels: map[string]string{"dc": "a"}, Params: map[string]string{"collect": "cpu"},
+		}},
+	})
+	require.NoError(t, err)
+	id := created[0].ID
+	require.NoError(t, ChangeTargetWithID(id, &target.TargetChg{ScrapeTime: 60}))
+	require.NoError(t, DeleteTargetWithID(id, 0))
+
+	// Act
+	history, historyErr := ListTargetRevisions(id)
+	rollbackErr := RollbackTarget(id, 1)
+
+	// Assert
+	require.NoError(t, historyErr)
+	require.Len(t, history, 3)
+	assert.EqualValues(t, 1, history[0].Revision)
+	assert.Equal(t, 30, history[0].ScrapeTime)
+	assert.Equal(t, map[string]string{"dc": "a"}, history[0].Labels)
+	assert.Equal(t, map[string]string{"app": "node"}, history[0].Selectors)
+	assert.Equal(t, 60, history[1].ScrapeTime)
+	assert.True(t, history[2].Deleted)
+
+	require.NoError(t, rollbackErr)
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "app", Value: "node"}, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, id, listed[0].ID)
+	assert.Equal(t, "http://10.0.0.1:9100", listed[0].Address)
+	assert.Equal(t, 30, listed[0].ScrapeTime)
+	assert.Equal(t, map[string]string{"dc": "a"}, listed[0].Labels)
+	assert.Equal(t, map[string]string{"collect": "cpu"}, listed[0].Params)
+
+	history, err = ListTargetRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	assert.False(t, history[3].Deleted)
+	assert.Greater(t, history[3].Revision, history[2].Revision)
+
+	// 恢复后标识重新生效，重复提交不会创建新的 target
+	upserted, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", ScrapeTime: 30, Labels: map[string]string{"dc": "a"}, Params: map[string]string{"collect": "cpu"}}},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, target.UpsertUnchanged, upserted[0].Status)
+	assert.Equal(t, id, upserted[0].ID)
+}
+
+// TestRollbackTarget_UnknownRevision 测试回滚到不存在的版本时返回 ErrTargetRevisionNotFound
+func TestRollbackTarget_UnknownRevision(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+
+	// Act
+	err = RollbackTarget(created[0].ID, 42)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrTargetRevisionNotFound)
+	_, err = ListTargetRevisions(9999)
+	assert.ErrorIs(t, err, ErrTargetNotFound)
+}
+
+// TestRollbackTarget_DeletesUnreferencedRows 测试回滚替换 labels 后删除不再被任何 target 引用的 label
+func TestRollbackTarget_DeletesUnreferencedRows(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	item := target.TargetItem{Address: "10.0.0.1:9100", ScrapeTime: 30, Labels: map[string]string{"dc": "a"}}
+	created, err := UpsertTargets(&target.Target{InstanceSelector: map[string]string{"app": "node"}, Targets: []target.TargetItem{item}})
+	require.NoError(t, err)
+	item.Labels = map[string]string{"dc": "b"}
+	_, err = UpsertTargets(&target.Target{InstanceSelector: map[string]string{"app": "node"}, Targets: []target.TargetItem{item}})
+	require.NoError(t, err)
+
+	// Act
+	err = RollbackTarget(created[0].ID, 1)
+
+	// Assert
+	require.NoError(t, err)
+	var labels []Label
+	require.NoError(t, db.Find(&labels).Error)
+	require.Len(t, labels, 1)
+	assert.Equal(t, "a", labels[0].Value)
+}
+
+// TestDeleteTargetWithID_BumpsVersionOnce 测试删除 target 只递增一次版本号，版本不一致时不删除
+func TestDeleteTargetWithID_BumpsVersionOnce(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+	id := created[0].ID
+
+	// Act
+	staleErr := DeleteTargetWithID(id, 2)
+	deleteErr := DeleteTargetWithID(id, 1)
+
+	// Assert
+	assert.ErrorIs(t, staleErr, ErrTargetVersionConflict)
+	require.NoError(t, deleteErr)
+	history, err := ListTargetRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, history, 2)
+	assert.EqualValues(t, 2, history[1].Revision)
+	assert.True(t, history[1].Deleted)
+	assert.ErrorIs(t, DeleteTargetWithID(id, 0), ErrTargetNotFound)
+	assert.ErrorIs(t, ChangeTargetWithID(id, &target.TargetChg{ScrapeTime: 60}), ErrTargetNotFound)
+}
+
+// TestTargetRevision_AuthFingerprint 测试认证摘要在轮换主密钥后不变，替换凭据内容后改变
+func TestTargetRevision_AuthFingerprint(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	from := setupTestCipher(t, 1)
+	info, err := CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "token"}})
+	require.NoError(t, err)
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Auth: &target.TargetAuth{Credential: info.UID}}},
+	})
+	require.NoError(t, err)
+	id := created[0].ID
+	to, err := secret.NewCipher(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+
+	// Act
+	_, err = RotateEncryptionKey(db, from, to)
+	require.NoError(t, err)
+	secret.SetDefault(to)
+	require.NoError(t, ChangeTargetWithID(id, &target.TargetChg{ScrapeTime: 60}))
+	_, err = RotateCredential(info.UID, credential.Secret{Token: "rotated"})
+	require.NoError(t, err)
+	require.NoError(t, ChangeTargetWithID(id, &target.TargetChg{ScrapeTime: 90}))
+
+	// Assert
+	history, err := ListTargetRevisions(id)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.NotEmpty(t, history[0].AuthFingerprint)
+	assert.Equal(t, history[0].AuthFingerprint, history[1].AuthFingerprint)
+	assert.NotEqual(t, history[1].AuthFingerprint, history[2].AuthFingerprint)
+}
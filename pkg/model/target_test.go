@@ -0,0 +1,310 @@
+package model
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/profile"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/validation"
+)
+
+// TestCreateTargets_TLSTargetIsProxied 测试配置了 TLS 的 target 经由代理抓取，SD 中只携带 target 引用
+func TestCreateTargets_TLSTargetIsProxied(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	config.CONFIG = &config.Config{ProxyAddress: "http://pantheon:8899/ph/v1/proxy"}
+	t.Cleanup(func() { config.CONFIG = nil })
+	tlsConfig := &target.TargetTLS{CAFile: "/etc/pantheon/ca.pem", ServerName: "node.internal"}
+
+	// Act
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1:9100", TLS: tlsConfig},
+			{Address: "10.0.0.2:9100"},
+		},
+	}))
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "app", Value: "node"}, nil)
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, results, 2)
+	var proxied, direct *TargetList
+	for i := range results {
+		if results[i].Labels["instance"] == "10.0.0.1:9100" {
+			proxied = &results[i]
+		} else {
+			direct = &results[i]
+		}
+	}
+	require.NotNil(t, proxied)
+	require.NotNil(t, direct)
+	assert.Equal(t, []string{"pantheon:8899"}, proxied.Targets)
+	assert.Equal(t, "https", proxied.Labels["__param_schema"], "TLS settings should imply https")
+	assert.NotContains(t, proxied.Labels, "__param_cred")
+	assert.Equal(t, []string{"10.0.0.2:9100"}, direct.Targets)
+	assert.NotContains(t, direct.Labels, "__param_tid")
+
+	targetID, err := strconv.ParseUint(proxied.Labels["__param_tid"], 10, 64)
+	require.NoError(t, err)
+	stored, err := GetProxyTargetByID(uint(targetID))
+	require.NoError(t, err)
+	assert.Equal(t, tlsConfig, stored.TLS.API())
+}
+
+// TestChangeTargetWithID_ClearTLS 测试传入空的 TLS 配置可以清除已有配置
+func TestChangeTargetWithID_ClearTLS(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", TLS: &target.TargetTLS{InsecureSkipVerify: true}}},
+	}))
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "app", Value: "node"}, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	require.NotNil(t, listed[0].TLS)
+
+	// Act
+	err = ChangeTargetWithID(listed[0].ID, &target.TargetChg{TLS: &target.TargetTLS{}})
+
+	// Assert
+	require.NoError(t, err)
+	stored, err := GetProxyTargetByID(listed[0].ID)
+	require.NoError(t, err)
+	assert.True(t, stored.TLS.IsEmpty())
+}
+
+// TestCreateTargets_TLSCertWithoutKey 测试只设置客户端证书而没有私钥时返回错误
+func TestCreateTargets_TLSCertWithoutKey(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+
+	// Act
+	err := CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", TLS: &target.TargetTLS{CertFile: "/etc/pantheon/client.pem"}}},
+	})
+
+	// Assert
+	assert.Error(t, err)
+}
+
+// TestCreateTargets_HeadersAreProxiedAndRedacted 测试配置了请求头的 target 经由代理抓取，请求头加密保存且默认不返回值
+func TestCreateTargets_HeadersAreProxiedAndRedacted(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	setupTestCipher(t, 1)
+	config.CONFIG = &config.Config{ProxyAddress: "http://pantheon:8899/ph/v1/proxy"}
+	t.Cleanup(func() { config.CONFIG = nil })
+	selector := &query.QueryWithLabel{Key: "app", Value: "node"}
+
+	// Act
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Headers: map[string]string{"x-api-key": "secret-key"}}},
+	}))
+	results, err := ListTargetWithSelector(selector, nil)
+	require.NoError(t, err)
+	listed, err := ListTargetWithCtl(selector, false)
+	require.NoError(t, err)
+
+	// Assert
+	require.Len(t, results, 1)
+	assert.Equal(t, []string{"pantheon:8899"}, results[0].Targets)
+	require.Len(t, listed, 1)
+	assert.Equal(t, map[string]string{"X-Api-Key": redactedHeaderValue}, listed[0].Headers)
+
+	var raw string
+	require.NoError(t, db.Table(targetTableName).Where("id = ?", listed[0].ID).Pluck("headers", &raw).Error)
+	assert.NotContains(t, raw, "secret-key")
+	stored, err := GetProxyTargetByID(listed[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-key", stored.Headers["X-Api-Key"])
+	revealed, err := GetTargetByID(listed[0].ID, true)
+	require.NoError(t, err)
+	assert.Equal(t, "secret-key", revealed.Headers["X-Api-Key"])
+}
+
+// TestChangeTargetWithID_Headers 测试修改请求头时整体替换，传入空对象可以清除，保留请求头不能设置
+func TestChangeTargetWithID_Headers(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Headers: map[string]string{"X-Api-Key": "a", "X-Tenant": "ops"}}},
+	}))
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "app", Value: "node"}, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	id := listed[0].ID
+
+	// Act
+	replaceErr := ChangeTargetWithID(id, &target.TargetChg{Headers: map[string]string{"X-Api-Key": "b"}})
+	replaced, err := GetProxyTargetByID(id)
+	require.NoError(t, err)
+	reservedErr := ChangeTargetWithID(id, &target.TargetChg{Headers: map[string]string{"authorization": "Bearer x"}})
+	clearErr := ChangeTargetWithID(id, &target.TargetChg{Headers: map[string]string{}})
+	cleared, err := GetProxyTargetByID(id)
+	require.NoError(t, err)
+
+	// Assert
+	require.NoError(t, replaceErr)
+	assert.Equal(t, EncryptedHeaders{"X-Api-Key": "b"}, replaced.Headers)
+	assert.Error(t, reservedErr)
+	require.NoError(t, clearErr)
+	assert.Empty(t, cleared.Headers)
+}
+
+// TestCreateTargets_ZoneUsesZoneProxy 测试网络区域中的 target 在 SD 中指向区域的代理，未定义的区域返回错误
+func TestCreateTargets_ZoneUsesZoneProxy(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	config.CONFIG = &config.Config{
+		ProxyAddress: "http://pantheon:8899/ph/v1/proxy",
+		Zones: []config.ZoneConfig{
+			{Name: "dmz", ProxyAddress: "http://pantheon-dmz:8899/ph/v1/proxy"},
+			{Name: "office", Upstream: "socks5://jump-office:1080"},
+		},
+	}
+	t.Cleanup(func() { config.CONFIG = nil })
+
+	// Act
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets: []target.TargetItem{
+			{Address: "172.16.0.1:9100", Zone: "dmz"},
+			{Address: "192.168.0.1:9100", Zone: "office"},
+		},
+	}))
+	unknownErr := CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100", Zone: "lab"}},
+	})
+	results, err := ListTargetWithSelector(&query.QueryWithLabel{Key: "app", Value: "node"}, nil)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Error(t, unknownErr)
+	require.Len(t, results, 2)
+	proxies := map[string]string{}
+	for _, result := range results {
+		require.Len(t, result.Targets, 1)
+		proxies[result.Labels["instance"]] = result.Targets[0]
+	}
+	assert.Equal(t, map[string]string{
+		"172.16.0.1:9100":  "pantheon-dmz:8899",
+		"192.168.0.1:9100": "pantheon:8899",
+	}, proxies)
+	found, err := FindProxyTarget("http", "172.16.0.1", "9100", "/metrics", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "dmz", found.Zone)
+}
+
+// TestCreateTargets_ValidationPolicy 测试合并 profile 后校验 labels，违规时不创建任何 target
+func TestCreateTargets_ValidationPolicy(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	config.CONFIG = &config.Config{Validation: config.ValidationConfig{
+		Required: []config.RequiredLabelsConfig{{Selector: "prom=fed", Labels: []string{"env", "team"}}},
+	}}
+	t.Cleanup(func() { config.CONFIG = nil })
+	_, err := CreateProfile(&profile.Profile{Name: "node", Port: 9100, Labels: map[string]string{"team": "infra"}})
+	require.NoError(t, err)
+
+	// Act
+	invalidErr := CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets: []target.TargetItem{
+			{Address: "10.0.0.1", Profile: "node", Labels: map[string]string{"env": "prod"}},
+			{Address: "10.0.0.2:9100", Labels: map[string]string{"__metrics_path__": "/debug"}},
+		},
+	})
+	validErr := CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1", Profile: "node", Labels: map[string]string{"env": "prod"}}},
+	})
+
+	// Assert
+	var validationError *validation.Error
+	require.ErrorAs(t, invalidErr, &validationError)
+	fields := make([]string, 0, len(validationError.Violations))
+	for _, violation := range validationError.Violations {
+		fields = append(fields, violation.Field)
+	}
+	assert.Equal(t, []string{"targets[1].labels.__metrics_path__", "targets[1].labels.env", "targets[1].labels.team"}, fields)
+	require.NoError(t, validErr)
+	var count int64
+	require.NoError(t, DB.Model(&Target{}).Count(&count).Error)
+	assert.Equal(t, int64(1), count)
+}
+
+// TestChangeTargetWithID_ResourceVersion 测试携带过期的 resource_version 修改或删除 target 时返回冲突
+func TestChangeTargetWithID_ResourceVersion(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "node"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	}))
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "app", Value: "node"}, false)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	id, version := listed[0].ID, listed[0].ResourceVersion
+	require.EqualValues(t, 1, version)
+
+	// Act
+	firstErr := ChangeTargetWithID(id, &target.TargetChg{ScrapeTime: 60, ResourceVersion: version})
+	staleErr := ChangeTargetWithID(id, &target.TargetChg{ScrapeTime: 90, ResourceVersion: version})
+	staleDeleteErr := DeleteTargetWithID(id, version)
+
+	// Assert
+	require.NoError(t, firstErr)
+	assert.ErrorIs(t, staleErr, ErrTargetVersionConflict)
+	assert.ErrorIs(t, staleDeleteErr, ErrTargetVersionConflict)
+	stored, err := GetTargetByID(id, false)
+	require.NoError(t, err)
+	assert.Equal(t, 60, stored.ScrapeTime)
+	assert.EqualValues(t, 2, stored.ResourceVersion)
+	require.NoError(t, DeleteTargetWithID(id, stored.ResourceVersion))
+}
+
+// TestSDWatermark_ChangesWithSDOutput 测试 target 与 selector 的修改改变 SD 水位，没有修改时水位不变
+func TestSDWatermark_ChangesWithSDOutput(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	watermark := func() string {
+		value, err := SDWatermark()
+		require.NoError(t, err)
+		return value
+	}
+	empty := watermark()
+
+	// Act & Assert
+	require.NoError(t, CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}, {Address: "10.0.0.2:9100"}},
+	}))
+	created := watermark()
+	assert.NotEqual(t, empty, created)
+	assert.Equal(t, created, watermark(), "watermark is stable without changes")
+
+	listed, err := ListTargetWithCtl(&query.QueryWithLabel{Key: "prom", Value: "fed"}, false)
+	require.NoError(t, err)
+	require.NoError(t, ChangeTargetWithID(listed[0].ID, &target.TargetChg{ScrapeTime: 60}))
+	changed := watermark()
+	assert.NotEqual(t, created, changed, "changing a target moves the watermark")
+
+	require.NoError(t, UpdateSelectorByKeyValue("prom", "fed", "prom", "main"))
+	renamed := watermark()
+	assert.NotEqual(t, changed, renamed, "renaming a selector moves the watermark")
+
+	require.NoError(t, DeleteTargetWithID(listed[1].ID, 0))
+	assert.NotEqual(t, renamed, watermark(), "deleting a target moves the watermark")
+}
@@ -1,6 +1,8 @@
 package model
 
 import (
+	"fmt"
+	"strings"
 	"testing"
 
 	"github.com/glebarez/sqlite"
@@ -11,15 +13,21 @@ import (
 
 // 创建一个内存中的 SQLite 数据库用于测试，更接近真实场景，同时保持测试的隔离性
 func SetupTestDB(t *testing.T) *gorm.DB {
-	// 使用 :memory: 创建内存数据库，每次测试都是全新的
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{
+	// 使用以测试名命名的共享内存数据库，每次测试都是全新的，
+	// 同时连接池中的多个连接（如事务之外的查询）看到的是同一个数据库
+	dsn := fmt.Sprintf("file:%s?mode=memory&cache=shared", strings.NewReplacer("/", "_", " ", "_").Replace(t.Name()))
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
 		Logger: logger.Default.LogMode(logger.Silent), // 测试时关闭日志
 	})
 	require.NoError(t, err, "Failed to create in-memory database")
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	// 测试结束时关闭连接，释放共享内存数据库
+	t.Cleanup(func() { _ = sqlDB.Close() })
 
 	// 自动迁移所有模型表结构
 	// 注意：迁移顺序很重要，被引用的表需要先创建
-	err = db.AutoMigrate(&Label{}, &Param{}, &Selector{}, &Target{})
+	err = db.AutoMigrate(&Label{}, &Param{}, &Selector{}, &Target{}, &Credential{}, &MetricRule{}, &BlackboxExporter{}, &Profile{}, &RelabelRule{}, &TargetRevision{}, &Webhook{}, &WebhookDelivery{}, &WebhookDeadLetter{})
 	require.NoError(t, err, "Failed to migrate database schema")
 
 	// 将全局 DB 变量指向测试数据库
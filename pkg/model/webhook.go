@@ -0,0 +1,515 @@
+package model
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/plugin/soft_delete"
+
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+const (
+	webhookTableName           = "webhooks"
+	webhookDeliveryTableName   = "webhook_deliveries"
+	webhookDeadLetterTableName = "webhook_dead_letters"
+)
+
+var (
+	ErrWebhookNotFound    = errors.New("webhook not found")
+	ErrWebhookExists      = errors.New("webhook already exists")
+	ErrDeadLetterNotFound = errors.New("dead letter not found")
+)
+
+// Webhook 事件订阅，事件在修改提交的同一事务中写入 webhook_deliveries，由投递器异步发送
+type Webhook struct {
+	ID            uint                  `gorm:"primarykey"`
+	IsDel         soft_delete.DeletedAt `gorm:"softDelete:flag"`
+	Name          string                `gorm:"index;type:varchar(255)"`
+	URL           string                `gorm:"type:varchar(2048)"`
+	SelectorKey   string                `gorm:"type:varchar(255)"` // 为空时接收所有 target 的事件
+	SelectorValue string                `gorm:"type:varchar(255)"`
+	Events        []string              `gorm:"serializer:json;type:text"` // 为空时接收所有事件
+	Secret        EncryptedString       `gorm:"type:text"`
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+func (*Webhook) TableName() string {
+	return webhookTableName
+}
+
+type webhookSecretRow struct {
+	ID     uint
+	Secret string
+}
+
+func webhookSecretRows(tx *gorm.DB) (rows []webhookSecretRow, err error) {
+	err = tx.Table(webhookTableName).Select("id", "secret").Where("secret IS NOT NULL AND secret <> ''").Find(&rows).Error
+	return
+}
+
+// encryptWebhookSecrets 加密配置主密钥之前写入的明文签名密钥
+func encryptWebhookSecrets(tx *gorm.DB) (int, error) {
+	if secret.Default() == nil {
+		return 0, nil
+	}
+	rows, err := webhookSecretRows(tx)
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, row := range rows {
+		if secret.IsEncrypted(row.Secret) {
+			continue
+		}
+		encrypted, err := secret.Encrypt(row.Secret)
+		if err != nil {
+			return count, err
+		}
+		if err = tx.Table(webhookTableName).Where("id = ?", row.ID).Update("secret", encrypted).Error; err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// rewrapWebhookSecrets 使用新的主密钥重新加密签名密钥的数据密钥
+func rewrapWebhookSecrets(tx *gorm.DB, from, to *secret.Cipher) (int, error) {
+	rows, err := webhookSecretRows(tx)
+	if err != nil {
+		return 0, err
+	}
+	for _, row := range rows {
+		rewrapped, err := from.Rewrap(row.Secret, to)
+		if err != nil {
+			return 0, fmt.Errorf("secret of webhook %d: %w", row.ID, err)
+		}
+		if err = tx.Table(webhookTableName).Where("id = ?", row.ID).Update("secret", rewrapped).Error; err != nil {
+			return 0, err
+		}
+	}
+	return len(rows), nil
+}
+
+// WebhookDelivery 一个事件对一个订阅的投递，同时作为待投递队列与投递记录
+type WebhookDelivery struct {
+	ID             uint      `gorm:"primarykey"`
+	WebhookID      uint      `gorm:"index"`
+	Webhook        Webhook   `gorm:"foreignKey:WebhookID"`
+	EventID        string    `gorm:"index;type:char(32)"`
+	Event          string    `gorm:"type:varchar(64)"`
+	Payload        string    `gorm:"type:text"`
+	Status         string    `gorm:"index:idx_webhook_deliveries_due;type:varchar(16)"`
+	NextAttemptAt  time.Time `gorm:"index:idx_webhook_deliveries_due"`
+	Attempts       int
+	LastStatusCode int
+	LastError      string `gorm:"type:text"`
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+func (*WebhookDelivery) TableName() string {
+	return webhookDeliveryTableName
+}
+
+// WebhookDeadLetter 重试次数用尽的投递
+type WebhookDeadLetter struct {
+	ID         uint   `gorm:"primarykey"`
+	DeliveryID uint   `gorm:"index"`
+	WebhookID  uint   `gorm:"index"`
+	EventID    string `gorm:"type:char(32)"`
+	Event      string `gorm:"type:varchar(64)"`
+	Payload    string `gorm:"type:text"`
+	Attempts   int
+	LastError  string `gorm:"type:text"`
+	CreatedAt  time.Time
+}
+
+func (*WebhookDeadLetter) TableName() string {
+	return webhookDeadLetterTableName
+}
+
+func (w *Webhook) apply(request *webhook.Webhook) error {
+	parsed, err := url.Parse(request.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("invalid webhook url %q, expected http(s)://host/path", request.URL)
+	}
+	w.SelectorKey, w.SelectorValue = "", ""
+	if request.Selector != "" {
+		key, value, found := strings.Cut(request.Selector, "=")
+		if !found || key == "" || value == "" {
+			return fmt.Errorf("invalid selector %q, expected key=value", request.Selector)
+		}
+		w.SelectorKey, w.SelectorValue = key, value
+	}
+	for _, event := range request.Events {
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("invalid event %q. Valid values are %s", event, strings.Join(webhook.Events, ", "))
+		}
+	}
+	w.Name, w.URL, w.Events = request.Name, request.URL, request.Events
+	w.Secret = EncryptedString(request.Secret)
+	return nil
+}
+
+func (w *Webhook) info() webhook.WebhookInfo {
+	info := webhook.WebhookInfo{
+		ID:        w.ID,
+		Name:      w.Name,
+		URL:       w.URL,
+		Events:    w.Events,
+		HasSecret: w.Secret != "",
+		CreatedAt: w.CreatedAt,
+		UpdatedAt: w.UpdatedAt,
+	}
+	if w.SelectorKey != "" {
+		info.Selector = w.SelectorKey + "=" + w.SelectorValue
+	}
+	return info
+}
+
+// subscribes 判断订阅是否接收事件，selectors 中任意一个包含订阅的 selector 即匹配
+func (w *Webhook) subscribes(event string, selectors ...map[string]string) bool {
+	if len(w.Events) > 0 {
+		subscribed := false
+		for _, subscribedEvent := range w.Events {
+			subscribed = subscribed || subscribedEvent == event
+		}
+		if !subscribed {
+			return false
+		}
+	}
+	if w.SelectorKey == "" {
+		return true
+	}
+	for _, candidate := range selectors {
+		if value, exists := candidate[w.SelectorKey]; exists && value == w.SelectorValue {
+			return true
+		}
+	}
+	return false
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range webhook.Events {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
+
+func getWebhookByName(tx *gorm.DB, name string) (*Webhook, error) {
+	found := &Webhook{}
+	result := tx.Where("name = ?", name).Limit(1).Find(found)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, ErrWebhookNotFound
+	}
+	return found, nil
+}
+
+// CreateWebhook 创建订阅，名称不能重复
+func CreateWebhook(request *webhook.Webhook) (info webhook.WebhookInfo, encounterError error) {
+	if _, encounterError = getWebhookByName(DB, request.Name); encounterError == nil {
+		return info, ErrWebhookExists
+	} else if !errors.Is(encounterError, ErrWebhookNotFound) {
+		return
+	}
+	created := &Webhook{}
+	if encounterError = created.apply(request); encounterError != nil {
+		return
+	}
+	if encounterError = DB.Create(created).Error; encounterError != nil {
+		return
+	}
+	return created.info(), nil
+}
+
+// ListWebhooks 查询所有订阅
+func ListWebhooks() (results []webhook.WebhookInfo, encounterError error) {
+	results = make([]webhook.WebhookInfo, 0)
+	var webhooks []Webhook
+	if encounterError = DB.Order("name").Find(&webhooks).Error; encounterError != nil {
+		return
+	}
+	for i := range webhooks {
+		results = append(results, webhooks[i].info())
+	}
+	return results, nil
+}
+
+// UpdateWebhook 使用请求的内容替换订阅，secret 为空时保留原有的 secret
+func UpdateWebhook(name string, request *webhook.Webhook) (info webhook.WebhookInfo, encounterError error) {
+	var found *Webhook
+	if found, encounterError = getWebhookByName(DB, name); encounterError != nil {
+		return
+	}
+	if request.Name != name {
+		return info, fmt.Errorf("webhook cannot be renamed")
+	}
+	secret := found.Secret
+	if encounterError = found.apply(request); encounterError != nil {
+		return
+	}
+	if request.Secret == "" {
+		found.Secret = secret
+	}
+	if encounterError = DB.Save(found).Error; encounterError != nil {
+		return
+	}
+	return found.info(), nil
+}
+
+// DeleteWebhook 删除订阅，尚未投递的事件不再发送
+func DeleteWebhook(name string) error {
+	found, err := getWebhookByName(DB, name)
+	if err != nil {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("webhook_id = ? AND status = ?", found.ID, webhook.StatusPending).Delete(&WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(found).Error
+	})
+}
+
+// enqueueWebhookEvent 在修改所在的事务中为匹配的订阅写入待投递的事件，事务提交后才会被投递
+func enqueueWebhookEvent(tx *gorm.DB, event webhook.Event, selectors ...map[string]string) error {
+	var webhooks []Webhook
+	if err := tx.Select("id", "selector_key", "selector_value", "events").Find(&webhooks).Error; err != nil {
+		return err
+	}
+	var payload []byte
+	for i := range webhooks {
+		if !webhooks[i].subscribes(event.Type, selectors...) {
+			continue
+		}
+		if payload == nil {
+			eventID, err := newCredentialUID()
+			if err != nil {
+				return err
+			}
+			event.ID, event.Timestamp = eventID, time.Now().UTC()
+			if payload, err = json.Marshal(event); err != nil {
+				return err
+			}
+		}
+		delivery := &WebhookDelivery{
+			WebhookID:     webhooks[i].ID,
+			EventID:       event.ID,
+			Event:         event.Type,
+			Payload:       string(payload),
+			Status:        webhook.StatusPending,
+			NextAttemptAt: event.Timestamp,
+		}
+		if err := tx.Create(delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// enqueueTargetEvent 发送 target 的版本，订阅按版本中的 selector 过滤
+func enqueueTargetEvent(tx *gorm.DB, eventType string, revision *TargetRevision) error {
+	return enqueueWebhookEvent(tx, webhook.Event{
+		Type:   eventType,
+		Target: &webhook.TargetEvent{ID: revision.TargetID, TargetRevision: revision.info()},
+	}, revision.Selectors)
+}
+
+// ErrWebhookLeaseLost 投递的租约已经到期并被其他投递器领取
+var ErrWebhookLeaseLost = errors.New("webhook delivery lease lost")
+
+// ClaimWebhookDelivery 领取一个到期的投递，领取后 lease 时间内不会被其他投递器重复领取，
+// 没有到期的投递时返回 nil。返回的 NextAttemptAt 为租约的到期时间
+func ClaimWebhookDelivery(now time.Time, lease time.Duration) (*WebhookDelivery, error) {
+	// 取整到秒，避免数据库时间精度不同导致之后按租约到期时间的比较失败
+	leaseUntil := now.Add(lease).Truncate(time.Second)
+	for {
+		var due WebhookDelivery
+		err := DB.Preload("Webhook").Where("status = ? AND next_attempt_at <= ?", webhook.StatusPending, now).
+			Order("next_attempt_at").Order("id").First(&due).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		result := DB.Model(&WebhookDelivery{}).Where("id = ? AND status = ? AND next_attempt_at = ?", due.ID, webhook.StatusPending, due.NextAttemptAt).
+			UpdateColumn("next_attempt_at", leaseUntil)
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		// 被其他投递器抢先领取时领取下一个
+		if result.RowsAffected == 1 {
+			due.NextAttemptAt = leaseUntil
+			return &due, nil
+		}
+	}
+}
+
+// leasedWebhookDelivery 只匹配租约仍由本次领取持有的投递
+func leasedWebhookDelivery(tx *gorm.DB, delivery *WebhookDelivery) *gorm.DB {
+	return tx.Model(&WebhookDelivery{}).Where("id = ? AND status = ? AND next_attempt_at = ?", delivery.ID, webhook.StatusPending, delivery.NextAttemptAt)
+}
+
+// CompleteWebhookDelivery 记录一次成功的投递，租约已经失效时返回 ErrWebhookLeaseLost
+func CompleteWebhookDelivery(delivery *WebhookDelivery, statusCode int, deliveredAt time.Time) error {
+	result := leasedWebhookDelivery(DB, delivery).Updates(map[string]interface{}{
+		"status":           webhook.StatusSucceeded,
+		"attempts":         delivery.Attempts + 1,
+		"last_status_code": statusCode,
+		"last_error":       "",
+		"delivered_at":     deliveredAt,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrWebhookLeaseLost
+	}
+	return nil
+}
+
+// FailWebhookDelivery 记录一次失败的投递，nextAttemptAt 为零值时重试次数已用尽，转入 dead letter。
+// 租约已经失效时返回 ErrWebhookLeaseLost
+func FailWebhookDelivery(delivery *WebhookDelivery, statusCode int, reason string, nextAttemptAt time.Time) error {
+	attempts := delivery.Attempts + 1
+	return DB.Transaction(func(tx *gorm.DB) error {
+		updates := map[string]interface{}{
+			"attempts":         attempts,
+			"last_status_code": statusCode,
+			"last_error":       reason,
+		}
+		if nextAttemptAt.IsZero() {
+			updates["status"] = webhook.StatusDead
+		} else {
+			updates["next_attempt_at"] = nextAttemptAt
+		}
+		result := leasedWebhookDelivery(tx, delivery).Updates(updates)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookLeaseLost
+		}
+		if !nextAttemptAt.IsZero() {
+			return nil
+		}
+		return tx.Create(&WebhookDeadLetter{
+			DeliveryID: delivery.ID,
+			WebhookID:  delivery.WebhookID,
+			EventID:    delivery.EventID,
+			Event:      delivery.Event,
+			Payload:    delivery.Payload,
+			Attempts:   attempts,
+			LastError:  reason,
+		}).Error
+	})
+}
+
+// ListWebhookDeliveries 查询订阅的投递记录，最新的在前
+func ListWebhookDeliveries(name string, request *webhook.QueryDeliveries) (results []webhook.Delivery, encounterError error) {
+	var found *Webhook
+	if found, encounterError = getWebhookByName(DB, name); encounterError != nil {
+		return
+	}
+	limit := request.Limit
+	if limit <= 0 || limit > 1000 {
+		limit = 100
+	}
+	tx := DB.Where("webhook_id = ?", found.ID)
+	if request.Status != "" {
+		tx = tx.Where("status = ?", request.Status)
+	}
+	var deliveries []WebhookDelivery
+	if encounterError = tx.Order("id DESC").Limit(limit).Find(&deliveries).Error; encounterError != nil {
+		return
+	}
+	results = make([]webhook.Delivery, 0, len(deliveries))
+	for i := range deliveries {
+		delivery := webhook.Delivery{
+			ID:             deliveries[i].ID,
+			Webhook:        found.Name,
+			EventID:        deliveries[i].EventID,
+			Event:          deliveries[i].Event,
+			Status:         deliveries[i].Status,
+			Attempts:       deliveries[i].Attempts,
+			LastStatusCode: deliveries[i].LastStatusCode,
+			LastError:      deliveries[i].LastError,
+			DeliveredAt:    deliveries[i].DeliveredAt,
+			CreatedAt:      deliveries[i].CreatedAt,
+		}
+		if deliveries[i].Status == webhook.StatusPending {
+			delivery.NextAttemptAt = &deliveries[i].NextAttemptAt
+		}
+		results = append(results, delivery)
+	}
+	return results, nil
+}
+
+// ListWebhookDeadLetters 查询所有 dead letter
+func ListWebhookDeadLetters() (results []webhook.DeadLetter, encounterError error) {
+	results = make([]webhook.DeadLetter, 0)
+	var letters []WebhookDeadLetter
+	if encounterError = DB.Order("id").Find(&letters).Error; encounterError != nil {
+		return
+	}
+	names := make(map[uint]string)
+	var webhooks []Webhook
+	if encounterError = DB.Unscoped().Select("id", "name").Find(&webhooks).Error; encounterError != nil {
+		return
+	}
+	for _, found := range webhooks {
+		names[found.ID] = found.Name
+	}
+	for _, letter := range letters {
+		results = append(results, webhook.DeadLetter{
+			ID:         letter.ID,
+			DeliveryID: letter.DeliveryID,
+			Webhook:    names[letter.WebhookID],
+			EventID:    letter.EventID,
+			Event:      letter.Event,
+			Payload:    letter.Payload,
+			Attempts:   letter.Attempts,
+			LastError:  letter.LastError,
+			CreatedAt:  letter.CreatedAt,
+		})
+	}
+	return results, nil
+}
+
+// RedeliverWebhookDeadLetter 将 dead letter 重新放入投递队列，重试次数重新计算
+func RedeliverWebhookDeadLetter(id uint) error {
+	return DB.Transaction(func(tx *gorm.DB) error {
+		letter := &WebhookDeadLetter{}
+		result := tx.Where("id = ?", id).Limit(1).Find(letter)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrDeadLetterNotFound
+		}
+		if err := tx.Model(&WebhookDelivery{}).Where("id = ?", letter.DeliveryID).Updates(map[string]interface{}{
+			"status":          webhook.StatusPending,
+			"attempts":        0,
+			"next_attempt_at": time.Now().UTC(),
+		}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(letter).Error
+	})
+}
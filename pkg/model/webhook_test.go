@@ -0,0 +1,188 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+// TestWebhookEvents_FilteredBySelectorAndType 测试 target 的修改按订阅的 selector 与事件类型生成投递
+func TestWebhookEvents_FilteredBySelectorAndType(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	_, err := CreateWebhook(&webhook.Webhook{Name: "all", URL: "http://127.0.0.1/all"})
+	require.NoError(t, err)
+	_, err = CreateWebhook(&webhook.Webhook{Name: "fed", URL: "http://127.0.0.1/fed", Selector: "prom=fed"})
+	require.NoError(t, err)
+	_, err = CreateWebhook(&webhook.Webhook{Name: "deleted", URL: "http://127.0.0.1/deleted", Events: []string{webhook.EventTargetDeleted}})
+	require.NoError(t, err)
+
+	// Act
+	created, err := UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "edge"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+	require.NoError(t, ChangeTargetWithID(created[0].ID, &target.TargetChg{ScrapeTime: 60}))
+	require.NoError(t, DeleteTargetWithID(created[0].ID, 0))
+
+	// Assert
+	events := func(name string) []string {
+		found, err := getWebhookByName(db, name)
+		require.NoError(t, err)
+		var types []string
+		require.NoError(t, db.Model(&WebhookDelivery{}).Where("webhook_id = ?", found.ID).Order("id").Pluck("event", &types).Error)
+		return types
+	}
+	assert.Equal(t, []string{webhook.EventTargetCreated, webhook.EventTargetUpdated, webhook.EventTargetDeleted}, events("all"))
+	assert.Empty(t, events("fed"), "targets of other selectors are not sent")
+	assert.Equal(t, []string{webhook.EventTargetDeleted}, events("deleted"))
+
+	delivery := &WebhookDelivery{}
+	require.NoError(t, db.Where("event = ?", webhook.EventTargetUpdated).First(delivery).Error)
+	var payload webhook.Event
+	require.NoError(t, json.Unmarshal([]byte(delivery.Payload), &payload))
+	assert.Equal(t, delivery.EventID, payload.ID)
+	require.NotNil(t, payload.Target)
+	assert.Equal(t, created[0].ID, payload.Target.ID)
+	assert.Equal(t, 60, payload.Target.ScrapeTime)
+	assert.Equal(t, "http://10.0.0.1:9100", payload.Target.Address)
+}
+
+// TestWebhookEvents_SelectorRenameMatchesOldAndNew 测试 selector 重命名时订阅了旧值或新值的 webhook 都收到通知
+func TestWebhookEvents_SelectorRenameMatchesOldAndNew(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	_, err := CreateWebhook(&webhook.Webhook{Name: "old", URL: "http://127.0.0.1/old", Selector: "prom=fed", Events: []string{webhook.EventSelectorUpdated}})
+	require.NoError(t, err)
+	_, err = CreateWebhook(&webhook.Webhook{Name: "new", URL: "http://127.0.0.1/new", Selector: "prom=main", Events: []string{webhook.EventSelectorUpdated}})
+	require.NoError(t, err)
+	_, err = CreateWebhook(&webhook.Webhook{Name: "other", URL: "http://127.0.0.1/other", Selector: "prom=edge"})
+	require.NoError(t, err)
+	_, err = UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+
+	// Act
+	err = UpdateSelectorByKeyValue("prom", "fed", "prom", "main")
+
+	// Assert
+	require.NoError(t, err)
+	var deliveries []WebhookDelivery
+	require.NoError(t, db.Preload("Webhook").Order("id").Find(&deliveries).Error)
+	require.Len(t, deliveries, 2)
+	for _, delivery := range deliveries {
+		assert.Equal(t, webhook.EventSelectorUpdated, delivery.Event)
+		var payload webhook.Event
+		require.NoError(t, json.Unmarshal([]byte(delivery.Payload), &payload))
+		require.NotNil(t, payload.Selector)
+		assert.Equal(t, "prom=fed", payload.Selector.Old)
+		assert.Equal(t, "prom=main", payload.Selector.New)
+		assert.Len(t, payload.Selector.Targets, 1)
+	}
+	assert.ElementsMatch(t, []string{"old", "new"}, []string{deliveries[0].Webhook.Name, deliveries[1].Webhook.Name})
+}
+
+// TestClaimWebhookDelivery_LeaseLost 测试租约到期后投递被其他投递器领取，原投递器不能再记录结果
+func TestClaimWebhookDelivery_LeaseLost(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	_, err := CreateWebhook(&webhook.Webhook{Name: "all", URL: "http://127.0.0.1/all"})
+	require.NoError(t, err)
+	_, err = UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+	now := time.Now().UTC()
+
+	// Act
+	first, err := ClaimWebhookDelivery(now, 20*time.Second)
+	require.NoError(t, err)
+	held, err := ClaimWebhookDelivery(now.Add(time.Second), 20*time.Second)
+	require.NoError(t, err)
+	second, err := ClaimWebhookDelivery(now.Add(time.Minute), 20*time.Second)
+	require.NoError(t, err)
+	staleErr := CompleteWebhookDelivery(first, 200, now.Add(time.Minute))
+	staleFailErr := FailWebhookDelivery(first, 500, "timeout", time.Time{})
+	completeErr := CompleteWebhookDelivery(second, 200, now.Add(time.Minute))
+
+	// Assert
+	require.NotNil(t, first)
+	assert.Nil(t, held, "a leased delivery is not claimed again")
+	require.NotNil(t, second)
+	assert.Equal(t, first.ID, second.ID)
+	assert.ErrorIs(t, staleErr, ErrWebhookLeaseLost)
+	assert.ErrorIs(t, staleFailErr, ErrWebhookLeaseLost)
+	require.NoError(t, completeErr)
+
+	var stored WebhookDelivery
+	require.NoError(t, db.First(&stored, first.ID).Error)
+	assert.Equal(t, webhook.StatusSucceeded, stored.Status)
+	assert.Equal(t, 1, stored.Attempts)
+	var letters int64
+	require.NoError(t, db.Model(&WebhookDeadLetter{}).Count(&letters).Error)
+	assert.Zero(t, letters)
+}
+
+// TestCreateWebhook_Validation 测试非法的 URL、selector 与事件类型被拒绝，名称不能重复
+func TestCreateWebhook_Validation(t *testing.T) {
+	// Arrange
+	_ = SetupTestDB(t)
+	_, err := CreateWebhook(&webhook.Webhook{Name: "cmdb", URL: "https://cmdb.example.com/hooks", Secret: "s3cret"})
+	require.NoError(t, err)
+
+	// Act
+	_, schemeErr := CreateWebhook(&webhook.Webhook{Name: "ftp", URL: "ftp://example.com"})
+	_, selectorErr := CreateWebhook(&webhook.Webhook{Name: "bad", URL: "http://example.com", Selector: "prom"})
+	_, eventErr := CreateWebhook(&webhook.Webhook{Name: "bad", URL: "http://example.com", Events: []string{"target.moved"}})
+	_, existsErr := CreateWebhook(&webhook.Webhook{Name: "cmdb", URL: "http://example.com"})
+	updated, updateErr := UpdateWebhook("cmdb", &webhook.Webhook{Name: "cmdb", URL: "https://cmdb2.example.com/hooks"})
+
+	// Assert
+	assert.Error(t, schemeErr)
+	assert.Error(t, selectorErr)
+	assert.Error(t, eventErr)
+	assert.ErrorIs(t, existsErr, ErrWebhookExists)
+	require.NoError(t, updateErr)
+	assert.True(t, updated.HasSecret, "empty secret keeps the current one")
+	assert.Equal(t, "https://cmdb2.example.com/hooks", updated.URL)
+}
+
+// TestRotateEncryptionKey_WebhookSecret 测试明文签名密钥在配置主密钥后被加密，轮换主密钥后使用新密钥解密
+func TestRotateEncryptionKey_WebhookSecret(t *testing.T) {
+	// Arrange
+	db := SetupTestDB(t)
+	_, err := CreateWebhook(&webhook.Webhook{Name: "cmdb", URL: "http://cmdb.example.com/hook", Secret: "s3cret"})
+	require.NoError(t, err)
+	from := setupTestCipher(t, 1)
+	require.NoError(t, EncryptCredentials(db))
+	to, err := secret.NewCipher(bytes.Repeat([]byte{2}, 32))
+	require.NoError(t, err)
+
+	// Act
+	count, err := RotateEncryptionKey(db, from, to)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+	var raw string
+	require.NoError(t, db.Table(webhookTableName).Where("name = ?", "cmdb").Pluck("secret", &raw).Error)
+	assert.True(t, secret.IsEncrypted(raw))
+	_, err = from.Decrypt(raw)
+	assert.Error(t, err)
+	secret.SetDefault(to)
+	found := &Webhook{}
+	require.NoError(t, db.Where("name = ?", "cmdb").First(found).Error)
+	assert.Equal(t, "s3cret", string(found.Secret))
+}
@@ -0,0 +1,161 @@
+// Package relabel 实现与 Prometheus relabel_configs 语义一致的标签改写，
+// 在 SD 返回结果之前作用于 target 的标签 (包括 __address__ 等 __ 开头的元标签)
+package relabel
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Action 规则的动作
+type Action string
+
+const (
+	Replace   Action = "replace"
+	Keep      Action = "keep"
+	Drop      Action = "drop"
+	LabelMap  Action = "labelmap"
+	LabelDrop Action = "labeldrop"
+	LabelKeep Action = "labelkeep"
+)
+
+// 与 Prometheus 的默认值一致
+const (
+	DefaultSeparator   = ";"
+	DefaultRegex       = "(.*)"
+	DefaultReplacement = "$1"
+)
+
+var labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// Config 一条规则，Separator、Regex 与 Action 为空时使用默认值，Replacement 按原样使用
+type Config struct {
+	SourceLabels []string
+	Separator    string
+	Regex        string
+	TargetLabel  string
+	Replacement  string
+	Action       Action
+}
+
+// Rule 编译后的规则
+type Rule struct {
+	Config
+	regex *regexp.Regexp
+}
+
+// Compile 校验规则并编译正则，正则与 Prometheus 一样完整匹配
+func Compile(conf Config) (*Rule, error) {
+	if conf.Separator == "" {
+		conf.Separator = DefaultSeparator
+	}
+	if conf.Regex == "" {
+		conf.Regex = DefaultRegex
+	}
+	if conf.Action == "" {
+		conf.Action = Replace
+	}
+	regex, err := regexp.Compile("^(?:" + conf.Regex + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", conf.Regex, err)
+	}
+	for _, name := range conf.SourceLabels {
+		if !labelNameRe.MatchString(name) {
+			return nil, fmt.Errorf("invalid source label %q", name)
+		}
+	}
+	switch conf.Action {
+	case Replace:
+		if conf.TargetLabel == "" {
+			return nil, fmt.Errorf("target_label is required for action replace")
+		}
+	case Keep, Drop:
+		if len(conf.SourceLabels) == 0 {
+			return nil, fmt.Errorf("source_labels is required for action %s", conf.Action)
+		}
+	case LabelMap:
+	case LabelDrop, LabelKeep:
+		if len(conf.SourceLabels) > 0 || conf.TargetLabel != "" {
+			return nil, fmt.Errorf("source_labels and target_label are not allowed for action %s", conf.Action)
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q, expected replace, keep, drop, labelmap, labeldrop or labelkeep", conf.Action)
+	}
+	return &Rule{Config: conf, regex: regex}, nil
+}
+
+// Process 依次应用规则，返回改写后的标签，第二个返回值为 false 时 target 被丢弃
+// 传入的标签不会被修改
+func Process(labels map[string]string, rules ...*Rule) (map[string]string, bool) {
+	result := make(map[string]string, len(labels))
+	for name, value := range labels {
+		result[name] = value
+	}
+	for _, rule := range rules {
+		if !rule.apply(result) {
+			return nil, false
+		}
+	}
+	return result, true
+}
+
+func (r *Rule) apply(labels map[string]string) bool {
+	values := make([]string, 0, len(r.SourceLabels))
+	for _, name := range r.SourceLabels {
+		values = append(values, labels[name])
+	}
+	value := strings.Join(values, r.Separator)
+
+	switch r.Action {
+	case Keep:
+		return r.regex.MatchString(value)
+	case Drop:
+		return !r.regex.MatchString(value)
+	case Replace:
+		indexes := r.regex.FindStringSubmatchIndex(value)
+		if indexes == nil {
+			return true
+		}
+		target := string(r.regex.ExpandString(nil, r.TargetLabel, value, indexes))
+		if !labelNameRe.MatchString(target) {
+			return true
+		}
+		replacement := string(r.regex.ExpandString(nil, r.Replacement, value, indexes))
+		if replacement == "" {
+			delete(labels, target)
+		} else {
+			labels[target] = replacement
+		}
+	case LabelMap:
+		// 按名称排序，结果与 map 的遍历顺序无关
+		for _, name := range sortedNames(labels) {
+			if r.regex.MatchString(name) {
+				labels[r.regex.ReplaceAllString(name, r.Replacement)] = labels[name]
+			}
+		}
+	case LabelDrop:
+		for name := range labels {
+			if r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	case LabelKeep:
+		for name := range labels {
+			if !r.regex.MatchString(name) {
+				delete(labels, name)
+			}
+		}
+	}
+	return true
+}
+
+func sortedNames(labels map[string]string) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
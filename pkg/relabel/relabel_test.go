@@ -0,0 +1,90 @@
+package relabel
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func mustCompile(t *testing.T, conf Config) *Rule {
+	t.Helper()
+	rule, err := Compile(conf)
+	require.NoError(t, err)
+	return rule
+}
+
+// TestProcess_Replace 测试 replace 使用捕获组改写标签，替换结果为空时删除标签
+func TestProcess_Replace(t *testing.T) {
+	// Arrange
+	labels := map[string]string{"__address__": "10.0.0.1:9100", "env": "production", "dc": "bj"}
+	rules := []*Rule{
+		mustCompile(t, Config{SourceLabels: []string{"__address__"}, Regex: `([^:]+):\d+`, TargetLabel: "host", Replacement: "$1"}),
+		mustCompile(t, Config{SourceLabels: []string{"env"}, Regex: "prod.*", TargetLabel: "env", Replacement: "prod"}),
+		mustCompile(t, Config{SourceLabels: []string{"dc", "env"}, Regex: "bj;(.*)", TargetLabel: "${1}_dc", Replacement: "beijing"}),
+		mustCompile(t, Config{SourceLabels: []string{"missing"}, TargetLabel: "dc", Replacement: ""}),
+	}
+
+	// Act
+	result, kept := Process(labels, rules...)
+
+	// Assert
+	require.True(t, kept)
+	assert.Equal(t, map[string]string{"__address__": "10.0.0.1:9100", "env": "prod", "host": "10.0.0.1", "prod_dc": "beijing"}, result)
+	assert.Equal(t, "production", labels["env"], "input labels must not be modified")
+}
+
+// TestProcess_KeepDrop 测试 keep 与 drop 按拼接后的源标签完整匹配
+func TestProcess_KeepDrop(t *testing.T) {
+	// Arrange
+	keep := mustCompile(t, Config{SourceLabels: []string{"env"}, Regex: "prod", Action: Keep})
+	drop := mustCompile(t, Config{SourceLabels: []string{"team", "env"}, Regex: "legacy;.*", Action: Drop})
+
+	// Act
+	_, prodKept := Process(map[string]string{"env": "prod", "team": "infra"}, keep, drop)
+	_, partialKept := Process(map[string]string{"env": "production"}, keep)
+	_, legacyKept := Process(map[string]string{"env": "prod", "team": "legacy"}, keep, drop)
+
+	// Assert
+	assert.True(t, prodKept)
+	assert.False(t, partialKept, "regex must match the whole value")
+	assert.False(t, legacyKept)
+}
+
+// TestProcess_LabelMapAndDrop 测试 labelmap 复制匹配的标签，labeldrop 与 labelkeep 按名称删除标签
+func TestProcess_LabelMapAndDrop(t *testing.T) {
+	// Arrange
+	labels := map[string]string{"__meta_team": "infra", "__meta_env": "prod", "tmp_id": "1", "instance": "a"}
+	rules := []*Rule{
+		mustCompile(t, Config{Regex: "__meta_(.+)", Replacement: "$1", Action: LabelMap}),
+		mustCompile(t, Config{Regex: "tmp_.*", Action: LabelDrop}),
+		mustCompile(t, Config{Regex: "__meta_.*|team|env|instance", Action: LabelKeep}),
+	}
+
+	// Act
+	result, kept := Process(labels, rules...)
+
+	// Assert
+	require.True(t, kept)
+	assert.Equal(t, map[string]string{"__meta_team": "infra", "__meta_env": "prod", "team": "infra", "env": "prod", "instance": "a"}, result)
+}
+
+// TestCompile_Invalid 测试无效的规则在编译时被拒绝
+func TestCompile_Invalid(t *testing.T) {
+	for name, conf := range map[string]Config{
+		"regex":          {TargetLabel: "a", Regex: "("},
+		"replace target": {SourceLabels: []string{"a"}},
+		"keep source":    {Action: Keep},
+		"labeldrop":      {Action: LabelDrop, TargetLabel: "a"},
+		"action":         {Action: "hashmod"},
+		"source":         {SourceLabels: []string{"a-b"}, TargetLabel: "a"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// Act
+			_, err := Compile(conf)
+
+			// Assert
+			assert.Error(t, err)
+		})
+	}
+}
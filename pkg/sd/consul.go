@@ -0,0 +1,132 @@
+package sd
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+const (
+	ConsulNodeName   = "pantheon"
+	ConsulDatacenter = "pantheon"
+)
+
+var consulMetaKeyReplacer = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// ConsulService 对应 Consul catalog 中的一个服务实例
+type ConsulService struct {
+	ID      string
+	Service string
+	Tags    []string
+	Address string
+	Port    int
+	Meta    map[string]string
+}
+
+// consulServiceKeyEscaper 转义 key 中的分隔符，使不同的 selector 不会得到相同的服务名
+var consulServiceKeyEscaper = strings.NewReplacer("%", "%25", "=", "%3D")
+
+// ConsulServiceName 将 selector 映射为 Consul 服务名，格式为 key=value，
+// key 中的 = 与 % 被转义，因此第一个 = 之前总是完整的 key
+func ConsulServiceName(key, value string) string {
+	return fmt.Sprintf("%s=%s", consulServiceKeyEscaper.Replace(key), value)
+}
+
+// ConsulServices 将 selector 的 SD 输出转换为 Consul 服务实例
+// 普通 label 同时作为 tag (key=value) 与 meta 输出，instance 只输出到 meta；
+// __metrics_path__、__scheme__ 等元信息与 __param_* 以去掉前缀后的 meta 输出，
+// 以便 consul_sd_configs 通过 relabel 还原
+func ConsulServices(serviceName string, targets []model.TargetList) []ConsulService {
+	services := make([]ConsulService, 0, len(targets))
+	for _, target := range targets {
+		if len(target.Targets) == 0 {
+			continue
+		}
+
+		service := ConsulService{
+			Service: serviceName,
+			Tags:    []string{},
+			Meta:    map[string]string{},
+		}
+		service.Address, service.Port = splitAddress(target.Targets[0], target.Labels["__scheme__"])
+
+		keys := make([]string, 0, len(target.Labels))
+		for key := range target.Labels {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			value := target.Labels[key]
+			switch {
+			case strings.HasPrefix(key, "__param_"):
+				service.Meta[consulMetaKey("param_"+strings.TrimPrefix(key, "__param_"))] = value
+			case strings.HasPrefix(key, "__") && strings.HasSuffix(key, "__"):
+				service.Meta[consulMetaKey(strings.Trim(key, "_"))] = value
+			case strings.HasPrefix(key, "__"):
+				continue
+			case key == "instance":
+				service.Meta[key] = value
+			default:
+				service.Meta[consulMetaKey(key)] = value
+				service.Tags = append(service.Tags, fmt.Sprintf("%s=%s", key, value))
+			}
+		}
+
+		sum := sha1.Sum([]byte(fmt.Sprintf("%s|%v", target.Targets[0], keysAndValues(keys, target.Labels))))
+		service.ID = fmt.Sprintf("%s-%s", serviceName, hex.EncodeToString(sum[:6]))
+		services = append(services, service)
+	}
+
+	sort.Slice(services, func(i, j int) bool {
+		return services[i].ID < services[j].ID
+	})
+	return services
+}
+
+// ConsulServiceTags 汇总服务所有实例的 tag，用于 /v1/catalog/services
+func ConsulServiceTags(services []ConsulService) []string {
+	seen := map[string]bool{}
+	tags := []string{}
+	for _, service := range services {
+		for _, tag := range service.Tags {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+		}
+	}
+	sort.Strings(tags)
+	return tags
+}
+
+func splitAddress(address, scheme string) (string, int) {
+	host, portString, err := net.SplitHostPort(address)
+	if err != nil {
+		if scheme == "https" {
+			return address, 443
+		}
+		return address, 80
+	}
+	port, _ := strconv.Atoi(portString)
+	return host, port
+}
+
+func consulMetaKey(key string) string {
+	return consulMetaKeyReplacer.ReplaceAllString(key, "_")
+}
+
+func keysAndValues(keys []string, labels map[string]string) []string {
+	pairs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, key+"="+labels[key])
+	}
+	return pairs
+}
@@ -0,0 +1,77 @@
+package sd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// TestConsulServices 测试 SD 输出转换为 Consul 服务实例
+func TestConsulServices(t *testing.T) {
+	// Arrange
+	targets := []model.TargetList{
+		{
+			Targets: []string{"10.0.0.1:9100"},
+			Labels: map[string]string{
+				"instance":         "10.0.0.1:9100",
+				"env":              "prod",
+				"__metrics_path__": "/metrics",
+				"__scheme__":       "http",
+				"__param_module":   "http_2xx",
+			},
+		},
+		{
+			Targets: []string{"exporter.example.com"},
+			Labels:  map[string]string{"__scheme__": "https"},
+		},
+	}
+
+	// Act
+	services := ConsulServices(ConsulServiceName("prom", "fed"), targets)
+
+	// Assert
+	require.Len(t, services, 2)
+	byAddress := map[string]ConsulService{}
+	for _, service := range services {
+		assert.Equal(t, "prom=fed", service.Service)
+		byAddress[service.Address] = service
+	}
+
+	node := byAddress["10.0.0.1"]
+	assert.Equal(t, 9100, node.Port)
+	assert.Contains(t, node.Tags, "env=prod")
+	assert.Equal(t, "/metrics", node.Meta["metrics_path"])
+	assert.Equal(t, "http_2xx", node.Meta["param_module"])
+	assert.Equal(t, "prod", node.Meta["env"])
+
+	assert.Equal(t, 443, byAddress["exporter.example.com"].Port, "https target without port should default to 443")
+	assert.Equal(t, "10.0.0.1:9100", node.Meta["instance"])
+	assert.Equal(t, []string{"env=prod"}, ConsulServiceTags(services))
+}
+
+// TestConsulServiceName_Distinct 测试 key 或 value 中包含分隔符的 selector 得到不同的服务名
+func TestConsulServiceName_Distinct(t *testing.T) {
+	// Arrange
+	selectors := [][2]string{
+		{"a-b", "c"}, {"a", "b-c"},
+		{"a=b", "c"}, {"a", "b=c"},
+		{"a%3Db", "c"}, {"a", "b"},
+	}
+
+	// Act
+	names := map[string][2]string{}
+	for _, selector := range selectors {
+		name := ConsulServiceName(selector[0], selector[1])
+		if previous, exists := names[name]; exists {
+			t.Fatalf("selectors %v and %v share the service name %s", previous, selector, name)
+		}
+		names[name] = selector
+	}
+
+	// Assert
+	assert.Len(t, names, len(selectors))
+	assert.Equal(t, "prom=fed", ConsulServiceName("prom", "fed"))
+}
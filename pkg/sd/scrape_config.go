@@ -0,0 +1,111 @@
+package sd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+const (
+	FormatPrometheus = "prometheus"
+	FormatVMAgent    = "vmagent"
+)
+
+var jobNameReplacer = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// scrapeConfigTemplate 生成的 scrape_config 中，http_sd 返回的 __scheme__、__metrics_path__、
+// __scrape_interval__ 与 __param_* 会被 Prometheus/vmagent 直接使用；
+// 对于走代理的 target，__address__ 指向 pantheon proxy，真实地址通过 __param_host/__param_port 传递
+// 模板中的字符串都经过 quote，selector 中的 :、# 与引号不会破坏生成的 YAML
+var scrapeConfigTemplate = template.Must(template.New("scrape_config").Funcs(template.FuncMap{"quote": quote}).Parse(`# Generated by pantheon for selector {{ quote (printf "%s=%s" .Key .Value) }}
+{{- if eq .Format "vmagent" }}
+# Load with: vmagent -promscrape.config=<this file>
+{{- end }}
+scrape_configs:
+  - job_name: {{ quote .JobName }}
+    http_sd_configs:
+      - url: {{ quote .URL }}
+        refresh_interval: 30s
+        # Credentials for pantheon-server, uncomment one if the SD endpoint requires authentication.
+        # basic_auth:
+        #   username: <username>
+        #   password: <password>
+{{- if eq .Format "vmagent" }}
+        # bearer_token_file: <path to token file>
+{{- else }}
+        # authorization:
+        #   type: Bearer
+        #   credentials_file: <path to token file>
+{{- end }}
+    relabel_configs:
+      # Proxied targets are scraped through pantheon, keep the real upstream as instance.
+      - source_labels: [__param_host, __param_port]
+        regex: "(.+);(.+)"
+        target_label: instance
+        replacement: "${1}:${2}"
+`))
+
+// quote 将字符串渲染为 YAML 双引号字符串，JSON 字符串同时是合法的 YAML 标量
+func quote(value string) (string, error) {
+	quoted, err := json.Marshal(value)
+	return string(quoted), err
+}
+
+type scrapeConfigData struct {
+	Format  string
+	Key     string
+	Value   string
+	JobName string
+	URL     string
+}
+
+// ParseSelector 解析 key=value 形式的 selector
+func ParseSelector(selector string) (string, string, error) {
+	kv := strings.SplitN(selector, "=", 2)
+	if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+		return "", "", fmt.Errorf("invalid selector format: %s. Expected format: key=value", selector)
+	}
+	return kv[0], kv[1], nil
+}
+
+// RenderScrapeConfig 为 selector 生成可以直接使用的 Prometheus 或 vmagent scrape_config
+func RenderScrapeConfig(serverURL, selector, format, jobName string) ([]byte, error) {
+	key, value, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "" {
+		format = FormatPrometheus
+	}
+	if format != FormatPrometheus && format != FormatVMAgent {
+		return nil, fmt.Errorf("invalid format: %s. Valid values are '%s' or '%s'", format, FormatPrometheus, FormatVMAgent)
+	}
+
+	if jobName == "" {
+		jobName = jobNameReplacer.ReplaceAllString(fmt.Sprintf("pantheon-%s-%s", key, value), "_")
+	}
+
+	data := scrapeConfigData{
+		Format:  format,
+		Key:     key,
+		Value:   value,
+		JobName: jobName,
+		URL:     SelectorURL(serverURL, key, value),
+	}
+
+	var buf bytes.Buffer
+	if err := scrapeConfigTemplate.Execute(&buf, data); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// SelectorURL 返回 selector 对应的 http_sd 地址
+func SelectorURL(serverURL, key, value string) string {
+	return fmt.Sprintf("%s/ph/v1/targets/selector/%s/%s", strings.TrimRight(serverURL, "/"), url.PathEscape(key), url.PathEscape(value))
+}
@@ -0,0 +1,71 @@
+package sd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+// TestRenderScrapeConfig_Prometheus 测试生成的 scrape_config 可以被解析并指向正确的 SD 地址
+func TestRenderScrapeConfig_Prometheus(t *testing.T) {
+	// Act
+	data, err := RenderScrapeConfig("http://10.0.0.1:8899/", "prom=fed", FormatPrometheus, "")
+
+	// Assert
+	require.NoError(t, err)
+	var parsed struct {
+		ScrapeConfigs []struct {
+			JobName       string `yaml:"job_name"`
+			HTTPSDConfigs []struct {
+				URL string `yaml:"url"`
+			} `yaml:"http_sd_configs"`
+			RelabelConfigs []map[string]interface{} `yaml:"relabel_configs"`
+		} `yaml:"scrape_configs"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &parsed), "generated config should be valid YAML")
+	require.Len(t, parsed.ScrapeConfigs, 1)
+	assert.Equal(t, "pantheon-prom-fed", parsed.ScrapeConfigs[0].JobName)
+	require.Len(t, parsed.ScrapeConfigs[0].HTTPSDConfigs, 1)
+	assert.Equal(t, "http://10.0.0.1:8899/ph/v1/targets/selector/prom/fed", parsed.ScrapeConfigs[0].HTTPSDConfigs[0].URL)
+	require.Len(t, parsed.ScrapeConfigs[0].RelabelConfigs, 1)
+	relabel := parsed.ScrapeConfigs[0].RelabelConfigs[0]
+	assert.Equal(t, []interface{}{"__param_host", "__param_port"}, relabel["source_labels"])
+	assert.Regexp(t, "^"+relabel["regex"].(string)+"$", "10.0.0.2;9100", "rule should match the labels of proxied targets")
+}
+
+// TestRenderScrapeConfig_QuotesSelector 测试 selector 与 job 名称中的特殊字符不会破坏生成的 YAML
+func TestRenderScrapeConfig_QuotesSelector(t *testing.T) {
+	// Act
+	data, err := RenderScrapeConfig("http://127.0.0.1:8899", "team=a: b #c\"d\nscrape_configs:", FormatPrometheus, "job: \"x\" # y")
+
+	// Assert
+	require.NoError(t, err)
+	var parsed struct {
+		ScrapeConfigs []struct {
+			JobName       string `yaml:"job_name"`
+			HTTPSDConfigs []struct {
+				URL string `yaml:"url"`
+			} `yaml:"http_sd_configs"`
+		} `yaml:"scrape_configs"`
+	}
+	require.NoError(t, yaml.Unmarshal(data, &parsed), "generated config should be valid YAML")
+	require.Len(t, parsed.ScrapeConfigs, 1)
+	assert.Equal(t, "job: \"x\" # y", parsed.ScrapeConfigs[0].JobName)
+	require.Len(t, parsed.ScrapeConfigs[0].HTTPSDConfigs, 1)
+	assert.Equal(t, SelectorURL("http://127.0.0.1:8899", "team", "a: b #c\"d\nscrape_configs:"), parsed.ScrapeConfigs[0].HTTPSDConfigs[0].URL)
+}
+
+// TestRenderScrapeConfig_Invalid 测试非法参数
+func TestRenderScrapeConfig_Invalid(t *testing.T) {
+	_, err := RenderScrapeConfig("http://127.0.0.1:8899", "prom", FormatPrometheus, "")
+	assert.Error(t, err, "selector without value should be rejected")
+
+	_, err = RenderScrapeConfig("http://127.0.0.1:8899", "prom=fed", "telegraf", "")
+	assert.Error(t, err, "unknown format should be rejected")
+
+	data, err := RenderScrapeConfig("http://127.0.0.1:8899", "prom=fed", FormatVMAgent, "node")
+	require.NoError(t, err)
+	assert.Contains(t, string(data), `job_name: "node"`)
+}
@@ -0,0 +1,295 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/config"
+)
+
+// 密文格式: enc:v1:<key id>:<被主密钥加密的数据密钥>:<被数据密钥加密的内容>
+// 每个值使用独立的随机数据密钥 (DEK)，DEK 由主密钥 (KEK) 加密后与密文一起保存，
+// 轮换主密钥时只需要重新加密 DEK，无需重新加密内容
+const (
+	encryptedPrefix = "enc:v1:"
+	keySize         = 32
+)
+
+var (
+	ErrKeyMismatch = errors.New("value was encrypted with a different master key")
+	ErrNoKey       = errors.New("no master key configured")
+
+	mu            sync.RWMutex
+	defaultCipher *Cipher
+)
+
+// Cipher 使用主密钥进行信封加密
+type Cipher struct {
+	kek []byte
+	kid string
+}
+
+// NewCipher 根据 32 字节主密钥创建 Cipher
+func NewCipher(key []byte) (*Cipher, error) {
+	if len(key) != keySize {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", keySize, len(key))
+	}
+	sum := sha256.Sum256(key)
+	return &Cipher{kek: key, kid: hex.EncodeToString(sum[:4])}, nil
+}
+
+// KeyID 返回主密钥的标识，用于判断密文由哪个主密钥加密
+func (c *Cipher) KeyID() string {
+	return c.kid
+}
+
+// Encrypt 加密明文，空字符串不加密
+func (c *Cipher) Encrypt(plaintext string) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dek := make([]byte, keySize)
+	if _, err := rand.Read(dek); err != nil {
+		return "", err
+	}
+	wrapped, err := seal(c.kek, dek)
+	if err != nil {
+		return "", err
+	}
+	ciphertext, err := seal(dek, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		encryptedPrefix + c.kid,
+		base64.RawStdEncoding.EncodeToString(wrapped),
+		base64.RawStdEncoding.EncodeToString(ciphertext),
+	}, ":"), nil
+}
+
+// Decrypt 解密密文，未加密的值原样返回，兼容加密之前写入的数据
+func (c *Cipher) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return value, nil
+	}
+	kid, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	if kid != c.kid {
+		return "", ErrKeyMismatch
+	}
+	dek, err := open(c.kek, wrapped)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := open(dek, ciphertext)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// Rewrap 使用新的主密钥重新加密数据密钥，内容密文保持不变
+func (c *Cipher) Rewrap(value string, to *Cipher) (string, error) {
+	if !IsEncrypted(value) {
+		return to.Encrypt(value)
+	}
+	kid, wrapped, ciphertext, err := parse(value)
+	if err != nil {
+		return "", err
+	}
+	if kid != c.kid {
+		return "", ErrKeyMismatch
+	}
+	dek, err := open(c.kek, wrapped)
+	if err != nil {
+		return "", err
+	}
+	rewrapped, err := seal(to.kek, dek)
+	if err != nil {
+		return "", err
+	}
+	return strings.Join([]string{
+		encryptedPrefix + to.kid,
+		base64.RawStdEncoding.EncodeToString(rewrapped),
+		base64.RawStdEncoding.EncodeToString(ciphertext),
+	}, ":"), nil
+}
+
+// Fingerprint 返回明文的带密钥摘要，用于在不解密的情况下查找相同内容
+func (c *Cipher) Fingerprint(plaintext string) string {
+	mac := hmac.New(sha256.New, c.kek)
+	mac.Write([]byte("fingerprint:"))
+	mac.Write([]byte(plaintext))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// IsEncrypted 判断值是否为密文
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, encryptedPrefix)
+}
+
+// LoadKey 从 key_file 或 key_env 指定的环境变量读取主密钥
+// 内容可以是 32 字节原始数据、64 位十六进制或 base64 编码
+func LoadKey(conf config.EncryptionConfig) ([]byte, error) {
+	var raw []byte
+	switch {
+	case conf.KeyFile != "":
+		data, err := os.ReadFile(conf.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read key file: %w", err)
+		}
+		raw = data
+	case conf.KeyEnv != "":
+		value, exists := os.LookupEnv(conf.KeyEnv)
+		if !exists {
+			return nil, fmt.Errorf("environment variable %s is not set", conf.KeyEnv)
+		}
+		raw = []byte(value)
+	default:
+		return nil, ErrNoKey
+	}
+	return decodeKey(raw)
+}
+
+// LoadCipher 根据配置创建 Cipher
+func LoadCipher(conf config.EncryptionConfig) (*Cipher, error) {
+	key, err := LoadKey(conf)
+	if err != nil {
+		return nil, err
+	}
+	return NewCipher(key)
+}
+
+// Init 根据配置初始化全局 Cipher，未配置主密钥时凭据以明文保存
+func Init(conf config.EncryptionConfig) error {
+	c, err := LoadCipher(conf)
+	if errors.Is(err, ErrNoKey) {
+		klog.Warning("No encryption key configured, credentials will be stored in plaintext. Set [encryption] key_file or key_env")
+		SetDefault(nil)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	SetDefault(c)
+	return nil
+}
+
+// SetDefault 设置全局 Cipher
+func SetDefault(c *Cipher) {
+	mu.Lock()
+	defer mu.Unlock()
+	defaultCipher = c
+}
+
+// Default 返回全局 Cipher，未配置时为 nil
+func Default() *Cipher {
+	mu.RLock()
+	defer mu.RUnlock()
+	return defaultCipher
+}
+
+// Encrypt 使用全局 Cipher 加密，未配置主密钥时返回明文
+func Encrypt(plaintext string) (string, error) {
+	if c := Default(); c != nil {
+		return c.Encrypt(plaintext)
+	}
+	return plaintext, nil
+}
+
+// Decrypt 使用全局 Cipher 解密
+func Decrypt(value string) (string, error) {
+	if c := Default(); c != nil {
+		return c.Decrypt(value)
+	}
+	if IsEncrypted(value) {
+		return "", ErrNoKey
+	}
+	return value, nil
+}
+
+// Fingerprint 使用全局 Cipher 计算摘要，未配置主密钥时退化为 sha256
+func Fingerprint(plaintext string) string {
+	if c := Default(); c != nil {
+		return c.Fingerprint(plaintext)
+	}
+	sum := sha256.Sum256([]byte("fingerprint:" + plaintext))
+	return hex.EncodeToString(sum[:])
+}
+
+func decodeKey(raw []byte) ([]byte, error) {
+	if len(raw) == keySize {
+		return raw, nil
+	}
+	text := strings.TrimSpace(string(raw))
+	if key, err := hex.DecodeString(text); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	if key, err := base64.StdEncoding.DecodeString(text); err == nil && len(key) == keySize {
+		return key, nil
+	}
+	return nil, fmt.Errorf("master key must be %d bytes, hex or base64 encoded", keySize)
+}
+
+func parse(value string) (kid string, wrapped, ciphertext []byte, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, encryptedPrefix), ":")
+	if len(parts) != 3 {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value")
+	}
+	if wrapped, err = base64.RawStdEncoding.DecodeString(parts[1]); err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	if ciphertext, err = base64.RawStdEncoding.DecodeString(parts[2]); err != nil {
+		return "", nil, nil, fmt.Errorf("malformed encrypted value: %w", err)
+	}
+	return parts[0], wrapped, ciphertext, nil
+}
+
+func seal(key, plaintext []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func open(key, data []byte) ([]byte, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("malformed encrypted value")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt value: %w", err)
+	}
+	return plaintext, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
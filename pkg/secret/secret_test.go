@@ -0,0 +1,118 @@
+package secret
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestCipher(t *testing.T, fill byte) *Cipher {
+	t.Helper()
+	c, err := NewCipher(bytes.Repeat([]byte{fill}, keySize))
+	require.NoError(t, err)
+	return c
+}
+
+// TestCipher_RoundTrip 测试加密后可以解密，且每次加密结果不同
+func TestCipher_RoundTrip(t *testing.T) {
+	// Arrange
+	c := newTestCipher(t, 1)
+
+	// Act
+	first, err := c.Encrypt("user:pass")
+	require.NoError(t, err)
+	second, err := c.Encrypt("user:pass")
+	require.NoError(t, err)
+	plaintext, err := c.Decrypt(first)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "user:pass", plaintext)
+	assert.True(t, IsEncrypted(first))
+	assert.NotContains(t, first, "user")
+	assert.NotEqual(t, first, second, "each value should use its own data key")
+}
+
+// TestCipher_PlaintextPassthrough 测试加密之前写入的明文原样返回
+func TestCipher_PlaintextPassthrough(t *testing.T) {
+	// Arrange
+	c := newTestCipher(t, 1)
+
+	// Act
+	plaintext, err := c.Decrypt("token")
+	empty, emptyErr := c.Encrypt("")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "token", plaintext)
+	require.NoError(t, emptyErr)
+	assert.Empty(t, empty)
+}
+
+// TestCipher_Rewrap 测试轮换主密钥后旧密钥不能再解密
+func TestCipher_Rewrap(t *testing.T) {
+	// Arrange
+	from := newTestCipher(t, 1)
+	to := newTestCipher(t, 2)
+	encrypted, err := from.Encrypt("token")
+	require.NoError(t, err)
+
+	// Act
+	rewrapped, err := from.Rewrap(encrypted, to)
+
+	// Assert
+	require.NoError(t, err)
+	plaintext, err := to.Decrypt(rewrapped)
+	require.NoError(t, err)
+	assert.Equal(t, "token", plaintext)
+	_, err = from.Decrypt(rewrapped)
+	assert.ErrorIs(t, err, ErrKeyMismatch)
+}
+
+// TestDecrypt_NoKey 测试未配置主密钥时无法读取密文
+func TestDecrypt_NoKey(t *testing.T) {
+	// Arrange
+	encrypted, err := newTestCipher(t, 1).Encrypt("token")
+	require.NoError(t, err)
+	SetDefault(nil)
+
+	// Act
+	_, err = Decrypt(encrypted)
+
+	// Assert
+	assert.ErrorIs(t, err, ErrNoKey)
+}
+
+// TestDecodeKey 测试主密钥支持原始、十六进制和 base64 格式
+func TestDecodeKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, keySize)
+	testCases := []struct {
+		name    string
+		raw     []byte
+		wantErr bool
+	}{
+		{name: "raw", raw: key},
+		{name: "hex", raw: []byte(hex.EncodeToString(key) + "\n")},
+		{name: "base64", raw: []byte(base64.StdEncoding.EncodeToString(key))},
+		{name: "too short", raw: []byte("short"), wantErr: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			decoded, err := decodeKey(tc.raw)
+
+			// Assert
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, key, decoded)
+		})
+	}
+}
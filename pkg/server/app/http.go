@@ -1,8 +1,12 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
+	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"k8s.io/klog/v2"
@@ -11,22 +15,36 @@ import (
 	"github.com/cylonchau/pantheon/pkg/server/router"
 )
 
-var http *gin.Engine
-var stopCh = make(chan struct{})
+// shutdownTimeout 退出时等待进行中的请求完成的最长时间
+const shutdownTimeout = 10 * time.Second
+
+var engine *gin.Engine
 
 func init() {
 	gin.DefaultWriter = ioutil.Discard
 	gin.DisableConsoleColor()
 }
 
-func NewHTTPSever() (err error) {
-	http = gin.New()
-	router.RegisteredRouter(http)
+// NewHTTPSever 启动 HTTP 服务，stopCh 关闭时优雅退出
+func NewHTTPSever(stopCh <-chan struct{}) (err error) {
+	engine = gin.New()
+	router.RegisteredRouter(engine)
+	server := &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", config.CONFIG.Address, config.CONFIG.Port),
+		Handler: engine,
+	}
+	go func() {
+		<-stopCh
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			klog.Errorf("Failed to shutdown HTTP server: %v", err)
+		}
+	}()
 	klog.V(0).Infof("Listening and serving HTTP on %s:%s", config.CONFIG.Address, config.CONFIG.Port)
 
-	if err = http.Run(fmt.Sprintf("%s:%s", config.CONFIG.Address, config.CONFIG.Port)); err != nil {
+	if err = server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		return err
 	}
-	<-stopCh
-	return
+	return nil
 }
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/gc"
+	"github.com/cylonchau/pantheon/pkg/model"
+	"github.com/cylonchau/pantheon/pkg/secret"
+)
+
+// newDBCommand 检查与修复 labels、params、selectors 与关联表的完整性
+func newDBCommand(opts *Options) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db",
+		Short: "Check and repair the integrity of the database",
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			if err := opts.Complete(); err != nil {
+				return fmt.Errorf("failed complete: %w", err)
+			}
+			if err := secret.Init(config.CONFIG.Encryption); err != nil {
+				return err
+			}
+			return model.InitDB(config.CONFIG.DatabaseDriver)
+		},
+	}
+	cmd.PersistentFlags().StringVar(&opts.ConfigFile, "config", "./config.toml", "The path to the configuration file.")
+
+	var orphanSelector string
+	checkCmd := &cobra.Command{
+		Use:          "check",
+		Short:        "Report orphaned, duplicate and dangling rows and targets without selector, exits non-zero when problems are found",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			report, err := repairIntegrity(model.IntegrityOptions{DryRun: true})
+			if err != nil {
+				return err
+			}
+			if problems := report.Problems(); problems > 0 {
+				return fmt.Errorf("%d problems found, run db repair to fix them", problems)
+			}
+			return nil
+		},
+	}
+	repairCmd := &cobra.Command{
+		Use:          "repair",
+		Short:        "Remove orphaned and dangling rows, merge duplicates and attach targets without selector to --orphan-selector",
+		SilenceUsage: true,
+		Args:         cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			options := model.IntegrityOptions{}
+			options.DryRun, _ = cmd.Flags().GetBool("dry-run")
+			if !cmd.Flags().Changed("orphan-selector") {
+				orphanSelector = config.CONFIG.GC.OrphanSelector
+			}
+			var err error
+			if options.OrphanSelector, err = gc.ParseSelector(orphanSelector); err != nil {
+				return err
+			}
+			_, err = repairIntegrity(options)
+			return err
+		},
+	}
+	repairCmd.Flags().Bool("dry-run", false, "Only print what would be repaired.")
+	repairCmd.Flags().StringVar(&orphanSelector, "orphan-selector", "", "key=value selector attached to targets without selector, defaults to [gc] orphan_selector.")
+
+	cmd.AddCommand(checkCmd, repairCmd)
+	return cmd
+}
+
+func repairIntegrity(options model.IntegrityOptions) (*model.IntegrityReport, error) {
+	report, err := model.RepairIntegrity(options)
+	if err != nil {
+		return nil, err
+	}
+	printIntegrityReport(report)
+	return report, nil
+}
+
+func printIntegrityReport(report *model.IntegrityReport) {
+	if report.DryRun {
+		fmt.Println("Dry run, nothing was changed.")
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tDANGLING JOINS\tDUPLICATES\tORPHANED")
+	for _, table := range report.Tables {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%d\n", table.Table, table.DanglingJoins, table.Duplicates, table.Orphaned)
+	}
+	w.Flush()
+
+	if len(report.TargetsWithoutSelector) > 0 {
+		fmt.Printf("targets without selector: %v\n", report.TargetsWithoutSelector)
+		fmt.Printf("attached to the orphan selector: %d\n", len(report.Reattached))
+	}
+	if report.DryRun {
+		fmt.Printf("%d problems found\n", report.Problems())
+		return
+	}
+	fmt.Printf("%d problems repaired\n", report.Problems())
+}
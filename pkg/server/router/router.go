@@ -2,13 +2,22 @@ package router
 
 import (
 	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 
 	"github.com/cylonchau/pantheon/docs"
+	v1Consul "github.com/cylonchau/pantheon/pkg/server/v1/consul"
+	v1Credential "github.com/cylonchau/pantheon/pkg/server/v1/credential"
+	v1MetricRule "github.com/cylonchau/pantheon/pkg/server/v1/metricrule"
+	v1Probe "github.com/cylonchau/pantheon/pkg/server/v1/probe"
+	v1Profile "github.com/cylonchau/pantheon/pkg/server/v1/profile"
 	v1Proxy "github.com/cylonchau/pantheon/pkg/server/v1/proxy"
+	v1Relabel "github.com/cylonchau/pantheon/pkg/server/v1/relabel"
+	v1SD "github.com/cylonchau/pantheon/pkg/server/v1/sd"
 	v1Selector "github.com/cylonchau/pantheon/pkg/server/v1/selector"
 	v1Target "github.com/cylonchau/pantheon/pkg/server/v1/target"
+	v1Webhook "github.com/cylonchau/pantheon/pkg/server/v1/webhook"
 	v2Target "github.com/cylonchau/pantheon/pkg/server/v2/target"
 	"github.com/cylonchau/pantheon/pkg/version"
 )
@@ -32,9 +41,38 @@ func RegisteredRouter(e *gin.Engine) {
 	proxyHanderV1 := &v1Proxy.ProxyHanderV1{}
 	proxyHanderV1.RegisterProxyAPI(phv1Group)
 
+	credentialHanderV1 := &v1Credential.CredentialHanderV1{}
+	credentialHanderV1.RegisterCredentialAPI(phv1Group)
+
+	metricRuleHanderV1 := &v1MetricRule.MetricRuleHanderV1{}
+	metricRuleHanderV1.RegisterMetricRuleAPI(phv1Group)
+
+	probeHanderV1 := &v1Probe.ProbeHanderV1{}
+	probeHanderV1.RegisterProbeAPI(phv1Group)
+
+	profileHanderV1 := &v1Profile.ProfileHanderV1{}
+	profileHanderV1.RegisterProfileAPI(phv1Group)
+
+	relabelRuleHanderV1 := &v1Relabel.RelabelRuleHanderV1{}
+	relabelRuleHanderV1.RegisterRelabelRuleAPI(phv1Group)
+
+	webhookHanderV1 := &v1Webhook.WebhookHanderV1{}
+	webhookHanderV1.RegisterWebhookAPI(phv1Group)
+
+	sdHanderV1 := &v1SD.SDHanderV1{}
+	sdHanderV1.RegisterSDAPI(phv1Group)
+
 	targetHanderV2 := &v2Target.TargetHanderV2{}
 	targetHanderV2.RegisterTargetAPI(phv2Group)
 
+	// Consul catalog 兼容接口，路径与 Consul 保持一致
+	consulGroup := e.Group("/v1")
+	consulHanderV1 := v1Consul.NewConsulHanderV1()
+	consulHanderV1.RegisterConsulAPI(consulGroup)
+
+	// pantheon 自身的指标
+	e.GET("/metrics", gin.WrapH(promhttp.Handler()))
+
 	e.Handle("GET", "/doc/*any",
 		ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/doc/doc.json")))
 	e.GET("/doc", func(c *gin.Context) {
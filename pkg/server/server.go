@@ -3,15 +3,23 @@ package server
 import (
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"k8s.io/klog/v2"
 
 	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/filesd"
+	"github.com/cylonchau/pantheon/pkg/gc"
 	"github.com/cylonchau/pantheon/pkg/migration"
 	"github.com/cylonchau/pantheon/pkg/model"
+	"github.com/cylonchau/pantheon/pkg/secret"
 	"github.com/cylonchau/pantheon/pkg/server/app"
+	"github.com/cylonchau/pantheon/pkg/webhook"
 )
 
 type Options struct {
@@ -20,12 +28,18 @@ type Options struct {
 	h          bool
 	migration  bool
 	upgrade    bool
+	rotateKey  string
 	sqlDriver  string
 	errCh      chan error
+	stopCh     chan struct{}
+	stopOnce   sync.Once
+	background sync.WaitGroup
 }
 
 func NewOptions() *Options {
-	return &Options{}
+	return &Options{
+		stopCh: make(chan struct{}),
+	}
 }
 
 // NewProxyCommand creates a *cobra.Command object with default parameters
@@ -64,6 +78,7 @@ func NewProxyCommand() *cobra.Command {
 	fs.AddGoFlagSet(flag.CommandLine) // for --boot-id-file and --machine-id-file
 
 	_ = cmd.MarkFlagFilename("config", "yaml", "yml", "json")
+	cmd.AddCommand(newDBCommand(opts))
 
 	return cmd
 }
@@ -72,6 +87,7 @@ func (o *Options) AddFlags(fs *pflag.FlagSet) {
 	fs.StringVar(&o.ConfigFile, "config", "./config.toml", "The path to the configuration file.")
 	fs.BoolVar(&o.migration, "migration", false, "Inital database and tables.")
 	fs.BoolVar(&o.upgrade, "upgrade", false, "If true, update the database schema to the latest version.")
+	fs.StringVar(&o.rotateKey, "rotate-key", "", "Re-encrypt all credentials with the master key in this file, then exit.")
 	fs.StringVar(&o.sqlDriver, "sql-driver", "sqlite", "enable which sql backend.")
 
 }
@@ -98,6 +114,15 @@ func (o *Options) Complete() error {
 }
 
 func (o *Options) Run() error {
+	if o.rotateKey != "" {
+		return migration.RotateKey(o.sqlDriver, o.rotateKey)
+	}
+
+	// 凭据加解密依赖主密钥，需要在访问数据库之前初始化
+	if err := secret.Init(config.CONFIG.Encryption); err != nil {
+		return err
+	}
+
 	if o.migration {
 		return migration.Migrate(o.sqlDriver)
 	}
@@ -110,7 +135,60 @@ func (o *Options) Run() error {
 		if err := model.InitDB(config.CONFIG.DatabaseDriver); err != nil {
 			return err
 		}
+		// 投递 inventory 变更事件到订阅的 webhook
+		o.runInBackground(webhook.NewDispatcher(config.CONFIG.Webhook).Run)
+
+		// 定期清理孤立与重复的关联数据
+		if config.CONFIG.GC.Enable {
+			collector, err := gc.NewCollector(config.CONFIG.GC)
+			if err != nil {
+				return err
+			}
+			o.runInBackground(collector.Run)
+		}
 	}
 
-	return app.NewHTTPSever()
+	// file_sd 模式，将 SD 输出定期写入文件，供不支持 http_sd 的 Prometheus 使用
+	if config.CONFIG.FileSD.Enable {
+		writer, err := filesd.NewWriter(config.CONFIG.FileSD)
+		if err != nil {
+			return err
+		}
+		o.runInBackground(writer.Run)
+	}
+
+	// 收到退出信号时关闭 stopCh，HTTP 服务与后台任务随之退出
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	go func() {
+		select {
+		case sig := <-signals:
+			klog.V(0).Infof("Received signal %s, shutting down", sig)
+			o.stop()
+		case <-o.stopCh:
+		}
+	}()
+
+	err := app.NewHTTPSever(o.stopCh)
+	// HTTP 服务异常退出时同样停止后台任务，并等待其完成最后一次写入
+	o.stop()
+	o.background.Wait()
+	return err
+}
+
+// runInBackground 在后台运行任务直到 stopCh 关闭，Run 返回前等待任务结束
+func (o *Options) runInBackground(run func(stopCh <-chan struct{})) {
+	o.background.Add(1)
+	go func() {
+		defer o.background.Done()
+		run(o.stopCh)
+	}()
+}
+
+// stop 关闭 stopCh，可以重复调用
+func (o *Options) stop() {
+	o.stopOnce.Do(func() {
+		close(o.stopCh)
+	})
 }
@@ -0,0 +1,464 @@
+package consul
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/model"
+	"github.com/cylonchau/pantheon/pkg/sd"
+)
+
+const (
+	defaultWait     = 5 * time.Minute
+	maxWait         = 10 * time.Minute
+	pollInterval    = time.Second
+	servicesListKey = ""
+)
+
+// ConsulHanderV1 只读模拟 Consul catalog/health 接口，使 consul_sd_configs 可以直接指向 pantheon
+type ConsulHanderV1 struct {
+	catalog *catalog
+}
+
+func NewConsulHanderV1() *ConsulHanderV1 {
+	return &ConsulHanderV1{catalog: newCatalog(loadCatalog, model.SDWatermark)}
+}
+
+func (h *ConsulHanderV1) RegisterConsulAPI(g *gin.RouterGroup) {
+	g.GET("/agent/self", h.agentSelf)
+	catalogGroup := g.Group("/catalog")
+	catalogGroup.GET("/services", h.listServices)
+	catalogGroup.GET("/service/:name", h.getService)
+	healthGroup := g.Group("/health")
+	healthGroup.GET("/service/:name", h.getHealthService)
+}
+
+type catalogService struct {
+	ID                       string
+	Node                     string
+	Address                  string
+	Datacenter               string
+	TaggedAddresses          map[string]string
+	NodeMeta                 map[string]string
+	ServiceID                string
+	ServiceName              string
+	ServiceAddress           string
+	ServiceTags              []string
+	ServiceMeta              map[string]string
+	ServicePort              int
+	ServiceEnableTagOverride bool
+	CreateIndex              uint64
+	ModifyIndex              uint64
+}
+
+type healthNode struct {
+	ID              string
+	Node            string
+	Address         string
+	Datacenter      string
+	TaggedAddresses map[string]string
+	Meta            map[string]string
+}
+
+type healthService struct {
+	ID      string
+	Service string
+	Tags    []string
+	Address string
+	Meta    map[string]string
+	Port    int
+}
+
+type healthCheck struct {
+	Node        string
+	CheckID     string
+	Name        string
+	Status      string
+	ServiceID   string
+	ServiceName string
+}
+
+type serviceEntry struct {
+	Node    healthNode
+	Service healthService
+	Checks  []healthCheck
+}
+
+// agentSelf godoc
+// @Summary Consul agent self
+// @Description Consul compatible agent information, used by consul_sd_configs to detect the datacenter.
+// @Tags Consul
+// @Produce json
+// @Success 200 {object} interface{}
+// @Router /v1/agent/self [get]
+func (h *ConsulHanderV1) agentSelf(c *gin.Context) {
+	query.RawSuccessResponse(c, gin.H{
+		"Config": gin.H{
+			"Datacenter": sd.ConsulDatacenter,
+			"NodeName":   sd.ConsulNodeName,
+		},
+		"Member": gin.H{
+			"Name": sd.ConsulNodeName,
+		},
+	})
+}
+
+// listServices godoc
+// @Summary Consul catalog services
+// @Description List selectors as Consul services, supports blocking queries with index and wait.
+// @Tags Consul
+// @Produce json
+// @Param index query int false "blocking query index"
+// @Param wait query string false "blocking query wait time, e.g. 5m"
+// @Success 200 {object} interface{}
+// @Router /v1/catalog/services [get]
+func (h *ConsulHanderV1) listServices(c *gin.Context) {
+	h.blockingQuery(c, servicesListKey, func(snapshot *catalogSnapshot) interface{} {
+		services := map[string][]string{}
+		for name, instances := range snapshot.services {
+			services[name] = sd.ConsulServiceTags(instances)
+		}
+		return services
+	})
+}
+
+// getService godoc
+// @Summary Consul catalog service
+// @Description List the targets of a selector as Consul service instances, supports blocking queries.
+// @Tags Consul
+// @Produce json
+// @Param name path string true "service name, <selector key>-<selector value>"
+// @Param index query int false "blocking query index"
+// @Param wait query string false "blocking query wait time, e.g. 5m"
+// @Success 200 {object} interface{}
+// @Router /v1/catalog/service/{name} [get]
+func (h *ConsulHanderV1) getService(c *gin.Context) {
+	name := c.Param("name")
+	h.blockingQuery(c, name, func(snapshot *catalogSnapshot) interface{} {
+		instances := snapshot.services[name]
+		results := make([]catalogService, 0, len(instances))
+		for _, instance := range instances {
+			results = append(results, catalogService{
+				ID:              sd.ConsulNodeName,
+				Node:            sd.ConsulNodeName,
+				Address:         instance.Address,
+				Datacenter:      sd.ConsulDatacenter,
+				TaggedAddresses: map[string]string{},
+				NodeMeta:        map[string]string{},
+				ServiceID:       instance.ID,
+				ServiceName:     instance.Service,
+				ServiceAddress:  instance.Address,
+				ServiceTags:     instance.Tags,
+				ServiceMeta:     instance.Meta,
+				ServicePort:     instance.Port,
+			})
+		}
+		return results
+	})
+}
+
+// getHealthService godoc
+// @Summary Consul health service
+// @Description List the targets of a selector as passing Consul service entries, supports blocking queries.
+// @Tags Consul
+// @Produce json
+// @Param name path string true "service name, <selector key>-<selector value>"
+// @Param index query int false "blocking query index"
+// @Param wait query string false "blocking query wait time, e.g. 5m"
+// @Success 200 {object} interface{}
+// @Router /v1/health/service/{name} [get]
+func (h *ConsulHanderV1) getHealthService(c *gin.Context) {
+	name := c.Param("name")
+	h.blockingQuery(c, name, func(snapshot *catalogSnapshot) interface{} {
+		instances := snapshot.services[name]
+		results := make([]serviceEntry, 0, len(instances))
+		for _, instance := range instances {
+			results = append(results, serviceEntry{
+				Node: healthNode{
+					ID:              sd.ConsulNodeName,
+					Node:            sd.ConsulNodeName,
+					Address:         instance.Address,
+					Datacenter:      sd.ConsulDatacenter,
+					TaggedAddresses: map[string]string{},
+					Meta:            map[string]string{},
+				},
+				Service: healthService{
+					ID:      instance.ID,
+					Service: instance.Service,
+					Tags:    instance.Tags,
+					Address: instance.Address,
+					Meta:    instance.Meta,
+					Port:    instance.Port,
+				},
+				Checks: []healthCheck{{
+					Node:        sd.ConsulNodeName,
+					CheckID:     "serfHealth",
+					Name:        "Serf Health Status",
+					Status:      "passing",
+					ServiceID:   instance.ID,
+					ServiceName: instance.Service,
+				}},
+			})
+		}
+		return results
+	})
+}
+
+// loadCatalog 读取所有 selector 对应的服务实例
+func loadCatalog() (map[string][]sd.ConsulService, error) {
+	selectors, err := model.ListSelector()
+	if err != nil {
+		return nil, err
+	}
+	services := make(map[string][]sd.ConsulService, len(selectors))
+	for _, selector := range selectors {
+		targets, err := model.ListTargetWithSelector(&query.QueryWithLabel{Key: selector.Key, Value: selector.Value}, nil)
+		if err != nil {
+			return nil, err
+		}
+		name := sd.ConsulServiceName(selector.Key, selector.Value)
+		services[name] = sd.ConsulServices(name, targets)
+	}
+	return services, nil
+}
+
+// blockingQuery 实现 Consul 的阻塞查询语义：
+// 当请求的 index 不小于当前 index 时，等待共享的轮询发现内容变化或 wait 超时
+func (h *ConsulHanderV1) blockingQuery(c *gin.Context, key string, render func(*catalogSnapshot) interface{}) {
+	var waitIndex uint64
+	if raw := c.Query("index"); raw != "" {
+		parsed, err := strconv.ParseUint(raw, 10, 64)
+		if err != nil {
+			query.API400Response(c, fmt.Errorf("invalid index: %s", raw))
+			return
+		}
+		waitIndex = parsed
+	}
+
+	wait := defaultWait
+	if raw := c.Query("wait"); raw != "" {
+		parsed, err := parseWait(raw)
+		if err != nil {
+			query.API400Response(c, err)
+			return
+		}
+		wait = parsed
+	}
+	if wait > maxWait {
+		wait = maxWait
+	}
+
+	snapshot, err := h.catalog.wait(c.Request.Context(), key, waitIndex, wait)
+	if err != nil {
+		query.API500Response(c, err)
+		return
+	}
+	if snapshot == nil {
+		// 客户端已断开
+		return
+	}
+	content, err := json.Marshal(render(snapshot))
+	if err != nil {
+		query.API500Response(c, err)
+		return
+	}
+	c.Header("X-Consul-Index", strconv.FormatUint(snapshot.index(key), 10))
+	c.Header("X-Consul-Knownleader", "true")
+	c.Header("X-Consul-Lastcontact", "0")
+	c.Data(http.StatusOK, "application/json", content)
+}
+
+// parseWait 解析 Consul 风格的 wait 参数，未带单位时按秒处理
+func parseWait(raw string) (time.Duration, error) {
+	if seconds, err := strconv.Atoi(raw); err == nil {
+		return time.Duration(seconds) * time.Second, nil
+	}
+	wait, err := time.ParseDuration(raw)
+	if err != nil {
+		return 0, fmt.Errorf("invalid wait: %s", raw)
+	}
+	return wait, nil
+}
+
+// catalogSnapshot 某一时刻所有服务的实例与 modify index，只读
+type catalogSnapshot struct {
+	services map[string][]sd.ConsulService
+	hashes   map[string][32]byte
+	indexes  map[string]uint64
+}
+
+// index 返回资源的 modify index，不存在的服务使用服务列表的 index，服务出现时随之变化
+func (s *catalogSnapshot) index(key string) uint64 {
+	if index, exists := s.indexes[key]; exists {
+		return index
+	}
+	return s.indexes[servicesListKey]
+}
+
+// catalog 所有阻塞查询共享的服务目录：有等待中的请求时由一个 goroutine 按 pollInterval 检查水位，
+// 水位变化时才重新读取，内容变化时唤醒等待的请求，index 只为当前存在的服务保存
+// index 初始值取启动时间，避免 pantheon 重启后 index 回退
+type catalog struct {
+	load      func() (map[string][]sd.ConsulService, error)
+	watermark func() (string, error)
+
+	mu       sync.Mutex
+	current  uint64
+	snapshot *catalogSnapshot
+	loaded   string // 当前快照读取前的水位
+	changed  chan struct{}
+	watchers int
+	polling  bool
+}
+
+func newCatalog(load func() (map[string][]sd.ConsulService, error), watermark func() (string, error)) *catalog {
+	return &catalog{
+		load:      load,
+		watermark: watermark,
+		current:   uint64(time.Now().Unix()),
+		changed:   make(chan struct{}),
+	}
+}
+
+// sync 水位变化时重新读取服务目录，否则返回当前的快照
+func (ct *catalog) sync() (*catalogSnapshot, error) {
+	watermark, err := ct.watermark()
+	if err != nil {
+		return nil, err
+	}
+	ct.mu.Lock()
+	snapshot, loaded := ct.snapshot, ct.loaded
+	ct.mu.Unlock()
+	if snapshot != nil && loaded == watermark {
+		return snapshot, nil
+	}
+	return ct.refresh(watermark)
+}
+
+// refresh 重新读取服务目录，内容变化的资源分配新的 index
+func (ct *catalog) refresh(watermark string) (*catalogSnapshot, error) {
+	services, err := ct.load()
+	if err != nil {
+		return nil, err
+	}
+	next := &catalogSnapshot{
+		services: services,
+		hashes:   make(map[string][32]byte, len(services)+1),
+		indexes:  make(map[string]uint64, len(services)+1),
+	}
+	tags := make(map[string][]string, len(services))
+	for name, instances := range services {
+		content, err := json.Marshal(instances)
+		if err != nil {
+			return nil, err
+		}
+		next.hashes[name] = sha256.Sum256(content)
+		tags[name] = sd.ConsulServiceTags(instances)
+	}
+	content, err := json.Marshal(tags)
+	if err != nil {
+		return nil, err
+	}
+	next.hashes[servicesListKey] = sha256.Sum256(content)
+
+	ct.mu.Lock()
+	defer ct.mu.Unlock()
+	ct.loaded = watermark
+	changed := false
+	for key, hash := range next.hashes {
+		if ct.snapshot != nil {
+			if previous, exists := ct.snapshot.hashes[key]; exists && previous == hash {
+				next.indexes[key] = ct.snapshot.indexes[key]
+				continue
+			}
+		}
+		ct.current++
+		next.indexes[key] = ct.current
+		changed = true
+	}
+	if ct.snapshot != nil && len(ct.snapshot.hashes) != len(next.hashes) {
+		changed = true
+	}
+	if !changed && ct.snapshot != nil {
+		return ct.snapshot, nil
+	}
+	ct.snapshot = next
+	close(ct.changed)
+	ct.changed = make(chan struct{})
+	return next, nil
+}
+
+// wait 返回 index 大于 waitIndex 的快照，waitIndex 为 0 时立即返回最新的快照；
+// 超时返回当前快照，ctx 结束时返回 nil
+func (ct *catalog) wait(ctx context.Context, key string, waitIndex uint64, wait time.Duration) (*catalogSnapshot, error) {
+	if waitIndex == 0 {
+		return ct.sync()
+	}
+
+	ct.mu.Lock()
+	ct.watchers++
+	if !ct.polling {
+		ct.polling = true
+		go ct.poll()
+	}
+	snapshot, changed := ct.snapshot, ct.changed
+	ct.mu.Unlock()
+	defer func() {
+		ct.mu.Lock()
+		ct.watchers--
+		ct.mu.Unlock()
+	}()
+
+	if snapshot == nil {
+		if _, err := ct.sync(); err != nil {
+			return nil, err
+		}
+		ct.mu.Lock()
+		snapshot, changed = ct.snapshot, ct.changed
+		ct.mu.Unlock()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for snapshot.index(key) <= waitIndex {
+		select {
+		case <-ctx.Done():
+			return nil, nil
+		case <-timer.C:
+			return snapshot, nil
+		case <-changed:
+			ct.mu.Lock()
+			snapshot, changed = ct.snapshot, ct.changed
+			ct.mu.Unlock()
+		}
+	}
+	return snapshot, nil
+}
+
+// poll 有等待中的请求时定期检查水位，所有请求共享同一次查询
+func (ct *catalog) poll() {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		ct.mu.Lock()
+		if ct.watchers == 0 {
+			ct.polling = false
+			ct.mu.Unlock()
+			return
+		}
+		ct.mu.Unlock()
+		if _, err := ct.sync(); err != nil {
+			klog.Errorf("Failed to refresh consul catalog: %v", err)
+		}
+	}
+}
@@ -0,0 +1,176 @@
+package consul
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/sd"
+)
+
+// fakeCatalog 记录 load 的调用次数，可在测试中修改服务目录，每次修改递增水位
+type fakeCatalog struct {
+	mu       sync.Mutex
+	services map[string][]sd.ConsulService
+	version  int
+	loads    int32
+}
+
+func (f *fakeCatalog) watermark() (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return strconv.Itoa(f.version), nil
+}
+
+func (f *fakeCatalog) load() (map[string][]sd.ConsulService, error) {
+	atomic.AddInt32(&f.loads, 1)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	services := make(map[string][]sd.ConsulService, len(f.services))
+	for name, instances := range f.services {
+		services[name] = instances
+	}
+	return services, nil
+}
+
+func (f *fakeCatalog) set(name string, instances []sd.ConsulService) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.services[name] = instances
+	f.version++
+}
+
+// TestCatalog_WatchersShareOnePoller 测试多个阻塞查询共享同一次刷新，内容变化时全部返回
+func TestCatalog_WatchersShareOnePoller(t *testing.T) {
+	// Arrange
+	fake := &fakeCatalog{services: map[string][]sd.ConsulService{
+		"prom=fed": {{Service: "prom=fed", Address: "10.0.0.1", Port: 9100}},
+	}}
+	ct := newCatalog(fake.load, fake.watermark)
+	initial, err := ct.wait(context.Background(), "prom=fed", 0, time.Second)
+	require.NoError(t, err)
+	waitIndex := initial.index("prom=fed")
+
+	// Act
+	const watchers = 10
+	results := make(chan uint64, watchers)
+	for i := 0; i < watchers; i++ {
+		go func() {
+			snapshot, err := ct.wait(context.Background(), "prom=fed", waitIndex, 10*time.Second)
+			if err != nil || snapshot == nil {
+				results <- 0
+				return
+			}
+			results <- snapshot.index("prom=fed")
+		}()
+	}
+	time.Sleep(100 * time.Millisecond)
+	fake.set("prom=fed", []sd.ConsulService{{Service: "prom=fed", Address: "10.0.0.2", Port: 9100}})
+
+	// Assert
+	for i := 0; i < watchers; i++ {
+		select {
+		case index := <-results:
+			assert.Greater(t, index, waitIndex)
+		case <-time.After(5 * time.Second):
+			t.Fatal("watcher was not woken up after the catalog changed")
+		}
+	}
+	// 每个 pollInterval 只刷新一次，而不是每个请求各自刷新
+	assert.LessOrEqual(t, atomic.LoadInt32(&fake.loads), int32(4))
+}
+
+// TestCatalog_ReloadOnlyWhenWatermarkChanges 测试水位不变时不重新读取服务目录
+func TestCatalog_ReloadOnlyWhenWatermarkChanges(t *testing.T) {
+	// Arrange
+	fake := &fakeCatalog{services: map[string][]sd.ConsulService{
+		"prom=fed": {{Service: "prom=fed", Address: "10.0.0.1", Port: 9100}},
+	}}
+	ct := newCatalog(fake.load, fake.watermark)
+	initial, err := ct.wait(context.Background(), "prom=fed", 0, time.Second)
+	require.NoError(t, err)
+
+	// Act
+	snapshot, waitErr := ct.wait(context.Background(), "prom=fed", initial.index("prom=fed"), 2500*time.Millisecond)
+	unchanged, unchangedErr := ct.wait(context.Background(), "prom=fed", 0, time.Second)
+	fake.set("prom=fed", []sd.ConsulService{{Service: "prom=fed", Address: "10.0.0.2", Port: 9100}})
+	changed, changedErr := ct.wait(context.Background(), "prom=fed", 0, time.Second)
+
+	// Assert
+	require.NoError(t, waitErr)
+	require.NoError(t, unchangedErr)
+	require.NoError(t, changedErr)
+	assert.Equal(t, initial.index("prom=fed"), snapshot.index("prom=fed"), "blocking query times out without changes")
+	assert.Same(t, initial, unchanged)
+	assert.Greater(t, changed.index("prom=fed"), initial.index("prom=fed"))
+	assert.EqualValues(t, 2, atomic.LoadInt32(&fake.loads), "the catalog is only reloaded after the watermark changed")
+}
+
+// TestCatalog_UnknownServiceNotIndexed 测试不存在的服务名不会被记录，服务出现后阻塞查询返回
+func TestCatalog_UnknownServiceNotIndexed(t *testing.T) {
+	// Arrange
+	fake := &fakeCatalog{services: map[string][]sd.ConsulService{}}
+	ct := newCatalog(fake.load, fake.watermark)
+	for _, name := range []string{"a", "b", "c"} {
+		_, err := ct.wait(context.Background(), name, 0, time.Second)
+		require.NoError(t, err)
+	}
+	initial, err := ct.wait(context.Background(), "missing", 0, time.Second)
+	require.NoError(t, err)
+
+	// Act
+	done := make(chan uint64, 1)
+	go func() {
+		snapshot, _ := ct.wait(context.Background(), "missing", initial.index("missing"), 10*time.Second)
+		done <- snapshot.index("missing")
+	}()
+	time.Sleep(100 * time.Millisecond)
+	fake.set("missing", []sd.ConsulService{{Service: "missing", Address: "10.0.0.1", Port: 9100}})
+
+	// Assert
+	assert.Len(t, initial.indexes, 1, "only the services list is indexed")
+	select {
+	case index := <-done:
+		assert.Greater(t, index, initial.index("missing"))
+	case <-time.After(5 * time.Second):
+		t.Fatal("watcher was not woken up after the service appeared")
+	}
+}
+
+// TestCatalog_ContextCanceled 测试客户端断开后等待的请求结束，轮询随之停止
+func TestCatalog_ContextCanceled(t *testing.T) {
+	// Arrange
+	fake := &fakeCatalog{services: map[string][]sd.ConsulService{}}
+	ct := newCatalog(fake.load, fake.watermark)
+	initial, err := ct.wait(context.Background(), servicesListKey, 0, time.Second)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act
+	done := make(chan struct{})
+	go func() {
+		snapshot, _ := ct.wait(ctx, servicesListKey, initial.index(servicesListKey), 10*time.Second)
+		assert.Nil(t, snapshot)
+		close(done)
+	}()
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watcher did not return after the context was canceled")
+	}
+	assert.Eventually(t, func() bool {
+		ct.mu.Lock()
+		defer ct.mu.Unlock()
+		return !ct.polling && ct.watchers == 0
+	}, 3*pollInterval, 50*time.Millisecond)
+}
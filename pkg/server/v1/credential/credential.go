@@ -0,0 +1,164 @@
+package credential
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type CredentialHanderV1 struct{}
+
+func (h *CredentialHanderV1) RegisterCredentialAPI(g *gin.RouterGroup) {
+	credentialGroup := g.Group("/credentials")
+	credentialGroup.GET("", h.listCredentials)
+	credentialGroup.PUT("", h.createCredential)
+	credentialGroup.GET("/:uid", h.getCredential)
+	credentialGroup.POST("/:uid/rotate", h.rotateCredential)
+	credentialGroup.DELETE("/:uid", h.deleteCredential)
+}
+
+// listCredentials godoc
+// @Summary List credentials
+// @Description List credentials without secrets, with the number of targets referencing each one.
+// @Tags Credentials
+// @Produce json
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} credential.CredentialInfo
+// @Router /ph/v1/credentials [get]
+func (h *CredentialHanderV1) listCredentials(c *gin.Context) {
+	credentials, encounterError := model.ListCredentials()
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, credentials)
+}
+
+// createCredential godoc
+// @Summary Create credential
+// @Description Create a basic or bearer credential, targets reference it by the returned uid.
+// @Tags Credentials
+// @Accept json
+// @Produce json
+// @Param query body credential.Credential true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} credential.CredentialInfo
+// @Router /ph/v1/credentials [put]
+func (h *CredentialHanderV1) createCredential(c *gin.Context) {
+	var encounterError error
+	request := &credential.Credential{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.CreateCredential(request)
+	if encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// getCredential godoc
+// @Summary Get credential
+// @Description Get a credential by uid, the secret is only returned when show_secrets is set.
+// @Tags Credentials
+// @Produce json
+// @Param uid path string true "credential uid"
+// @Param show_secrets query bool false "return the secret of the credential"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} credential.CredentialInfo
+// @Router /ph/v1/credentials/{uid} [get]
+func (h *CredentialHanderV1) getCredential(c *gin.Context) {
+	var encounterError error
+	uidQuery := &query.QueryWithUID{}
+	if encounterError = c.ShouldBindUri(uidQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	secretsQuery := &query.QueryWithSecrets{}
+	if encounterError = c.ShouldBindQuery(secretsQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.GetCredentialInfo(uidQuery.UID, secretsQuery.ShowSecrets)
+	if encounterError != nil {
+		credentialErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// rotateCredential godoc
+// @Summary Rotate credential
+// @Description Replace the secret of a credential in place, the uid and the SD output stay unchanged.
+// @Tags Credentials
+// @Accept json
+// @Produce json
+// @Param uid path string true "credential uid"
+// @Param query body credential.Secret true "new secret"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} credential.CredentialInfo
+// @Router /ph/v1/credentials/{uid}/rotate [post]
+func (h *CredentialHanderV1) rotateCredential(c *gin.Context) {
+	var encounterError error
+	uidQuery := &query.QueryWithUID{}
+	if encounterError = c.ShouldBindUri(uidQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	secret := &credential.Secret{}
+	if encounterError = c.ShouldBindJSON(secret); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.RotateCredential(uidQuery.UID, *secret)
+	if encounterError != nil {
+		credentialErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// deleteCredential godoc
+// @Summary Delete credential
+// @Description Delete a credential, fails with 409 while targets still reference it.
+// @Tags Credentials
+// @Produce json
+// @Param uid path string true "credential uid"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Router /ph/v1/credentials/{uid} [delete]
+func (h *CredentialHanderV1) deleteCredential(c *gin.Context) {
+	var encounterError error
+	uidQuery := &query.QueryWithUID{}
+	if encounterError = c.ShouldBindUri(uidQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	if encounterError = model.DeleteCredential(uidQuery.UID); encounterError != nil {
+		credentialErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func credentialErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrCredentialNotFound):
+		query.API404Response(c, err)
+	case errors.Is(err, model.ErrCredentialInUse):
+		query.API409Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
@@ -0,0 +1,154 @@
+package metricrule
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/metricrule"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type MetricRuleHanderV1 struct{}
+
+func (h *MetricRuleHanderV1) RegisterMetricRuleAPI(g *gin.RouterGroup) {
+	ruleGroup := g.Group("/metric_rules")
+	ruleGroup.GET("", h.listMetricRules)
+	ruleGroup.PUT("", h.createMetricRule)
+	ruleGroup.GET("/:id", h.getMetricRule)
+	ruleGroup.POST("/:id", h.updateMetricRule)
+	ruleGroup.DELETE("/:id", h.deleteMetricRule)
+}
+
+// listMetricRules godoc
+// @Summary List metric rules
+// @Description List the metric filtering rules applied by the scrape proxy.
+// @Tags MetricRules
+// @Produce json
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} metricrule.MetricRuleInfo
+// @Router /ph/v1/metric_rules [get]
+func (h *MetricRuleHanderV1) listMetricRules(c *gin.Context) {
+	rules, encounterError := model.ListMetricRules()
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, rules)
+}
+
+// createMetricRule godoc
+// @Summary Create metric rule
+// @Description Create a rule for a target or for all targets of a selector, matching targets are scraped through the proxy
+// @Description which drops or keeps metric families, drops labels and adds static labels before returning the response.
+// @Tags MetricRules
+// @Accept json
+// @Produce json
+// @Param query body metricrule.MetricRule true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} metricrule.MetricRuleInfo
+// @Router /ph/v1/metric_rules [put]
+func (h *MetricRuleHanderV1) createMetricRule(c *gin.Context) {
+	var encounterError error
+	request := &metricrule.MetricRule{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.CreateMetricRule(request)
+	if encounterError != nil {
+		metricRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// getMetricRule godoc
+// @Summary Get metric rule
+// @Tags MetricRules
+// @Produce json
+// @Param id path int true "metric rule id"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} metricrule.MetricRuleInfo
+// @Router /ph/v1/metric_rules/{id} [get]
+func (h *MetricRuleHanderV1) getMetricRule(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.GetMetricRule(idQuery.ID)
+	if encounterError != nil {
+		metricRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// updateMetricRule godoc
+// @Summary Update metric rule
+// @Description Replace a metric rule with the request body.
+// @Tags MetricRules
+// @Accept json
+// @Produce json
+// @Param id path int true "metric rule id"
+// @Param query body metricrule.MetricRule true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} metricrule.MetricRuleInfo
+// @Router /ph/v1/metric_rules/{id} [post]
+func (h *MetricRuleHanderV1) updateMetricRule(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &metricrule.MetricRule{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.UpdateMetricRule(idQuery.ID, request)
+	if encounterError != nil {
+		metricRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// deleteMetricRule godoc
+// @Summary Delete metric rule
+// @Tags MetricRules
+// @Produce json
+// @Param id path int true "metric rule id"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Router /ph/v1/metric_rules/{id} [delete]
+func (h *MetricRuleHanderV1) deleteMetricRule(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	if encounterError = model.DeleteMetricRule(idQuery.ID); encounterError != nil {
+		metricRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func metricRuleErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrMetricRuleNotFound):
+		query.API404Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
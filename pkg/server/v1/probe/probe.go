@@ -0,0 +1,183 @@
+package probe
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/probe"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type ProbeHanderV1 struct{}
+
+func (h *ProbeHanderV1) RegisterProbeAPI(g *gin.RouterGroup) {
+	probeGroup := g.Group("/probes")
+	probeGroup.GET("", h.listProbes)
+	probeGroup.PUT("", h.createProbes)
+
+	exporterGroup := g.Group("/probe_exporters")
+	exporterGroup.GET("", h.listExporters)
+	exporterGroup.PUT("", h.createExporter)
+	exporterGroup.POST("/:name", h.updateExporter)
+	exporterGroup.DELETE("/:name", h.deleteExporter)
+}
+
+// listProbes godoc
+// @Summary List probes
+// @Description List blackbox probe targets, optionally filtered by exporter and module.
+// @Tags Probes
+// @Produce json
+// @Param exporter query string false "exporter name"
+// @Param module query string false "blackbox module"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} probe.ProbeInfo
+// @Router /ph/v1/probes [get]
+func (h *ProbeHanderV1) listProbes(c *gin.Context) {
+	var encounterError error
+	probeQuery := &probe.QueryProbes{}
+	if encounterError = c.ShouldBindQuery(probeQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	probes, encounterError := model.ListProbes(probeQuery)
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, probes)
+}
+
+// createProbes godoc
+// @Summary Create probes
+// @Description Create one probe target per URL or host, scraped through the referenced blackbox exporter with the module.
+// @Description In SD the address is the exporter and the instance label is the probed target. Probes are deleted as targets.
+// @Tags Probes
+// @Accept json
+// @Produce json
+// @Param query body probe.Probe true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Router /ph/v1/probes [put]
+func (h *ProbeHanderV1) createProbes(c *gin.Context) {
+	var encounterError error
+	request := &probe.Probe{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	if encounterError = model.CreateProbes(request); encounterError != nil {
+		probeErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+// listExporters godoc
+// @Summary List blackbox exporters
+// @Description List blackbox exporters with the number of probes referencing each one.
+// @Tags Probes
+// @Produce json
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} probe.ExporterInfo
+// @Router /ph/v1/probe_exporters [get]
+func (h *ProbeHanderV1) listExporters(c *gin.Context) {
+	exporters, encounterError := model.ListExporters()
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, exporters)
+}
+
+// createExporter godoc
+// @Summary Create blackbox exporter
+// @Description Register a blackbox exporter, probes reference it by name.
+// @Tags Probes
+// @Accept json
+// @Produce json
+// @Param query body probe.Exporter true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} probe.ExporterInfo
+// @Router /ph/v1/probe_exporters [put]
+func (h *ProbeHanderV1) createExporter(c *gin.Context) {
+	var encounterError error
+	request := &probe.Exporter{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.CreateExporter(request)
+	if encounterError != nil {
+		probeErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// updateExporter godoc
+// @Summary Update blackbox exporter
+// @Description Change the address of a blackbox exporter, all probes referencing it follow.
+// @Tags Probes
+// @Accept json
+// @Produce json
+// @Param name path string true "exporter name"
+// @Param query body probe.Exporter true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} probe.ExporterInfo
+// @Router /ph/v1/probe_exporters/{name} [post]
+func (h *ProbeHanderV1) updateExporter(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &probe.Exporter{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.UpdateExporter(nameQuery.Name, request)
+	if encounterError != nil {
+		probeErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// deleteExporter godoc
+// @Summary Delete blackbox exporter
+// @Description Delete a blackbox exporter, exporters still referenced by probes cannot be deleted.
+// @Tags Probes
+// @Produce json
+// @Param name path string true "exporter name"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Failure 409 {object} query.Response
+// @Router /ph/v1/probe_exporters/{name} [delete]
+func (h *ProbeHanderV1) deleteExporter(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	if encounterError = model.DeleteExporter(nameQuery.Name); encounterError != nil {
+		probeErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func probeErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrExporterNotFound):
+		query.API404Response(c, err)
+	case errors.Is(err, model.ErrExporterExists), errors.Is(err, model.ErrExporterInUse):
+		query.API409Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
@@ -0,0 +1,162 @@
+package profile
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/profile"
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type ProfileHanderV1 struct{}
+
+func (h *ProfileHanderV1) RegisterProfileAPI(g *gin.RouterGroup) {
+	profileGroup := g.Group("/profiles")
+	profileGroup.GET("", h.listProfiles)
+	profileGroup.PUT("", h.createProfile)
+	profileGroup.GET("/:name", h.getProfile)
+	profileGroup.POST("/:name", h.updateProfile)
+	profileGroup.DELETE("/:name", h.deleteProfile)
+}
+
+// listProfiles godoc
+// @Summary List profiles
+// @Description List target profiles with the number of targets created from each one.
+// @Tags Profiles
+// @Produce json
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} profile.ProfileInfo
+// @Router /ph/v1/profiles [get]
+func (h *ProfileHanderV1) listProfiles(c *gin.Context) {
+	profiles, encounterError := model.ListProfiles()
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, profiles)
+}
+
+// createProfile godoc
+// @Summary Create profile
+// @Description Create a named profile holding the default port, metric path, intervals, labels, params and auth of an exporter type.
+// @Description Targets reference it with the profile field, fields set on the target take precedence.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param query body profile.Profile true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} profile.ProfileInfo
+// @Router /ph/v1/profiles [put]
+func (h *ProfileHanderV1) createProfile(c *gin.Context) {
+	var encounterError error
+	request := &profile.Profile{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.CreateProfile(request)
+	if encounterError != nil {
+		profileErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// getProfile godoc
+// @Summary Get profile
+// @Tags Profiles
+// @Produce json
+// @Param name path string true "profile name"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} profile.ProfileInfo
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/profiles/{name} [get]
+func (h *ProfileHanderV1) getProfile(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.GetProfile(nameQuery.Name)
+	if encounterError != nil {
+		profileErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// updateProfile godoc
+// @Summary Update profile
+// @Description Replace a profile. With propagate=true the targets created from it follow the change,
+// @Description fields changed on a target after its creation are kept. changed in the response is the number of targets changed.
+// @Tags Profiles
+// @Accept json
+// @Produce json
+// @Param name path string true "profile name"
+// @Param propagate query bool false "update the targets created from the profile"
+// @Param query body profile.Profile true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} profile.ProfileInfo
+// @Router /ph/v1/profiles/{name} [post]
+func (h *ProfileHanderV1) updateProfile(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	options := &profile.UpdateOptions{}
+	if encounterError = c.ShouldBindQuery(options); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &profile.Profile{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.UpdateProfile(nameQuery.Name, request, options.Propagate)
+	if encounterError != nil {
+		profileErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// deleteProfile godoc
+// @Summary Delete profile
+// @Description Delete a profile, profiles still referenced by targets cannot be deleted.
+// @Tags Profiles
+// @Produce json
+// @Param name path string true "profile name"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Failure 409 {object} query.Response
+// @Router /ph/v1/profiles/{name} [delete]
+func (h *ProfileHanderV1) deleteProfile(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	if encounterError = model.DeleteProfile(nameQuery.Name); encounterError != nil {
+		profileErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func profileErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrProfileNotFound):
+		query.API404Response(c, err)
+	case errors.Is(err, model.ErrProfileExists), errors.Is(err, model.ErrProfileInUse):
+		query.API409Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
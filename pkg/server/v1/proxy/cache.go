@@ -0,0 +1,152 @@
+package proxy
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cylonchau/pantheon/pkg/exposition"
+)
+
+// cacheHeader 标识响应来自缓存、合并的请求还是新的抓取
+const cacheHeader = "X-Pantheon-Cache"
+
+// responseCache 代理共享的抓取缓存，在 RegisterProxyAPI 时重置
+var responseCache = newScrapeCache()
+
+const (
+	cacheHit       = "hit"
+	cacheCoalesced = "coalesced"
+	cacheMiss      = "miss"
+)
+
+// 转发给客户端时忽略的响应头，内容长度与编码由代理重新生成
+var skippedResponseHeaders = map[string]bool{
+	"Connection":        true,
+	"Keep-Alive":        true,
+	"Transfer-Encoding": true,
+	"Content-Length":    true,
+	"Content-Encoding":  true,
+}
+
+// scrapeResponse 缓存的抓取结果
+type scrapeResponse struct {
+	status  int
+	header  http.Header
+	body    []byte
+	expires time.Time
+}
+
+// scrapeCall 正在进行的抓取，相同 key 的并发请求等待同一个结果
+type scrapeCall struct {
+	done     chan struct{}
+	response *scrapeResponse
+	err      error
+}
+
+// scrapeCache HA Prometheus 对同一个 target 的重复抓取在窗口内只访问一次 target
+type scrapeCache struct {
+	mu        sync.Mutex
+	entries   map[string]*scrapeResponse
+	calls     map[string]*scrapeCall
+	nextSweep time.Time
+}
+
+func newScrapeCache() *scrapeCache {
+	return &scrapeCache{
+		entries: make(map[string]*scrapeResponse),
+		calls:   make(map[string]*scrapeCall),
+	}
+}
+
+// do 返回 key 对应的抓取结果，窗口内的结果直接返回，并发的相同请求合并为一次抓取
+// 只缓存 200 响应，失败的抓取只在并发请求之间共享
+func (s *scrapeCache) do(key string, window time.Duration, fetch func() (*scrapeResponse, error)) (*scrapeResponse, string, error) {
+	now := time.Now()
+	s.mu.Lock()
+	if cached, exists := s.entries[key]; exists && now.Before(cached.expires) {
+		s.mu.Unlock()
+		return cached, cacheHit, nil
+	}
+	if call, exists := s.calls[key]; exists {
+		s.mu.Unlock()
+		<-call.done
+		return call.response, cacheCoalesced, call.err
+	}
+	call := &scrapeCall{done: make(chan struct{})}
+	s.calls[key] = call
+	s.mu.Unlock()
+
+	call.response, call.err = fetch()
+
+	s.mu.Lock()
+	delete(s.calls, key)
+	if call.err == nil && call.response.status == http.StatusOK {
+		call.response.expires = time.Now().Add(window)
+		s.entries[key] = call.response
+	}
+	s.sweep(time.Now(), window)
+	s.mu.Unlock()
+	close(call.done)
+	return call.response, cacheMiss, call.err
+}
+
+// sweep 定期清理过期的缓存，调用方需持有锁
+func (s *scrapeCache) sweep(now time.Time, window time.Duration) {
+	if now.Before(s.nextSweep) {
+		return
+	}
+	for key, cached := range s.entries {
+		if !now.Before(cached.expires) {
+			delete(s.entries, key)
+		}
+	}
+	s.nextSweep = now.Add(window)
+}
+
+// cacheWindow 计算 target 的缓存窗口，最长为抓取间隔的一半，保证不会返回上一个周期的结果
+func cacheWindow(configured, scrapeTime int) time.Duration {
+	window := seconds(configured)
+	if limit := seconds(scrapeTime) / 2; window > limit {
+		window = limit
+	}
+	return window
+}
+
+// fetchTimeout 计算共享抓取的超时，抓取不随客户端断开而取消，因此总是需要超时：
+// 优先使用 proxy_timeout，未配置时使用 target 的 scrape_timeout，再使用缓存窗口
+func fetchTimeout(proxyTimeout, scrapeTimeout int, window time.Duration) time.Duration {
+	if proxyTimeout > 0 {
+		return seconds(proxyTimeout)
+	}
+	if scrapeTimeout > 0 {
+		return seconds(scrapeTimeout)
+	}
+	return window
+}
+
+// fetchScrape 抓取 target 并读取经过过滤的完整响应，用于缓存与合并请求
+func fetchScrape(ctx context.Context, transport http.RoundTripper, request *http.Request, maxBytes int64, filter *exposition.Filter) (*scrapeResponse, error) {
+	resp, err := transport.RoundTrip(request.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if err = limitResponse(maxBytes)(resp); err != nil {
+		return nil, err
+	}
+	filterResponse(resp, filter)
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	header := make(http.Header)
+	for key, values := range resp.Header {
+		if !skippedResponseHeaders[key] {
+			header[key] = values
+		}
+	}
+	return &scrapeResponse{status: resp.StatusCode, header: header, body: body}, nil
+}
@@ -0,0 +1,204 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/exposition"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// federateContentType 联邦接口只输出文本格式，抓取 target 时同样只请求文本格式
+const federateContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// federateResult 单个 target 的抓取结果
+type federateResult struct {
+	labels   map[string]string
+	body     []byte
+	duration time.Duration
+	err      error
+}
+
+// federate godoc
+// @Summary Federate targets of a selector
+// @Description Scrape every target of the selector concurrently with their credentials, headers and params,
+// @Description and return the merged exposition in one response. instance and the labels of each target are added to
+// @Description every series, and synthetic up and scrape_duration_seconds series are added for each target.
+// @Description Metric rules of the targets are applied. Configure the Prometheus job with honor_labels: true.
+// @Tags Proxy
+// @Produce plain
+// @Param key path string true "Selector key"
+// @Param value path string true "Selector value"
+// @Success 200 {string} string
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/federate/selector/{key}/{value} [get]
+func (p *ProxyHanderV1) federate(c *gin.Context) {
+	key, value := c.Param("key"), c.Param("value")
+	targets, err := model.ListFederateTargets(key, value)
+	if err != nil {
+		query.API500Response(c, err)
+		return
+	}
+	if len(targets) == 0 {
+		query.API404Response(c, fmt.Errorf("no targets match selector %s=%s", key, value))
+		return
+	}
+
+	// federate_timeout 与 Prometheus 的抓取超时限制整个请求，超时未完成的 target 记为 up 0
+	ctx := c.Request.Context()
+	if timeout := federateTimeout(c.Request.Header.Get("X-Prometheus-Scrape-Timeout-Seconds")); timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	// federate_concurrency 限制同时抓取的 target 数量
+	concurrency := config.CONFIG.Proxy.FederateConcurrency
+	if concurrency <= 0 || concurrency > len(targets) {
+		concurrency = len(targets)
+	}
+	results := make([]federateResult, len(targets))
+	slots := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+	for i := range targets {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int) {
+			defer wg.Done()
+			defer func() { <-slots }()
+			start := time.Now()
+			results[i].labels = federateLabels(&targets[i])
+			results[i].body, results[i].err = scrapeForFederation(ctx, &targets[i], results[i].labels)
+			results[i].duration = time.Since(start)
+		}(i)
+	}
+	wg.Wait()
+
+	merger := exposition.NewMerger()
+	var synthetic bytes.Buffer
+	synthetic.WriteString("# HELP up Whether the target was scraped successfully by the federation endpoint.\n# TYPE up gauge\n")
+	for i := range results {
+		up := 1.0
+		if results[i].err != nil {
+			up = 0
+			klog.V(2).Infof("Failed to federate target %d of selector %s=%s: %v", targets[i].ID, key, value, results[i].err)
+		} else {
+			merger.Add(results[i].body)
+		}
+		synthetic.WriteString(exposition.FormatSample("up", results[i].labels, up))
+	}
+	synthetic.WriteString("# HELP scrape_duration_seconds Duration of the scrape by the federation endpoint.\n# TYPE scrape_duration_seconds gauge\n")
+	for i := range results {
+		synthetic.WriteString(exposition.FormatSample("scrape_duration_seconds", results[i].labels, results[i].duration.Seconds()))
+	}
+	merger.Add(synthetic.Bytes())
+
+	c.Header("Content-Type", federateContentType)
+	c.Status(http.StatusOK)
+	if _, err = merger.WriteTo(c.Writer); err != nil {
+		klog.V(2).Infof("Failed to write federation response for selector %s=%s: %v", key, value, err)
+	}
+}
+
+// federateTimeout 返回整个联邦请求的超时时间，取 federate_timeout 与 Prometheus 抓取超时中较小的值
+func federateTimeout(scrapeTimeout string) time.Duration {
+	timeout := seconds(config.CONFIG.Proxy.FederateTimeout)
+	if value, err := strconv.ParseFloat(scrapeTimeout, 64); err == nil && value > 0 {
+		if limit := time.Duration(value * float64(time.Second)); timeout <= 0 || limit < timeout {
+			timeout = limit
+		}
+	}
+	return timeout
+}
+
+// federateLabels 返回添加到 target 所有样本上的标签，与 SD 输出一致：
+// instance 默认为 target 地址，可以被 target 的 labels 覆盖，blackbox 的 target 参数优先
+func federateLabels(registered *model.Target) map[string]string {
+	labels := map[string]string{"instance": registered.Address}
+	for _, label := range registered.Labels {
+		if exposition.ValidLabelName(label.Key) {
+			labels[label.Key] = label.Value
+		}
+	}
+	for _, param := range registered.Params {
+		if param.Key == "target" {
+			labels["instance"] = param.Value
+		}
+	}
+	return labels
+}
+
+// scrapeForFederation 使用 target 的凭据、请求头与参数抓取 target，返回经过过滤规则处理并添加了标签的结果
+func scrapeForFederation(ctx context.Context, registered *model.Target, labels map[string]string) ([]byte, error) {
+	transport, err := transportFor(registered.TLS, registered.Zone)
+	if err != nil {
+		return nil, err
+	}
+	targetURL, err := url.Parse(fmt.Sprintf("%s://%s%s", registered.Schema, registered.Address, registered.MetricPath))
+	if err != nil {
+		return nil, fmt.Errorf("invalid target URL")
+	}
+	targetURL.RawQuery = registered.ScrapeParams().Encode()
+
+	var authorization string
+	if registered.CredentialID != 0 {
+		credential, err := model.GetCredentialByID(registered.CredentialID)
+		if err != nil {
+			return nil, err
+		}
+		if authorization, err = authorizationFor(credential, registered.Zone); err != nil {
+			return nil, err
+		}
+	}
+
+	// 先应用 target 的过滤规则，再添加标签
+	rules, err := rulesFor(registered.ID)
+	if err != nil {
+		return nil, err
+	}
+	labelRule, err := exposition.Compile("", "", "", labels)
+	if err != nil {
+		return nil, err
+	}
+	filter := exposition.NewFilter(append(rules, labelRule)...)
+
+	request, err := http.NewRequest(http.MethodGet, targetURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range registered.Headers {
+		request.Header.Set(name, value)
+	}
+	if authorization != "" {
+		request.Header.Set("Authorization", authorization)
+	}
+	request.Header.Set("Accept", textFormat)
+
+	if registered.ScrapeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, seconds(registered.ScrapeTimeout))
+		defer cancel()
+	}
+	response, err := fetchScrape(ctx, transport, request, config.CONFIG.Proxy.MaxResponseBytes, filter)
+	if err != nil {
+		return nil, err
+	}
+	if response.status != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code %d", response.status)
+	}
+	if !isTextFormat(response.header.Get("Content-Type")) {
+		return nil, fmt.Errorf("unsupported content type %q", response.header.Get("Content-Type"))
+	}
+	return response.body, nil
+}
@@ -0,0 +1,36 @@
+package proxy
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	rejectInvalidRequest     = "invalid_request"
+	rejectUnregisteredTarget = "unregistered_target"
+	rejectCredentialMismatch = "credential_mismatch"
+	rejectDeniedDestination  = "denied_destination"
+)
+
+var proxyRejectedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "pantheon",
+	Subsystem: "proxy",
+	Name:      "rejected_requests_total",
+	Help:      "Number of proxy requests rejected, by reason.",
+}, []string{"reason"})
+
+var proxyCacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "pantheon",
+	Subsystem: "proxy",
+	Name:      "cache_requests_total",
+	Help:      "Number of proxy requests served with the response cache enabled, by result (hit, coalesced, miss).",
+}, []string{"result"})
+
+func init() {
+	prometheus.MustRegister(proxyRejectedRequests, proxyCacheRequests)
+	for _, reason := range []string{rejectInvalidRequest, rejectUnregisteredTarget, rejectCredentialMismatch, rejectDeniedDestination} {
+		proxyRejectedRequests.WithLabelValues(reason)
+	}
+	for _, result := range []string{cacheHit, cacheCoalesced, cacheMiss} {
+		proxyCacheRequests.WithLabelValues(result)
+	}
+}
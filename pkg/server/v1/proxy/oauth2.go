@@ -0,0 +1,73 @@
+package proxy
+
+import (
+	"context"
+	"net/http"
+	"sync"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// tokenSourceKey 令牌请求经由 target 所在网络区域的代理发出，不同区域分别缓存
+type tokenSourceKey struct {
+	id   uint
+	zone string
+}
+
+// cachedTokenSource 凭据轮换后指纹变化，替换为新的 token source 重新获取令牌
+type cachedTokenSource struct {
+	fingerprint string
+	source      oauth2.TokenSource
+}
+
+// tokenSources 按凭据与网络区域缓存 oauth2 token source，令牌在过期前由 token source 自动刷新
+var tokenSources sync.Map // tokenSourceKey -> *cachedTokenSource
+
+// authorizationFor 返回转发给 zone 中 target 的 Authorization 头，oauth2 凭据使用缓存的访问令牌
+func authorizationFor(cred *model.Credential, zone string) (string, error) {
+	if cred.Type != credential.TypeOAuth2 {
+		return cred.AuthorizationHeader(), nil
+	}
+	key := tokenSourceKey{id: cred.ID, zone: zone}
+	cached, exists := tokenSources.Load(key)
+	if !exists || cached.(*cachedTokenSource).fingerprint != cred.Fingerprint {
+		conf, err := cred.OAuth2()
+		if err != nil {
+			return "", err
+		}
+		clientConfig := &clientcredentials.Config{
+			ClientID:     conf.ClientID,
+			ClientSecret: conf.ClientSecret,
+			TokenURL:     conf.TokenURL,
+			Scopes:       conf.Scopes,
+		}
+		// 获取令牌同样经过代理的连接池、地址限制与网络区域的上游代理
+		transport, err := transportFor(model.TargetTLS{}, zone)
+		if err != nil {
+			return "", err
+		}
+		client := &http.Client{Transport: transport, Timeout: seconds(config.CONFIG.ProxyTimeout)}
+		ctx := context.WithValue(context.Background(), oauth2.HTTPClient, client)
+		cached = &cachedTokenSource{fingerprint: cred.Fingerprint, source: clientConfig.TokenSource(ctx)}
+		// 旧指纹的 token source 被替换，不再保留
+		tokenSources.Store(key, cached)
+	}
+	token, err := cached.(*cachedTokenSource).source.Token()
+	if err != nil {
+		return "", err
+	}
+	return token.Type() + " " + token.AccessToken, nil
+}
+
+// resetTokenSources 清空缓存的令牌，Transport 重新创建后调用
+func resetTokenSources() {
+	tokenSources.Range(func(key, _ interface{}) bool {
+		tokenSources.Delete(key)
+		return true
+	})
+}
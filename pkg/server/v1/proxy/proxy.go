@@ -1,6 +1,8 @@
 package proxy
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httputil"
@@ -13,127 +15,120 @@ import (
 
 	"github.com/cylonchau/pantheon/pkg/api/query"
 	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/exposition"
+	"github.com/cylonchau/pantheon/pkg/model"
 )
 
 type ProxyHanderV1 struct{}
 
+// 这些参数由代理自身使用，不转发给 target
+var reservedParams = map[string]bool{
+	"schema": true, "host": true, "port": true, "path": true,
+	"cred": true, "tid": true, "base": true, "bearer": true,
+}
+
 func (p *ProxyHanderV1) RegisterProxyAPI(g *gin.RouterGroup) {
+	if err := setupTransport(config.CONFIG.Proxy, config.CONFIG.Zones); err != nil {
+		klog.Exitf("Invalid [proxy] configuration: %v", err)
+	}
+	responseCache = newScrapeCache()
+	resetTokenSources()
 	proxyGroup := g.Group("/proxy")
 	proxyGroup.GET("", p.proxy)
+	federateGroup := g.Group("/federate")
+	federateGroup.GET("/selector/:key/:value", p.federate)
 }
 
 // proxy godoc
 // @Summary Reverse Proxy
-// @Description This endpoint proxies requests to another server.
+// @Description Scrape a registered target on behalf of Prometheus. Only targets that exist in pantheon can be proxied,
+// @Description either by target ID or by a schema/host/port/path that matches a registered target.
+// @Description Metric rules of the target and its selectors are applied to the response.
 // @Tags Proxy
 // @Accept json
 // @Produce json
-// @Param schema query string true "Protocol (http/https)"
-// @Param host query string true "Host to proxy to"
-// @Param port query string true "Port to proxy to"
-// @Param base query string false "Basic auth credentials"
-// @Param bearer query string false "Bearer token for authentication"
+// @Param tid query string false "Target ID, takes precedence over schema/host/port/path"
+// @Param schema query string false "Protocol (http/https)"
+// @Param host query string false "Host of the registered target"
+// @Param port query string false "Port of the registered target"
+// @Param path query string false "Metric path of the registered target"
+// @Param cred query string false "Credential UID, must match the credential of the target"
 // @Param param1 query string false "Additional parameter 1"
 // @Param param2 query string false "Additional parameter 2"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} interface{}
+// @Failure 403 {object} query.Response
 // @Router /ph/v1/proxy [get]
 func (p *ProxyHanderV1) proxy(c *gin.Context) {
-
 	start := time.Now()
-	duration := time.Since(start)
 
-	// 1. 获取参数和参数校验
-	schema := c.Query("schema")
-	host := c.Query("host")
-	port := c.Query("port")
-	base := c.Query("base")
-	bearer := c.Query("bearer")
-	path := c.Query("path")
-
-	// 验证必需的参数
-	if schema == "" || host == "" || port == "" {
-		query.API400Response(c, fmt.Errorf("schema, host, port are required"))
+	// 1. 只允许抓取已注册的 target
+	registered, reason, err := resolveTarget(c)
+	if err != nil {
+		if reason != "" {
+			reject(c, reason, err)
+			return
+		}
+		query.API500Response(c, err)
 		return
 	}
 
-	// 验证 schema
-	if schema != "http" && schema != "https" {
-		query.API400Response(c, fmt.Errorf("invalid schema; only 'http' and 'https' are allowed"))
+	// 2. 在服务端解析 target 的凭据，凭据内容不会出现在 SD 输出中，客户端传入的认证信息一律忽略
+	cred := c.Query("cred")
+	var credential *model.Credential
+	if registered.CredentialID != 0 {
+		if credential, err = model.GetCredentialByID(registered.CredentialID); err != nil {
+			query.API500Response(c, err)
+			return
+		}
+		if cred != "" && cred != credential.UID {
+			reject(c, rejectCredentialMismatch, fmt.Errorf("credential %s does not belong to target %d", cred, registered.ID))
+			return
+		}
+	} else if cred != "" {
+		reject(c, rejectCredentialMismatch, fmt.Errorf("target %d has no credential", registered.ID))
 		return
 	}
 
-	// 验证 host
-	if host == "" {
-		query.API400Response(c, fmt.Errorf("invalid host"))
-		return
-	}
-	// 验证 port
-	portNum, err := strconv.Atoi(port)
-	if err != nil || portNum < 1 || portNum > 65535 {
-		query.API400Response(c, fmt.Errorf("invalid port; must be a number between 1 and 65535"))
+	// 3. 所有 target 共享连接池，配置了 TLS 或位于网络区域中的 target 使用对应的 Transport
+	transport, err := transportFor(registered.TLS, registered.Zone)
+	if err != nil {
+		query.API500Response(c, err)
 		return
 	}
 
-	// 构建目标 URL，省略默认端口
-	target := fmt.Sprintf("%s://%s", schema, host)
-
-	if (schema == "http" && portNum != 80) || (schema == "https" && portNum != 443) {
-		target = fmt.Sprintf("%s:%s", target, port)
+	// 构建目标 URL，地址与路径均来自已注册的 target
+	targetURL, err := url.Parse(fmt.Sprintf("%s://%s%s", registered.Schema, registered.Address, registered.MetricPath))
+	if err != nil {
+		query.API500Response(c, fmt.Errorf("invalid target URL"))
+		return
 	}
+	// 只转发 target 保存的参数，如 blackbox exporter 的 module 与 target，客户端传入的参数一律忽略，
+	// 否则知道 tid 的调用方可以让 exporter 访问任意地址
+	targetURL.RawQuery = registered.ScrapeParams().Encode()
 
-	if path != "" {
-		decodedPath, _ := url.QueryUnescape(path)
-		if decodedPath[0] != '/' {
-			decodedPath = "/" + decodedPath
+	// oauth2 凭据在转发前获取访问令牌，令牌缓存到过期前
+	var authorization string
+	if credential != nil {
+		if authorization, err = authorizationFor(credential, registered.Zone); err != nil {
+			c.Status(failureStatus(c, registered, targetURL, err))
+			return
 		}
-		target = fmt.Sprintf("%s%s", target, decodedPath)
 	}
 
-	// 解析目标 URL
-	targetURL, err := url.Parse(target)
+	// 4. 按 target 与所属 selector 的过滤规则处理抓取结果
+	filter, err := filterFor(registered.ID)
 	if err != nil {
-		query.API400Response(c, fmt.Errorf("invalid target URL"))
+		query.API500Response(c, err)
 		return
 	}
-	fmt.Println(targetURL)
-	// 创建反向代理
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-	// 设置超时
-
-	proxy.Transport = http.Client{
-		Timeout: time.Duration(config.CONFIG.ProxyTimeout) * time.Second, // 设置更长的超时时间
-	}.Transport
-	// 设置认证头和其他参数
-	proxy.Director = func(req *http.Request) {
-		req.URL = targetURL
-
-		// 添加认证头
-		if base != "" {
-			req.Header.Set("Authorization", "Basic "+base)
-		} else if bearer != "" {
-			req.Header.Set("Authorization", "Bearer "+bearer)
-		}
 
-		// 添加 User-Agent
-		if userAgent := c.Request.Header.Get("User-Agent"); userAgent != "" {
-			req.Header.Set("User-Agent", userAgent)
-		}
-
-		// 添加其他参数，排除已知参数
-		query := c.Request.URL.Query()
-		for key := range query {
-			if key != "schema" && key != "host" && key != "port" && key != "base" && key != "bearer" && key != "path" {
-				for _, value := range query[key] {
-					req.URL.Query().Add(key, value)
-				}
-			}
-		}
-		// 将参数更新到请求 URL
-		req.URL.RawQuery = req.URL.Query().Encode()
+	// 5. 开启缓存时，HA Prometheus 对同一 target 的抓取在窗口内共享一次请求的结果
+	if window := cacheWindow(config.CONFIG.Proxy.CacheWindow, registered.ScrapeTime); window > 0 {
+		serveCached(c, registered, targetURL, authorization, transport, filter, window)
+	} else {
+		serveStream(c, registered, targetURL, authorization, transport, filter)
 	}
-	klog.V(4).Infof("Proxying request to: %s", targetURL.String())
-	proxy.ServeHTTP(c.Writer, c.Request)
 	if c.Writer.Status() != http.StatusOK {
 		klog.Errorf("Failed to proxy request, status code: %d", c.Writer.Status())
 	}
@@ -148,6 +143,185 @@ func (p *ProxyHanderV1) proxy(c *gin.Context) {
 		c.Writer.Size(),
 		c.Request.Referer(),
 		c.Request.UserAgent(),
-		duration,
+		time.Since(start),
 	)
 }
+
+// serveStream 以反向代理的方式将 target 的响应直接转发给客户端
+func serveStream(c *gin.Context, registered *model.Target, targetURL *url.URL, authorization string, transport http.RoundTripper, filter *exposition.Filter) {
+	proxy := httputil.NewSingleHostReverseProxy(targetURL)
+	proxy.Transport = transport
+	limit := limitResponse(config.CONFIG.Proxy.MaxResponseBytes)
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		if err := limit(resp); err != nil {
+			return err
+		}
+		filterResponse(resp, filter)
+		return nil
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, r *http.Request, err error) {
+		w.WriteHeader(failureStatus(c, registered, targetURL, err))
+	}
+	// 设置认证头和其他参数
+	proxy.Director = func(req *http.Request) {
+		req.URL = targetURL
+		// 使用 target 的地址作为 Host 头
+		req.Host = ""
+
+		// 添加 target 的自定义请求头，如 API 网关需要的 X-Api-Key
+		for name, value := range registered.Headers {
+			req.Header.Set(name, value)
+		}
+
+		// 添加认证头，客户端访问 pantheon 使用的认证头不转发给 target
+		req.Header.Del("Authorization")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+
+		// 添加 User-Agent
+		if userAgent := c.Request.Header.Get("User-Agent"); userAgent != "" {
+			req.Header.Set("User-Agent", userAgent)
+		}
+
+		// 过滤规则只能处理未压缩的文本格式，由 Transport 负责解压
+		if filter != nil {
+			req.Header.Del("Accept-Encoding")
+			req.Header.Set("Accept", textAccept(req.Header.Get("Accept")))
+		}
+	}
+
+	// proxy_timeout 限制整个抓取请求的耗时
+	request := c.Request
+	if config.CONFIG.ProxyTimeout > 0 {
+		ctx, cancel := context.WithTimeout(request.Context(), time.Duration(config.CONFIG.ProxyTimeout)*time.Second)
+		defer cancel()
+		request = request.WithContext(ctx)
+	}
+	klog.V(4).Infof("Proxying request to: %s", targetURL.String())
+	proxy.ServeHTTP(c.Writer, request)
+}
+
+// serveCached 从缓存返回 target 的响应，缓存未命中时由第一个请求抓取，并发的相同请求等待其结果
+// 抓取不受单个客户端断开的影响，响应按 Accept 头区分，避免不同格式的抓取共享结果
+func serveCached(c *gin.Context, registered *model.Target, targetURL *url.URL, authorization string, transport http.RoundTripper, filter *exposition.Filter, window time.Duration) {
+	accept := c.Request.Header.Get("Accept")
+	key := fmt.Sprintf("%d?%s|%s", registered.ID, targetURL.RawQuery, accept)
+
+	response, result, err := responseCache.do(key, window, func() (*scrapeResponse, error) {
+		request, err := http.NewRequest(http.MethodGet, targetURL.String(), nil)
+		if err != nil {
+			return nil, err
+		}
+		for name, value := range registered.Headers {
+			request.Header.Set(name, value)
+		}
+		if authorization != "" {
+			request.Header.Set("Authorization", authorization)
+		}
+		for _, header := range []string{"Accept", "User-Agent", "X-Prometheus-Scrape-Timeout-Seconds"} {
+			if value := c.Request.Header.Get(header); value != "" {
+				request.Header.Set(header, value)
+			}
+		}
+		if filter != nil {
+			request.Header.Set("Accept", textAccept(request.Header.Get("Accept")))
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), fetchTimeout(config.CONFIG.ProxyTimeout, registered.ScrapeTimeout, window))
+		defer cancel()
+		klog.V(4).Infof("Proxying request to: %s", targetURL.String())
+		return fetchScrape(ctx, transport, request, config.CONFIG.Proxy.MaxResponseBytes, filter)
+	})
+	proxyCacheRequests.WithLabelValues(result).Inc()
+	c.Header(cacheHeader, result)
+	if err != nil {
+		c.Status(failureStatus(c, registered, targetURL, err))
+		return
+	}
+
+	for key, values := range response.header {
+		c.Writer.Header()[key] = values
+	}
+	c.Writer.WriteHeader(response.status)
+	c.Writer.Write(response.body)
+}
+
+// failureStatus 记录抓取失败的原因并返回对应的状态码
+func failureStatus(c *gin.Context, registered *model.Target, targetURL *url.URL, err error) int {
+	if errors.Is(err, errDestinationDenied) {
+		proxyRejectedRequests.WithLabelValues(rejectDeniedDestination).Inc()
+		klog.Warningf("Rejected proxy request from %s (%s): target %d: %v", c.ClientIP(), rejectDeniedDestination, registered.ID, err)
+		return http.StatusForbidden
+	}
+	klog.V(2).Infof("Failed to proxy request to %s: %v", targetURL.String(), err)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadGateway
+}
+
+// resolveTarget 根据 tid 或 schema/host/port/path 查找已注册的 target
+// 请求被拒绝时返回拒绝原因，数据库错误时原因为空
+func resolveTarget(c *gin.Context) (*model.Target, string, error) {
+	if tid := c.Query("tid"); tid != "" {
+		targetID, err := strconv.ParseUint(tid, 10, 64)
+		if err != nil {
+			return nil, rejectInvalidRequest, fmt.Errorf("invalid tid")
+		}
+		found, err := model.GetProxyTargetByID(uint(targetID))
+		if errors.Is(err, model.ErrTargetNotFound) {
+			return nil, rejectUnregisteredTarget, fmt.Errorf("unknown target: %s", tid)
+		}
+		return found, "", err
+	}
+
+	schema := c.Query("schema")
+	host := c.Query("host")
+	port := c.Query("port")
+	path := c.Query("path")
+
+	// 验证必需的参数
+	if schema == "" || host == "" || port == "" {
+		return nil, rejectInvalidRequest, fmt.Errorf("tid or schema, host, port are required")
+	}
+	// 验证 schema
+	if schema != "http" && schema != "https" {
+		return nil, rejectInvalidRequest, fmt.Errorf("invalid schema; only 'http' and 'https' are allowed")
+	}
+	// 验证 port
+	portNum, err := strconv.Atoi(port)
+	if err != nil || portNum < 1 || portNum > 65535 {
+		return nil, rejectInvalidRequest, fmt.Errorf("invalid port; must be a number between 1 and 65535")
+	}
+	if path == "" || path[0] != '/' {
+		path = "/" + path
+	}
+
+	// 地址相同的 target 按客户端传入的其他参数区分
+	params := url.Values{}
+	for key, values := range c.Request.URL.Query() {
+		if !reservedParams[key] {
+			params[key] = values
+		}
+	}
+	found, err := model.FindProxyTarget(schema, host, port, path, params)
+	if errors.Is(err, model.ErrTargetNotFound) {
+		return nil, rejectUnregisteredTarget, fmt.Errorf("no registered target matches %s://%s:%s%s", schema, host, port, path)
+	}
+	if errors.Is(err, model.ErrTargetAmbiguous) {
+		return nil, rejectInvalidRequest, err
+	}
+	return found, "", err
+}
+
+// reject 拒绝代理请求，记录日志与指标
+func reject(c *gin.Context, reason string, err error) {
+	proxyRejectedRequests.WithLabelValues(reason).Inc()
+	klog.Warningf("Rejected proxy request from %s (%s): %v", c.ClientIP(), reason, err)
+	if reason == rejectInvalidRequest {
+		query.API400Response(c, err)
+		return
+	}
+	query.API403Response(c, err)
+}
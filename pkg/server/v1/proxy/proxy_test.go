@@ -0,0 +1,991 @@
+package proxy
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/credential"
+	"github.com/cylonchau/pantheon/pkg/api/metricrule"
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// setupProxyServer 使用给定配置启动代理，返回代理地址
+func setupProxyServer(t *testing.T, conf *config.Config) string {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	model.SetupTestDB(t)
+	config.CONFIG = conf
+	t.Cleanup(func() { config.CONFIG = nil })
+
+	engine := gin.New()
+	(&ProxyHanderV1{}).RegisterProxyAPI(engine.Group("/ph/v1"))
+	server := httptest.NewServer(engine)
+	t.Cleanup(server.Close)
+	return server.URL + "/ph/v1/proxy"
+}
+
+// exporterServer 模拟 exporter，统计新建连接数
+type exporterServer struct {
+	*httptest.Server
+	newConns atomic.Int64
+}
+
+func newExporterServer(t *testing.T, handler http.HandlerFunc) *exporterServer {
+	t.Helper()
+	exporter := &exporterServer{}
+	exporter.Server = httptest.NewUnstartedServer(handler)
+	exporter.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			exporter.newConns.Add(1)
+		}
+	}
+	exporter.Start()
+	t.Cleanup(exporter.Close)
+	return exporter
+}
+
+func (e *exporterServer) port() string {
+	_, port, _ := net.SplitHostPort(e.Listener.Addr().String())
+	return port
+}
+
+func proxyURL(proxyAddress, port, path string) string {
+	values := url.Values{}
+	values.Set("schema", "http")
+	values.Set("host", "127.0.0.1")
+	values.Set("port", port)
+	values.Set("path", path)
+	return proxyAddress + "?" + values.Encode()
+}
+
+// registerTarget 注册 target，返回 target ID
+func registerTarget(t *testing.T, port, path string, auth *target.TargetAuth) uint {
+	t.Helper()
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "test"},
+		Targets:          []target.TargetItem{{Address: "127.0.0.1:" + port, MetricPath: path, Auth: auth}},
+	}))
+	found, err := model.FindProxyTarget("http", "127.0.0.1", port, path, nil)
+	require.NoError(t, err)
+	return found.ID
+}
+
+// TestProxy_MaxResponseBytes 测试超过 max_response_bytes 的响应被拒绝
+func TestProxy_MaxResponseBytes(t *testing.T) {
+	// Arrange
+	body := strings.Repeat("x", 1024)
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			// 不设置 Content-Length，分块返回
+			w.Write([]byte(body[:512]))
+			w.(http.Flusher).Flush()
+			w.Write([]byte(body[512:]))
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.Write([]byte(body))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{MaxResponseBytes: 100}})
+	registerTarget(t, exporter.port(), "/metrics", nil)
+	registerTarget(t, exporter.port(), "/chunked", nil)
+
+	// Act
+	resp, err := http.Get(proxyURL(proxyAddress, exporter.port(), "/metrics"))
+	require.NoError(t, err)
+	resp.Body.Close()
+	chunked, err := http.Get(proxyURL(proxyAddress, exporter.port(), "/chunked"))
+	var chunkedBody []byte
+	if err == nil {
+		chunkedBody, err = io.ReadAll(chunked.Body)
+		chunked.Body.Close()
+	}
+
+	// Assert
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	// 长度未知的响应在超过限制时中断，客户端不会拿到完整内容
+	assert.True(t, err != nil || len(chunkedBody) <= 100, "oversized chunked response should be aborted")
+}
+
+// TestProxy_Timeout 测试 proxy_timeout 生效，超时返回 504
+func TestProxy_Timeout(t *testing.T) {
+	// Arrange
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{ProxyTimeout: 1})
+	registerTarget(t, exporter.port(), "/metrics", nil)
+
+	// Act
+	start := time.Now()
+	resp, err := http.Get(proxyURL(proxyAddress, exporter.port(), "/metrics"))
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, time.Since(start), 3*time.Second)
+}
+
+// TestProxy_Load5kTargets 模拟 Prometheus 每个抓取周期经由代理抓取 5k 个 target
+// 验证所有抓取成功，到 exporter 的连接数受 max_conns_per_host 限制并在周期之间复用，且没有 goroutine 泄漏
+// 默认周期为 2s，使用真实周期运行: PANTHEON_PROXY_LOAD_INTERVAL=15s go test -run TestProxy_Load5kTargets ./pkg/server/v1/proxy/
+func TestProxy_Load5kTargets(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping load test in short mode")
+	}
+	const (
+		exporters          = 50
+		targetsPerExporter = 100
+		rounds             = 3
+		maxConnsPerHost    = 4
+	)
+	interval := 2 * time.Second
+	if value := os.Getenv("PANTHEON_PROXY_LOAD_INTERVAL"); value != "" {
+		parsed, err := time.ParseDuration(value)
+		require.NoError(t, err)
+		interval = parsed
+	}
+
+	// Arrange
+	metrics := strings.Repeat("node_cpu_seconds_total{cpu=\"0\",mode=\"idle\"} 1234.5\n", 100)
+	servers := make([]*exporterServer, exporters)
+	for i := range servers {
+		servers[i] = newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(metrics))
+		})
+	}
+	proxyAddress := setupProxyServer(t, &config.Config{
+		ProxyTimeout: 10,
+		Proxy: config.ProxyConfig{
+			DialTimeout:           5,
+			ResponseHeaderTimeout: 10,
+			IdleConnTimeout:       90,
+			MaxIdleConns:          10000,
+			MaxConnsPerHost:       maxConnsPerHost,
+			MaxResponseBytes:      1 << 20,
+		},
+	})
+	client := &http.Client{
+		Timeout:   10 * time.Second,
+		Transport: &http.Transport{MaxIdleConnsPerHost: 1000},
+	}
+	// 与 SD 输出一致，通过 target ID 访问代理
+	targets := make([]model.Target, 0, exporters*targetsPerExporter)
+	for _, server := range servers {
+		for i := 0; i < targetsPerExporter; i++ {
+			targets = append(targets, model.Target{Address: "127.0.0.1:" + server.port(), Schema: "http", MetricPath: fmt.Sprintf("/metrics/%d", i)})
+		}
+	}
+	require.NoError(t, model.DB.CreateInBatches(targets, 500).Error)
+	urls := make([]string, 0, len(targets))
+	for _, registered := range targets {
+		urls = append(urls, fmt.Sprintf("%s?tid=%d", proxyAddress, registered.ID))
+	}
+	baseline := runtime.NumGoroutine()
+
+	// Act
+	var failures atomic.Int64
+	var firstErr atomic.Value
+	for round := 0; round < rounds; round++ {
+		// 与 Prometheus 一样将抓取均匀分布在整个周期内
+		var wg sync.WaitGroup
+		spread := interval / time.Duration(len(urls))
+		start := time.Now()
+		for i, scrapeURL := range urls {
+			if wait := time.Until(start.Add(spread * time.Duration(i))); wait > 0 {
+				time.Sleep(wait)
+			}
+			wg.Add(1)
+			go func(scrapeURL string) {
+				defer wg.Done()
+				resp, err := client.Get(scrapeURL)
+				if err != nil {
+					failures.Add(1)
+					firstErr.CompareAndSwap(nil, err.Error())
+					return
+				}
+				body, err := io.ReadAll(resp.Body)
+				resp.Body.Close()
+				if err != nil || resp.StatusCode != http.StatusOK || len(body) != len(metrics) {
+					failures.Add(1)
+					firstErr.CompareAndSwap(nil, fmt.Sprintf("status %d, %d bytes, %v", resp.StatusCode, len(body), err))
+				}
+			}(scrapeURL)
+		}
+		wg.Wait()
+		if elapsed := time.Since(start); elapsed < interval {
+			time.Sleep(interval - elapsed)
+		}
+	}
+
+	// Assert
+	assert.Zero(t, failures.Load(), "first failure: %v", firstErr.Load())
+	var totalConns int64
+	for _, server := range servers {
+		assert.LessOrEqual(t, server.newConns.Load(), int64(maxConnsPerHost), "connections to an exporter should be capped and reused")
+		totalConns += server.newConns.Load()
+	}
+	t.Logf("%d scrapes over %d rounds used %d upstream connections", rounds*len(urls), rounds, totalConns)
+
+	// 关闭空闲连接后 goroutine 应回到基线附近
+	client.CloseIdleConnections()
+	transportMu.RLock()
+	baseTransport.CloseIdleConnections()
+	transportMu.RUnlock()
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine() <= baseline+20
+	}, 5*time.Second, 100*time.Millisecond, "goroutines leaked: baseline %d, now %d", baseline, runtime.NumGoroutine())
+}
+
+// TestProxy_RejectsUnregisteredTarget 测试代理拒绝未注册的地址，并记录拒绝指标
+func TestProxy_RejectsUnregisteredTarget(t *testing.T) {
+	// Arrange
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	before := testutil.ToFloat64(proxyRejectedRequests.WithLabelValues(rejectUnregisteredTarget))
+	values := url.Values{"schema": {"http"}, "host": {"169.254.169.254"}, "port": {"80"}, "path": {"/latest/meta-data/"}}
+
+	// Act
+	resp, err := http.Get(proxyAddress + "?" + values.Encode())
+	require.NoError(t, err)
+	resp.Body.Close()
+	unknownID, err := http.Get(proxyAddress + "?tid=42")
+	require.NoError(t, err)
+	unknownID.Body.Close()
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Equal(t, http.StatusForbidden, unknownID.StatusCode)
+	assert.Equal(t, before+2, testutil.ToFloat64(proxyRejectedRequests.WithLabelValues(rejectUnregisteredTarget)))
+}
+
+// TestProxy_DenyCIDR 测试已注册的 target 位于 deny_cidrs 中时同样被拒绝
+func TestProxy_DenyCIDR(t *testing.T) {
+	// Arrange
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{DenyCIDRs: []string{"127.0.0.0/8"}}})
+	targetID := registerTarget(t, exporter.port(), "/metrics", nil)
+	before := testutil.ToFloat64(proxyRejectedRequests.WithLabelValues(rejectDeniedDestination))
+
+	// Act
+	resp, err := http.Get(fmt.Sprintf("%s?tid=%d", proxyAddress, targetID))
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Zero(t, exporter.newConns.Load())
+	assert.Equal(t, before+1, testutil.ToFloat64(proxyRejectedRequests.WithLabelValues(rejectDeniedDestination)))
+}
+
+// TestProxy_UsesTargetCredential 测试代理使用 target 自身的凭据，忽略客户端传入的认证信息与其他参数
+func TestProxy_UsesTargetCredential(t *testing.T) {
+	// Arrange
+	var gotAuthorization, gotModule string
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		gotModule = r.URL.Query().Get("module")
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	info, err := model.CreateCredential(&credential.Credential{Name: "node", Type: credential.TypeBearer, Secret: credential.Secret{Token: "stored"}})
+	require.NoError(t, err)
+	targetID := registerTarget(t, exporter.port(), "/metrics", &target.TargetAuth{Credential: info.UID})
+
+	// Act
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?tid=%d&cred=%s&bearer=injected&module=http_2xx", proxyAddress, targetID, info.UID), nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "Bearer prometheus")
+	resp, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	resp.Body.Close()
+	mismatch, err := http.Get(fmt.Sprintf("%s?tid=%d&cred=other", proxyAddress, targetID))
+	require.NoError(t, err)
+	mismatch.Body.Close()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer stored", gotAuthorization)
+	assert.Empty(t, gotModule)
+	assert.Equal(t, http.StatusForbidden, mismatch.StatusCode)
+}
+
+// TestProxy_ForwardsOnlyStoredParams 测试代理只转发 target 保存的参数，客户端无法通过 target 等参数改变抓取地址
+func TestProxy_ForwardsOnlyStoredParams(t *testing.T) {
+	// Arrange
+	var gotQuery url.Values
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotQuery = r.URL.Query()
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "test"},
+		Targets: []target.TargetItem{{
+			Address:    "127.0.0.1:" + exporter.port(),
+			MetricPath: "/probe",
+			Params:     map[string]string{"module": "http_2xx", "target": "https://example.com"},
+		}},
+	}))
+	found, err := model.FindProxyTarget("http", "127.0.0.1", exporter.port(), "/probe", nil)
+	require.NoError(t, err)
+
+	// Act
+	resp, err := http.Get(fmt.Sprintf("%s?tid=%d&module=tcp_connect&target=169.254.169.254:80&extra=1", proxyAddress, found.ID))
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, url.Values{"module": {"http_2xx"}, "target": {"https://example.com"}}, gotQuery)
+}
+
+// TestProxy_AmbiguousTarget 测试多个 target 地址相同时按参数区分，无法区分时拒绝请求
+func TestProxy_AmbiguousTarget(t *testing.T) {
+	// Arrange
+	var gotTarget string
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotTarget = r.URL.Query().Get("target")
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "test"},
+		Targets: []target.TargetItem{
+			{Address: "127.0.0.1:" + exporter.port(), MetricPath: "/probe", Params: map[string]string{"target": "a.example.com"}},
+			{Address: "127.0.0.1:" + exporter.port(), MetricPath: "/probe", Params: map[string]string{"target": "b.example.com"}},
+		},
+	}))
+	query := url.Values{"schema": {"http"}, "host": {"127.0.0.1"}, "port": {exporter.port()}, "path": {"/probe"}}
+	before := testutil.ToFloat64(proxyRejectedRequests.WithLabelValues(rejectInvalidRequest))
+
+	// Act
+	ambiguous, err := http.Get(proxyAddress + "?" + query.Encode())
+	require.NoError(t, err)
+	ambiguous.Body.Close()
+	query.Set("target", "b.example.com")
+	matched, err := http.Get(proxyAddress + "?" + query.Encode())
+	require.NoError(t, err)
+	matched.Body.Close()
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, ambiguous.StatusCode)
+	assert.Equal(t, before+1, testutil.ToFloat64(proxyRejectedRequests.WithLabelValues(rejectInvalidRequest)))
+	assert.Equal(t, http.StatusOK, matched.StatusCode)
+	assert.Equal(t, "b.example.com", gotTarget)
+}
+
+// TestProxy_CacheCoalescesScrapes 测试 HA Prometheus 同时抓取同一个 target 时只访问一次 exporter
+func TestProxy_CacheCoalescesScrapes(t *testing.T) {
+	// Arrange
+	var scrapes atomic.Int64
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		scrapes.Add(1)
+		time.Sleep(200 * time.Millisecond)
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{CacheWindow: 10}})
+	targetID := registerTarget(t, exporter.port(), "/metrics", nil)
+	scrapeURL := fmt.Sprintf("%s?tid=%d", proxyAddress, targetID)
+	before := testutil.ToFloat64(proxyCacheRequests.WithLabelValues(cacheHit))
+
+	// Act
+	var wg sync.WaitGroup
+	bodies := make([]string, 10)
+	statuses := make([]int, 10)
+	for i := range bodies {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			resp, err := http.Get(scrapeURL)
+			if err != nil {
+				return
+			}
+			defer resp.Body.Close()
+			body, _ := io.ReadAll(resp.Body)
+			statuses[i], bodies[i] = resp.StatusCode, string(body)
+		}(i)
+	}
+	wg.Wait()
+	cached, err := http.Get(scrapeURL)
+	require.NoError(t, err)
+	cached.Body.Close()
+
+	// Assert
+	for i := range bodies {
+		assert.Equal(t, http.StatusOK, statuses[i])
+		assert.Equal(t, "up 1\n", bodies[i])
+	}
+	assert.Equal(t, int64(1), scrapes.Load())
+	assert.Equal(t, cacheHit, cached.Header.Get(cacheHeader))
+	assert.Equal(t, "text/plain; version=0.0.4", cached.Header.Get("Content-Type"))
+	assert.Equal(t, before+1, testutil.ToFloat64(proxyCacheRequests.WithLabelValues(cacheHit)))
+}
+
+// TestProxy_CacheFetchTimeout 测试未配置 proxy_timeout 时共享的抓取使用 target 的 scrape_timeout 超时，不会一直等待
+func TestProxy_CacheFetchTimeout(t *testing.T) {
+	// Arrange
+	release := make(chan struct{})
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	})
+	t.Cleanup(func() { close(release) })
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{CacheWindow: 10}})
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "test"},
+		Targets:          []target.TargetItem{{Address: "127.0.0.1:" + exporter.port(), MetricPath: "/metrics", ScrapeTime: 30, ScrapeTimeout: 1}},
+	}))
+
+	// Act
+	start := time.Now()
+	resp, err := http.Get(proxyURL(proxyAddress, exporter.port(), "/metrics"))
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusGatewayTimeout, resp.StatusCode)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
+
+// TestProxy_CacheWindowBelowScrapeInterval 测试缓存窗口不超过抓取间隔的一半，且失败的抓取不被缓存
+func TestProxy_CacheWindowBelowScrapeInterval(t *testing.T) {
+	// Arrange
+	var scrapes atomic.Int64
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		scrapes.Add(1)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{CacheWindow: 60}})
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"app": "test"},
+		Targets: []target.TargetItem{
+			{Address: "127.0.0.1:" + exporter.port(), MetricPath: "/metrics", ScrapeTime: 2},
+			{Address: "127.0.0.1:" + exporter.port(), MetricPath: "/broken", ScrapeTime: 2},
+		},
+	}))
+	get := func(path string) *http.Response {
+		resp, err := http.Get(proxyURL(proxyAddress, exporter.port(), path))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp
+	}
+
+	// Act
+	first := get("/metrics")
+	second := get("/metrics")
+	time.Sleep(1100 * time.Millisecond)
+	third := get("/metrics")
+	get("/broken")
+	broken := get("/broken")
+
+	// Assert
+	assert.Equal(t, cacheMiss, first.Header.Get(cacheHeader))
+	assert.Equal(t, cacheHit, second.Header.Get(cacheHeader))
+	// 抓取间隔为 2s，窗口被限制为 1s
+	assert.Equal(t, cacheMiss, third.Header.Get(cacheHeader))
+	assert.Equal(t, http.StatusInternalServerError, broken.StatusCode)
+	assert.Equal(t, cacheMiss, broken.Header.Get(cacheHeader))
+	assert.Equal(t, int64(4), scrapes.Load())
+}
+
+// TestProxy_AppliesMetricRules 测试代理按 selector 的规则过滤抓取结果，并向 target 请求未压缩的文本格式
+func TestProxy_AppliesMetricRules(t *testing.T) {
+	for _, cacheWindow := range []int{0, 10} {
+		t.Run(fmt.Sprintf("cache_window=%d", cacheWindow), func(t *testing.T) {
+			// Arrange
+			var gotAccept string
+			exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+				gotAccept = r.Header.Get("Accept")
+				w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+				w.Write([]byte("# TYPE vendor_debug gauge\nvendor_debug{pod=\"a\"} 1\n# TYPE up gauge\nup{pod=\"a\"} 1\n"))
+			})
+			proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{CacheWindow: cacheWindow}})
+			targetID := registerTarget(t, exporter.port(), "/metrics", nil)
+			_, err := model.CreateMetricRule(&metricrule.MetricRule{
+				Name: "test", Selector: "app=test", Drop: "vendor_.*", DropLabels: "pod", AddLabels: map[string]string{"dc": "bj"},
+			})
+			require.NoError(t, err)
+
+			// Act
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?tid=%d", proxyAddress, targetID), nil)
+			require.NoError(t, err)
+			request.Header.Set("Accept", "application/vnd.google.protobuf;proto=io.prometheus.client.MetricFamily;encoding=delimited;q=0.7,text/plain;version=0.0.4;q=0.3")
+			resp, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			body, err := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "# TYPE up gauge\nup{dc=\"bj\"} 1\n", string(body))
+			assert.Equal(t, "text/plain;version=0.0.4;q=0.3", gotAccept)
+		})
+	}
+}
+
+// TestProxy_OAuth2Credential 测试代理使用 client credentials 获取令牌，缓存令牌并在过期前刷新
+func TestProxy_OAuth2Credential(t *testing.T) {
+	// Arrange
+	var tokenRequests atomic.Int64
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		clientID, clientSecret, ok := r.BasicAuth()
+		if err := r.ParseForm(); err != nil || r.Form.Get("grant_type") != "client_credentials" ||
+			!ok || clientID != "pantheon" || clientSecret != "secret" || r.Form.Get("scope") != "metrics:read" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		// 令牌在 11s 后过期，提前 10s 刷新，即 1s 后需要重新获取
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":11}`, tokenRequests.Add(1))
+	}))
+	t.Cleanup(tokenServer.Close)
+	var authorizations []string
+	var mu sync.Mutex
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	info, err := model.CreateCredential(&credential.Credential{Name: "saas", Type: credential.TypeOAuth2, Secret: credential.Secret{
+		OAuth2: &credential.OAuth2{TokenURL: tokenServer.URL, ClientID: "pantheon", ClientSecret: "secret", Scopes: []string{"metrics:read"}},
+	}})
+	require.NoError(t, err)
+	targetID := registerTarget(t, exporter.port(), "/metrics", &target.TargetAuth{Credential: info.UID})
+	scrape := func() int {
+		resp, err := http.Get(fmt.Sprintf("%s?tid=%d", proxyAddress, targetID))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Act
+	first, second := scrape(), scrape()
+	time.Sleep(1100 * time.Millisecond)
+	third := scrape()
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK, http.StatusOK}, []int{first, second, third})
+	assert.Equal(t, []string{"Bearer token-1", "Bearer token-1", "Bearer token-2"}, authorizations)
+	assert.Equal(t, int64(2), tokenRequests.Load())
+}
+
+// TestProxy_OAuth2CredentialRotated 测试凭据轮换后使用新的 client secret 获取令牌，旧指纹的 token source 不再保留
+func TestProxy_OAuth2CredentialRotated(t *testing.T) {
+	// Arrange
+	var clientSecrets []string
+	var mu sync.Mutex
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, clientSecret, _ := r.BasicAuth()
+		mu.Lock()
+		clientSecrets = append(clientSecrets, clientSecret)
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%s","token_type":"Bearer","expires_in":3600}`, clientSecret)
+	}))
+	t.Cleanup(tokenServer.Close)
+	var authorizations []string
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		authorizations = append(authorizations, r.Header.Get("Authorization"))
+		mu.Unlock()
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	info, err := model.CreateCredential(&credential.Credential{Name: "saas", Type: credential.TypeOAuth2, Secret: credential.Secret{
+		OAuth2: &credential.OAuth2{TokenURL: tokenServer.URL, ClientID: "pantheon", ClientSecret: "old"},
+	}})
+	require.NoError(t, err)
+	targetID := registerTarget(t, exporter.port(), "/metrics", &target.TargetAuth{Credential: info.UID})
+	scrape := func() int {
+		resp, err := http.Get(fmt.Sprintf("%s?tid=%d", proxyAddress, targetID))
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	// Act
+	first := scrape()
+	_, err = model.RotateCredential(info.UID, credential.Secret{
+		OAuth2: &credential.OAuth2{TokenURL: tokenServer.URL, ClientID: "pantheon", ClientSecret: "new"},
+	})
+	require.NoError(t, err)
+	second := scrape()
+
+	// Assert
+	assert.Equal(t, []int{http.StatusOK, http.StatusOK}, []int{first, second})
+	assert.Equal(t, []string{"old", "new"}, clientSecrets)
+	assert.Equal(t, []string{"Bearer token-old", "Bearer token-new"}, authorizations)
+	cached := 0
+	tokenSources.Range(func(_, _ interface{}) bool {
+		cached++
+		return true
+	})
+	assert.Equal(t, 1, cached)
+}
+
+// TestProxy_OAuth2TokenFailure 测试获取令牌失败时不访问 target，返回 502
+func TestProxy_OAuth2TokenFailure(t *testing.T) {
+	// Arrange
+	tokenServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	t.Cleanup(tokenServer.Close)
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{})
+	info, err := model.CreateCredential(&credential.Credential{Name: "saas", Type: credential.TypeOAuth2, Secret: credential.Secret{
+		OAuth2: &credential.OAuth2{TokenURL: tokenServer.URL, ClientID: "pantheon", ClientSecret: "wrong"},
+	}})
+	require.NoError(t, err)
+	targetID := registerTarget(t, exporter.port(), "/metrics", &target.TargetAuth{Credential: info.UID})
+
+	// Act
+	resp, err := http.Get(fmt.Sprintf("%s?tid=%d", proxyAddress, targetID))
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadGateway, resp.StatusCode)
+	assert.Zero(t, exporter.newConns.Load())
+}
+
+// TestProxy_InjectsTargetHeaders 测试代理抓取时添加 target 的自定义请求头，覆盖客户端传入的同名请求头
+func TestProxy_InjectsTargetHeaders(t *testing.T) {
+	for _, cacheWindow := range []int{0, 10} {
+		t.Run(fmt.Sprintf("cache_window=%d", cacheWindow), func(t *testing.T) {
+			// Arrange
+			var gotKey, gotTenant string
+			exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+				gotKey, gotTenant = r.Header.Get("X-Api-Key"), r.Header.Get("X-Tenant")
+				w.Write([]byte("up 1\n"))
+			})
+			proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{CacheWindow: cacheWindow}})
+			require.NoError(t, model.CreateTargets(&target.Target{
+				InstanceSelector: map[string]string{"app": "test"},
+				Targets: []target.TargetItem{{
+					Address:    "127.0.0.1:" + exporter.port(),
+					MetricPath: "/metrics",
+					Headers:    map[string]string{"x-api-key": "stored", "X-Tenant": "ops"},
+				}},
+			}))
+			found, err := model.FindProxyTarget("http", "127.0.0.1", exporter.port(), "/metrics", nil)
+			require.NoError(t, err)
+
+			// Act
+			request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s?tid=%d", proxyAddress, found.ID), nil)
+			require.NoError(t, err)
+			request.Header.Set("X-Api-Key", "prometheus")
+			resp, err := http.DefaultClient.Do(request)
+			require.NoError(t, err)
+			resp.Body.Close()
+
+			// Assert
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			assert.Equal(t, "stored", gotKey)
+			assert.Equal(t, "ops", gotTenant)
+		})
+	}
+}
+
+// TestFederate_MergesSelectorTargets 测试联邦接口抓取 selector 下的所有 target，合并结果并添加标签与 up 指标
+func TestFederate_MergesSelectorTargets(t *testing.T) {
+	// Arrange
+	var gotAuthorization, gotModule string
+	healthy := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		gotAuthorization = r.Header.Get("Authorization")
+		gotModule = r.URL.Query().Get("module")
+		w.Write([]byte("# HELP go_goroutines Number of goroutines.\n# TYPE go_goroutines gauge\ngo_goroutines 42\n"))
+	})
+	other := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("# HELP go_goroutines Number of goroutines.\n# TYPE go_goroutines gauge\ngo_goroutines{instance=\"spoofed\"} 7\n"))
+	})
+	broken := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{FederateConcurrency: 2}})
+	federateAddress := strings.TrimSuffix(proxyAddress, "/proxy") + "/federate/selector"
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"site": "edge"},
+		Targets: []target.TargetItem{
+			{
+				Address:    "127.0.0.1:" + healthy.port(),
+				MetricPath: "/metrics",
+				Labels:     map[string]string{"dc": "bj"},
+				Params:     map[string]string{"module": "http_2xx"},
+				Auth:       &target.TargetAuth{BearerToken: "stored"},
+			},
+			{Address: "127.0.0.1:" + other.port(), MetricPath: "/metrics"},
+			{Address: "127.0.0.1:" + broken.port(), MetricPath: "/metrics"},
+		},
+	}))
+
+	// Act
+	resp, err := http.Get(federateAddress + "/site/edge")
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+	missing, err := http.Get(federateAddress + "/site/unknown")
+	require.NoError(t, err)
+	missing.Body.Close()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer stored", gotAuthorization)
+	assert.Equal(t, "http_2xx", gotModule)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	assert.Equal(t, []string{
+		"# HELP go_goroutines Number of goroutines.",
+		"# TYPE go_goroutines gauge",
+		fmt.Sprintf(`go_goroutines{dc="bj",instance="127.0.0.1:%s"} 42`, healthy.port()),
+		fmt.Sprintf(`go_goroutines{instance="127.0.0.1:%s"} 7`, other.port()),
+		"# HELP up Whether the target was scraped successfully by the federation endpoint.",
+		"# TYPE up gauge",
+		fmt.Sprintf(`up{dc="bj",instance="127.0.0.1:%s"} 1`, healthy.port()),
+		fmt.Sprintf(`up{instance="127.0.0.1:%s"} 1`, other.port()),
+		fmt.Sprintf(`up{instance="127.0.0.1:%s"} 0`, broken.port()),
+		"# HELP scrape_duration_seconds Duration of the scrape by the federation endpoint.",
+		"# TYPE scrape_duration_seconds gauge",
+	}, lines[:11])
+	assert.Len(t, lines, 14)
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
+
+// TestFederate_Timeout 测试超过抓取超时的 target 记为 up 0，不影响其他 target
+func TestFederate_Timeout(t *testing.T) {
+	// Arrange
+	slow := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+	})
+	fast := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up_fast 1\n"))
+	})
+	proxyAddress := setupProxyServer(t, &config.Config{Proxy: config.ProxyConfig{FederateTimeout: 30}})
+	require.NoError(t, model.CreateTargets(&target.Target{
+		InstanceSelector: map[string]string{"site": "edge"},
+		Targets: []target.TargetItem{
+			{Address: "127.0.0.1:" + slow.port(), MetricPath: "/metrics"},
+			{Address: "127.0.0.1:" + fast.port(), MetricPath: "/metrics"},
+		},
+	}))
+
+	// Act
+	request, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(proxyAddress, "/proxy")+"/federate/selector/site/edge", nil)
+	require.NoError(t, err)
+	request.Header.Set("X-Prometheus-Scrape-Timeout-Seconds", "0.5")
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(request)
+	require.NoError(t, err)
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	require.NoError(t, err)
+
+	// Assert
+	assert.Less(t, time.Since(start), 3*time.Second)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, string(body), fmt.Sprintf("up_fast{instance=\"127.0.0.1:%s\"} 1\n", fast.port()))
+	assert.Contains(t, string(body), fmt.Sprintf("up{instance=\"127.0.0.1:%s\"} 0\n", slow.port()))
+	assert.Contains(t, string(body), fmt.Sprintf("up{instance=\"127.0.0.1:%s\"} 1\n", fast.port()))
+}
+
+// pipe 在两个连接之间转发数据，任意一端关闭后结束
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() { io.Copy(a, b); done <- struct{}{} }()
+	go func() { io.Copy(b, a); done <- struct{}{} }()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// newConnectUpstream 模拟网络区域的 HTTP CONNECT 上游代理，记录隧道的目标地址
+func newConnectUpstream(t *testing.T, tunnels chan<- string) *httptest.Server {
+	t.Helper()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect || r.Header.Get("Proxy-Authorization") != "Basic anVtcDpzZWNyZXQ=" {
+			w.WriteHeader(http.StatusProxyAuthRequired)
+			return
+		}
+		backend, err := net.Dial("tcp", r.Host)
+		if err != nil {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		tunnels <- r.Host
+		w.WriteHeader(http.StatusOK)
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			backend.Close()
+			return
+		}
+		pipe(conn, backend)
+	}))
+	t.Cleanup(upstream.Close)
+	return upstream
+}
+
+// newSOCKS5Upstream 模拟网络区域的 SOCKS5 上游代理 (无认证)，记录连接的目标地址
+func newSOCKS5Upstream(t *testing.T, tunnels chan<- string) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func(conn net.Conn) {
+				// 协商认证方式：VER NMETHODS METHODS
+				header := make([]byte, 2)
+				if _, err := io.ReadFull(conn, header); err != nil {
+					conn.Close()
+					return
+				}
+				io.ReadFull(conn, make([]byte, header[1]))
+				conn.Write([]byte{5, 0})
+				// 连接请求：VER CMD RSV ATYP(IPv4) ADDR PORT
+				request := make([]byte, 10)
+				if _, err := io.ReadFull(conn, request); err != nil || request[3] != 1 {
+					conn.Close()
+					return
+				}
+				address := net.JoinHostPort(net.IP(request[4:8]).String(), strconv.Itoa(int(request[8])<<8|int(request[9])))
+				backend, err := net.Dial("tcp", address)
+				if err != nil {
+					conn.Write([]byte{5, 5, 0, 1, 0, 0, 0, 0, 0, 0})
+					conn.Close()
+					return
+				}
+				tunnels <- address
+				conn.Write([]byte{5, 0, 0, 1, 0, 0, 0, 0, 0, 0})
+				pipe(conn, backend)
+			}(conn)
+		}
+	}()
+	return listener
+}
+
+// TestProxy_ZoneUpstream 测试网络区域中的 target 经由区域的上游代理抓取，IP 形式的地址仍受 deny_cidrs 限制
+func TestProxy_ZoneUpstream(t *testing.T) {
+	exporter := newExporterServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("up 1\n"))
+	})
+	testCases := []struct {
+		name     string
+		upstream func(t *testing.T, tunnels chan<- string) string
+		deny     []string
+		want     int
+	}{
+		{
+			name: "connect",
+			upstream: func(t *testing.T, tunnels chan<- string) string {
+				upstream := newConnectUpstream(t, tunnels)
+				return strings.Replace(upstream.URL, "http://", "http://jump:secret@", 1)
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "socks5",
+			upstream: func(t *testing.T, tunnels chan<- string) string {
+				return "socks5://" + newSOCKS5Upstream(t, tunnels).Addr().String()
+			},
+			want: http.StatusOK,
+		},
+		{
+			name: "denied destination",
+			upstream: func(t *testing.T, tunnels chan<- string) string {
+				return "socks5://" + newSOCKS5Upstream(t, tunnels).Addr().String()
+			},
+			deny: []string{"127.0.0.0/8"},
+			want: http.StatusForbidden,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			tunnels := make(chan string, 10)
+			proxyAddress := setupProxyServer(t, &config.Config{
+				Proxy: config.ProxyConfig{DenyCIDRs: tc.deny},
+				Zones: []config.ZoneConfig{{Name: "dmz", Upstream: tc.upstream(t, tunnels)}},
+			})
+			require.NoError(t, model.CreateTargets(&target.Target{
+				InstanceSelector: map[string]string{"app": "test"},
+				Targets:          []target.TargetItem{{Address: "127.0.0.1:" + exporter.port(), MetricPath: "/metrics", Zone: "dmz"}},
+			}))
+			found, err := model.FindProxyTarget("http", "127.0.0.1", exporter.port(), "/metrics", nil)
+			require.NoError(t, err)
+
+			// Act
+			resp, err := http.Get(fmt.Sprintf("%s?tid=%d", proxyAddress, found.ID))
+			require.NoError(t, err)
+			body, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+
+			// Assert
+			assert.Equal(t, tc.want, resp.StatusCode)
+			if tc.want != http.StatusOK {
+				assert.Empty(t, tunnels)
+				return
+			}
+			assert.Equal(t, "up 1\n", string(body))
+			select {
+			case address := <-tunnels:
+				assert.Equal(t, "127.0.0.1:"+exporter.port(), address)
+			default:
+				t.Fatal("the scrape did not go through the upstream")
+			}
+		})
+	}
+}
+
+// TestSetupTransport_InvalidZone 测试上游代理配置错误时拒绝启动
+func TestSetupTransport_InvalidZone(t *testing.T) {
+	testCases := [][]config.ZoneConfig{
+		{{Name: "dmz", Upstream: "ftp://jump:21"}},
+		{{Name: "dmz", Upstream: "socks5://"}},
+		{{Name: "dmz"}, {Name: "dmz"}},
+		{{Upstream: "socks5://jump:1080"}},
+	}
+	for _, zones := range testCases {
+		assert.Error(t, setupTransport(config.ProxyConfig{}, zones))
+	}
+	require.NoError(t, setupTransport(config.ProxyConfig{}, nil))
+}
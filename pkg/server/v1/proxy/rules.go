@@ -0,0 +1,87 @@
+package proxy
+
+import (
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cylonchau/pantheon/pkg/exposition"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+// textFormat 配置了过滤规则时向 target 请求文本格式，规则无法处理 protobuf 格式
+const textFormat = "text/plain;version=0.0.4"
+
+// compiledRule 编译后的规则，规则更新后重新编译
+type compiledRule struct {
+	updatedAt time.Time
+	rule      exposition.Rule
+}
+
+// compiledRules 按规则 ID 缓存编译结果，避免每次抓取都编译正则
+var compiledRules sync.Map
+
+// filterFor 返回作用于 target 的过滤器，没有规则时返回 nil
+func filterFor(targetID uint) (*exposition.Filter, error) {
+	compiled, err := rulesFor(targetID)
+	if err != nil {
+		return nil, err
+	}
+	return exposition.NewFilter(compiled...), nil
+}
+
+// rulesFor 返回作用于 target 的已编译规则
+func rulesFor(targetID uint) ([]exposition.Rule, error) {
+	rules, err := model.MetricRulesForTarget(targetID)
+	if err != nil || len(rules) == 0 {
+		return nil, err
+	}
+	compiled := make([]exposition.Rule, 0, len(rules))
+	for i := range rules {
+		if cached, exists := compiledRules.Load(rules[i].ID); exists && cached.(compiledRule).updatedAt.Equal(rules[i].UpdatedAt) {
+			compiled = append(compiled, cached.(compiledRule).rule)
+			continue
+		}
+		rule, err := rules[i].Compile()
+		if err != nil {
+			return nil, err
+		}
+		compiledRules.Store(rules[i].ID, compiledRule{updatedAt: rules[i].UpdatedAt, rule: rule})
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// textAccept 去掉 Accept 头中的 protobuf 格式，保留 Prometheus 对文本与 OpenMetrics 格式的协商
+func textAccept(accept string) string {
+	kept := make([]string, 0)
+	for _, part := range strings.Split(accept, ",") {
+		if part = strings.TrimSpace(part); part != "" && !strings.Contains(part, "protobuf") {
+			kept = append(kept, part)
+		}
+	}
+	if len(kept) == 0 {
+		return textFormat
+	}
+	return strings.Join(kept, ",")
+}
+
+// filterResponse 过滤文本格式的抓取结果，过滤后长度未知
+func filterResponse(resp *http.Response, filter *exposition.Filter) {
+	if filter == nil || resp.StatusCode != http.StatusOK || !isTextFormat(resp.Header.Get("Content-Type")) {
+		return
+	}
+	resp.Body = filter.Reader(resp.Body)
+	resp.ContentLength = -1
+	resp.Header.Del("Content-Length")
+}
+
+func isTextFormat(contentType string) bool {
+	if contentType == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "text/plain" || mediaType == "application/openmetrics-text")
+}
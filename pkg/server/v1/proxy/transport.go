@@ -0,0 +1,251 @@
+package proxy
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+var (
+	errResponseTooLarge  = errors.New("response body exceeds max_response_bytes")
+	errDestinationDenied = errors.New("destination address is not allowed")
+)
+
+// transportKey 以网络区域、TLS 配置和证书文件的修改时间作为缓存键，证书更新后自动使用新的 Transport
+type transportKey struct {
+	zone     string
+	settings model.TargetTLS
+	modTimes [3]int64
+}
+
+var (
+	transportMu   sync.RWMutex
+	baseTransport = http.DefaultTransport.(*http.Transport).Clone()
+	transports    sync.Map // transportKey -> *http.Transport
+	zoneDialers   map[string]dialFunc
+)
+
+// setupTransport 根据配置创建所有 target 共享的连接池，需要在处理请求之前调用
+func setupTransport(conf config.ProxyConfig, zones []config.ZoneConfig) error {
+	filter, err := newDestinationFilter(conf.AllowCIDRs, conf.DenyCIDRs)
+	if err != nil {
+		return err
+	}
+	dialers, err := newZoneDialers(zones, conf, filter)
+	if err != nil {
+		return err
+	}
+	// 在建立连接时检查实际连接的 IP，DNS 解析结果变化也无法绕过
+	dialer := &net.Dialer{
+		Timeout:   seconds(conf.DialTimeout),
+		KeepAlive: 30 * time.Second,
+		Control:   filter.control,
+	}
+	// 不使用环境变量中的 HTTP 代理，否则只能检查代理本身的地址
+	transport := &http.Transport{
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		TLSHandshakeTimeout:   seconds(conf.TLSHandshakeTimeout),
+		ResponseHeaderTimeout: seconds(conf.ResponseHeaderTimeout),
+		IdleConnTimeout:       seconds(conf.IdleConnTimeout),
+		MaxIdleConns:          conf.MaxIdleConns,
+		MaxIdleConnsPerHost:   conf.MaxConnsPerHost,
+		MaxConnsPerHost:       conf.MaxConnsPerHost,
+		ExpectContinueTimeout: time.Second,
+	}
+	if transport.MaxIdleConnsPerHost == 0 {
+		transport.MaxIdleConnsPerHost = http.DefaultMaxIdleConnsPerHost
+	}
+
+	transportMu.Lock()
+	defer transportMu.Unlock()
+	baseTransport.CloseIdleConnections()
+	baseTransport = transport
+	zoneDialers = dialers
+	transports.Range(func(key, value interface{}) bool {
+		value.(*http.Transport).CloseIdleConnections()
+		transports.Delete(key)
+		return true
+	})
+	return nil
+}
+
+// destinationFilter 根据 CIDR 列表限制代理可以连接的地址
+type destinationFilter struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+func newDestinationFilter(allow, deny []string) (*destinationFilter, error) {
+	filter := &destinationFilter{}
+	var err error
+	if filter.allow, err = parseCIDRs(allow); err != nil {
+		return nil, fmt.Errorf("invalid allow_cidrs: %w", err)
+	}
+	if filter.deny, err = parseCIDRs(deny); err != nil {
+		return nil, fmt.Errorf("invalid deny_cidrs: %w", err)
+	}
+	return filter, nil
+}
+
+func (f *destinationFilter) allowed(ip net.IP) bool {
+	for _, network := range f.deny {
+		if network.Contains(ip) {
+			return false
+		}
+	}
+	if len(f.allow) == 0 {
+		return true
+	}
+	for _, network := range f.allow {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+func (f *destinationFilter) control(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !f.allowed(ip) {
+		return fmt.Errorf("%w: %s", errDestinationDenied, host)
+	}
+	return nil
+}
+
+func parseCIDRs(values []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(values))
+	for _, value := range values {
+		_, network, err := net.ParseCIDR(value)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// transportFor 返回 target 使用的 Transport，相同网络区域与 TLS 配置的 target 共享连接池
+// 没有配置上游代理的网络区域直接连接
+func transportFor(settings model.TargetTLS, zone string) (http.RoundTripper, error) {
+	transportMu.RLock()
+	defer transportMu.RUnlock()
+	dial, upstream := zoneDialers[zone]
+	if !upstream {
+		zone = ""
+	}
+	if settings.IsEmpty() && !upstream {
+		return baseTransport, nil
+	}
+	key := transportKey{zone: zone, settings: settings}
+	for i, file := range []string{settings.CAFile, settings.CertFile, settings.KeyFile} {
+		key.modTimes[i] = modTime(file)
+	}
+	if cached, exists := transports.Load(key); exists {
+		return cached.(*http.Transport), nil
+	}
+
+	transport := baseTransport.Clone()
+	if !settings.IsEmpty() {
+		tlsConfig, err := newTLSConfig(settings)
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+	if upstream {
+		transport.DialContext = dial
+	}
+	actual, _ := transports.LoadOrStore(key, transport)
+	return actual.(*http.Transport), nil
+}
+
+// limitResponse 限制响应大小，Content-Length 已知时直接拒绝，否则读取超过限制时中断
+func limitResponse(maxBytes int64) func(*http.Response) error {
+	return func(resp *http.Response) error {
+		if maxBytes <= 0 {
+			return nil
+		}
+		if resp.ContentLength > maxBytes {
+			return errResponseTooLarge
+		}
+		resp.Body = &limitedBody{ReadCloser: resp.Body, remaining: maxBytes}
+		return nil
+	}
+}
+
+type limitedBody struct {
+	io.ReadCloser
+	remaining int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.remaining < 0 {
+		return 0, errResponseTooLarge
+	}
+	// 多读一个字节用于判断是否超过限制
+	if int64(len(p)) > b.remaining+1 {
+		p = p[:b.remaining+1]
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.remaining -= int64(n)
+	if b.remaining < 0 {
+		return n + int(b.remaining), errResponseTooLarge
+	}
+	return n, err
+}
+
+func seconds(value int) time.Duration {
+	return time.Duration(value) * time.Second
+}
+
+func newTLSConfig(settings model.TargetTLS) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         settings.ServerName,
+		InsecureSkipVerify: settings.InsecureSkipVerify,
+	}
+	if settings.CAFile != "" {
+		caData, err := os.ReadFile(settings.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tls ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caData) {
+			return nil, fmt.Errorf("no certificates found in tls ca_file %s", settings.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if settings.CertFile != "" {
+		certificate, err := tls.LoadX509KeyPair(settings.CertFile, settings.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load tls client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+	return tlsConfig, nil
+}
+
+func modTime(file string) int64 {
+	if file == "" {
+		return 0
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
@@ -0,0 +1,160 @@
+package proxy
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+
+	netproxy "golang.org/x/net/proxy"
+
+	"github.com/cylonchau/pantheon/pkg/config"
+)
+
+// dialFunc 与 http.Transport.DialContext 的签名一致
+type dialFunc func(ctx context.Context, network, address string) (net.Conn, error)
+
+// newZoneDialers 为配置了上游代理的网络区域创建 Dial 函数
+func newZoneDialers(zones []config.ZoneConfig, conf config.ProxyConfig, filter *destinationFilter) (map[string]dialFunc, error) {
+	dialers := make(map[string]dialFunc)
+	seen := make(map[string]bool)
+	for _, zone := range zones {
+		if zone.Name == "" {
+			return nil, fmt.Errorf("zone name is required")
+		}
+		if seen[zone.Name] {
+			return nil, fmt.Errorf("duplicate zone %s", zone.Name)
+		}
+		seen[zone.Name] = true
+		if zone.Upstream == "" {
+			continue
+		}
+		dial, err := newUpstreamDialer(zone.Upstream, seconds(conf.DialTimeout), filter)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+		}
+		dialers[zone.Name] = dial
+	}
+	return dialers, nil
+}
+
+// newUpstreamDialer 创建经由上游代理连接 target 的 Dial 函数
+// 上游代理的地址来自配置，不受 allow_cidrs/deny_cidrs 限制；target 的主机名由上游代理解析，
+// 因此只有 IP 形式的 target 地址在本地检查
+func newUpstreamDialer(upstream string, timeout time.Duration, filter *destinationFilter) (dialFunc, error) {
+	upstreamURL, err := url.Parse(upstream)
+	if err != nil {
+		return nil, fmt.Errorf("invalid upstream %q: %w", upstream, err)
+	}
+	if upstreamURL.Host == "" {
+		return nil, fmt.Errorf("invalid upstream %q: host is required", upstream)
+	}
+	forward := &net.Dialer{Timeout: timeout, KeepAlive: 30 * time.Second}
+
+	var dial dialFunc
+	switch upstreamURL.Scheme {
+	case "http", "https":
+		dial = (&connectDialer{upstream: upstreamURL, forward: forward, timeout: timeout}).DialContext
+	case "socks5", "socks5h":
+		var auth *netproxy.Auth
+		if upstreamURL.User != nil {
+			password, _ := upstreamURL.User.Password()
+			auth = &netproxy.Auth{User: upstreamURL.User.Username(), Password: password}
+		}
+		socks, err := netproxy.SOCKS5("tcp", upstreamURL.Host, auth, forward)
+		if err != nil {
+			return nil, err
+		}
+		dial = socks.(netproxy.ContextDialer).DialContext
+	default:
+		return nil, fmt.Errorf("unsupported upstream scheme %q, expected http, https or socks5", upstreamURL.Scheme)
+	}
+
+	return func(ctx context.Context, network, address string) (net.Conn, error) {
+		host, _, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, err
+		}
+		if ip := net.ParseIP(host); ip != nil && !filter.allowed(ip) {
+			return nil, fmt.Errorf("%w: %s", errDestinationDenied, host)
+		}
+		return dial(ctx, network, address)
+	}, nil
+}
+
+// connectDialer 通过 HTTP CONNECT 隧道连接 target，http 与 https 的 target 都使用隧道
+type connectDialer struct {
+	upstream *url.URL
+	forward  *net.Dialer
+	timeout  time.Duration
+}
+
+func (d *connectDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, "tcp", d.upstream.Host)
+	if err != nil {
+		return nil, err
+	}
+	// 建立隧道的耗时受 ctx 与 dial_timeout 的限制
+	deadline, ok := ctx.Deadline()
+	if d.timeout > 0 && (!ok || time.Now().Add(d.timeout).Before(deadline)) {
+		deadline, ok = time.Now().Add(d.timeout), true
+	}
+	if ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if d.upstream.Scheme == "https" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: d.upstream.Hostname()})
+		if err = tlsConn.HandshakeContext(ctx); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	request := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.upstream.User != nil {
+		password, _ := d.upstream.User.Password()
+		credentials := base64.StdEncoding.EncodeToString([]byte(d.upstream.User.Username() + ":" + password))
+		request.Header.Set("Proxy-Authorization", "Basic "+credentials)
+	}
+	if err = request.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, request)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("upstream %s refused CONNECT %s: %s", d.upstream.Host, address, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	if reader.Buffered() > 0 {
+		return &bufferedConn{Conn: conn, reader: reader}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 读取 CONNECT 响应时多读取的数据需要先返回
+type bufferedConn struct {
+	net.Conn
+	reader *bufio.Reader
+}
+
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.reader.Read(p)
+}
@@ -0,0 +1,192 @@
+package relabel
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/relabel"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type RelabelRuleHanderV1 struct{}
+
+// relabelRuleQuery 查询规则时按 selector 过滤
+type relabelRuleQuery struct {
+	Selector string `form:"selector"`
+}
+
+func (h *RelabelRuleHanderV1) RegisterRelabelRuleAPI(g *gin.RouterGroup) {
+	ruleGroup := g.Group("/relabel_rules")
+	ruleGroup.GET("", h.listRelabelRules)
+	ruleGroup.PUT("", h.createRelabelRule)
+	ruleGroup.POST("/test", h.testRelabelRule)
+	ruleGroup.GET("/:id", h.getRelabelRule)
+	ruleGroup.POST("/:id", h.updateRelabelRule)
+	ruleGroup.DELETE("/:id", h.deleteRelabelRule)
+}
+
+// listRelabelRules godoc
+// @Summary List relabel rules
+// @Description List the relabel rules applied to SD responses, in the order they are applied.
+// @Tags RelabelRules
+// @Produce json
+// @Param selector query string false "only the rules of the key=value selector"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} relabel.RelabelRuleInfo
+// @Router /ph/v1/relabel_rules [get]
+func (h *RelabelRuleHanderV1) listRelabelRules(c *gin.Context) {
+	var encounterError error
+	ruleQuery := &relabelRuleQuery{}
+	if encounterError = c.ShouldBindQuery(ruleQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	rules, encounterError := model.ListRelabelRules(ruleQuery.Selector)
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, rules)
+}
+
+// createRelabelRule godoc
+// @Summary Create relabel rule
+// @Description Create a Prometheus style relabel rule for a selector, the SD response of the selector is relabeled before it is returned.
+// @Description Supported actions are replace, keep, drop, labelmap, labeldrop and labelkeep, __address__ is the address of the target.
+// @Tags RelabelRules
+// @Accept json
+// @Produce json
+// @Param query body relabel.RelabelRule true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} relabel.RelabelRuleInfo
+// @Router /ph/v1/relabel_rules [put]
+func (h *RelabelRuleHanderV1) createRelabelRule(c *gin.Context) {
+	var encounterError error
+	request := &relabel.RelabelRule{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.CreateRelabelRule(request)
+	if encounterError != nil {
+		relabelRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// testRelabelRule godoc
+// @Summary Test relabel rule
+// @Description Show the effect of a rule on the current targets of its selector without saving it.
+// @Description before is the current SD output with the saved rules applied, after is the result of the rule.
+// @Tags RelabelRules
+// @Accept json
+// @Produce json
+// @Param query body relabel.RelabelRule true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} relabel.TestResult
+// @Router /ph/v1/relabel_rules/test [post]
+func (h *RelabelRuleHanderV1) testRelabelRule(c *gin.Context) {
+	var encounterError error
+	request := &relabel.RelabelRule{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	results, encounterError := model.TestRelabelRule(request)
+	if encounterError != nil {
+		relabelRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, results)
+}
+
+// getRelabelRule godoc
+// @Summary Get relabel rule
+// @Tags RelabelRules
+// @Produce json
+// @Param id path int true "relabel rule id"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} relabel.RelabelRuleInfo
+// @Router /ph/v1/relabel_rules/{id} [get]
+func (h *RelabelRuleHanderV1) getRelabelRule(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.GetRelabelRule(idQuery.ID)
+	if encounterError != nil {
+		relabelRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// updateRelabelRule godoc
+// @Summary Update relabel rule
+// @Description Replace a relabel rule with the request body.
+// @Tags RelabelRules
+// @Accept json
+// @Produce json
+// @Param id path int true "relabel rule id"
+// @Param query body relabel.RelabelRule true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} relabel.RelabelRuleInfo
+// @Router /ph/v1/relabel_rules/{id} [post]
+func (h *RelabelRuleHanderV1) updateRelabelRule(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &relabel.RelabelRule{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	info, encounterError := model.UpdateRelabelRule(idQuery.ID, request)
+	if encounterError != nil {
+		relabelRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// deleteRelabelRule godoc
+// @Summary Delete relabel rule
+// @Tags RelabelRules
+// @Produce json
+// @Param id path int true "relabel rule id"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Router /ph/v1/relabel_rules/{id} [delete]
+func (h *RelabelRuleHanderV1) deleteRelabelRule(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+
+	if encounterError = model.DeleteRelabelRule(idQuery.ID); encounterError != nil {
+		relabelRuleErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func relabelRuleErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrRelabelRuleNotFound):
+		query.API404Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
@@ -0,0 +1,62 @@
+package sd
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	apisd "github.com/cylonchau/pantheon/pkg/api/sd"
+	"github.com/cylonchau/pantheon/pkg/sd"
+)
+
+type SDHanderV1 struct{}
+
+func (s *SDHanderV1) RegisterSDAPI(g *gin.RouterGroup) {
+	sdGroup := g.Group("/sd")
+	sdGroup.GET("/config", s.getScrapeConfig)
+}
+
+// getScrapeConfig godoc
+// @Summary Generate scrape config for a selector
+// @Description Generate a ready-to-use Prometheus or vmagent scrape_config pointing at the SD endpoint of the selector.
+// @Tags SD
+// @Produce plain
+// @Param selector query string true "selector in key=value format"
+// @Param format query string false "prometheus or vmagent"
+// @Param job_name query string false "job name of the generated scrape config"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {string} string
+// @Router /ph/v1/sd/config [get]
+func (s *SDHanderV1) getScrapeConfig(c *gin.Context) {
+	var enconterError error
+	configQuery := &apisd.ScrapeConfigQuery{}
+	if enconterError = c.ShouldBindQuery(configQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+
+	data, enconterError := sd.RenderScrapeConfig(serverURL(c), configQuery.Selector, configQuery.Format, configQuery.JobName)
+	if enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+	c.Data(http.StatusOK, "application/yaml; charset=utf-8", data)
+}
+
+// serverURL 根据请求推导 pantheon-server 对外的访问地址
+func serverURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if forwarded := c.GetHeader("X-Forwarded-Proto"); forwarded != "" {
+		scheme = forwarded
+	}
+	host := c.Request.Host
+	if forwarded := c.GetHeader("X-Forwarded-Host"); forwarded != "" {
+		host = forwarded
+	}
+	return fmt.Sprintf("%s://%s", scheme, host)
+}
@@ -1,6 +1,8 @@
 package selector
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/cylonchau/pantheon/pkg/api/query"
@@ -58,6 +60,10 @@ func (t *SelectorHanderV1) updateSelector(c *gin.Context) {
 
 	// 2. 调用模型层进行更新
 	if err := model.UpdateSelectorByKeyValue(request.OldKey, request.OldValue, request.NewKey, request.NewValue); err != nil {
+		if errors.Is(err, model.ErrTargetConflict) {
+			query.API409Response(c, err)
+			return
+		}
 		query.API400Response(c, err)
 		return
 	}
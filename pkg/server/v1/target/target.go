@@ -1,6 +1,8 @@
 package target
 
 import (
+	"errors"
+
 	"github.com/gin-gonic/gin"
 
 	"github.com/cylonchau/pantheon/pkg/api/query"
@@ -17,6 +19,8 @@ func (t *TargetHanderV1) RegisterTargetAPI(g *gin.RouterGroup) {
 	targetGroup.GET("/:id", t.getTargetOne)
 	targetGroup.PUT("", t.createTargets)
 	targetGroup.POST("/:id", t.changeTargetWithID)
+	targetGroup.GET("/:id/revisions", t.listTargetRevisions)
+	targetGroup.POST("/:id/rollback", t.rollbackTarget)
 	targetGroup.DELETE("", t.deleteTarget)
 	targetGroup.DELETE("/name/:name", t.deleteTargetWithName)
 	targetGroup.DELETE("/:id", t.deleteTargetWithID)
@@ -32,6 +36,9 @@ func (t *TargetHanderV1) RegisterTargetAPI(g *gin.RouterGroup) {
 // @Produce json
 // @Param key path string true "selector key name"
 // @Param value path string true "selector value name"
+// @Param shard query int false "shard index, from 0 to shards-1"
+// @Param shards query int false "total number of shards, 0 disables sharding"
+// @Param replicas query int false "replication factor, the number of shards scraping each target"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} interface{}
 // @Router /ph/v1/targets/selector/{key}/{value} [get]
@@ -44,7 +51,17 @@ func (t *TargetHanderV1) listTargetWithSeletor(c *gin.Context) {
 		return
 	}
 
-	if targetMap, enconterError := model.ListTargetWithSelector(targetQuery); enconterError == nil {
+	shardQuery := &query.QueryWithShard{}
+	if enconterError = c.ShouldBindQuery(shardQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+	if enconterError = model.ValidateShard(shardQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+
+	if targetMap, enconterError := model.ListTargetWithSelector(targetQuery, shardQuery); enconterError == nil {
 		query.RawSuccessResponse(c, targetMap)
 		return
 	}
@@ -58,6 +75,7 @@ func (t *TargetHanderV1) listTargetWithSeletor(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Target ID"
+// @Param show_secrets query bool false "return credential secrets instead of the credential reference"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} model.TargetRaw // 假设 model.Target 是目标的结构体
 // @Router /ph/v1/targets/{id} [get]
@@ -69,7 +87,13 @@ func (t *TargetHanderV1) getTargetOne(c *gin.Context) {
 		return
 	}
 
-	target, enconterError := model.GetTargetByID(targetQuery.ID) // 假设有这个函数
+	secretsQuery := &query.QueryWithSecrets{}
+	if enconterError = c.ShouldBindQuery(secretsQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+
+	target, enconterError := model.GetTargetByID(targetQuery.ID, secretsQuery.ShowSecrets) // 假设有这个函数
 	if enconterError != nil {
 		query.API400Response(c, enconterError)
 		return
@@ -86,6 +110,7 @@ func (t *TargetHanderV1) getTargetOne(c *gin.Context) {
 // @Produce json
 // @Param key path string true "label key name"
 // @Param value path string true "label value name"
+// @Param show_secrets query bool false "return credential secrets instead of the credential reference"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} interface{}
 // @Router /ph/v1/targets/cmd/{key}/{value} [get]
@@ -98,7 +123,13 @@ func (t *TargetHanderV1) listTargetByCmd(c *gin.Context) {
 		return
 	}
 
-	if targetMap, enconterError := model.ListTargetWithCtl(targetQuery); enconterError == nil {
+	secretsQuery := &query.QueryWithSecrets{}
+	if enconterError = c.ShouldBindQuery(secretsQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+
+	if targetMap, enconterError := model.ListTargetWithCtl(targetQuery, secretsQuery.ShowSecrets); enconterError == nil {
 		query.RawSuccessResponse(c, targetMap)
 		return
 	}
@@ -106,14 +137,15 @@ func (t *TargetHanderV1) listTargetByCmd(c *gin.Context) {
 }
 
 // createTargets godoc
-// @Summary Create prometheus target.
-// @Description Create prometheus target.
+// @Summary Create or update prometheus targets.
+// @Description Upsert targets by identity (schema, address, metric path, params and selectors).
+// @Description Existing targets get their labels and scrape intervals replaced, the status of each target is returned.
 // @Tags Targets
 // @Accept json
 // @Produce json
 // @Param query body target.Target false "body"
 // @securityDefinitions.apikey BearerAuth
-// @Success 200 {object} interface{}
+// @Success 200 {array} target.UpsertResult
 // @Router /ph/v1/targets [PUT]
 func (t *TargetHanderV1) createTargets(c *gin.Context) {
 	// 1. 获取参数和参数校验
@@ -124,12 +156,13 @@ func (t *TargetHanderV1) createTargets(c *gin.Context) {
 		return
 	}
 
-	if enconterError = model.CreateTargets(targetQuery); enconterError != nil {
-		query.API400Response(c, enconterError)
+	results, enconterError := model.UpsertTargets(targetQuery)
+	if enconterError != nil {
+		targetErrorResponse(c, enconterError)
 		return
 	}
 
-	query.SuccessResponse(c, query.OK, nil)
+	query.SuccessResponse(c, query.OK, results)
 }
 
 // createTargets godoc
@@ -191,8 +224,10 @@ func (t *TargetHanderV1) deleteTargetWithName(c *gin.Context) {
 // @Tags Targets
 // @Accept x-www-form-urlencoded
 // @Param name path int true "target id"
+// @Param resource_version query int false "delete only if the target is still at this version"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} interface{}
+// @Failure 409 {object} query.Response
 // @Router /ph/v1/targets/{id} [DELETE]
 func (t *TargetHanderV1) deleteTargetWithID(c *gin.Context) {
 	// 1. 获取参数和参数校验
@@ -203,10 +238,15 @@ func (t *TargetHanderV1) deleteTargetWithID(c *gin.Context) {
 		query.API400Response(c, enconterError)
 		return
 	}
-	if enconterError = model.DeleteTargetWithID(targetQuery.ID); enconterError != nil {
+	versionQuery := &query.QueryWithResourceVersion{}
+	if enconterError = c.ShouldBindQuery(versionQuery); enconterError != nil {
 		query.API400Response(c, enconterError)
 		return
 	}
+	if enconterError = model.DeleteTargetWithID(targetQuery.ID, versionQuery.ResourceVersion); enconterError != nil {
+		targetErrorResponse(c, enconterError)
+		return
+	}
 	query.SuccessResponse(c, query.OK, nil)
 }
 
@@ -230,12 +270,14 @@ func (t *TargetHanderV1) cleanDeletedTargets(c *gin.Context) {
 // changeTargetWithID godoc
 // @Summary Update prometheus target with target id.
 // @Description Update prometheus instance target id with provided parameters.
+// @Description When resource_version is set, a target modified since that version is rejected with 409.
 // @Tags Targets
 // @Accept json
 // @Param id path int true "target id"
 // @Param target body target.TargetChg true "Target update information"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} interface{}
+// @Failure 409 {object} query.Response
 // @Router /ph/v1/targets/{id} [post]
 func (t *TargetHanderV1) changeTargetWithID(c *gin.Context) {
 	var encounterError error
@@ -255,7 +297,7 @@ func (t *TargetHanderV1) changeTargetWithID(c *gin.Context) {
 	}
 
 	if encounterError = model.ChangeTargetWithID(targetQuery.ID, updates); encounterError != nil {
-		query.API400Response(c, encounterError)
+		targetErrorResponse(c, encounterError)
 		return
 	}
 
@@ -290,3 +332,72 @@ func (t *TargetHanderV1) deleteTargetWithLabel(c *gin.Context) {
 	}
 	query.SuccessResponse(c, query.OK, nil)
 }
+
+// listTargetRevisions godoc
+// @Summary List target revisions.
+// @Description List every version of a target, including soft-deleted targets, ordered by revision.
+// @Tags Targets
+// @Produce json
+// @Param id path int true "target id"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} target.TargetRevision
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/targets/{id}/revisions [get]
+func (t *TargetHanderV1) listTargetRevisions(c *gin.Context) {
+	var encounterError error
+	targetQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(targetQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	revisions, encounterError := model.ListTargetRevisions(targetQuery.ID)
+	if encounterError != nil {
+		targetErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, revisions)
+}
+
+// rollbackTarget godoc
+// @Summary Roll back a target to a revision.
+// @Description Restore address, metric path, intervals, labels, params, selectors and credential of a revision as a new revision.
+// @Description A soft-deleted target is undeleted.
+// @Tags Targets
+// @Accept json
+// @Produce json
+// @Param id path int true "target id"
+// @Param query body target.TargetRollback true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Failure 404 {object} query.Response
+// @Failure 409 {object} query.Response
+// @Router /ph/v1/targets/{id}/rollback [post]
+func (t *TargetHanderV1) rollbackTarget(c *gin.Context) {
+	var encounterError error
+	targetQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(targetQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &target.TargetRollback{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	if encounterError = model.RollbackTarget(targetQuery.ID, request.Revision); encounterError != nil {
+		targetErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func targetErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrTargetNotFound), errors.Is(err, model.ErrTargetRevisionNotFound):
+		query.API404Response(c, err)
+	case errors.Is(err, model.ErrTargetConflict), errors.Is(err, model.ErrTargetVersionConflict):
+		query.API409Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
@@ -0,0 +1,210 @@
+package webhook
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/cylonchau/pantheon/pkg/api/query"
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type WebhookHanderV1 struct{}
+
+func (h *WebhookHanderV1) RegisterWebhookAPI(g *gin.RouterGroup) {
+	webhookGroup := g.Group("/webhooks")
+	webhookGroup.GET("", h.listWebhooks)
+	webhookGroup.PUT("", h.createWebhook)
+	webhookGroup.POST("/:name", h.updateWebhook)
+	webhookGroup.DELETE("/:name", h.deleteWebhook)
+	webhookGroup.GET("/:name/deliveries", h.listDeliveries)
+
+	deadLetterGroup := g.Group("/webhook_dead_letters")
+	deadLetterGroup.GET("", h.listDeadLetters)
+	deadLetterGroup.POST("/:id/redeliver", h.redeliver)
+}
+
+// listWebhooks godoc
+// @Summary List webhooks
+// @Description List the webhook subscriptions, secrets are never returned.
+// @Tags Webhooks
+// @Produce json
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} webhook.WebhookInfo
+// @Router /ph/v1/webhooks [get]
+func (h *WebhookHanderV1) listWebhooks(c *gin.Context) {
+	webhooks, encounterError := model.ListWebhooks()
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, webhooks)
+}
+
+// createWebhook godoc
+// @Summary Create webhook
+// @Description Subscribe a URL to inventory change events. Events are sent as JSON after the change commits,
+// @Description signed with HMAC-SHA256 of the secret in the X-Pantheon-Signature header.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param query body webhook.Webhook true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} webhook.WebhookInfo
+// @Failure 409 {object} query.Response
+// @Router /ph/v1/webhooks [put]
+func (h *WebhookHanderV1) createWebhook(c *gin.Context) {
+	var encounterError error
+	request := &webhook.Webhook{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.CreateWebhook(request)
+	if encounterError != nil {
+		webhookErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// updateWebhook godoc
+// @Summary Update webhook
+// @Description Replace a webhook subscription, an empty secret keeps the current one.
+// @Tags Webhooks
+// @Accept json
+// @Produce json
+// @Param name path string true "webhook name"
+// @Param query body webhook.Webhook true "body"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} webhook.WebhookInfo
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/webhooks/{name} [post]
+func (h *WebhookHanderV1) updateWebhook(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &webhook.Webhook{}
+	if encounterError = c.ShouldBindJSON(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	info, encounterError := model.UpdateWebhook(nameQuery.Name, request)
+	if encounterError != nil {
+		webhookErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, info)
+}
+
+// deleteWebhook godoc
+// @Summary Delete webhook
+// @Description Delete a webhook subscription, pending deliveries are dropped.
+// @Tags Webhooks
+// @Produce json
+// @Param name path string true "webhook name"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/webhooks/{name} [delete]
+func (h *WebhookHanderV1) deleteWebhook(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	if encounterError = model.DeleteWebhook(nameQuery.Name); encounterError != nil {
+		webhookErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+// listDeliveries godoc
+// @Summary List webhook deliveries
+// @Description List the latest deliveries of a webhook with their status, attempts and last error.
+// @Tags Webhooks
+// @Produce json
+// @Param name path string true "webhook name"
+// @Param status query string false "pending, succeeded or dead"
+// @Param limit query int false "max number of deliveries, default 100"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} webhook.Delivery
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/webhooks/{name}/deliveries [get]
+func (h *WebhookHanderV1) listDeliveries(c *gin.Context) {
+	var encounterError error
+	nameQuery := &query.QueryWithName{}
+	if encounterError = c.ShouldBindUri(nameQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	request := &webhook.QueryDeliveries{}
+	if encounterError = c.ShouldBindQuery(request); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	deliveries, encounterError := model.ListWebhookDeliveries(nameQuery.Name, request)
+	if encounterError != nil {
+		webhookErrorResponse(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, deliveries)
+}
+
+// listDeadLetters godoc
+// @Summary List webhook dead letters
+// @Description List the deliveries that exhausted their retries.
+// @Tags Webhooks
+// @Produce json
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {array} webhook.DeadLetter
+// @Router /ph/v1/webhook_dead_letters [get]
+func (h *WebhookHanderV1) listDeadLetters(c *gin.Context) {
+	letters, encounterError := model.ListWebhookDeadLetters()
+	if encounterError != nil {
+		query.API500Response(c, encounterError)
+		return
+	}
+	query.RawSuccessResponse(c, letters)
+}
+
+// redeliver godoc
+// @Summary Redeliver dead letter
+// @Description Put a dead letter back into the delivery queue with a fresh retry budget.
+// @Tags Webhooks
+// @Produce json
+// @Param id path int true "dead letter id"
+// @securityDefinitions.apikey BearerAuth
+// @Success 200 {object} query.Response
+// @Failure 404 {object} query.Response
+// @Router /ph/v1/webhook_dead_letters/{id}/redeliver [post]
+func (h *WebhookHanderV1) redeliver(c *gin.Context) {
+	var encounterError error
+	idQuery := &query.QueryWithID{}
+	if encounterError = c.ShouldBindUri(idQuery); encounterError != nil {
+		query.API400Response(c, encounterError)
+		return
+	}
+	if encounterError = model.RedeliverWebhookDeadLetter(idQuery.ID); encounterError != nil {
+		webhookErrorResponse(c, encounterError)
+		return
+	}
+	query.SuccessResponse(c, query.OK, nil)
+}
+
+func webhookErrorResponse(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, model.ErrWebhookNotFound), errors.Is(err, model.ErrDeadLetterNotFound):
+		query.API404Response(c, err)
+	case errors.Is(err, model.ErrWebhookExists):
+		query.API409Response(c, err)
+	default:
+		query.API400Response(c, err)
+	}
+}
@@ -23,6 +23,9 @@ func (t *TargetHanderV2) RegisterTargetAPI(g *gin.RouterGroup) {
 // @Produce json
 // @Param key path string true "selector key name"
 // @Param value path string true "selector value name"
+// @Param shard query int false "shard index, from 0 to shards-1"
+// @Param shards query int false "total number of shards, 0 disables sharding"
+// @Param replicas query int false "replication factor, the number of shards scraping each target"
 // @securityDefinitions.apikey BearerAuth
 // @Success 200 {object} interface{}
 // @Router /ph/v2/targets/selector/{key}/{value} [get]
@@ -35,7 +38,17 @@ func (t *TargetHanderV2) listTargetWithSeletor(c *gin.Context) {
 		return
 	}
 
-	if targetMap, enconterError := model.ListTargetWithSelector(targetQuery); enconterError == nil {
+	shardQuery := &query.QueryWithShard{}
+	if enconterError = c.ShouldBindQuery(shardQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+	if enconterError = model.ValidateShard(shardQuery); enconterError != nil {
+		query.API400Response(c, enconterError)
+		return
+	}
+
+	if targetMap, enconterError := model.ListTargetWithSelector(targetQuery, shardQuery); enconterError == nil {
 		query.RawSuccessResponse(c, targetMap)
 		return
 	}
@@ -0,0 +1,190 @@
+// Package validation 按配置的策略校验 target 的 labels、params 与 selectors，
+// 收集所有违规项后统一返回，接口以结构化的 400 响应返回给调用方
+package validation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+)
+
+// ReservedPrefix Prometheus 保留的标签前缀，如 __address__、__metrics_path__
+const ReservedPrefix = "__"
+
+var (
+	labelNameRe = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+	// param 在 SD 中以 __param_<name> 输出，名称可以以数字开头
+	paramNameRe = regexp.MustCompile(`^[a-zA-Z0-9_]+$`)
+)
+
+// Violation 一项违规，Field 为请求中的字段路径，如 targets[0].labels.env
+type Violation struct {
+	Field  string `json:"field"`
+	Value  string `json:"value,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// Error 校验失败时返回的错误，包含所有违规项
+type Error struct {
+	Violations []Violation
+}
+
+func (e *Error) Error() string {
+	parts := make([]string, 0, len(e.Violations))
+	for _, violation := range e.Violations {
+		parts = append(parts, violation.Field+": "+violation.Reason)
+	}
+	return "validation failed: " + strings.Join(parts, "; ")
+}
+
+// Details 返回结构化的违规列表，作为 400 响应的 data
+func (e *Error) Details() interface{} {
+	return e.Violations
+}
+
+// Validator 按策略校验并收集违规项
+type Validator struct {
+	conf       config.ValidationConfig
+	violations []Violation
+}
+
+// New 创建校验器
+func New(conf config.ValidationConfig) *Validator {
+	return &Validator{conf: conf}
+}
+
+func (v *Validator) add(field, value, reason string) {
+	v.violations = append(v.violations, Violation{Field: field, Value: value, Reason: reason})
+}
+
+// Err 没有违规时返回 nil
+func (v *Validator) Err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &Error{Violations: v.violations}
+}
+
+// Labels 校验 label 名称与取值，名称与 CreateLabels 一样按去除空白并转为小写后的结果校验
+func (v *Validator) Labels(field string, labels map[string]string) {
+	for _, key := range sortedKeys(labels) {
+		value := labels[key]
+		name := NormalizeLabelName(key)
+		path := field + "." + key
+		switch {
+		case !labelNameRe.MatchString(name):
+			v.add(path, key, "invalid label name, must match [a-zA-Z_][a-zA-Z0-9_]*")
+			continue
+		case strings.HasPrefix(name, ReservedPrefix) && !v.conf.AllowReservedLabels:
+			v.add(path, key, "label names starting with __ are reserved")
+			continue
+		}
+		value = strings.TrimSpace(value)
+		switch {
+		case value == "":
+			v.add(path, value, "label value must not be empty")
+		case !utf8.ValidString(value):
+			v.add(path, value, "label value must be valid UTF-8")
+		}
+		if allowed, exists := v.allowedValues(name); exists && !contains(allowed, value) {
+			v.add(path, value, fmt.Sprintf("value not allowed, expected one of %s", strings.Join(allowed, ", ")))
+		}
+	}
+}
+
+// Params 校验参数名称，参数在 SD 中以 __param_<name> 输出
+func (v *Validator) Params(field string, params map[string]string) {
+	for _, key := range sortedKeys(params) {
+		if !paramNameRe.MatchString(key) {
+			v.add(field+"."+key, key, "invalid param name, must match [a-zA-Z0-9_]+")
+		}
+		if !utf8.ValidString(params[key]) {
+			v.add(field+"."+key, params[key], "param value must be valid UTF-8")
+		}
+	}
+}
+
+// Selectors 校验 selector，selector 以 key=value 及 /key/value 的形式出现在接口中
+func (v *Validator) Selectors(field string, selectors map[string]string) {
+	for _, key := range sortedKeys(selectors) {
+		value := selectors[key]
+		path := field + "." + key
+		if strings.TrimSpace(key) == "" || strings.ContainsAny(key, "=/ ") {
+			v.add(path, key, "invalid selector key, must not be empty or contain '=', '/' or spaces")
+		}
+		if strings.TrimSpace(value) == "" || strings.ContainsAny(value, "/") || !utf8.ValidString(value) {
+			v.add(path, value, "invalid selector value, must not be empty or contain '/'")
+		}
+	}
+}
+
+// RequiredLabels 检查 target 是否设置了所属 selector 要求的 label
+func (v *Validator) RequiredLabels(field string, selectors, labels map[string]string) {
+	present := make(map[string]bool, len(labels))
+	for key, value := range labels {
+		if strings.TrimSpace(value) != "" {
+			present[NormalizeLabelName(key)] = true
+		}
+	}
+	for _, required := range v.conf.Required {
+		key, value, _ := strings.Cut(required.Selector, "=")
+		if selectors[key] != value {
+			continue
+		}
+		for _, name := range required.Labels {
+			if !present[NormalizeLabelName(name)] {
+				v.add(field+"."+name, "", fmt.Sprintf("label is required for targets of selector %s", required.Selector))
+			}
+		}
+	}
+}
+
+func (v *Validator) allowedValues(name string) ([]string, bool) {
+	for key, values := range v.conf.AllowedValues {
+		if NormalizeLabelName(key) == name {
+			return values, true
+		}
+	}
+	return nil, false
+}
+
+// Target 校验创建 target 的请求，targets 中的 labels 与 params 需要已合并 profile
+func Target(conf config.ValidationConfig, selectors map[string]string, items []target.TargetItem) error {
+	v := New(conf)
+	v.Selectors("selectors", selectors)
+	for i := range items {
+		field := fmt.Sprintf("targets[%d]", i)
+		v.Labels(field+".labels", items[i].Labels)
+		v.Params(field+".params", items[i].Params)
+		v.RequiredLabels(field+".labels", selectors, items[i].Labels)
+	}
+	return v.Err()
+}
+
+// NormalizeLabelName 与 CreateLabels 保持一致，去除空白并转为小写
+func NormalizeLabelName(name string) string {
+	return strings.ToLower(strings.TrimSpace(name))
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/config"
+)
+
+func violationFields(t *testing.T, err error) []string {
+	t.Helper()
+	var validationError *Error
+	require.True(t, errors.As(err, &validationError), "expected a validation error, got %v", err)
+	fields := make([]string, 0, len(validationError.Violations))
+	for _, violation := range validationError.Violations {
+		fields = append(fields, violation.Field)
+	}
+	return fields
+}
+
+// TestTarget_LabelNames 测试无效的 label 名称与保留的 __ 前缀被拒绝，并收集所有违规项
+func TestTarget_LabelNames(t *testing.T) {
+	// Arrange
+	items := []target.TargetItem{
+		{Address: "10.0.0.1:9100", Labels: map[string]string{"dc": "bj", "Env": "prod"}},
+		{Address: "10.0.0.2:9100", Labels: map[string]string{"__metrics_path__": "/debug", "app-name": "api", "team": " "}},
+	}
+
+	// Act
+	err := Target(config.ValidationConfig{}, map[string]string{"prom": "fed"}, items)
+
+	// Assert
+	assert.Equal(t, []string{"targets[1].labels.__metrics_path__", "targets[1].labels.app-name", "targets[1].labels.team"}, violationFields(t, err))
+}
+
+// TestTarget_AllowReserved 测试配置允许后可以使用 __ 开头的 label
+func TestTarget_AllowReserved(t *testing.T) {
+	// Arrange
+	items := []target.TargetItem{{Address: "10.0.0.1:9100", Labels: map[string]string{"__scheme__": "https"}}}
+
+	// Act
+	err := Target(config.ValidationConfig{AllowReservedLabels: true}, map[string]string{"prom": "fed"}, items)
+
+	// Assert
+	assert.NoError(t, err)
+}
+
+// TestTarget_AllowedValuesAndRequired 测试 label 的取值列表与 selector 要求的 label
+func TestTarget_AllowedValuesAndRequired(t *testing.T) {
+	// Arrange
+	conf := config.ValidationConfig{
+		AllowedValues: map[string][]string{"env": {"prod", "dev"}},
+		Required:      []config.RequiredLabelsConfig{{Selector: "prom=fed", Labels: []string{"env", "team"}}},
+	}
+	items := []target.TargetItem{
+		{Address: "10.0.0.1:9100", Labels: map[string]string{"ENV": "prod", "team": "infra"}},
+		{Address: "10.0.0.2:9100", Labels: map[string]string{"env": "test"}},
+	}
+
+	// Act
+	fedErr := Target(conf, map[string]string{"prom": "fed"}, items)
+	otherErr := Target(conf, map[string]string{"prom": "edge"}, items[:1])
+
+	// Assert
+	assert.Equal(t, []string{"targets[1].labels.env", "targets[1].labels.team"}, violationFields(t, fedErr))
+	assert.NoError(t, otherErr)
+}
+
+// TestTarget_ParamsAndSelectors 测试参数名称与 selector 的格式
+func TestTarget_ParamsAndSelectors(t *testing.T) {
+	// Arrange
+	items := []target.TargetItem{{Address: "10.0.0.1:9115", Params: map[string]string{"module": "http_2xx", "target-url": "x"}}}
+
+	// Act
+	err := Target(config.ValidationConfig{}, map[string]string{"prom": "fed/a", "": "x"}, items)
+
+	// Assert
+	assert.Equal(t, []string{"selectors.", "selectors.prom", "targets[0].params.target-url"}, violationFields(t, err))
+	assert.Contains(t, err.Error(), "validation failed: ")
+}
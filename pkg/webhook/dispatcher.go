@@ -0,0 +1,174 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"k8s.io/klog/v2"
+
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+const (
+	defaultPollInterval   = 2
+	defaultTimeout        = 10
+	defaultMaxAttempts    = 8
+	defaultInitialBackoff = 1
+	defaultMaxBackoff     = 300
+	// 每轮最多投递的数量
+	batchSize = 100
+)
+
+// Dispatcher 周期性领取到期的投递并发送，失败时按指数退避重试，重试次数用尽后转入 dead letter
+type Dispatcher struct {
+	client         *http.Client
+	pollInterval   time.Duration
+	timeout        time.Duration
+	maxAttempts    int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	now            func() time.Time
+}
+
+// NewDispatcher 根据配置创建投递器，未配置的项使用默认值
+func NewDispatcher(conf config.WebhookConfig) *Dispatcher {
+	seconds := func(value, defaultValue int) time.Duration {
+		if value <= 0 {
+			value = defaultValue
+		}
+		return time.Duration(value) * time.Second
+	}
+	maxAttempts := conf.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+	d := &Dispatcher{
+		pollInterval:   seconds(conf.PollInterval, defaultPollInterval),
+		timeout:        seconds(conf.Timeout, defaultTimeout),
+		maxAttempts:    maxAttempts,
+		initialBackoff: seconds(conf.InitialBackoff, defaultInitialBackoff),
+		maxBackoff:     seconds(conf.MaxBackoff, defaultMaxBackoff),
+		now:            func() time.Time { return time.Now().UTC() },
+	}
+	d.client = &http.Client{Timeout: d.timeout}
+	return d
+}
+
+// Run 按 poll interval 周期性投递，直到 stopCh 关闭
+func (d *Dispatcher) Run(stopCh <-chan struct{}) {
+	klog.V(0).Infof("Dispatching webhook deliveries every %s", d.pollInterval)
+	ticker := time.NewTicker(d.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := d.Dispatch(); err != nil {
+			klog.Errorf("Failed to dispatch webhook deliveries: %v", err)
+		}
+		select {
+		case <-stopCh:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Dispatch 投递一轮到期的事件，返回投递成功的数量
+func (d *Dispatcher) Dispatch() (delivered int, encounterError error) {
+	for i := 0; i < batchSize; i++ {
+		// 每次只领取一个投递，租约覆盖一次投递的超时；投递器异常退出后其他投递器可以在租约到期后接手
+		delivery, err := model.ClaimWebhookDelivery(d.now(), 2*d.timeout)
+		if err != nil {
+			return delivered, err
+		}
+		if delivery == nil {
+			return delivered, nil
+		}
+		succeeded, err := d.deliver(delivery)
+		if err != nil {
+			if errors.Is(err, model.ErrWebhookLeaseLost) {
+				klog.Warningf("webhook %s delivery %d: %v", delivery.Webhook.Name, delivery.ID, err)
+				continue
+			}
+			encounterError = err
+			continue
+		}
+		if succeeded {
+			delivered++
+		}
+	}
+	return delivered, encounterError
+}
+
+// deliver 发送一次投递并记录结果，返回是否投递成功
+func (d *Dispatcher) deliver(delivery *model.WebhookDelivery) (bool, error) {
+	statusCode, err := d.send(delivery)
+	if err == nil {
+		return true, model.CompleteWebhookDelivery(delivery, statusCode, d.now())
+	}
+
+	klog.V(2).Infof("webhook %s delivery %d attempt %d failed: %v", delivery.Webhook.Name, delivery.ID, delivery.Attempts+1, err)
+	var nextAttemptAt time.Time
+	if delivery.Attempts+1 < d.maxAttempts {
+		nextAttemptAt = d.now().Add(d.backoff(delivery.Attempts + 1))
+	} else {
+		klog.Warningf("webhook %s delivery %d moved to dead letter after %d attempts: %v", delivery.Webhook.Name, delivery.ID, delivery.Attempts+1, err)
+	}
+	return false, model.FailWebhookDelivery(delivery, statusCode, err.Error(), nextAttemptAt)
+}
+
+// backoff 第 attempt 次失败后的等待时间，initial * 2^(attempt-1)，不超过 max
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	wait := d.initialBackoff
+	for i := 1; i < attempt && wait < d.maxBackoff; i++ {
+		wait *= 2
+	}
+	if wait > d.maxBackoff {
+		wait = d.maxBackoff
+	}
+	return wait
+}
+
+func (d *Dispatcher) send(delivery *model.WebhookDelivery) (int, error) {
+	// 订阅在投递前被删除
+	if delivery.Webhook.ID == 0 {
+		return 0, fmt.Errorf("webhook %d no longer exists", delivery.WebhookID)
+	}
+	body := []byte(delivery.Payload)
+	request, err := http.NewRequest(http.MethodPost, delivery.Webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(webhook.HeaderEvent, delivery.Event)
+	request.Header.Set(webhook.HeaderDelivery, delivery.EventID)
+	if secret := string(delivery.Webhook.Secret); secret != "" {
+		request.Header.Set(webhook.HeaderSignature, Sign(secret, body))
+	}
+
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return response.StatusCode, fmt.Errorf("received status: %s", response.Status)
+	}
+	return response.StatusCode, nil
+}
+
+// Sign 计算请求体的签名，接收方使用相同的 secret 计算并比较 X-Pantheon-Signature
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,180 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/cylonchau/pantheon/pkg/api/target"
+	"github.com/cylonchau/pantheon/pkg/api/webhook"
+	"github.com/cylonchau/pantheon/pkg/config"
+	"github.com/cylonchau/pantheon/pkg/model"
+)
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// newReceiver 启动本地的 webhook 接收端，按顺序返回 statuses 中的状态码，用完后返回 200
+func newReceiver(t *testing.T, statuses ...int) (*httptest.Server, func() []receivedRequest) {
+	var mu sync.Mutex
+	var received []receivedRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		received = append(received, receivedRequest{header: r.Header.Clone(), body: body})
+		status := http.StatusOK
+		if len(received) <= len(statuses) {
+			status = statuses[len(received)-1]
+		}
+		mu.Unlock()
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []receivedRequest {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]receivedRequest(nil), received...)
+	}
+}
+
+// newTestDispatcher 创建使用可控时钟的投递器
+func newTestDispatcher(conf config.WebhookConfig) (*Dispatcher, *time.Time) {
+	d := NewDispatcher(conf)
+	now := time.Now().UTC()
+	d.now = func() time.Time { return now }
+	return d, &now
+}
+
+// TestDispatch_SignedDelivery 测试事件提交后被投递，请求体携带正确的 HMAC 签名与事件 header
+func TestDispatch_SignedDelivery(t *testing.T) {
+	// Arrange
+	_ = model.SetupTestDB(t)
+	server, received := newReceiver(t)
+	_, err := model.CreateWebhook(&webhook.Webhook{Name: "cmdb", URL: server.URL, Selector: "prom=fed", Secret: "s3cret"})
+	require.NoError(t, err)
+	created, err := model.UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+	d, _ := newTestDispatcher(config.WebhookConfig{})
+
+	// Act
+	delivered, dispatchErr := d.Dispatch()
+	again, againErr := d.Dispatch()
+
+	// Assert
+	require.NoError(t, dispatchErr)
+	require.NoError(t, againErr)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, 0, again, "succeeded deliveries are not sent again")
+
+	requests := received()
+	require.Len(t, requests, 1)
+	assert.Equal(t, webhook.EventTargetCreated, requests[0].header.Get(webhook.HeaderEvent))
+	assert.Equal(t, Sign("s3cret", requests[0].body), requests[0].header.Get(webhook.HeaderSignature))
+	var event webhook.Event
+	require.NoError(t, json.Unmarshal(requests[0].body, &event))
+	assert.Equal(t, requests[0].header.Get(webhook.HeaderDelivery), event.ID)
+	require.NotNil(t, event.Target)
+	assert.Equal(t, created[0].ID, event.Target.ID)
+	assert.Equal(t, map[string]string{"prom": "fed"}, event.Target.Selectors)
+
+	deliveries, err := model.ListWebhookDeliveries("cmdb", &webhook.QueryDeliveries{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, webhook.StatusSucceeded, deliveries[0].Status)
+	assert.Equal(t, 1, deliveries[0].Attempts)
+	assert.Equal(t, http.StatusOK, deliveries[0].LastStatusCode)
+	assert.NotNil(t, deliveries[0].DeliveredAt)
+}
+
+// TestDispatch_RetryBackoffAndDeadLetter 测试失败的投递按指数退避重试，重试次数用尽后进入 dead letter 并可以重新投递
+func TestDispatch_RetryBackoffAndDeadLetter(t *testing.T) {
+	// Arrange
+	_ = model.SetupTestDB(t)
+	server, received := newReceiver(t, http.StatusInternalServerError, http.StatusBadGateway, http.StatusServiceUnavailable)
+	_, err := model.CreateWebhook(&webhook.Webhook{Name: "flaky", URL: server.URL})
+	require.NoError(t, err)
+	_, err = model.UpsertTargets(&target.Target{
+		InstanceSelector: map[string]string{"prom": "fed"},
+		Targets:          []target.TargetItem{{Address: "10.0.0.1:9100"}},
+	})
+	require.NoError(t, err)
+	d, now := newTestDispatcher(config.WebhookConfig{MaxAttempts: 3, InitialBackoff: 10, MaxBackoff: 15})
+
+	// Act & Assert
+	// 第一次失败后等待 10 秒
+	_, err = d.Dispatch()
+	require.NoError(t, err)
+	deliveries, err := model.ListWebhookDeliveries("flaky", &webhook.QueryDeliveries{})
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+	assert.Equal(t, webhook.StatusPending, deliveries[0].Status)
+	assert.Equal(t, http.StatusInternalServerError, deliveries[0].LastStatusCode)
+	require.NotNil(t, deliveries[0].NextAttemptAt)
+	assert.WithinDuration(t, now.Add(10*time.Second), *deliveries[0].NextAttemptAt, time.Second)
+
+	// 未到重试时间时不投递
+	*now = now.Add(5 * time.Second)
+	_, err = d.Dispatch()
+	require.NoError(t, err)
+	assert.Len(t, received(), 1)
+
+	// 第二次失败后等待时间翻倍，但不超过 max_backoff
+	*now = now.Add(5 * time.Second)
+	_, err = d.Dispatch()
+	require.NoError(t, err)
+	deliveries, err = model.ListWebhookDeliveries("flaky", &webhook.QueryDeliveries{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, deliveries[0].Attempts)
+	assert.WithinDuration(t, now.Add(15*time.Second), *deliveries[0].NextAttemptAt, time.Second)
+
+	// 第三次失败后重试次数用尽
+	*now = now.Add(15 * time.Second)
+	_, err = d.Dispatch()
+	require.NoError(t, err)
+	assert.Len(t, received(), 3)
+	dead, err := model.ListWebhookDeliveries("flaky", &webhook.QueryDeliveries{Status: webhook.StatusDead})
+	require.NoError(t, err)
+	require.Len(t, dead, 1)
+	letters, err := model.ListWebhookDeadLetters()
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, "flaky", letters[0].Webhook)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Contains(t, letters[0].LastError, "503")
+
+	// 重新投递后接收端恢复，投递成功
+	require.NoError(t, model.RedeliverWebhookDeadLetter(letters[0].ID))
+	*now = time.Now().UTC().Add(time.Second)
+	delivered, err := d.Dispatch()
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Len(t, received(), 4)
+	letters, err = model.ListWebhookDeadLetters()
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+// TestDispatcherBackoff 测试退避时间按 initial * 2^(attempt-1) 增长并以 max 为上限
+func TestDispatcherBackoff(t *testing.T) {
+	// Arrange
+	d := NewDispatcher(config.WebhookConfig{InitialBackoff: 1, MaxBackoff: 10})
+
+	// Act & Assert
+	assert.Equal(t, time.Second, d.backoff(1))
+	assert.Equal(t, 2*time.Second, d.backoff(2))
+	assert.Equal(t, 8*time.Second, d.backoff(4))
+	assert.Equal(t, 10*time.Second, d.backoff(5))
+	assert.Equal(t, 10*time.Second, d.backoff(30))
+}